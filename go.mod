@@ -14,7 +14,9 @@ require (
 	google.golang.org/api v0.115.0
 	k8s.io/klog/v2 v2.100.1
 	k8s.io/release v0.15.1
+	k8s.io/utils v0.0.0-20230726121419-3b25d923346b
 	sigs.k8s.io/boskos v0.0.0-20230524062849-a7ef97ee445d
+	sigs.k8s.io/yaml v1.3.0
 )
 
 require (
@@ -301,12 +303,10 @@ require (
 	k8s.io/client-go v0.26.2 // indirect
 	k8s.io/kube-openapi v0.0.0-20230501164219-8b0f38b5fd1f // indirect
 	k8s.io/test-infra v0.0.0-20220913174101-46ac1a6cf806 // indirect
-	k8s.io/utils v0.0.0-20230726121419-3b25d923346b // indirect
 	sigs.k8s.io/bom v0.4.1 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/promo-tools/v3 v3.5.2 // indirect
 	sigs.k8s.io/release-sdk v0.10.0 // indirect
 	sigs.k8s.io/release-utils v0.7.3 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
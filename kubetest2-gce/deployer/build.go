@@ -25,7 +25,6 @@ import (
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/build"
-	"sigs.k8s.io/kubetest2/pkg/exec"
 )
 
 func (d *deployer) Build() error {
@@ -63,21 +62,19 @@ func (d *deployer) Build() error {
 		// this code path supports the kubernetes/cloud-provider-gcp build
 		klog.V(2).Info("starting the build")
 
-		var cmd exec.Cmd
+		var err error
 		// determine the build system for kubernetes/cloud-provider-gcp
-		if _, err := os.Stat(path.Join(d.RepoRoot, "Makefile")); err == nil {
+		buildOptions := d.BuildOptions.CommonBuildOptions
+		if _, statErr := os.Stat(path.Join(d.RepoRoot, "Makefile")); statErr == nil {
 			// For releases that uses Makefile
-			cmd = exec.Command("make", "release-tars")
-		} else if _, err := os.Stat(path.Join(d.RepoRoot, "BUILD")); err == nil {
+			err = d.run(d.RepoRoot, append(os.Environ(), build.GoCacheProgEnv(buildOptions.GoCacheProg)...), "make", "release-tars")
+		} else if _, statErr := os.Stat(path.Join(d.RepoRoot, "BUILD")); statErr == nil {
 			// For releases that uses Bazel
-			cmd = exec.Command("bazel", "build", "//release:release-tars")
+			args := append([]string{"build"}, build.BazelRemoteCacheArgs(buildOptions.BazelRemoteCache)...)
+			err = d.run(d.RepoRoot, os.Environ(), "bazel", append(args, "//release:release-tars")...)
 		} else {
 			return fmt.Errorf("cannot determine build system")
 		}
-
-		exec.InheritOutput(cmd)
-		cmd.SetDir(d.RepoRoot)
-		err := cmd.Run()
 		if err != nil {
 			return fmt.Errorf("error during make step of build: %s", err)
 		}
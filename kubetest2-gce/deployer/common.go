@@ -22,14 +22,15 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/boskos"
+	"sigs.k8s.io/kubetest2/pkg/build"
 )
 
 const (
+	// gceProjectResourceType is the default boskos resource type of projects
 	gceProjectResourceType = "gce-project"
 )
 
@@ -52,28 +53,35 @@ func (d *deployer) initialize() error {
 			return fmt.Errorf("init failed to verify flags for up: %s", err)
 		}
 
-		if d.GCPProject == "" {
+		if d.GCPProject == "" && d.BoskosLocation == "" && len(d.FallbackGCPProjects) > 0 {
+			klog.V(1).Info("No GCP project or boskos location provided, acquiring a fallback project")
+			if err := d.acquireFallbackProject(); err != nil {
+				return err
+			}
+		} else if d.GCPProject == "" {
 			klog.V(1).Info("No GCP project provided, acquiring from Boskos")
 
-			boskosClient, err := boskos.NewClient(d.BoskosLocation)
+			lease, err := d.NewLease()
 			if err != nil {
 				return fmt.Errorf("failed to make boskos client: %s", err)
 			}
-			d.boskos = boskosClient
-
-			resource, err := boskos.Acquire(
-				d.boskos,
-				gceProjectResourceType,
-				time.Duration(d.BoskosAcquireTimeoutSeconds)*time.Second,
-				time.Duration(d.BoskosHeartbeatIntervalSeconds)*time.Second,
-				d.boskosHeartbeatClose,
-			)
+			d.lease = lease
 
+			resources, err := d.lease.Acquire(d.BoskosResourceType, 1)
 			if err != nil {
-				return fmt.Errorf("init failed to get project from boskos: %s", err)
+				if len(d.FallbackGCPProjects) == 0 {
+					return fmt.Errorf("init failed to get project from boskos: %s", err)
+				}
+				klog.Warningf("failed to get project from boskos, acquiring a fallback project instead: %v", err)
+				d.lease = nil
+				if err := d.acquireFallbackProject(); err != nil {
+					return err
+				}
+			} else {
+				d.GCPProject = resources[0].Name
+				klog.V(1).Infof("Got project %s from boskos", d.GCPProject)
+				d.usePreProvisioned(boskos.ParseProjectUserData(resources[0]))
 			}
-			d.GCPProject = resource.Name
-			klog.V(1).Infof("Got project %s from boskos", d.GCPProject)
 		}
 
 	}
@@ -87,6 +95,33 @@ func (d *deployer) initialize() error {
 	return nil
 }
 
+// acquireFallbackProject acquires a project from --fallback-gcp-projects
+// instead of boskos
+func (d *deployer) acquireFallbackProject() error {
+	projects, err := boskos.AcquireFallback(boskos.NewLocker(d.ProjectLockLocation), d.FallbackGCPProjects, 1)
+	if err != nil {
+		return fmt.Errorf("init failed to get fallback project: %s", err)
+	}
+	d.fallbackProject = projects[0]
+	d.GCPProject = d.fallbackProject
+	return nil
+}
+
+// usePreProvisioned uses the setup pre-provisioned in the boskos project
+// instead of creating it
+func (d *deployer) usePreProvisioned(userData boskos.ProjectUserData) {
+	if userData.Network != "" {
+		klog.V(1).Infof("Using network %s pre-provisioned in project %s", userData.Network, d.GCPProject)
+		d.network = userData.Network
+		d.subnetwork = userData.Subnetwork
+		d.preProvisionedNetwork = true
+	}
+	if userData.ServiceAccount != "" && d.NodeServiceAccount == "" {
+		klog.V(1).Infof("Using node service account %s pre-provisioned in project %s", userData.ServiceAccount, d.GCPProject)
+		d.NodeServiceAccount = userData.ServiceAccount
+	}
+}
+
 func (d *deployer) buildEnv() []string {
 	// The base env currently does not inherit the current os env (except for PATH)
 	// because (for now) it doesn't have to. In future, this may have to change when
@@ -166,14 +201,40 @@ func (d *deployer) buildEnv() []string {
 		env = append(env, "CREATE_CUSTOM_NETWORK=true")
 	}
 
+	if d.subnetwork != "" {
+		env = append(env, fmt.Sprintf("SUBNETWORK=%s", d.subnetwork))
+	}
+
+	// the network pre-provisioned with the project outlives the cluster
+	if d.preProvisionedNetwork {
+		env = append(env, "KUBE_DELETE_NETWORK=false")
+	}
+
 	// MASTER_SIZE and NODE_SIZE are used by kube-up script to decide on the
 	// shape of the cluster. We want to overwrite them only when they are set.
-	// Otherwise, let's use script default.
-	if d.MasterSize != "" {
-		env = append(env, fmt.Sprintf("MASTER_SIZE=%s", d.MasterSize))
+	// Otherwise, let's use script default, unless the script default does not
+	// match the architecture of the build.
+	masterSize, nodeSize := d.MasterSize, d.NodeSize
+	if machineType, ok := archMachineTypes[build.NodeArch(d.BuildOptions.CommonBuildOptions.TargetBuildArch)]; ok {
+		if masterSize == "" {
+			masterSize = machineType
+		}
+		if nodeSize == "" {
+			nodeSize = machineType
+		}
+	}
+	if masterSize != "" {
+		env = append(env, fmt.Sprintf("MASTER_SIZE=%s", masterSize))
+	}
+	if nodeSize != "" {
+		env = append(env, fmt.Sprintf("NODE_SIZE=%s", nodeSize))
+	}
+	if d.MasterImage != "" {
+		env = append(env, fmt.Sprintf("KUBE_GCE_MASTER_IMAGE=%s", d.MasterImage))
 	}
-	if d.NodeSize != "" {
-		env = append(env, fmt.Sprintf("NODE_SIZE=%s", d.NodeSize))
+	// the FIPS enabled image takes precedence for the nodes
+	if d.NodeImage != "" && !d.FIPSNodes() {
+		env = append(env, fmt.Sprintf("KUBE_GCE_NODE_IMAGE=%s", d.NodeImage))
 	}
 
 	// KUBECTL_PATH points to the kubectl existing in $PATH
@@ -210,8 +271,15 @@ func (d *deployer) buildEnv() []string {
 		env = append(env, fmt.Sprintf("KUBE_FEATURE_GATES=%s", d.FeatureGates))
 	}
 
+	if d.FIPSNodes() {
+		env = append(env, "KUBE_NODE_OS_DISTRIBUTION=ubuntu")
+		env = append(env, fmt.Sprintf("KUBE_GCE_NODE_IMAGE=%s", d.FIPSNodeImage))
+		env = append(env, fmt.Sprintf("KUBE_GCE_NODE_PROJECT=%s", d.FIPSNodeImageProject))
+	}
+
 	if d.BuildOptions.CommonBuildOptions.TargetBuildArch != "" {
-		env = append(env, fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", d.BuildOptions.CommonBuildOptions.TargetBuildArch))
+		platforms := build.Platforms(d.BuildOptions.CommonBuildOptions.TargetBuildArch)
+		env = append(env, fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", strings.Join(platforms, " ")))
 	}
 
 	if d.Env != nil {
@@ -225,6 +293,18 @@ func (d *deployer) buildEnv() []string {
 	return env
 }
 
+// FIPSNodes returns true if the nodes run --fips-node-image with --fips,
+// implementing types.DeployerWithFIPS
+func (d *deployer) FIPSNodes() bool {
+	return d.commonOptions.FIPS() && d.FIPSNodeImage != ""
+}
+
+// archMachineTypes are the default machine types of the architectures whose
+// VMs the kube-up script defaults do not run on, nor its default images
+var archMachineTypes = map[string]string{
+	"arm64": "t2a-standard-2",
+}
+
 // Taken from the kubetest bash (gce) deployer
 // Calculates the cluster IP range based on the no. of nodes in the cluster.
 // Note: This mimics the function get-cluster-ip-range used by kube-up script.
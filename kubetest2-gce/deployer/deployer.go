@@ -29,11 +29,11 @@ import (
 
 	"k8s.io/klog/v2"
 
-	"sigs.k8s.io/boskos/client"
-
 	"sigs.k8s.io/kubetest2/kubetest2-gce/deployer/options"
 	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/boskos"
 	"sigs.k8s.io/kubetest2/pkg/build"
+	"sigs.k8s.io/kubetest2/pkg/inventory"
 	"sigs.k8s.io/kubetest2/pkg/types"
 )
 
@@ -54,33 +54,44 @@ type deployer struct {
 	kubectlPath    string
 	logsDir        string
 
-	// boskos struct field will be non-nil when the deployer is
+	// lease will be non-nil when the deployer is
 	// using boskos to acquire a GCP project
-	boskos *client.Client
+	lease *boskos.Lease
 
-	// this channel serves as a signal channel for the hearbeat goroutine
-	// so that it can be explicitly closed
-	boskosHeartbeatClose chan struct{}
+	// fallbackProject is the project acquired from --fallback-gcp-projects
+	// when boskos is unreachable
+	fallbackProject string
 
 	// instancePrefix is set for a mandatory env and for firewall rule creation
 	// see buildEnv() and nodeTag()
 	instancePrefix string
 	// network is set for firewall rule creation, see buildEnv() and firewall.go
 	network string
+	// subnetwork and preProvisionedNetwork are set when the boskos project
+	// comes with a network, see usePreProvisioned()
+	subnetwork            string
+	preProvisionedNetwork bool
 
 	// env is passed to buildEnv() function, many env variables are set by other flags
 	Env []string `desc:"A list on env variables to pass to the kube-*.sh scripts"`
 
-	BoskosAcquireTimeoutSeconds    int    `desc:"How long (in seconds) to hang on a request to Boskos to acquire a resource before erroring."`
-	BoskosHeartbeatIntervalSeconds int    `desc:"How often (in seconds) to send a heartbeat to Boskos to hold the acquired resource. 0 means no heartbeat."`
-	RepoRoot                       string `desc:"The path to the root of the local kubernetes/cloud-provider-gcp repo. Necessary to call certain scripts. Defaults to the current directory. If operating in legacy mode, this should be set to the local kubernetes/kubernetes repo."`
-	GCPProject                     string `desc:"GCP Project to create VMs in. If unset, the deployer will attempt to get a project from boskos."`
-	GCPZone                        string `desc:"GCP Zone to create VMs in. If unset, kube-up.sh and kube-down.sh defaults apply."`
-	EnableComputeAPI               bool   `desc:"If set, the deployer will enable the compute API for the project during the Up phase. This is necessary if the project has not been used before. WARNING: The currently configured GCP account must have permission to enable this API on the configured project."`
-	OverwriteLogsDir               bool   `desc:"If set, will overwrite an existing logs directory if one is encountered during dumping of logs. Useful when runnning tests locally."`
-	BoskosLocation                 string `desc:"If set, manually specifies the location of the boskos server. If unset and boskos is needed, defaults to http://boskos.test-pods.svc.cluster.local."`
-	LegacyMode                     bool   `desc:"Set if the provided repo root is the kubernetes/kubernetes repo and not kubernetes/cloud-provider-gcp."`
-	NumNodes                       int    `desc:"The number of nodes in the cluster."`
+	*boskos.Options
+
+	RepoRoot           string `desc:"The path to the root of the local kubernetes/cloud-provider-gcp repo. Necessary to call certain scripts. Defaults to the current directory. If operating in legacy mode, this should be set to the local kubernetes/kubernetes repo."`
+	GCPProject         string `desc:"GCP Project to create VMs in. If unset, the deployer will attempt to get a project from boskos."`
+	GCPZone            string `desc:"GCP Zone to create VMs in. If unset, kube-up.sh and kube-down.sh defaults apply."`
+	EnableComputeAPI   bool   `desc:"If set, the deployer will enable the compute API for the project during the Up phase. This is necessary if the project has not been used before. WARNING: The currently configured GCP account must have permission to enable this API on the configured project."`
+	OverwriteLogsDir   bool   `desc:"If set, will overwrite an existing logs directory if one is encountered during dumping of logs. Useful when runnning tests locally."`
+	BoskosResourceType string `desc:"The resource type of the GCP project to acquire from boskos, e.g. gce-project or ingress-project."`
+	LegacyMode         bool   `desc:"Set if the provided repo root is the kubernetes/kubernetes repo and not kubernetes/cloud-provider-gcp."`
+	UsePTY             bool   `desc:"If set, kube-up.sh and the kubernetes/cloud-provider-gcp build run in a pseudo-terminal, for tools that prompt or behave differently without one. Their output is still recorded in the artifacts."`
+	NumNodes           int    `desc:"The number of nodes in the cluster."`
+
+	ControlPlaneVersion string `desc:"If set, the control plane is moved to this kubernetes release version after kube-up.sh, e.g. v1.30.2, with cluster/gce/upgrade.sh -M."`
+	NodeVersion         string `desc:"If set, the nodes are moved to this kubernetes release version after kube-up.sh, e.g. v1.29.6 for kubelets one minor version behind the control plane, with cluster/gce/upgrade.sh -N."`
+
+	FallbackGCPProjects []string `desc:"GCP projects to use when acquiring a project from boskos fails, or instead of boskos if --boskos-location is empty. The projects are arbitrated between runs by locks in --project-lock-location."`
+	ProjectLockLocation string   `desc:"Location of the locks of --fallback-gcp-projects, a local directory or gs://bucket/path to share the projects between hosts. Defaults to a directory in the temp dir."`
 
 	EnableCacheMutationDetector bool   `desc:"Sets the environment variable ENABLE_CACHE_MUTATION_DETECTOR=true during deployment. This should cause a panic if anything mutates a shared informer cache."`
 	RuntimeConfig               string `desc:"Sets the KUBE_RUNTIME_CONFIG environment variable during deployment."`
@@ -91,10 +102,16 @@ type deployer struct {
 	CloudProvider               string `desc:"Sets the CLOUD_PROVIDER environment variable during deployment."`
 	FeatureGates                string `desc:"Sets the KUBE_FEATURE_GATES environment variable during deployment."`
 
-	MasterSize string `desc:"Sets the MASTER_SIZE environment variable during deployment."`
-	NodeSize   string `desc:"Sets the NODE_SIZE environment variable during deployment."`
+	MasterSize string `desc:"Sets the MASTER_SIZE environment variable during deployment. Defaults to an arm64 machine type, t2a-standard-2, if --target-build-arch is linux/arm64."`
+	NodeSize   string `desc:"Sets the NODE_SIZE environment variable during deployment. Defaults to an arm64 machine type, t2a-standard-2, if --target-build-arch is linux/arm64."`
+
+	MasterImage string `desc:"Sets the KUBE_GCE_MASTER_IMAGE environment variable during deployment. Required if --target-build-arch is linux/arm64, as the default image is x86, e.g. an arm64 COS image of the cos-cloud project."`
+	NodeImage   string `desc:"Sets the KUBE_GCE_NODE_IMAGE environment variable during deployment. Required if --target-build-arch is linux/arm64, as the default image is x86, unless the nodes run --fips-node-image."`
 
 	IngressGCEImage string `desc:"Sets the ingress-gce image used for the Ingress and Loadbalancer controller."`
+
+	FIPSNodeImage        string `desc:"The FIPS enabled image of the nodes with --fips, e.g. an Ubuntu Pro FIPS image. Sets the KUBE_GCE_NODE_IMAGE environment variable during deployment, with KUBE_NODE_OS_DISTRIBUTION=ubuntu."`
+	FIPSNodeImageProject string `desc:"The project of --fips-node-image. Sets the KUBE_GCE_NODE_PROJECT environment variable during deployment."`
 }
 
 // pseudoUniqueSubstring returns a substring of a UUID
@@ -128,18 +145,19 @@ func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
 				Stager:          &build.NoopStager{},
 				Strategy:        "make",
 				TargetBuildArch: "linux/amd64",
+				// kube-up needs the release tars
+				RequiredBuildTargets: []string{build.ReleaseTarsTarget},
 			},
 		},
-		kubeconfigPath:       filepath.Join(opts.RunDir(), "kubetest2-kubeconfig"),
-		logsDir:              filepath.Join(artifacts.BaseDir(), "cluster-logs"),
-		boskosHeartbeatClose: make(chan struct{}),
+		kubeconfigPath: filepath.Join(opts.RunDir(), "kubetest2-kubeconfig"),
+		logsDir:        filepath.Join(artifacts.BaseDir(), "cluster-logs"),
 		// names need to start with an alphabet
-		instancePrefix:                 "kt2-" + pseudoUniqueSubstring(opts.RunID()),
-		network:                        "kt2-" + pseudoUniqueSubstring(opts.RunID()),
-		BoskosAcquireTimeoutSeconds:    5 * 60,
-		BoskosHeartbeatIntervalSeconds: 5 * 60,
-		BoskosLocation:                 "http://boskos.test-pods.svc.cluster.local.",
-		NumNodes:                       3,
+		instancePrefix:       "kt2-" + pseudoUniqueSubstring(opts.RunID()),
+		network:              "kt2-" + pseudoUniqueSubstring(opts.RunID()),
+		Options:              boskos.NewOptions(),
+		BoskosResourceType:   gceProjectResourceType,
+		NumNodes:             3,
+		FIPSNodeImageProject: "ubuntu-os-pro-cloud",
 	}
 
 	flagSet, err := gpflag.Parse(d)
@@ -159,10 +177,74 @@ var _ types.NewDeployer = New
 // assert that deployer implements types.Deployer
 var _ types.Deployer = &deployer{}
 
+// assert that deployer implements types.DeployerWithTestArgs
+var _ types.DeployerWithTestArgs = &deployer{}
+
+// assert that deployer implements types.DeployerWithVersionSkew
+var _ types.DeployerWithVersionSkew = &deployer{}
+
+// assert that deployer implements types.DeployerWithUpgrades
+var _ types.DeployerWithUpgrades = &deployer{}
+
+// assert that deployer implements types.DeployerWithCloudInventory
+var _ types.DeployerWithCloudInventory = &deployer{}
+
+// assert that deployer implements types.DeployerWithState
+var _ types.DeployerWithState = &deployer{}
+
+// assert that deployer implements types.DeployerWithFIPS
+var _ types.DeployerWithFIPS = &deployer{}
+
+// assert that deployer implements types.DeployerWithFeatures
+var _ types.DeployerWithFeatures = &deployer{}
+
+// assert that deployer implements types.DeployerWithLease
+var _ types.DeployerWithLease = &deployer{}
+
 func (d *deployer) Provider() string {
 	return Name
 }
 
+// TestArgs returns the e2e.test arguments for the cluster, implementing
+// types.DeployerWithTestArgs
+func (d *deployer) TestArgs() ([]string, error) {
+	if d.GCPProject == "" {
+		return nil, fmt.Errorf("no GCP project is known for the cluster")
+	}
+	args := []string{
+		"--provider=" + Name,
+		"--gce-project=" + d.GCPProject,
+		fmt.Sprintf("--num-nodes=%d", d.NumNodes),
+	}
+	// the network is only known to runs that brought the cluster up
+	if d.network != "" {
+		args = append(args, "--network="+d.network)
+	}
+	if d.GCPZone != "" {
+		args = append(args, "--gce-zone="+d.GCPZone)
+	}
+	return args, nil
+}
+
+// Features returns the features of the clusters of the kube-up scripts,
+// implementing types.DeployerWithFeatures
+func (d *deployer) Features() []string {
+	return []string{
+		types.FeatureLoadBalancer,
+		types.FeaturePersistentVolumes,
+		types.FeatureNodeSSH,
+	}
+}
+
+// LeaseLost returns a channel closed when the lease of the project acquired
+// from boskos is lost, implementing types.DeployerWithLease
+func (d *deployer) LeaseLost() <-chan struct{} {
+	if d.lease == nil {
+		return nil
+	}
+	return d.lease.Lost()
+}
+
 func (d *deployer) Version() string {
 	return GitTag
 }
@@ -178,3 +260,12 @@ func (d *deployer) Kubeconfig() (string, error) {
 
 	return d.kubeconfigPath, nil
 }
+
+// CloudResources returns the compute resources of the project the tests may
+// leave behind, implementing types.DeployerWithCloudInventory
+func (d *deployer) CloudResources() ([]string, error) {
+	if d.GCPProject == "" {
+		return nil, fmt.Errorf("no GCP project is known for the cluster")
+	}
+	return inventory.GCPResources(d.GCPProject)
+}
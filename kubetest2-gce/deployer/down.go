@@ -55,18 +55,20 @@ func (d *deployer) Down() error {
 	// ideally these should already be deleted by kube-down
 	d.deleteFirewallRuleNodePort()
 
-	if d.boskos != nil {
+	if d.lease != nil {
 		klog.V(2).Info("releasing boskos project")
-		err := boskos.Release(
-			d.boskos,
-			[]string{d.GCPProject},
-			d.boskosHeartbeatClose,
-		)
-		if err != nil {
+		if err := d.lease.Release(); err != nil {
 			return fmt.Errorf("down failed to release boskos project: %s", err)
 		}
 	}
 
+	if d.fallbackProject != "" {
+		klog.V(2).Info("releasing fallback project")
+		if err := boskos.ReleaseFallback(boskos.NewLocker(d.ProjectLockLocation), []string{d.fallbackProject}); err != nil {
+			return fmt.Errorf("down failed to release fallback project: %s", err)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// skewVersions moves the control plane and the nodes brought up by
+// kube-up.sh to --control-plane-version and --node-version, if set, so that
+// the cluster runs with the version skew to test.
+func (d *deployer) skewVersions() error {
+	if d.ControlPlaneVersion != "" {
+		if err := d.moveVersion(controlPlanePart, d.ControlPlaneVersion); err != nil {
+			return fmt.Errorf("failed to apply --control-plane-version: %v", err)
+		}
+	}
+	if d.NodeVersion != "" {
+		if err := d.moveVersion(nodesPart, d.NodeVersion); err != nil {
+			return fmt.Errorf("failed to apply --node-version: %v", err)
+		}
+	}
+	return nil
+}
+
+// the upgrade.sh flags selecting the part of the cluster to move
+const (
+	controlPlanePart = "-M"
+	nodesPart        = "-N"
+)
+
+// moveVersion moves part of the cluster to version with upgrade.sh, which
+// takes older versions as well
+func (d *deployer) moveVersion(part, version string) error {
+	script := filepath.Join(d.RepoRoot, "cluster", "gce", "upgrade.sh")
+	klog.V(1).Infof("Moving the cluster to %s with %s %s", version, script, part)
+	cmd := exec.Command(script, part, version)
+	cmd.SetEnv(d.buildEnv()...)
+	// upgrade.sh asks for confirmation before touching the cluster
+	cmd.SetStdin(strings.NewReader(strings.Repeat("y\n", 10)))
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error encountered during %s %s %s: %v", script, part, version, err)
+	}
+	return nil
+}
+
+// UpgradeSteps returns no steps, the cluster is only upgraded in place with
+// --upgrade-to, implementing types.DeployerWithUpgrades
+func (d *deployer) UpgradeSteps() []types.UpgradeStep {
+	return nil
+}
+
+// Upgrade moves the control plane and then the nodes to version,
+// implementing types.DeployerWithUpgrades
+func (d *deployer) Upgrade(version string) error {
+	if err := d.init(); err != nil {
+		return fmt.Errorf("upgrade failed to init: %s", err)
+	}
+	if err := d.moveVersion(controlPlanePart, version); err != nil {
+		return err
+	}
+	return d.moveVersion(nodesPart, version)
+}
+
+// CurrentVersion returns the version of the apiserver, implementing
+// types.DeployerWithUpgrades
+func (d *deployer) CurrentVersion() (string, error) {
+	if err := d.init(); err != nil {
+		return "", fmt.Errorf("failed to init: %s", err)
+	}
+	if d.kubectlPath == "" {
+		path, err := d.verifyKubectl()
+		if err != nil {
+			return "", err
+		}
+		d.kubectlPath = path
+	}
+	cmd := exec.Command(d.kubectlPath, "version", "-o", "json")
+	cmd.SetEnv(d.buildEnv()...)
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the version of the cluster: %v", err)
+	}
+	return parseServerVersion(out)
+}
+
+// parseServerVersion returns the git version of the server of the output of
+// kubectl version -o json
+func parseServerVersion(out []byte) (string, error) {
+	var version struct {
+		ServerVersion *struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal(out, &version); err != nil {
+		return "", fmt.Errorf("failed to parse the output of kubectl version: %v", err)
+	}
+	if version.ServerVersion == nil || version.ServerVersion.GitVersion == "" {
+		return "", fmt.Errorf("kubectl version reported no server version")
+	}
+	return version.ServerVersion.GitVersion, nil
+}
+
+// VersionSkew returns the versions of the control plane and the nodes,
+// implementing types.DeployerWithVersionSkew
+func (d *deployer) VersionSkew() (controlPlane, node string) {
+	node = d.NodeVersion
+	if node == "" {
+		node = d.ControlPlaneVersion
+	}
+	return d.ControlPlaneVersion, node
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	testCases := []struct {
+		name      string
+		output    string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "server version",
+			output:   `{"clientVersion":{"gitVersion":"v1.31.0"},"serverVersion":{"major":"1","minor":"30","gitVersion":"v1.30.2"}}`,
+			expected: "v1.30.2",
+		},
+		{
+			name:      "no server version",
+			output:    `{"clientVersion":{"gitVersion":"v1.31.0"}}`,
+			expectErr: true,
+		},
+		{
+			name:      "not json",
+			output:    `Client Version: v1.31.0`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		version, err := parseServerVersion([]byte(tc.output))
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: expected error: %v, got %v", tc.name, tc.expectErr, err)
+		}
+		if version != tc.expected {
+			t.Errorf("%s: expected version %q, got %q", tc.name, tc.expected, version)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+)
+
+// state is what Down needs to tear down the cluster of a previous run, see
+// types.DeployerWithState
+type state struct {
+	RepoRoot              string `json:"repoRoot"`
+	LegacyMode            bool   `json:"legacyMode,omitempty"`
+	GCPProject            string `json:"gcpProject"`
+	GCPZone               string `json:"gcpZone,omitempty"`
+	FallbackProject       string `json:"fallbackProject,omitempty"`
+	InstancePrefix        string `json:"instancePrefix"`
+	Network               string `json:"network"`
+	Subnetwork            string `json:"subnetwork,omitempty"`
+	PreProvisionedNetwork bool   `json:"preProvisionedNetwork,omitempty"`
+}
+
+func (d *deployer) State() ([]byte, error) {
+	return json.MarshalIndent(state{
+		RepoRoot:              d.RepoRoot,
+		LegacyMode:            d.LegacyMode,
+		GCPProject:            d.GCPProject,
+		GCPZone:               d.GCPZone,
+		FallbackProject:       d.fallbackProject,
+		InstancePrefix:        d.instancePrefix,
+		Network:               d.network,
+		Subnetwork:            d.subnetwork,
+		PreProvisionedNetwork: d.preProvisionedNetwork,
+	}, "", "  ")
+}
+
+// RestoreState restores the project and the resource names of a previous run.
+// A boskos lease of the previous run is not restored, the project is released
+// by boskos once the lease expires.
+func (d *deployer) RestoreState(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if d.RepoRoot == "" {
+		d.RepoRoot = s.RepoRoot
+	}
+	d.LegacyMode = d.LegacyMode || s.LegacyMode
+	if d.GCPProject == "" {
+		d.GCPProject = s.GCPProject
+		d.fallbackProject = s.FallbackProject
+	}
+	if d.GCPZone == "" {
+		d.GCPZone = s.GCPZone
+	}
+	d.instancePrefix = s.InstancePrefix
+	d.network = s.Network
+	d.subnetwork = s.Subnetwork
+	d.preProvisionedNetwork = s.PreProvisionedNetwork
+	return nil
+}
@@ -23,8 +23,10 @@ import (
 
 	"k8s.io/klog/v2"
 
+	"sigs.k8s.io/kubetest2/pkg/build"
 	"sigs.k8s.io/kubetest2/pkg/exec"
 	"sigs.k8s.io/kubetest2/pkg/fs"
+	"sigs.k8s.io/kubetest2/pkg/process"
 )
 
 const (
@@ -95,11 +97,7 @@ func (d *deployer) Up() error {
 	script := filepath.Join(d.RepoRoot, "cluster", "kube-up.sh")
 	klog.V(2).Infof("About to run script at: %s", script)
 
-	cmd := exec.Command(script)
-	cmd.SetEnv(env...)
-	exec.InheritOutput(cmd)
-
-	if err := cmd.Run(); err != nil {
+	if err := d.run("", env, script); err != nil {
 		return fmt.Errorf("error encountered during %s: %s", script, err)
 	}
 
@@ -111,6 +109,10 @@ func (d *deployer) Up() error {
 		klog.Errorf("cluster reported as down")
 	}
 
+	if err := d.skewVersions(); err != nil {
+		return err
+	}
+
 	klog.V(2).Info("about to create nodeport firewall rule")
 	if err := d.createFirewallRuleNodePort(); err != nil {
 		return fmt.Errorf("failed to create firewall rule: %s", err)
@@ -125,17 +127,10 @@ func enableComputeAPI(project string) error {
 	// already been enabled is a relatively fast no-op,
 	// so this can be called without consequence.
 
-	env := os.Environ()
-	cmd := exec.Command(
-		"gcloud",
-		"services",
-		"enable",
-		"compute.googleapis.com",
-		"--project="+project,
-	)
-	cmd.SetEnv(env...)
-	exec.InheritOutput(cmd)
-	err := cmd.Run()
+	// it is retried as it tends to fail transiently on the API side
+	err := process.ExecWithRetry(process.GCloudRetryPolicy, "gcloud",
+		[]string{"services", "enable", "compute.googleapis.com", "--project=" + project},
+		os.Environ())
 	if err != nil {
 		return fmt.Errorf("failed to enable compute API: %s", err)
 	}
@@ -152,6 +147,22 @@ func (d *deployer) verifyUpFlags() error {
 		return err
 	}
 
+	if d.commonOptions.FIPS() && d.FIPSNodeImage == "" {
+		return fmt.Errorf("--fips requires --fips-node-image, the nodes of kube-up have no FIPS enabled image by default")
+	}
+
+	// the default images of kube-up are x86, which the VMs of other
+	// architectures cannot boot
+	arch := build.NodeArch(d.BuildOptions.CommonBuildOptions.TargetBuildArch)
+	if _, ok := archMachineTypes[arch]; ok {
+		if d.MasterImage == "" {
+			return fmt.Errorf("--master-image must be set to an %s image with --target-build-arch=%s", arch, d.BuildOptions.CommonBuildOptions.TargetBuildArch)
+		}
+		if d.NodeImage == "" && !d.FIPSNodes() {
+			return fmt.Errorf("--node-image must be set to an %s image with --target-build-arch=%s", arch, d.BuildOptions.CommonBuildOptions.TargetBuildArch)
+		}
+	}
+
 	// verifyUpFlags does not check for a gcp project because it is
 	// assumed that one will be acquired from boskos if it is not set
 
@@ -201,3 +212,17 @@ func maybeSetupSSHKeys() {
 		klog.Warningf("failed to copy %s to %s: %v", maybePublicKey, publicKey, err)
 	}
 }
+
+// run runs name with args in dir with env, in a pseudo-terminal with --use-pty
+func (d *deployer) run(dir string, env []string, name string, args ...string) error {
+	if d.UsePTY {
+		return process.ExecWithPTYInDir(dir, name, args, env)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.SetEnv(env...)
+	if dir != "" {
+		cmd.SetDir(dir)
+	}
+	exec.InheritOutput(cmd)
+	return cmd.Run()
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	defaultGPUDriverInstallerManifest = "https://raw.githubusercontent.com/GoogleCloudPlatform/container-engine-accelerators/master/nvidia-driver-installer/cos/daemonset-preloaded.yaml"
+	defaultAcceleratorWaitTimeout     = 15 * time.Minute
+
+	gpuResourceName = "nvidia.com/gpu"
+	gpuNodeLabel    = "cloud.google.com/gke-accelerator"
+	tpuResourceName = "google.com/tpu"
+	tpuNodeLabel    = "cloud.google.com/gke-tpu-accelerator"
+
+	acceleratorPollInterval = 15 * time.Second
+)
+
+// acceleratorResource describes an extended resource exposed by the nodes
+// carrying a given label once their accelerators are usable.
+type acceleratorResource struct {
+	name  string
+	label string
+}
+
+// acceleratorArgs returns the gcloud flags to attach accelerators to a node pool.
+func acceleratorArgs(acceleratorType string, acceleratorCount int, tpuTopology string) []string {
+	var args []string
+	if acceleratorType != "" {
+		args = append(args, fmt.Sprintf("--accelerator=type=%s,count=%d", acceleratorType, acceleratorCount))
+	}
+	if tpuTopology != "" {
+		args = append(args, "--tpu-topology="+tpuTopology)
+	}
+	return args
+}
+
+// acceleratorResources returns the accelerator resources that are expected
+// to become allocatable in the clusters.
+func (d *Deployer) acceleratorResources() []acceleratorResource {
+	gpu, tpu := d.AcceleratorType != "", false
+	for _, enp := range d.extraNodePoolSpecs {
+		if enp.AcceleratorType != "" {
+			gpu = true
+		}
+		if enp.TPUTopology != "" {
+			tpu = true
+		}
+	}
+
+	var resources []acceleratorResource
+	if gpu {
+		resources = append(resources, acceleratorResource{name: gpuResourceName, label: gpuNodeLabel})
+	}
+	if tpu {
+		resources = append(resources, acceleratorResource{name: tpuResourceName, label: tpuNodeLabel})
+	}
+	return resources
+}
+
+// prepareAccelerators installs the GPU drivers if requested and waits for
+// the accelerators to become allocatable on all the clusters.
+func (d *Deployer) prepareAccelerators() error {
+	resources := d.acceleratorResources()
+	if len(resources) == 0 {
+		return nil
+	}
+
+	for _, kubecfg := range filepath.SplitList(d.kubecfgPath) {
+		if d.InstallGPUDriver {
+			klog.V(1).Infof("Installing GPU drivers from %s", d.GPUDriverInstallerManifest)
+			if err := runWithOutput(exec.Command("kubectl", "--kubeconfig="+kubecfg, "apply", "-f", d.GPUDriverInstallerManifest)); err != nil {
+				return fmt.Errorf("failed to install GPU drivers: %w", err)
+			}
+		}
+		for _, resource := range resources {
+			if err := waitForAllocatable(kubecfg, resource, d.AcceleratorWaitTimeout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// waitForAllocatable waits until every node carrying the resource label
+// reports a non-zero allocatable amount of the resource.
+func waitForAllocatable(kubecfg string, resource acceleratorResource, timeout time.Duration) error {
+	jsonPath := fmt.Sprintf(`{range .items[*]}{.status.allocatable.%s}{"\n"}{end}`, strings.ReplaceAll(resource.name, ".", `\.`))
+	deadline := time.Now().Add(timeout)
+	for {
+		lines, err := exec.OutputLines(exec.Command("kubectl", "--kubeconfig="+kubecfg,
+			"get", "nodes", "-l", resource.label, "-o", "jsonpath="+jsonPath))
+		if err != nil {
+			klog.Warningf("failed to get allocatable %s: %v", resource.name, execError(err))
+		} else if allAllocatable(lines) {
+			klog.V(1).Infof("%s is allocatable on %d node(s)", resource.name, len(lines))
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for %s to become allocatable", timeout, resource.name)
+		}
+		time.Sleep(acceleratorPollInterval)
+	}
+}
+
+// allAllocatable returns true if there is at least one node and every node
+// reports a non-zero allocatable amount.
+func allAllocatable(amounts []string) bool {
+	if len(amounts) == 0 {
+		return false
+	}
+	for _, amount := range amounts {
+		amount = strings.TrimSpace(amount)
+		if amount == "" || amount == "0" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func validateAutoscaling(minNodes, maxNodes int) error {
+	if maxNodes <= 0 {
+		return fmt.Errorf("max-nodes must be > 0")
+	}
+	if minNodes < 0 || minNodes > maxNodes {
+		return fmt.Errorf("min-nodes must be between 0 and max-nodes (%d), got %d", maxNodes, minNodes)
+	}
+	return nil
+}
+
+func validateAutoprovisioning(minCPU, maxCPU, minMemory, maxMemory int) error {
+	if maxCPU <= 0 || maxMemory <= 0 {
+		return fmt.Errorf("--autoprovisioning-max-cpu and --autoprovisioning-max-memory must be > 0")
+	}
+	if minCPU < 0 || minCPU > maxCPU {
+		return fmt.Errorf("--autoprovisioning-min-cpu must be between 0 and %d, got %d", maxCPU, minCPU)
+	}
+	if minMemory < 0 || minMemory > maxMemory {
+		return fmt.Errorf("--autoprovisioning-min-memory must be between 0 and %d, got %d", maxMemory, minMemory)
+	}
+	return nil
+}
+
+// autoscalingArgs returns the gcloud flags to enable the cluster autoscaler
+// for a node pool.
+func autoscalingArgs(minNodes, maxNodes int) []string {
+	return []string{
+		"--enable-autoscaling",
+		"--min-nodes=" + strconv.Itoa(minNodes),
+		"--max-nodes=" + strconv.Itoa(maxNodes),
+	}
+}
+
+// autoprovisioningArgs returns the gcloud flags to enable node
+// auto-provisioning with the given cluster wide resource limits.
+func autoprovisioningArgs(minCPU, maxCPU, minMemory, maxMemory int) []string {
+	return []string{
+		"--enable-autoprovisioning",
+		"--min-cpu=" + strconv.Itoa(minCPU),
+		"--max-cpu=" + strconv.Itoa(maxCPU),
+		"--min-memory=" + strconv.Itoa(minMemory),
+		"--max-memory=" + strconv.Itoa(maxMemory),
+	}
+}
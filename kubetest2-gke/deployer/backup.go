@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+const (
+	// RestoreCommand is the subcommand of the GKE deployer binary that
+	// restores a backup taken after Up, to be used as a hook by testers.
+	RestoreCommand = "restore"
+
+	// backupsEnv is the environment variable the backups taken after Up
+	// are exported in for the tester.
+	backupsEnv = "KUBETEST2_GKE_BACKUPS"
+
+	backupName = "after-up"
+)
+
+// backupRestoreArgs returns the args of a gcloud Backup for GKE command.
+func backupRestoreArgs(args ...string) []string {
+	return append([]string{"beta", "container", "backup-restore"}, args...)
+}
+
+func backupPlanName(clusterName string) string {
+	return clusterName + "-plan"
+}
+
+// backupResource returns the full resource name of the backup of a cluster.
+func backupResource(project, region, clusterName string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/backupPlans/%s/backups/%s", project, region, backupPlanName(clusterName), backupName)
+}
+
+// BackupClusters creates a backup plan for each cluster and takes a backup
+// of all the namespaces, including volume data, right after Up. The backups
+// are exported to the tester so they can be restored mid-run with the
+// restore subcommand.
+func (d *Deployer) BackupClusters() error {
+	region := regionFromLocation(d.Regions, d.Zones, d.retryCount)
+	location := locationName(d.Regions, d.Zones, d.retryCount)
+	var backups []string
+	for _, project := range d.Projects {
+		for _, cluster := range d.projectClustersLayout[project] {
+			plan := backupPlanName(cluster.name)
+			klog.V(1).Infof("Creating backup plan %s for cluster %s", plan, cluster.name)
+			if err := runWithOutput(exec.Command("gcloud", backupRestoreArgs("backup-plans", "create", plan,
+				"--project="+project,
+				"--location="+region,
+				fmt.Sprintf("--cluster=projects/%s/locations/%s/clusters/%s", project, location, cluster.name),
+				"--all-namespaces",
+				"--include-secrets",
+				"--include-volume-data",
+				"--backup-retain-days=1")...)); err != nil {
+				return fmt.Errorf("error creating backup plan for cluster %s: %w", cluster.name, err)
+			}
+			if err := runWithOutput(exec.Command("gcloud", backupRestoreArgs("backups", "create", backupName,
+				"--project="+project,
+				"--location="+region,
+				"--backup-plan="+plan,
+				"--wait-for-completion")...)); err != nil {
+				return fmt.Errorf("error backing up cluster %s: %w", cluster.name, err)
+			}
+			backups = append(backups, backupResource(project, region, cluster.name))
+		}
+	}
+
+	klog.V(1).Infof("Exporting backups %s=%s", backupsEnv, strings.Join(backups, ","))
+	if err := os.Setenv(backupsEnv, strings.Join(backups, ",")); err != nil {
+		return err
+	}
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "backups", strings.Join(backups, ","))
+}
+
+// DeleteBackups deletes the backups and backup plans of the clusters.
+// Errors are only logged, as the backups expire anyway.
+func (d *Deployer) DeleteBackups(retryCount int) {
+	region := regionFromLocation(d.Regions, d.Zones, retryCount)
+	for _, project := range d.Projects {
+		for _, cluster := range d.projectClustersLayout[project] {
+			plan := backupPlanName(cluster.name)
+			if err := runWithOutput(exec.Command("gcloud", backupRestoreArgs("backups", "delete", backupName,
+				"--project="+project,
+				"--location="+region,
+				"--backup-plan="+plan,
+				"--quiet")...)); err != nil {
+				klog.Errorf("Error deleting backup of cluster %s: %v", cluster.name, err)
+			}
+			if err := runWithOutput(exec.Command("gcloud", backupRestoreArgs("backup-plans", "delete", plan,
+				"--project="+project,
+				"--location="+region,
+				"--quiet")...)); err != nil {
+				klog.Errorf("Error deleting backup plan of cluster %s: %v", cluster.name, err)
+			}
+		}
+	}
+}
+
+// parseBackupResource parses the project, region and backup plan from the
+// full resource name of a backup.
+func parseBackupResource(backup string) (project, region, plan string, err error) {
+	parts := strings.Split(backup, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "backupPlans" || parts[6] != "backups" {
+		return "", "", "", fmt.Errorf("backup %q does not follow the expected format projects/<project>/locations/<region>/backupPlans/<plan>/backups/<backup>", backup)
+	}
+	return parts[1], parts[3], parts[5], nil
+}
+
+// Restore implements the restore subcommand, which restores a backup taken
+// after Up to the cluster it was taken from, replacing the namespaced
+// resources and volume data.
+func Restore(args []string) error {
+	var backup, name string
+	flags := pflag.NewFlagSet(RestoreCommand, pflag.ContinueOnError)
+	flags.StringVar(&backup, "backup", "", "Full resource name of the backup to restore, one of the backups exported in "+backupsEnv+". Must be set.")
+	flags.StringVar(&name, "name", "restore", "Name of the restore, must be unique per backup.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	project, region, plan, err := parseBackupResource(backup)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := exec.Output(exec.Command("gcloud", backupRestoreArgs("backup-plans", "describe", plan,
+		"--project="+project,
+		"--location="+region,
+		"--format=value(cluster)")...))
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster of backup plan %s: %s", plan, execError(err))
+	}
+
+	restorePlan := name + "-" + plan
+	if err := runWithOutput(exec.Command("gcloud", backupRestoreArgs("restore-plans", "create", restorePlan,
+		"--project="+project,
+		"--location="+region,
+		"--backup-plan="+fmt.Sprintf("projects/%s/locations/%s/backupPlans/%s", project, region, plan),
+		"--cluster="+strings.TrimSpace(string(cluster)),
+		"--all-namespaces",
+		"--namespaced-resource-restore-mode=delete-and-restore",
+		"--volume-data-restore-policy=restore-volume-data-from-backup")...)); err != nil {
+		return fmt.Errorf("error creating restore plan %s: %w", restorePlan, err)
+	}
+	if err := runWithOutput(exec.Command("gcloud", backupRestoreArgs("restores", "create", name,
+		"--project="+project,
+		"--location="+region,
+		"--restore-plan="+restorePlan,
+		"--backup="+backup,
+		"--wait-for-completion")...)); err != nil {
+		return fmt.Errorf("error restoring backup %s: %w", backup, err)
+	}
+	return nil
+}
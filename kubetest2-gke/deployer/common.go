@@ -22,7 +22,6 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/pkg/math"
 	"k8s.io/klog/v2"
@@ -31,10 +30,7 @@ import (
 )
 
 const (
-	defaultBoskosLocation                 = "http://boskos.test-pods.svc.cluster.local."
-	defaultGKEProjectResourceType         = "gke-project"
-	defaultBoskosAcquireTimeoutSeconds    = 300
-	defaultBoskosHeartbeatIntervalSeconds = 300
+	defaultGKEProjectResourceType = "gke-project"
 )
 
 func (d *Deployer) Init() error {
@@ -49,6 +45,12 @@ func (d *Deployer) Initialize() error {
 		klog.Warningf("--version is deprecated please use --cluster-version")
 		d.ClusterVersion = d.LegacyClusterVersion
 	}
+	if d.ControlPlaneVersion != "" {
+		if d.ClusterVersion != "" && d.ClusterVersion != d.ControlPlaneVersion {
+			return fmt.Errorf("--control-plane-version %q conflicts with --cluster-version %q", d.ControlPlaneVersion, d.ClusterVersion)
+		}
+		d.ClusterVersion = d.ControlPlaneVersion
+	}
 	if d.Kubetest2CommonOptions.ShouldUp() {
 		d.totalTryCount = math.Max(len(d.Regions), len(d.Zones))
 
@@ -66,31 +68,19 @@ func (d *Deployer) Initialize() error {
 			}
 		}
 
-		if len(d.Projects) == 0 {
-			klog.V(1).Infof("No GCP projects provided, acquiring from Boskos %d project/s", d.BoskosProjectsRequested)
-
-			boskosClient, err := boskos.NewClient(d.BoskosLocation)
-			if err != nil {
-				return fmt.Errorf("failed to make boskos client: %w", err)
+		if len(d.Projects) == 0 && d.BoskosLocation == "" && len(d.FallbackProjects) > 0 {
+			klog.V(1).Info("No GCP projects or Boskos location provided, acquiring fallback projects")
+			if err := d.acquireFallbackProjects(); err != nil {
+				return err
 			}
-			d.boskos = boskosClient
-			d.boskosHeartbeatClose = make(chan struct{})
-
-			for i := 0; i < len(d.BoskosProjectsRequested); i++ {
-				for j := 0; j < d.BoskosProjectsRequested[i]; j++ {
-					resource, err := boskos.Acquire(
-						d.boskos,
-						d.BoskosResourceType[i],
-						time.Duration(d.BoskosAcquireTimeoutSeconds)*time.Second,
-						time.Duration(d.BoskosHeartbeatIntervalSeconds)*time.Second,
-						d.boskosHeartbeatClose,
-					)
-
-					if err != nil {
-						return fmt.Errorf("init failed to get project from boskos: %w", err)
-					}
-					d.Projects = append(d.Projects, resource.Name)
-					klog.V(1).Infof("Got project %s from boskos", resource.Name)
+		} else if len(d.Projects) == 0 {
+			if err := d.acquireBoskosProjects(); err != nil {
+				if len(d.FallbackProjects) == 0 {
+					return err
+				}
+				klog.Warningf("%v, acquiring fallback projects instead", err)
+				if err := d.acquireFallbackProjects(); err != nil {
+					return err
 				}
 			}
 		}
@@ -184,6 +174,26 @@ func buildExtraNodePoolOptions(np string, enp *extraNodepool) error {
 				return fmt.Errorf("num-nodes must be a positive integer, got %d", n)
 			}
 			enp.NumNodes = n
+		case "min-nodes", "max-nodes":
+			n, err := strconv.Atoi(values.Get(k))
+			if err != nil {
+				return err
+			}
+			if k == "min-nodes" {
+				enp.MinNodes = n
+			} else {
+				enp.MaxNodes = n
+			}
+		case "accelerator-type":
+			enp.AcceleratorType = values.Get("accelerator-type")
+		case "accelerator-count":
+			n, err := strconv.Atoi(values.Get("accelerator-count"))
+			if err != nil {
+				return err
+			}
+			enp.AcceleratorCount = n
+		case "tpu-topology":
+			enp.TPUTopology = values.Get("tpu-topology")
 		default:
 			return fmt.Errorf("unknown parameter: %q", k)
 		}
@@ -207,5 +217,62 @@ func validateExtraNodepoolOptions(enp *extraNodepool) error {
 	if enp.NumNodes <= 0 {
 		return fmt.Errorf("num-nodes must be > 0")
 	}
+
+	if enp.MinNodes != 0 || enp.MaxNodes != 0 {
+		if err := validateAutoscaling(enp.MinNodes, enp.MaxNodes); err != nil {
+			return err
+		}
+	}
+
+	if enp.AcceleratorType != "" && enp.AcceleratorCount <= 0 {
+		return fmt.Errorf("accelerator-count must be > 0 when accelerator-type is set")
+	}
+
+	if enp.AcceleratorType != "" && enp.TPUTopology != "" {
+		return fmt.Errorf("accelerator-type and tpu-topology cannot both be set")
+	}
+	return nil
+}
+
+func (d *Deployer) acquireBoskosProjects() error {
+	klog.V(1).Infof("No GCP projects provided, acquiring from Boskos %d project/s", d.BoskosProjectsRequested)
+
+	lease, err := d.NewLease()
+	if err != nil {
+		return fmt.Errorf("failed to make boskos client: %w", err)
+	}
+	d.lease = lease
+
+	for i := 0; i < len(d.BoskosProjectsRequested); i++ {
+		resources, err := d.lease.Acquire(d.BoskosResourceType[i], d.BoskosProjectsRequested[i])
+		if err != nil {
+			// The projects acquired for the previous resource types are kept in d.Projects so Down releases them.
+			return fmt.Errorf("init failed to get %d of %d projects from boskos: %w", d.BoskosProjectsRequested[i], d.totalBoskosProjectsRequested, err)
+		}
+		for _, resource := range resources {
+			d.Projects = append(d.Projects, resource.Name)
+			klog.V(1).Infof("Got project %s of type %s from boskos", resource.Name, d.BoskosResourceType[i])
+		}
+	}
+	return nil
+}
+
+// acquireFallbackProjects acquires the projects from --fallback-gcp-projects
+// instead of boskos, releasing those acquired from boskos so far.
+func (d *Deployer) acquireFallbackProjects() error {
+	if d.lease != nil {
+		if err := d.lease.Release(); err != nil {
+			klog.Warningf("failed to release the projects acquired from boskos: %v", err)
+		}
+		d.lease = nil
+	}
+	d.Projects = nil
+
+	projects, err := boskos.AcquireFallback(boskos.NewLocker(d.ProjectLockLocation), d.FallbackProjects, d.totalBoskosProjectsRequested)
+	if err != nil {
+		return fmt.Errorf("init failed to get fallback projects: %w", err)
+	}
+	d.Projects = projects
+	d.fallbackProjects = projects
 	return nil
 }
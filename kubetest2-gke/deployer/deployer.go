@@ -28,11 +28,12 @@ import (
 	"github.com/octago/sflags/gen/gpflag"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
-	"sigs.k8s.io/boskos/client"
 
 	"sigs.k8s.io/kubetest2/kubetest2-gke/deployer/options"
 	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/boskos"
 	"sigs.k8s.io/kubetest2/pkg/build"
+	"sigs.k8s.io/kubetest2/pkg/inventory"
 	"sigs.k8s.io/kubetest2/pkg/types"
 )
 
@@ -76,6 +77,14 @@ var (
 	defaultWindowsNodePool = gkeNodePool{
 		Nodes: 1,
 	}
+
+	// node pools of alpha clusters must have auto-upgrade and auto-repair disabled.
+	alphaNodePoolArgs = []string{"--no-enable-autoupgrade", "--no-enable-autorepair"}
+
+	defaultArm64NodePool = gkeNodePool{
+		Nodes:       1,
+		MachineType: "t2a-standard-4",
+	}
 )
 
 type gkeNodePool struct {
@@ -97,11 +106,16 @@ type cluster struct {
 }
 
 type extraNodepool struct {
-	Index       int
-	Name        string
-	MachineType string
-	ImageType   string
-	NumNodes    int
+	Index            int
+	Name             string
+	MachineType      string
+	ImageType        string
+	NumNodes         int
+	MinNodes         int
+	MaxNodes         int
+	AcceleratorType  string
+	AcceleratorCount int
+	TPUTopology      string
 }
 
 type Deployer struct {
@@ -124,6 +138,9 @@ type Deployer struct {
 	// extra node pools to create, per cluster.
 	extraNodePoolSpecs []*extraNodepool
 
+	// --gcloud-extra-create-args split into args
+	gcloudExtraCreateArgs []string
+
 	kubecfgPath  string
 	testPrepared bool
 
@@ -140,13 +157,13 @@ type Deployer struct {
 	// the total number of Boskos projects to request
 	totalBoskosProjectsRequested int
 
-	// boskos struct field will be non-nil when the deployer is
-	// using boskos to acquire a GCP project
-	boskos *client.Client
+	// lease will be non-nil when the deployer is
+	// using boskos to acquire GCP projects
+	lease *boskos.Lease
 
-	// this channel serves as a signal channel for the hearbeat goroutine
-	// so that it can be explicitly closed
-	boskosHeartbeatClose chan struct{}
+	// fallbackProjects are the projects acquired from --fallback-gcp-projects
+	// when boskos is unreachable
+	fallbackProjects []string
 }
 
 // assert that New implements types.NewDeployer
@@ -155,14 +172,106 @@ var _ types.NewDeployer = New
 // assert that deployer implements types.Deployer
 var _ types.Deployer = &Deployer{}
 
+// assert that deployer implements types.DeployerWithTestArgs
+var _ types.DeployerWithTestArgs = &Deployer{}
+
+// assert that deployer implements types.DeployerWithCloudInventory
+var _ types.DeployerWithCloudInventory = &Deployer{}
+
+// assert that deployer implements types.DeployerWithState
+var _ types.DeployerWithState = &Deployer{}
+
+// assert that deployer implements types.DeployerWithNodeOS
+var _ types.DeployerWithNodeOS = &Deployer{}
+
+// assert that deployer implements types.DeployerWithFeatures
+var _ types.DeployerWithFeatures = &Deployer{}
+
+// assert that deployer implements types.DeployerWithLease
+var _ types.DeployerWithLease = &Deployer{}
+
 func (d *Deployer) Provider() string {
 	return Name
 }
 
+// TestArgs returns the e2e.test arguments for the first cluster, implementing
+// types.DeployerWithTestArgs
+func (d *Deployer) TestArgs() ([]string, error) {
+	if len(d.Projects) == 0 {
+		return nil, fmt.Errorf("no GCP project is known for the cluster")
+	}
+	args := []string{
+		"--provider=" + Name,
+		"--gce-project=" + d.Projects[0],
+	}
+	switch {
+	case len(d.Zones) != 0:
+		args = append(args, "--gce-zone="+locationName(d.Regions, d.Zones, d.retryCount))
+		// the nodes of a zonal cluster are all in its zone
+		if len(d.NodeLocations) == 0 && d.NumNodes > 0 {
+			args = append(args, fmt.Sprintf("--num-nodes=%d", d.NumNodes))
+		}
+	case len(d.Regions) != 0:
+		args = append(args, "--gce-region="+locationName(d.Regions, d.Zones, d.retryCount))
+	}
+	if d.Network != "" {
+		args = append(args, "--network="+d.Network)
+	}
+	return args, nil
+}
+
+// NodeOS returns the operating systems of the nodes, with Windows ones in the
+// node pool of --enable-windows, implementing types.DeployerWithNodeOS
+func (d *Deployer) NodeOS() []string {
+	if d.WindowsEnabled {
+		return []string{types.NodeOSLinux, types.NodeOSWindows}
+	}
+	return []string{types.NodeOSLinux}
+}
+
+// Features returns the features of the clusters, with Windows nodes in the
+// node pool of --enable-windows, implementing types.DeployerWithFeatures
+func (d *Deployer) Features() []string {
+	features := []string{
+		types.FeatureLoadBalancer,
+		types.FeaturePersistentVolumes,
+		types.FeatureNodeSSH,
+	}
+	if d.WindowsEnabled {
+		features = append(features, types.FeatureWindows)
+	}
+	return features
+}
+
+// LeaseLost returns a channel closed when the lease of the projects acquired
+// from boskos is lost, implementing types.DeployerWithLease
+func (d *Deployer) LeaseLost() <-chan struct{} {
+	if d.lease == nil {
+		return nil
+	}
+	return d.lease.Lost()
+}
+
 func (d *Deployer) Version() string {
 	return GitTag
 }
 
+// CloudResources returns the compute resources of the projects the tests may
+// leave behind, implementing types.DeployerWithCloudInventory
+func (d *Deployer) CloudResources() ([]string, error) {
+	var resources []string
+	for _, project := range d.Projects {
+		projectResources, err := inventory.GCPResources(project)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range projectResources {
+			resources = append(resources, project+"/"+r)
+		}
+	}
+	return resources, nil
+}
+
 // New implements deployer.New for gke
 func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
 	d := NewDeployer(opts)
@@ -183,17 +292,17 @@ func NewDeployer(opts types.Options) *Deployer {
 				Builder:  &build.NoopBuilder{},
 				Stager:   &build.NoopStager{},
 				Strategy: "make",
+				// the cluster version is staged from the release tars
+				RequiredBuildTargets: []string{build.ReleaseTarsTarget},
 			},
 		},
 		CommonOptions: &options.CommonOptions{
 			GCPSSHKeyIgnored: true,
 		},
 		ProjectOptions: &options.ProjectOptions{
-			BoskosLocation:                 defaultBoskosLocation,
-			BoskosResourceType:             []string{defaultGKEProjectResourceType},
-			BoskosAcquireTimeoutSeconds:    defaultBoskosAcquireTimeoutSeconds,
-			BoskosHeartbeatIntervalSeconds: defaultBoskosHeartbeatIntervalSeconds,
-			BoskosProjectsRequested:        []int{1},
+			Options:                 boskos.NewOptions(),
+			BoskosResourceType:      []string{defaultGKEProjectResourceType},
+			BoskosProjectsRequested: []int{1},
 		},
 		NetworkOptions: &options.NetworkOptions{
 			Network: "default",
@@ -210,6 +319,12 @@ func NewDeployer(opts types.Options) *Deployer {
 			WindowsNumNodes:    defaultWindowsNodePool.Nodes,
 			WindowsMachineType: defaultWindowsNodePool.MachineType,
 
+			Arm64NumNodes:    defaultArm64NodePool.Nodes,
+			Arm64MachineType: defaultArm64NodePool.MachineType,
+
+			GPUDriverInstallerManifest: defaultGPUDriverInstallerManifest,
+			AcceleratorWaitTimeout:     defaultAcceleratorWaitTimeout,
+
 			RetryableErrorPatterns: []string{gceStockoutErrorPattern},
 		},
 		localLogsDir: filepath.Join(artifacts.BaseDir(), "logs"),
@@ -224,16 +339,74 @@ func (d *Deployer) VerifyLocationFlags() error {
 	} else if len(d.Zones) != 0 && len(d.Regions) != 0 {
 		return fmt.Errorf("--zone and --region cannot both be set")
 	}
+	if err := verifyNodeLocations(d.NodeLocations, d.Regions, d.Zones); err != nil {
+		return err
+	}
 	return nil
 }
 
+// verifyNodeLocations validates that the node locations can be used with the
+// cluster locations. With backup regions or zones, the node locations may
+// span their regions, each retry using those in its own region, which must
+// have some.
+func verifyNodeLocations(nodeLocations, regions, zones []string) error {
+	if len(nodeLocations) == 0 {
+		return nil
+	}
+	var clusterRegions []string
+	for retryCount := 0; retryCount < len(regions)+len(zones); retryCount++ {
+		region := regionFromLocation(regions, zones, retryCount)
+		if len(nodeLocationsInRegion(nodeLocations, region)) == 0 {
+			return fmt.Errorf("none of the node locations %v is a zone in the cluster region %q", nodeLocations, region)
+		}
+		clusterRegions = append(clusterRegions, region)
+	}
+	for _, nodeLocation := range nodeLocations {
+		found := false
+		for _, region := range clusterRegions {
+			if strings.HasPrefix(nodeLocation, region+"-") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("node location %q is not a zone in the cluster regions %v", nodeLocation, clusterRegions)
+		}
+	}
+	return nil
+}
+
+// nodeLocationsInRegion returns the node locations that are zones in region.
+func nodeLocationsInRegion(nodeLocations []string, region string) []string {
+	var inRegion []string
+	for _, nodeLocation := range nodeLocations {
+		if strings.HasPrefix(nodeLocation, region+"-") {
+			inRegion = append(inRegion, nodeLocation)
+		}
+	}
+	return inRegion
+}
+
+// retryNodeLocations returns the node locations used for the current retry.
+func (d *Deployer) retryNodeLocations() []string {
+	return nodeLocationsInRegion(d.NodeLocations, regionFromLocation(d.Regions, d.Zones, d.retryCount))
+}
+
 // locationFlag builds the zone/region flag from the provided zone/region
 // used by gcloud commands.
 func locationFlag(regions, zones []string, retryCount int) string {
 	if len(zones) != 0 {
-		return "--zone=" + zones[retryCount]
+		return "--zone=" + locationName(regions, zones, retryCount)
+	}
+	return "--region=" + locationName(regions, zones, retryCount)
+}
+
+// locationName returns the zone/region used for the given retry.
+func locationName(regions, zones []string, retryCount int) string {
+	if len(zones) != 0 {
+		return zones[retryCount]
 	}
-	return "--region=" + regions[retryCount]
+	return regions[retryCount]
 }
 
 // regionFromLocation computes the region from the specified zone/region
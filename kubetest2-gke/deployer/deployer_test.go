@@ -16,7 +16,13 @@ limitations under the License.
 
 package deployer
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/kubetest2/kubetest2-gke/deployer/options"
+)
 
 func TestLocationFlag(t *testing.T) {
 	testCases := []struct {
@@ -87,3 +93,274 @@ func TestRegionFromLocation(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyNodeLocations(t *testing.T) {
+	testCases := []struct {
+		nodeLocations []string
+		regions       []string
+		zones         []string
+		expectErr     bool
+	}{
+		{
+			nodeLocations: []string{},
+			regions:       []string{"us-central1", "us-east1"},
+			expectErr:     false,
+		},
+		{
+			nodeLocations: []string{"us-central1-a", "us-central1-b"},
+			regions:       []string{"us-central1"},
+			expectErr:     false,
+		},
+		{
+			nodeLocations: []string{"us-central1-a", "us-central1-b"},
+			zones:         []string{"us-central1-c"},
+			expectErr:     false,
+		},
+		{
+			nodeLocations: []string{"us-central1-a", "us-east1-b"},
+			regions:       []string{"us-central1"},
+			expectErr:     true,
+		},
+		{
+			nodeLocations: []string{"us-central1-a"},
+			regions:       []string{"us-central1", "us-central2"},
+			expectErr:     true,
+		},
+		{
+			nodeLocations: []string{"us-central1-a", "us-central1-b", "us-east1-b", "us-east1-c"},
+			regions:       []string{"us-central1", "us-east1"},
+			expectErr:     false,
+		},
+		{
+			nodeLocations: []string{"us-central1-a", "us-east1-b"},
+			zones:         []string{"us-central1-c", "us-east1-c"},
+			expectErr:     false,
+		},
+		{
+			nodeLocations: []string{"us-central1-a", "us-east1-b", "us-west1-a"},
+			regions:       []string{"us-central1", "us-east1"},
+			expectErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := verifyNodeLocations(tc.nodeLocations, tc.regions, tc.zones)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("expected error: %v but got %v for node locations %v", tc.expectErr, err, tc.nodeLocations)
+		}
+	}
+}
+
+func TestRetryNodeLocations(t *testing.T) {
+	d := &Deployer{
+		ClusterOptions: &options.ClusterOptions{
+			Regions:       []string{"us-central1", "us-east1"},
+			NodeLocations: []string{"us-central1-a", "us-central1-b", "us-east1-b"},
+		},
+	}
+	expected := [][]string{{"us-central1-a", "us-central1-b"}, {"us-east1-b"}}
+	for retryCount := range d.Regions {
+		d.retryCount = retryCount
+		if got := d.retryNodeLocations(); !reflect.DeepEqual(got, expected[retryCount]) {
+			t.Errorf("expected node locations %v for retry %d but got %v", expected[retryCount], retryCount, got)
+		}
+	}
+}
+
+func TestTestArgs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		regions       []string
+		zones         []string
+		nodeLocations []string
+		expected      []string
+	}{
+		{
+			name:     "zonal cluster",
+			zones:    []string{"us-central1-c"},
+			expected: []string{"--provider=gke", "--gce-project=project", "--gce-zone=us-central1-c", "--num-nodes=3", "--network=default"},
+		},
+		{
+			name:          "zonal cluster with node locations",
+			zones:         []string{"us-central1-c"},
+			nodeLocations: []string{"us-central1-a", "us-central1-c"},
+			expected:      []string{"--provider=gke", "--gce-project=project", "--gce-zone=us-central1-c", "--network=default"},
+		},
+		{
+			name:     "regional cluster",
+			regions:  []string{"us-central1"},
+			expected: []string{"--provider=gke", "--gce-project=project", "--gce-region=us-central1", "--network=default"},
+		},
+	}
+
+	for _, tc := range testCases {
+		d := &Deployer{
+			ProjectOptions: &options.ProjectOptions{Projects: []string{"project"}},
+			ClusterOptions: &options.ClusterOptions{
+				Regions:       tc.regions,
+				Zones:         tc.zones,
+				NodeLocations: tc.nodeLocations,
+				NumNodes:      3,
+			},
+			NetworkOptions: &options.NetworkOptions{Network: "default"},
+		}
+		args, err := d.TestArgs()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("%s: expected test args %v but got %v", tc.name, tc.expected, args)
+		}
+	}
+}
+
+func TestExpiredClusters(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	clusters := []gkeCluster{
+		{
+			Name:           "old",
+			CreateTime:     now.Add(-48 * time.Hour),
+			ResourceLabels: map[string]string{runIDLabel: "a"},
+		},
+		{
+			Name:           "new",
+			CreateTime:     now.Add(-time.Hour),
+			ResourceLabels: map[string]string{runIDLabel: "b"},
+		},
+		{
+			Name:       "unlabeled",
+			CreateTime: now.Add(-48 * time.Hour),
+		},
+	}
+
+	got := expiredClusters(clusters, now, 24*time.Hour)
+	if len(got) != 1 || got[0].Name != "old" {
+		t.Errorf("expected only the old cluster to be expired but got %v", got)
+	}
+}
+
+func TestClusterLabels(t *testing.T) {
+	testCases := []struct {
+		runID    string
+		jobName  string
+		expected string
+	}{
+		{
+			runID:    "1234-abcd",
+			expected: "kubetest2-run-id=1234-abcd",
+		},
+		{
+			runID:    "1234-abcd",
+			jobName:  "ci-Kubernetes.e2e_GKE",
+			expected: "kubetest2-run-id=1234-abcd,kubetest2-job=ci-kubernetes-e2e_gke",
+		},
+	}
+
+	for _, tc := range testCases {
+		got := clusterLabels(tc.runID, tc.jobName)
+		if got != tc.expected {
+			t.Errorf("expected %q but got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestExtractLabels(t *testing.T) {
+	rest, labels := extractLabels([]string{"--quiet", "--labels=team=a", "--num-nodes=3", "--labels", "env=b,tier=c"})
+	if expected := []string{"--quiet", "--num-nodes=3"}; !reflect.DeepEqual(rest, expected) {
+		t.Errorf("expected the other args %v but got %v", expected, rest)
+	}
+	if expected := []string{"team=a", "env=b,tier=c"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected the labels %v but got %v", expected, labels)
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   []string
+		expected string
+	}{
+		{
+			name:     "run labels only",
+			values:   []string{"kubetest2-run-id=1234"},
+			expected: "kubetest2-run-id=1234",
+		},
+		{
+			name:     "user labels are kept",
+			values:   []string{"team=a,env=b", "kubetest2-run-id=1234"},
+			expected: "team=a,env=b,kubetest2-run-id=1234",
+		},
+		{
+			name:     "later values take precedence",
+			values:   []string{"kubetest2-run-id=mine,team=a", "team=b", "kubetest2-run-id=1234"},
+			expected: "kubetest2-run-id=1234,team=b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mergeLabels(tc.values...); got != tc.expected {
+				t.Errorf("expected %q but got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseBackupResource(t *testing.T) {
+	project, region, plan, err := parseBackupResource(backupResource("test-project", "us-central1", "kt2-cluster"))
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if project != "test-project" || region != "us-central1" || plan != "kt2-cluster-plan" {
+		t.Errorf("unexpected parse result: %q, %q, %q", project, region, plan)
+	}
+
+	if _, _, _, err := parseBackupResource("projects/test-project/backups/after-up"); err == nil {
+		t.Errorf("expected an error for a malformed backup name")
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	up := NewDeployer(nil)
+	up.Projects = []string{"host", "service"}
+	up.fallbackProjects = up.Projects
+	up.Clusters = []string{"a:0", "b:1"}
+	up.Zones = []string{"us-central1-c", "us-east1-b"}
+	up.retryCount = 1
+	up.Network = "kt2-net"
+	state, err := up.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	down := NewDeployer(nil)
+	if err := down.RestoreState(state); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(down.Projects, up.Projects) || !reflect.DeepEqual(down.fallbackProjects, up.fallbackProjects) {
+		t.Errorf("expected projects %v but got %v", up.Projects, down.Projects)
+	}
+	if !reflect.DeepEqual(down.Clusters, up.Clusters) {
+		t.Errorf("expected clusters %v but got %v", up.Clusters, down.Clusters)
+	}
+	if got := locationFlag(down.Regions, down.Zones, down.retryCount); got != "--zone=us-east1-b" {
+		t.Errorf("expected the location of the previous run but got %q", got)
+	}
+	if down.Network != up.Network {
+		t.Errorf("expected network %q but got %q", up.Network, down.Network)
+	}
+
+	// the flags that are set take precedence
+	flags := NewDeployer(nil)
+	flags.Projects = []string{"other"}
+	flags.Regions = []string{"us-west1"}
+	if err := flags.RestoreState(state); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flags.Projects, []string{"other"}) || flags.fallbackProjects != nil {
+		t.Errorf("expected the projects of the flags but got %v, %v", flags.Projects, flags.fallbackProjects)
+	}
+	if got := locationFlag(flags.Regions, flags.Zones, flags.retryCount); got != "--region=us-west1" {
+		t.Errorf("expected the location of the flags but got %q", got)
+	}
+}
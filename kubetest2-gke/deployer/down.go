@@ -36,12 +36,23 @@ func (d *Deployer) Down() error {
 		return nil
 	}
 
-	// If the GCP projects are acquired from Boskos, release the projects and
-	// rely on boskos-janitor to do clean-ups for them.
-	if d.totalBoskosProjectsRequested > 0 {
-		return boskos.Release(d.boskos, d.Projects, d.boskosHeartbeatClose)
+	if len(d.fallbackProjects) > 0 {
+		// Unlike with boskos, nothing cleans up the fallback projects,
+		// so they are only released after the clean-ups below.
+		defer func() {
+			if err := boskos.ReleaseFallback(boskos.NewLocker(d.ProjectLockLocation), d.fallbackProjects); err != nil {
+				klog.Errorf("Error releasing fallback projects: %v", err)
+			}
+		}()
+	} else if d.lease != nil {
+		// If the GCP projects are acquired from Boskos, release the projects and
+		// rely on boskos-janitor to do clean-ups for them.
+		return d.lease.Release()
 	}
 
+	if d.BackupEnabled {
+		d.DeleteBackups(d.retryCount)
+	}
 	d.DeleteClusters(d.retryCount)
 
 	numDeletedFWRules, errCleanFirewalls := d.CleanupNetworkFirewalls(d.Projects[0], d.Network)
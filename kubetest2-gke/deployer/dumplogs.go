@@ -19,6 +19,7 @@ package deployer
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"sigs.k8s.io/kubetest2/pkg/exec"
@@ -31,11 +32,9 @@ import (
 // TODO(RonWeber): This whole path is really gross, but this seemed
 // the least gross hack to get this done.
 //
-// TODO(RonWeber): Make this work with multizonal and regional clusters.
+// Nodes are grouped by the zone of their instance group, so regional and
+// multi-zonal clusters are dumped one zone at a time.
 func (d *Deployer) DumpClusterLogs() error {
-	if len(d.Zones) <= 0 {
-		return fmt.Errorf("DumpClusterLogs is currently only supported for zonal clusters")
-	}
 	// gkeLogDumpTemplate is a template of a shell script where
 	// - %[1]s is the project
 	// - %[2]s is the zone
@@ -64,31 +63,39 @@ export KUBE_NODE_OS_DISTRIBUTION='%[3]s'
 			return fmt.Errorf("%q or %q contain single quotes - nice try", d.localLogsDir, d.gcsLogsDir)
 		}
 
-		// Generate a slice of filters to be OR'd together below
-		var filters []string
+		// Generate a slice of filters per zone to be OR'd together below,
+		// so that nodes of regional and multi-zonal clusters are covered.
+		filtersByZone := map[string][]string{}
 		for _, cluster := range d.projectClustersLayout[project] {
 			if err := d.GetInstanceGroups(); err != nil {
 				return err
 			}
 			for _, ig := range d.instanceGroups[project][cluster.name] {
-				filters = append(filters, fmt.Sprintf("(metadata.created-by:*%s)", ig.path))
+				filtersByZone[ig.zone] = append(filtersByZone[ig.zone], fmt.Sprintf("(metadata.created-by:*%s)", ig.path))
 			}
 		}
+		zones := make([]string, 0, len(filtersByZone))
+		for zone := range filtersByZone {
+			zones = append(zones, zone)
+		}
+		sort.Strings(zones)
 
 		// Generate the log-dump.sh command-line
 		dumpCmd := fmt.Sprintf("./cluster/log-dump/log-dump.sh '%s'", d.localLogsDir)
 		if d.gcsLogsDir != "" {
 			dumpCmd += " " + d.gcsLogsDir
 		}
-		cmd := exec.Command("bash", "-c", fmt.Sprintf(gkeLogDumpTemplate,
-			project,
-			d.Zones[d.retryCount],
-			os.Getenv("NODE_OS_DISTRIBUTION"),
-			strings.Join(filters, " OR "),
-			dumpCmd))
-		cmd.SetDir(d.RepoRoot)
-		if err := runWithOutput(cmd); err != nil {
-			return err
+		for _, zone := range zones {
+			cmd := exec.Command("bash", "-c", fmt.Sprintf(gkeLogDumpTemplate,
+				project,
+				zone,
+				os.Getenv("NODE_OS_DISTRIBUTION"),
+				strings.Join(filtersByZone[zone], " OR "),
+				dumpCmd))
+			cmd.SetDir(d.RepoRoot)
+			if err := runWithOutput(cmd); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+// fleetMembershipsEnv is the environment variable the fleet memberships
+// of the clusters are exported in for the tester.
+const fleetMembershipsEnv = "KUBETEST2_FLEET_MEMBERSHIPS"
+
+func (d *Deployer) verifyFleetFlags() error {
+	if d.FleetProject == "" && (d.MultiClusterServicesEnabled || d.MultiClusterIngressEnabled) {
+		return fmt.Errorf("--fleet-project must be set to enable multi-cluster Services or Ingress")
+	}
+	return nil
+}
+
+// fleetMembership returns the full membership name of a cluster
+// registered to the fleet at creation time.
+func fleetMembership(fleetProject, clusterName string) string {
+	return fmt.Sprintf("projects/%s/locations/global/memberships/%s", fleetProject, clusterName)
+}
+
+// fleetMemberships returns the membership names of all the clusters.
+func (d *Deployer) fleetMemberships() []string {
+	var memberships []string
+	for _, project := range d.Projects {
+		for _, cluster := range d.projectClustersLayout[project] {
+			memberships = append(memberships, fleetMembership(d.FleetProject, cluster.name))
+		}
+	}
+	return memberships
+}
+
+// EnableFleetFeatures enables the requested multi-cluster features on the
+// fleet the clusters are registered to.
+func (d *Deployer) EnableFleetFeatures() error {
+	if d.FleetProject == "" {
+		return nil
+	}
+	if d.MultiClusterServicesEnabled {
+		if err := runWithOutput(exec.Command("gcloud", "container", "fleet", "multi-cluster-services", "enable",
+			"--project="+d.FleetProject)); err != nil {
+			return fmt.Errorf("error enabling multi-cluster Services: %w", err)
+		}
+	}
+	if d.MultiClusterIngressEnabled {
+		memberships := d.fleetMemberships()
+		if len(memberships) == 0 {
+			return fmt.Errorf("no cluster to use as the multi-cluster Ingress config cluster")
+		}
+		if err := runWithOutput(exec.Command("gcloud", "container", "fleet", "ingress", "enable",
+			"--config-membership="+memberships[0],
+			"--project="+d.FleetProject)); err != nil {
+			return fmt.Errorf("error enabling multi-cluster Ingress: %w", err)
+		}
+	}
+	return nil
+}
+
+// exportFleetMemberships exports the fleet memberships of the clusters to
+// the tester environment and records them in the metadata.
+func (d *Deployer) exportFleetMemberships() error {
+	if d.FleetProject == "" {
+		return nil
+	}
+	memberships := strings.Join(d.fleetMemberships(), ",")
+	klog.V(1).Infof("Exporting fleet memberships %s=%s", fleetMembershipsEnv, memberships)
+	if err := os.Setenv(fleetMembershipsEnv, memberships); err != nil {
+		return err
+	}
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "fleet-memberships", memberships)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// JanitorCommand is the subcommand of the GKE deployer binary that
+	// deletes leaked clusters.
+	JanitorCommand = "janitor"
+
+	// labels applied to every cluster created by the deployer
+	runIDLabel = "kubetest2-run-id"
+	jobLabel   = "kubetest2-job"
+)
+
+var invalidLabelCharsRe = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// labelValue sanitizes s to be a valid GCP label value.
+func labelValue(s string) string {
+	s = invalidLabelCharsRe.ReplaceAllString(strings.ToLower(s), "-")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	return s
+}
+
+// clusterLabels returns the labels identifying the run the clusters are
+// created by, in the format expected by gcloud --labels.
+func clusterLabels(runID, jobName string) string {
+	labels := []string{runIDLabel + "=" + labelValue(runID)}
+	if jobName != "" {
+		labels = append(labels, jobLabel+"="+labelValue(jobName))
+	}
+	return strings.Join(labels, ",")
+}
+
+// extractLabels removes the --labels flags from args, returning the other
+// args and the values of the flags.
+func extractLabels(args []string) (rest []string, labels []string) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.HasPrefix(args[i], "--labels="):
+			labels = append(labels, strings.TrimPrefix(args[i], "--labels="))
+		case args[i] == "--labels" && i+1 < len(args):
+			labels = append(labels, args[i+1])
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, labels
+}
+
+// mergeLabels merges the values of gcloud --labels flags into one, the
+// later values of a key taking precedence, as gcloud only honors the last
+// flag.
+func mergeLabels(values ...string) string {
+	var keys []string
+	labels := map[string]string{}
+	for _, value := range values {
+		for _, label := range strings.Split(value, ",") {
+			if label == "" {
+				continue
+			}
+			key, _, _ := strings.Cut(label, "=")
+			if _, ok := labels[key]; !ok {
+				keys = append(keys, key)
+			}
+			labels[key] = label
+		}
+	}
+	merged := make([]string, len(keys))
+	for i, key := range keys {
+		merged[i] = labels[key]
+	}
+	return strings.Join(merged, ",")
+}
+
+// gkeCluster is the subset of the gcloud cluster description used by the janitor.
+type gkeCluster struct {
+	Name           string            `json:"name"`
+	Location       string            `json:"location"`
+	CreateTime     time.Time         `json:"createTime"`
+	ResourceLabels map[string]string `json:"resourceLabels"`
+}
+
+// expiredClusters returns the clusters created by kubetest2 which are older than ttl.
+func expiredClusters(clusters []gkeCluster, now time.Time, ttl time.Duration) []gkeCluster {
+	var expired []gkeCluster
+	for _, cluster := range clusters {
+		if _, ok := cluster.ResourceLabels[runIDLabel]; !ok {
+			continue
+		}
+		if now.Sub(cluster.CreateTime) > ttl {
+			expired = append(expired, cluster)
+		}
+	}
+	return expired
+}
+
+// Janitor implements the janitor subcommand, which deletes the clusters
+// created by the deployer that are older than a TTL, to clean up clusters
+// leaked when Down never ran.
+func Janitor(args []string) error {
+	var projects []string
+	var ttl time.Duration
+	var dryRun bool
+	flags := pflag.NewFlagSet(JanitorCommand, pflag.ContinueOnError)
+	flags.StringSliceVar(&projects, "project", nil, "Comma separated list of GCP Project(s) to clean up. Must be set.")
+	flags.DurationVar(&ttl, "ttl", 24*time.Hour, "How long (in golang duration format) a cluster may exist before it is considered leaked.")
+	flags.BoolVar(&dryRun, "dry-run", false, "If true, only print the clusters that would be deleted.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return fmt.Errorf("--project must be set for the janitor")
+	}
+
+	now := time.Now()
+	for _, project := range projects {
+		out, err := exec.Output(exec.Command("gcloud", containerArgs("clusters", "list",
+			"--project="+project,
+			"--filter=resourceLabels."+runIDLabel+":*",
+			"--format=json")...))
+		if err != nil {
+			return fmt.Errorf("failed to list clusters in project %s: %s", project, execError(err))
+		}
+		var clusters []gkeCluster
+		if err := json.Unmarshal(out, &clusters); err != nil {
+			return fmt.Errorf("failed to parse clusters in project %s: %w", project, err)
+		}
+
+		for _, cluster := range expiredClusters(clusters, now, ttl) {
+			klog.V(0).Infof("Deleting cluster %s in %s/%s created at %v by run %s", cluster.Name, project, cluster.Location, cluster.CreateTime, cluster.ResourceLabels[runIDLabel])
+			if dryRun {
+				continue
+			}
+			if err := runWithOutput(exec.Command("gcloud", containerArgs("clusters", "delete", cluster.Name,
+				"--project="+project,
+				"--location="+cluster.Location,
+				"--quiet",
+				"--async")...)); err != nil {
+				klog.Errorf("Error deleting cluster %s: %v", cluster.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// jobName returns the name of the CI job running kubetest2, if any.
+func jobName() string {
+	return os.Getenv("JOB_NAME")
+}
@@ -39,6 +39,14 @@ etag: %s
 
 func (d *Deployer) VerifyNetworkFlags() error {
 
+	// Verify datapath args.
+	if d.DataplaneV2Enabled && d.NetworkPolicyEnabled {
+		return errors.New("--enable-network-policy cannot be used with --enable-dataplane-v2, which already enforces network policy")
+	}
+	if d.Autopilot && (d.DataplaneV2Enabled || d.NetworkPolicyEnabled) {
+		return errors.New("--enable-dataplane-v2 and --enable-network-policy are not supported for GKE Autopilot clusters, which always use Dataplane V2")
+	}
+
 	// Verify private cluster args.
 	if d.PrivateClusterAccessLevel != "" {
 		if d.PrivateClusterAccessLevel != string(no) &&
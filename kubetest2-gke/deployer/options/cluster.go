@@ -16,7 +16,10 @@ limitations under the License.
 
 package options
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type ExtraNodePoolOptions struct {
 	Name        string
@@ -28,14 +31,17 @@ type ExtraNodePoolOptions struct {
 type ClusterOptions struct {
 	Environment string `flag:"~environment" desc:"Container API endpoint to use, one of 'test', 'staging', 'prod', or a custom https:// URL. Defaults to prod if not provided"`
 
-	GcloudCommandGroup string `flag:"~gcloud-command-group" desc:"gcloud command group, can be one of empty, alpha, beta."`
-	Autopilot          bool   `flag:"~autopilot" desc:"Whether to create GKE Autopilot clusters or not."`
-	GcloudExtraFlags   string `flag:"~gcloud-extra-flags" desc:"Extra gcloud flags to pass when creating the clusters."`
-	CreateCommandFlag  string `flag:"~create-command" desc:"gcloud subcommand and additional flags used to create a cluster, such as container clusters create --quiet. If it's specified, --gcloud-command-group, --autopilot, --gcloud-extra-flags will be ignored."`
+	GcloudCommandGroup    string `flag:"~gcloud-command-group" desc:"gcloud command group, can be one of empty, alpha, beta."`
+	Autopilot             bool   `flag:"~autopilot" desc:"Whether to create GKE Autopilot clusters or not."`
+	GcloudExtraFlags      string `flag:"~gcloud-extra-flags" desc:"Extra gcloud flags to pass when creating the clusters."`
+	CreateCommandFlag     string `flag:"~create-command" desc:"gcloud subcommand and additional flags used to create a cluster, such as container clusters create --quiet. If it's specified, --gcloud-command-group, --autopilot, --gcloud-extra-flags will be ignored."`
+	GcloudExtraCreateArgs string `flag:"~gcloud-extra-create-args" desc:"Extra args appended verbatim to the end of the gcloud command creating the clusters, after all the flags set by the deployer, and split like a shell would, e.g. --metadata='a=b c'. Unlike --gcloud-extra-flags it is also respected with --create-command, so new GKE features can be tested before dedicated flags exist."`
 
 	Regions []string `flag:"~region" desc:"Comma separated list for use with gcloud commands to specify the cluster region(s). The first region will be considered the primary region, and the rest will be considered the backup regions."`
 	Zones   []string `flag:"~zone" desc:"Comma separated list for use with gcloud commands to specify the cluster zone(s). The first zone will be considered the primary zone, and the rest will be considered the backup zones."`
 
+	NodeLocations []string `flag:"~node-locations" desc:"Comma separated list of zones the cluster nodes will be placed in, e.g. to spread the nodes of a regional cluster over a subset of its zones. All zones must be in the region of the cluster. With backup regions or zones, the zones may span their regions, each attempt using those in its own region."`
+
 	NumClusters             int      `flag:"~num-clusters" desc:"Number of clusters to create, will auto-generate names as (kt2-<run-id>-<index>)."`
 	Clusters                []string `flag:"~cluster-name" desc:"Cluster names separated by comma. Must be set. For multi-project profile, it should be in the format of clusterA:0,clusterB:1,clusterC:2, where the index means the index of the project."`
 	MachineType             string   `flag:"~machine-type" desc:"For use with gcloud commands to specify the machine type for the cluster."`
@@ -44,6 +50,10 @@ type ClusterOptions struct {
 	ReleaseChannel          string   `desc:"Use a GKE release channel, could be one of empty, rapid, regular and stable - https://cloud.google.com/kubernetes-engine/docs/concepts/release-channels"`
 	LegacyClusterVersion    string   `flag:"~version,deprecated" desc:"Use --cluster-version instead"`
 	ClusterVersion          string   `desc:"Use a specific GKE version e.g. 1.16.13.gke-400, 'latest' or ''. If --build is specified it will default to building kubernetes from source."`
+	ControlPlaneVersion     string   `flag:"~control-plane-version" desc:"Alias of --cluster-version, the GKE version of the control plane, for symmetry with --node-version."`
+	NodeVersion             string   `flag:"~node-version" desc:"Use a specific GKE version for the nodes e.g. 1.29.4-gke.1043002, to test a kubelet skewed from the control plane. Defaults to the version of the control plane."`
+	KubernetesAlphaEnabled  bool     `flag:"~enable-kubernetes-alpha" desc:"Whether to create alpha clusters with all Kubernetes alpha APIs and features enabled or not. Alpha clusters cannot be auto-upgraded or auto-repaired, are not enrolled in a release channel unless one is given explicitly, and are deleted automatically by GKE after 30 days."`
+	UpgradeTargetVersion    string   `desc:"If set, after the first test pass upgrade the control plane and then the node pools to this GKE version, re-running the tester after each step."`
 	WorkloadIdentityEnabled bool     `flag:"~enable-workload-identity" desc:"Whether enable workload identity for the cluster or not. See the details in https://cloud.google.com/kubernetes-engine/docs/how-to/workload-identity."`
 	FirewallRuleAllow       string   `desc:"A list of protocols and ports whose traffic will be allowed for the firewall rules created for the cluster."`
 
@@ -52,8 +62,38 @@ type ClusterOptions struct {
 	WindowsMachineType string `flag:"~windows-machine-type" desc:"For use with gcloud commands to specify the machine type for Windows node in the cluster."`
 	WindowsImageType   string `flag:"~windows-image-type" desc:"The Windows image type to use for the cluster."`
 
+	Arm64Enabled     bool   `flag:"~enable-arm64" desc:"Whether enable an arm64 node pool in the cluster or not, to create a multi-arch cluster."`
+	Arm64NumNodes    int    `flag:"~arm64-num-nodes" desc:"For use with gcloud commands to specify the number of nodes for the arm64 node pool in the cluster."`
+	Arm64MachineType string `flag:"~arm64-machine-type" desc:"For use with gcloud commands to specify the machine type for arm64 nodes in the cluster, e.g. t2a-standard-4."`
+
+	AcceleratorType            string        `flag:"~accelerator-type" desc:"Type of the GPU accelerator to attach to each node of the default node pool, e.g. nvidia-tesla-t4."`
+	AcceleratorCount           int           `flag:"~accelerator-count" desc:"Number of GPU accelerators to attach to each node of the default node pool."`
+	InstallGPUDriver           bool          `flag:"~install-gpu-driver" desc:"Whether to install the GPU drivers on the clusters by applying the driver installer daemonset."`
+	GPUDriverInstallerManifest string        `flag:"~gpu-driver-installer-manifest" desc:"Path or URL of the GPU driver installer daemonset manifest applied when --install-gpu-driver is set."`
+	AcceleratorWaitTimeout     time.Duration `flag:"~accelerator-wait-timeout" desc:"How long (in golang duration format) to wait for accelerators to become allocatable on the nodes before testing."`
+
+	ShieldedSecureBoot          bool `flag:"~shielded-secure-boot" desc:"Whether to enable secure boot on the shielded VM nodes of all the node pools or not."`
+	ShieldedIntegrityMonitoring bool `flag:"~shielded-integrity-monitoring" desc:"Whether to enable integrity monitoring on the shielded VM nodes of all the node pools or not."`
+	ConfidentialNodesEnabled    bool `flag:"~enable-confidential-nodes" desc:"Whether to run all the node pools on Confidential GKE Nodes or not. Requires a machine type supporting confidential computing, e.g. n2d-standard-4."`
+
+	BackupEnabled bool `flag:"~enable-backup" desc:"Whether to enable Backup for GKE on the clusters and take a backup of all the namespaces after Up or not. The backups are exported to the tester in KUBETEST2_GKE_BACKUPS and can be restored with the restore subcommand. Requires --enable-workload-identity."`
+
+	AutoscalingEnabled bool `flag:"~enable-autoscaling" desc:"Whether to enable the cluster autoscaler for the default node pool or not. Requires --max-nodes."`
+	MinNodes           int  `flag:"~min-nodes" desc:"Minimum number of nodes per zone of the default node pool when autoscaling is enabled."`
+	MaxNodes           int  `flag:"~max-nodes" desc:"Maximum number of nodes per zone of the default node pool when autoscaling is enabled."`
+
+	AutoprovisioningEnabled   bool `flag:"~enable-autoprovisioning" desc:"Whether to enable node auto-provisioning for the cluster or not. Requires --autoprovisioning-max-cpu and --autoprovisioning-max-memory."`
+	AutoprovisioningMinCPU    int  `flag:"~autoprovisioning-min-cpu" desc:"Minimum number of cores in the cluster when node auto-provisioning is enabled."`
+	AutoprovisioningMaxCPU    int  `flag:"~autoprovisioning-max-cpu" desc:"Maximum number of cores in the cluster when node auto-provisioning is enabled."`
+	AutoprovisioningMinMemory int  `flag:"~autoprovisioning-min-memory" desc:"Minimum number of gigabytes of memory in the cluster when node auto-provisioning is enabled."`
+	AutoprovisioningMaxMemory int  `flag:"~autoprovisioning-max-memory" desc:"Maximum number of gigabytes of memory in the cluster when node auto-provisioning is enabled."`
+
 	NodePoolCreateConcurrency int      `flag:"~nodepool-create-concurrency" desc:"Number of nodepools to create concurrently, default is 1"`
-	ExtraNodePool             []string `flag:"~extra-nodepool" desc:"create an extra nodepool. repeat the flag for another nodepool. options as key=value&key=value... supported options are name,machine-type,image-type,num-nodes. "`
+	ExtraNodePool             []string `flag:"~extra-nodepool" desc:"create an extra nodepool. repeat the flag for another nodepool. options as key=value&key=value... supported options are name,machine-type,image-type,num-nodes,min-nodes,max-nodes,accelerator-type,accelerator-count,tpu-topology. Setting max-nodes enables autoscaling for the nodepool. "`
+
+	FleetProject                string `flag:"~fleet-project" desc:"If set, register the clusters as members of the fleet in this project at creation time, for multi-cluster tests."`
+	MultiClusterServicesEnabled bool   `flag:"~enable-multi-cluster-services" desc:"Whether to enable multi-cluster Services for the fleet or not. Requires --fleet-project."`
+	MultiClusterIngressEnabled  bool   `flag:"~enable-multi-cluster-ingress" desc:"Whether to enable multi-cluster Ingress for the fleet or not, using the first cluster as the config cluster. Requires --fleet-project."`
 
 	RetryableErrorPatterns []string `flag:"~retryable-error-patterns" desc:"Comma separated list of regex match patterns for retryable errors during cluster creation."`
 }
@@ -22,4 +22,7 @@ type NetworkOptions struct {
 	PrivateClusterAccessLevel    string   `flag:"~private-cluster-access-level" desc:"Private cluster access level, if not empty, must be one of 'no', 'limited' or 'unrestricted'. See the details in https://cloud.google.com/kubernetes-engine/docs/how-to/private-clusters."`
 	PrivateClusterMasterIPRanges []string `flag:"~private-cluster-master-ip-range" desc:"Private cluster master IP ranges. It should be IPv4 CIDR(s), and its length must be the same as the number of clusters if private cluster is requested."`
 	SubnetworkRanges             []string `flag:"~subnetwork-ranges" desc:"Subnetwork ranges as required for shared VPC setup as described in https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-shared-vpc#creating_a_network_and_two_subnets. For multi-project profile, it is required and should be in the format of 10.0.4.0/22 10.0.32.0/20 10.4.0.0/14,172.16.4.0/22 172.16.16.0/20 172.16.4.0/22, where the subnetworks configuration for different project are separated by comma, and the ranges of each subnetwork configuration is separated by space."`
+
+	DataplaneV2Enabled   bool `flag:"~enable-dataplane-v2" desc:"Whether to create the clusters with Dataplane V2 enabled. See the details in https://cloud.google.com/kubernetes-engine/docs/concepts/dataplane-v2."`
+	NetworkPolicyEnabled bool `flag:"~enable-network-policy" desc:"Whether to enable network policy enforcement with the legacy (Calico) datapath. Cannot be used together with --enable-dataplane-v2, which enforces network policy natively."`
 }
@@ -16,12 +16,17 @@ limitations under the License.
 
 package options
 
+import (
+	"sigs.k8s.io/kubetest2/pkg/boskos"
+)
+
 type ProjectOptions struct {
 	Projects []string `flag:"~project" desc:"Comma separated list of GCP Project(s) to use for creating the cluster."`
 
-	BoskosLocation                 string   `flag:"~boskos-location" desc:"If set, manually specifies the location of the Boskos server."`
-	BoskosAcquireTimeoutSeconds    int      `flag:"~boskos-acquire-timeout-seconds" desc:"How long (in seconds) to hang on a request to Boskos to acquire a resource before erroring."`
-	BoskosHeartbeatIntervalSeconds int      `flag:"~boskos-heartbeat-interval-seconds" desc:"How often (in seconds) to send a heartbeat to Boskos to hold the acquired resource. 0 means no heartbeat."`
-	BoskosResourceType             []string `flag:"~boskos-resource-type" desc:"If set, manually specifies the resource type(s) of GCP projects to acquire from Boskos."`
-	BoskosProjectsRequested        []int    `flag:"~projects-requested" desc:"Number of projects to request from Boskos. It is only respected if projects is empty, and must be larger than zero."`
+	*boskos.Options
+
+	BoskosResourceType      []string `flag:"~boskos-resource-type" desc:"If set, manually specifies the resource type(s) of GCP projects to acquire from Boskos."`
+	FallbackProjects        []string `flag:"~fallback-gcp-projects" desc:"GCP projects to use when acquiring projects from Boskos fails, or instead of Boskos if --boskos-location is empty. The projects are arbitrated between runs by locks in --project-lock-location."`
+	ProjectLockLocation     string   `flag:"~project-lock-location" desc:"Location of the locks of --fallback-gcp-projects, a local directory or gs://bucket/path to share the projects between hosts. Defaults to a directory in the temp dir."`
+	BoskosProjectsRequested []int    `flag:"~projects-requested" desc:"Number of projects to request from Boskos. It is only respected if projects is empty, and must be larger than zero."`
 }
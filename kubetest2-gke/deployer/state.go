@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+)
+
+// state is what Down needs to delete the clusters of a previous run, see
+// types.DeployerWithState
+type state struct {
+	Projects         []string `json:"projects"`
+	FallbackProjects []string `json:"fallbackProjects,omitempty"`
+	Clusters         []string `json:"clusters"`
+	Regions          []string `json:"regions,omitempty"`
+	Zones            []string `json:"zones,omitempty"`
+	RetryCount       int      `json:"retryCount,omitempty"`
+	Network          string   `json:"network"`
+	BackupEnabled    bool     `json:"backupEnabled,omitempty"`
+}
+
+func (d *Deployer) State() ([]byte, error) {
+	return json.MarshalIndent(state{
+		Projects:         d.Projects,
+		FallbackProjects: d.fallbackProjects,
+		Clusters:         d.Clusters,
+		Regions:          d.Regions,
+		Zones:            d.Zones,
+		RetryCount:       d.retryCount,
+		Network:          d.Network,
+		BackupEnabled:    d.BackupEnabled,
+	}, "", "  ")
+}
+
+// RestoreState restores the projects and the clusters of a previous run, the
+// flags that are set take precedence. Boskos leases of the previous run are
+// not restored, the clusters are deleted directly instead.
+func (d *Deployer) RestoreState(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if len(d.Projects) == 0 {
+		d.Projects = s.Projects
+		d.fallbackProjects = s.FallbackProjects
+	}
+	if len(d.Clusters) == 0 {
+		d.Clusters = s.Clusters
+	}
+	if len(d.Regions) == 0 && len(d.Zones) == 0 {
+		d.Regions = s.Regions
+		d.Zones = s.Zones
+		d.retryCount = s.RetryCount
+	}
+	if d.Network == "default" {
+		d.Network = s.Network
+	}
+	d.BackupEnabled = d.BackupEnabled || s.BackupEnabled
+	return nil
+}
@@ -21,13 +21,16 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/kballard/go-shellquote"
 	"github.com/pkg/math"
 	"golang.org/x/sync/errgroup"
 	"k8s.io/klog/v2"
 
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
 	"sigs.k8s.io/kubetest2/pkg/exec"
 	"sigs.k8s.io/kubetest2/pkg/metadata"
 )
@@ -54,10 +57,18 @@ func (d *Deployer) Up() error {
 	if err := d.CreateClusters(); err != nil {
 		return fmt.Errorf("error creating the clusters: %w", err)
 	}
+	if err := d.EnableFleetFeatures(); err != nil {
+		return err
+	}
 
 	if err := d.TestSetup(); err != nil {
 		return fmt.Errorf("error running setup for the tests: %w", err)
 	}
+	if d.BackupEnabled {
+		if err := d.BackupClusters(); err != nil {
+			return fmt.Errorf("error backing up the clusters: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -70,6 +81,9 @@ func (d *Deployer) CreateClusters() error {
 		d.retryCount = retryCount
 		shouldRetry, err := d.tryCreateClusters(retryCount)
 		if !shouldRetry {
+			if err == nil {
+				d.writeClusterLocationToMetadata()
+			}
 			return err
 		}
 	}
@@ -77,6 +91,16 @@ func (d *Deployer) CreateClusters() error {
 	return nil
 }
 
+// writeClusterLocationToMetadata records the zone or region the clusters
+// were finally created in, which may be one of the fallback locations.
+func (d *Deployer) writeClusterLocationToMetadata() {
+	location := locationName(d.Regions, d.Zones, d.retryCount)
+	klog.V(1).Infof("Clusters created in %s", location)
+	if err := metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "cluster-location", location); err != nil {
+		klog.Warningf("failed to record the cluster location in metadata: %v", err)
+	}
+}
+
 func (d *Deployer) tryCreateClusters(retryCount int) (shouldRetry bool, err error) {
 	shouldRetry = false
 	if err = d.CreateSubnets(); err != nil {
@@ -154,9 +178,33 @@ func (d *Deployer) CreateCluster(project string, cluster cluster, subNetworkArgs
 		if d.ImageType != "" {
 			args = append(args, "--image-type="+d.ImageType)
 		}
+		args = append(args, acceleratorArgs(d.AcceleratorType, d.AcceleratorCount, "")...)
+		args = append(args, d.nodeSecurityArgs()...)
+		if d.KubernetesAlphaEnabled {
+			args = append(args, "--enable-kubernetes-alpha")
+			args = append(args, alphaNodePoolArgs...)
+		}
+		if d.AutoscalingEnabled {
+			args = append(args, autoscalingArgs(d.MinNodes, d.MaxNodes)...)
+		}
+		if d.AutoprovisioningEnabled {
+			args = append(args, autoprovisioningArgs(d.AutoprovisioningMinCPU, d.AutoprovisioningMaxCPU, d.AutoprovisioningMinMemory, d.AutoprovisioningMaxMemory)...)
+		}
+		if nodeLocations := d.retryNodeLocations(); len(nodeLocations) != 0 {
+			args = append(args, "--node-locations="+strings.Join(nodeLocations, ","))
+		}
 		if d.WorkloadIdentityEnabled {
 			args = append(args, fmt.Sprintf("--workload-pool=%s.svc.id.goog", project))
 		}
+		if d.BackupEnabled {
+			args = append(args, "--addons=BackupRestore")
+		}
+		if d.DataplaneV2Enabled {
+			args = append(args, "--enable-dataplane-v2")
+		}
+		if d.NetworkPolicyEnabled {
+			args = append(args, "--enable-network-policy")
+		}
 	}
 
 	if d.ReleaseChannel != "" {
@@ -172,6 +220,10 @@ func (d *Deployer) CreateCluster(project string, cluster cluster, subNetworkArgs
 		} else {
 			args = append(args, "--cluster-version="+d.ClusterVersion)
 		}
+	} else if d.KubernetesAlphaEnabled {
+		// Alpha clusters cannot be auto-upgraded, so do not enroll them in
+		// the release channel of the version.
+		args = append(args, "--cluster-version="+d.ClusterVersion)
 	} else {
 		args = append(args, "--cluster-version="+d.ClusterVersion)
 		releaseChannel, err := resolveReleaseChannelForClusterVersion(d.ClusterVersion, locationArg)
@@ -181,8 +233,21 @@ func (d *Deployer) CreateCluster(project string, cluster cluster, subNetworkArgs
 			args = append(args, "--release-channel="+releaseChannel)
 		}
 	}
+	if d.NodeVersion != "" {
+		args = append(args, "--node-version="+d.NodeVersion)
+	}
+	if d.FleetProject != "" {
+		args = append(args, "--fleet-project="+d.FleetProject)
+	}
 	args = append(args, subNetworkArgs...)
 	args = append(args, privateClusterArgs...)
+	// the labels of the user are merged into those of the run, as only the
+	// last --labels is honored and the janitor relies on the run ones
+	args, userLabels := extractLabels(args)
+	extraCreateArgs, extraLabels := extractLabels(d.gcloudExtraCreateArgs)
+	labels := append(append(userLabels, extraLabels...), clusterLabels(d.Kubetest2CommonOptions.RunID(), jobName()))
+	args = append(args, "--labels="+mergeLabels(labels...))
+	args = append(args, extraCreateArgs...)
 	args = append(args, cluster.name)
 	output, err := runWithOutputAndReturn(exec.Command("gcloud", args...))
 	if err != nil {
@@ -198,6 +263,14 @@ func (d *Deployer) CreateCluster(project string, cluster cluster, subNetworkArgs
 		}
 	}
 
+	if d.Arm64Enabled {
+		args := d.createNodePoolCommand(project, cluster, locationArg, "arm64-pool", d.ImageType, d.Arm64MachineType, d.Arm64NumNodes)
+		output, err := runWithOutputAndReturn(exec.Command("gcloud", args...))
+		if err != nil {
+			return fmt.Errorf("error creating arm64 node-pool: %v, output: %q", err, output)
+		}
+	}
+
 	eg := new(errgroup.Group)
 	// serialize extra nodepool creates by default.
 	eg.SetLimit(1)
@@ -209,6 +282,10 @@ func (d *Deployer) CreateCluster(project string, cluster cluster, subNetworkArgs
 		enp := enp
 		eg.Go(func() error {
 			args := d.createNodePoolCommand(project, cluster, locationArg, enp.Name, enp.ImageType, enp.MachineType, enp.NumNodes)
+			args = append(args, acceleratorArgs(enp.AcceleratorType, enp.AcceleratorCount, enp.TPUTopology)...)
+			if enp.MaxNodes > 0 {
+				args = append(args, autoscalingArgs(enp.MinNodes, enp.MaxNodes)...)
+			}
 			output, err := runWithOutputAndReturn(exec.Command("gcloud", args...))
 			if err != nil {
 				return fmt.Errorf("error creating nodepool %q: %v, output: %q", enp.Name, err, output)
@@ -256,10 +333,33 @@ func (d *Deployer) createNodePoolCommand(project string, cluster cluster, locati
 		fs = append(fs, "--machine-type="+machineType)
 	}
 	fs = append(fs, "--num-nodes="+strconv.Itoa(numNodes))
+	if d.NodeVersion != "" {
+		fs = append(fs, "--node-version="+d.NodeVersion)
+	}
+	fs = append(fs, d.nodeSecurityArgs()...)
+	if d.KubernetesAlphaEnabled {
+		fs = append(fs, alphaNodePoolArgs...)
+	}
 
 	return fs
 }
 
+// nodeSecurityArgs returns the gcloud flags for the shielded VM and
+// confidential computing options shared by all the node pools.
+func (d *Deployer) nodeSecurityArgs() []string {
+	var args []string
+	if d.ShieldedSecureBoot {
+		args = append(args, "--shielded-secure-boot")
+	}
+	if d.ShieldedIntegrityMonitoring {
+		args = append(args, "--shielded-integrity-monitoring")
+	}
+	if d.ConfidentialNodesEnabled {
+		args = append(args, "--enable-confidential-nodes")
+	}
+	return args
+}
+
 func (d *Deployer) IsUp() (up bool, err error) {
 	if err := d.PrepareGcpIfNeeded(d.Projects[0]); err != nil {
 		return false, err
@@ -281,12 +381,39 @@ func (d *Deployer) IsUp() (up bool, err error) {
 			if len(lines) == 0 {
 				return false, fmt.Errorf("project had no nodes active: %s", project)
 			}
+
+			// for multi-zonal clusters, also make sure each node location has nodes
+			if nodeLocations := d.retryNodeLocations(); len(nodeLocations) != 0 {
+				nodeZones, err := exec.Output(exec.Command("kubectl", "get", "nodes",
+					"-o", `jsonpath={.items[*].metadata.labels.topology\.kubernetes\.io/zone}`))
+				if err != nil {
+					return false, fmt.Errorf("failed to get node zones: %s", execError(err))
+				}
+				if missing := missingZones(strings.Fields(string(nodeZones)), nodeLocations); len(missing) != 0 {
+					return false, fmt.Errorf("cluster %s had no nodes active in zones: %v", cluster.name, missing)
+				}
+			}
 		}
 	}
 
 	return true, nil
 }
 
+// missingZones returns the expected zones that none of the nodes are in.
+func missingZones(nodeZones, expected []string) []string {
+	found := map[string]bool{}
+	for _, zone := range nodeZones {
+		found[zone] = true
+	}
+	var missing []string
+	for _, zone := range expected {
+		if !found[zone] {
+			missing = append(missing, zone)
+		}
+	}
+	return missing
+}
+
 func (d *Deployer) TestSetup() error {
 	if d.testPrepared {
 		// Ensure setup is a singleton.
@@ -302,10 +429,48 @@ func (d *Deployer) TestSetup() error {
 	if err := d.EnsureFirewallRules(); err != nil {
 		return err
 	}
+	if err := d.prepareAccelerators(); err != nil {
+		return err
+	}
+	if err := d.exportFleetMemberships(); err != nil {
+		return err
+	}
+	if err := d.writeNodeArchitecturesToMetadata(); err != nil {
+		klog.Warningf("failed to record node architectures in metadata: %v", err)
+	}
 	d.testPrepared = true
 	return nil
 }
 
+// writeNodeArchitecturesToMetadata records the architectures of the nodes
+// across all clusters, so testers can select matching test images.
+func (d *Deployer) writeNodeArchitecturesToMetadata() error {
+	var archs []string
+	for _, kubecfg := range filepath.SplitList(d.kubecfgPath) {
+		out, err := exec.Output(exec.Command("kubectl", "--kubeconfig="+kubecfg,
+			"get", "nodes", "-o", "jsonpath={.items[*].status.nodeInfo.architecture}"))
+		if err != nil {
+			return fmt.Errorf("failed to get node architectures: %w", err)
+		}
+		archs = append(archs, strings.Fields(string(out))...)
+	}
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "node-architectures", uniqueSorted(archs))
+}
+
+// uniqueSorted returns the distinct values as a sorted comma separated list.
+func uniqueSorted(values []string) string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, ",")
+}
+
 // Kubeconfig returns a path to a kubeconfig file for the cluster in
 // a temp directory, creating one if one does not exist.
 // It also sets the KUBECONFIG environment variable appropriately.
@@ -371,12 +536,52 @@ func (d *Deployer) VerifyUpFlags() error {
 	if d.NumNodes <= 0 {
 		return fmt.Errorf("--num-nodes must be larger than 0")
 	}
+	if d.Autopilot && (d.ShieldedSecureBoot || d.ShieldedIntegrityMonitoring || d.ConfidentialNodesEnabled) {
+		return fmt.Errorf("--shielded-secure-boot, --shielded-integrity-monitoring and --enable-confidential-nodes are not supported for GKE Autopilot clusters")
+	}
+	if d.BackupEnabled && (d.Autopilot || !d.WorkloadIdentityEnabled) {
+		return fmt.Errorf("--enable-backup requires --enable-workload-identity and is not supported for GKE Autopilot clusters")
+	}
+	if d.KubernetesAlphaEnabled && d.Autopilot {
+		return fmt.Errorf("--enable-kubernetes-alpha is not supported for GKE Autopilot clusters")
+	}
+	if d.AutoscalingEnabled {
+		if err := validateAutoscaling(d.MinNodes, d.MaxNodes); err != nil {
+			return fmt.Errorf("invalid autoscaling flags: %w", err)
+		}
+	}
+	if d.AutoprovisioningEnabled {
+		if err := validateAutoprovisioning(d.AutoprovisioningMinCPU, d.AutoprovisioningMaxCPU, d.AutoprovisioningMinMemory, d.AutoprovisioningMaxMemory); err != nil {
+			return fmt.Errorf("invalid node auto-provisioning flags: %w", err)
+		}
+	}
+	if d.AcceleratorType != "" && d.AcceleratorCount <= 0 {
+		return fmt.Errorf("--accelerator-count must be larger than 0 when --accelerator-type is set")
+	}
 	if err := validateVersion(d.ClusterVersion); err != nil {
 		return err
 	}
 	if err := validateReleaseChannel(d.ReleaseChannel); err != nil {
 		return err
 	}
+	if err := validateVersion(d.UpgradeTargetVersion); err != nil {
+		return err
+	}
+	if err := validateVersion(d.NodeVersion); err != nil {
+		return err
+	}
+	if d.NodeVersion != "" && d.Autopilot {
+		return fmt.Errorf("--node-version cannot be used with --autopilot, whose nodes follow the control plane")
+	}
+	if err := d.verifyFleetFlags(); err != nil {
+		return err
+	}
+	// split like a shell would, so that quoted values may contain spaces
+	extraCreateArgs, err := shellquote.Split(d.GcloudExtraCreateArgs)
+	if err != nil {
+		return fmt.Errorf("invalid --gcloud-extra-create-args %q: %w", d.GcloudExtraCreateArgs, err)
+	}
+	d.gcloudExtraCreateArgs = extraCreateArgs
 
 	for _, np := range d.ExtraNodePool {
 		// defaults
@@ -138,6 +138,42 @@ func TestBuildExtraNodePoolOptions(t *testing.T) {
 			},
 			expectedError: "%!s(<nil>)",
 		},
+		{
+			name: "valid gpu nodepool",
+			np:   "name=gpu-nodepool&machine-type=n1-standard-4&image-type=cos_containerd&num-nodes=1&accelerator-type=nvidia-tesla-t4&accelerator-count=2",
+			expectedNodepool: extraNodepool{
+				Name:             "gpu-nodepool",
+				MachineType:      "n1-standard-4",
+				ImageType:        "cos_containerd",
+				NumNodes:         1,
+				AcceleratorType:  "nvidia-tesla-t4",
+				AcceleratorCount: 2,
+			},
+			expectedError: "%!s(<nil>)",
+		},
+		{
+			name: "valid autoscaling nodepool",
+			np:   "name=autoscaling-nodepool&machine-type=test-machine-type&image-type=test-image-type&num-nodes=1&min-nodes=1&max-nodes=5",
+			expectedNodepool: extraNodepool{
+				Name:        "autoscaling-nodepool",
+				MachineType: "test-machine-type",
+				ImageType:   "test-image-type",
+				NumNodes:    1,
+				MinNodes:    1,
+				MaxNodes:    5,
+			},
+			expectedError: "%!s(<nil>)",
+		},
+		{
+			name:          "min-nodes larger than max-nodes",
+			np:            "name=autoscaling-nodepool&machine-type=test-machine-type&image-type=test-image-type&num-nodes=1&min-nodes=6&max-nodes=5",
+			expectedError: "min-nodes must be between 0 and max-nodes (5), got 6",
+		},
+		{
+			name:          "accelerator-count not set",
+			np:            "name=gpu-nodepool&machine-type=n1-standard-4&image-type=cos_containerd&num-nodes=1&accelerator-type=nvidia-tesla-t4",
+			expectedError: "accelerator-count must be > 0 when accelerator-type is set",
+		},
 		{
 			name:          "num-nodes not set",
 			np:            "name=extra-nodepool&machine-type=test-machine-type&image-type=test-image-type",
@@ -182,3 +218,24 @@ func TestBuildExtraNodePoolOptions(t *testing.T) {
 
 	}
 }
+
+func TestUniqueSorted(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		values   []string
+		expected string
+	}{
+		{"empty", nil, ""},
+		{"single arch", []string{"amd64", "amd64", "amd64"}, "amd64"},
+		{"multi arch", []string{"arm64", "amd64", "arm64"}, "amd64,arm64"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := uniqueSorted(tc.values); got != tc.expected {
+				t.Errorf("expected %q but got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// assert that deployer implements types.DeployerWithUpgrades
+var _ types.DeployerWithUpgrades = &Deployer{}
+
+// UpgradeSteps returns the steps to upgrade the clusters to
+// --upgrade-target-version, the control plane first and then the node pools.
+func (d *Deployer) UpgradeSteps() []types.UpgradeStep {
+	if d.UpgradeTargetVersion == "" {
+		return nil
+	}
+	steps := []types.UpgradeStep{
+		{Name: "UpgradeControlPlane", Run: d.UpgradeControlPlanes},
+	}
+	// Node pools of GKE Autopilot clusters are upgraded automatically.
+	if !d.Autopilot {
+		steps = append(steps, types.UpgradeStep{Name: "UpgradeNodePools", Run: d.UpgradeNodePools})
+	}
+	return steps
+}
+
+// UpgradeControlPlanes upgrades the control plane of all the clusters.
+func (d *Deployer) UpgradeControlPlanes() error {
+	return d.upgradeControlPlanes(d.UpgradeTargetVersion)
+}
+
+// UpgradeNodePools upgrades all the node pools of all the clusters.
+func (d *Deployer) UpgradeNodePools() error {
+	return d.upgradeNodePools(d.UpgradeTargetVersion)
+}
+
+// Upgrade moves the control plane and then the node pools of all the
+// clusters to version, implementing types.DeployerWithUpgrades. GKE only
+// allows downgrading the control plane to an older patch version.
+func (d *Deployer) Upgrade(version string) error {
+	if err := validateVersion(version); err != nil {
+		return err
+	}
+	if err := d.upgradeControlPlanes(version); err != nil {
+		return err
+	}
+	// Node pools of GKE Autopilot clusters are upgraded automatically.
+	if d.Autopilot {
+		return nil
+	}
+	return d.upgradeNodePools(version)
+}
+
+// CurrentVersion returns the version of the control plane of the first
+// cluster, implementing types.DeployerWithUpgrades
+func (d *Deployer) CurrentVersion() (string, error) {
+	if err := d.Init(); err != nil {
+		return "", err
+	}
+	if len(d.Projects) == 0 || len(d.projectClustersLayout[d.Projects[0]]) == 0 {
+		return "", fmt.Errorf("no cluster is known to get the version of")
+	}
+	project := d.Projects[0]
+	cluster := d.projectClustersLayout[project][0]
+	lines, err := exec.OutputLines(exec.Command("gcloud", containerArgs("clusters", "describe", cluster.name,
+		"--project="+project,
+		locationFlag(d.Regions, d.Zones, d.retryCount),
+		"--format=value(currentMasterVersion)")...))
+	if err != nil {
+		return "", fmt.Errorf("error getting the version of cluster %s: %s", cluster.name, execError(err))
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no version reported for cluster %s", cluster.name)
+	}
+	return lines[0], nil
+}
+
+func (d *Deployer) upgradeControlPlanes(version string) error {
+	if err := d.Init(); err != nil {
+		return err
+	}
+	location := locationFlag(d.Regions, d.Zones, d.retryCount)
+	for _, project := range d.Projects {
+		for _, cluster := range d.projectClustersLayout[project] {
+			klog.V(1).Infof("Upgrading the control plane of cluster %s to %s", cluster.name, version)
+			if err := runWithOutput(exec.Command("gcloud", containerArgs("clusters", "upgrade", cluster.name,
+				"--master",
+				"--cluster-version="+version,
+				"--project="+project,
+				location,
+				"--quiet")...)); err != nil {
+				return fmt.Errorf("error upgrading the control plane of cluster %s: %w", cluster.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Deployer) upgradeNodePools(version string) error {
+	if err := d.Init(); err != nil {
+		return err
+	}
+	location := locationFlag(d.Regions, d.Zones, d.retryCount)
+	for _, project := range d.Projects {
+		for _, cluster := range d.projectClustersLayout[project] {
+			pools, err := exec.OutputLines(exec.Command("gcloud", containerArgs("node-pools", "list",
+				"--cluster="+cluster.name,
+				"--project="+project,
+				location,
+				"--format=value(name)")...))
+			if err != nil {
+				return fmt.Errorf("error listing the node pools of cluster %s: %s", cluster.name, execError(err))
+			}
+			for _, pool := range pools {
+				klog.V(1).Infof("Upgrading node pool %s of cluster %s to %s", pool, cluster.name, version)
+				if err := runWithOutput(exec.Command("gcloud", containerArgs("clusters", "upgrade", cluster.name,
+					"--node-pool="+pool,
+					"--cluster-version="+version,
+					"--project="+project,
+					location,
+					"--quiet")...)); err != nil {
+					return fmt.Errorf("error upgrading node pool %s of cluster %s: %w", pool, cluster.name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
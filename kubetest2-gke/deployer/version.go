@@ -171,3 +171,17 @@ func getServerConfig(loc string) (*container.ServerConfig, error) {
 
 	return cfg, nil
 }
+
+// VersionSkew returns the GKE versions of the control plane and the nodes,
+// implementing types.DeployerWithVersionSkew
+func (d *Deployer) VersionSkew() (controlPlane, node string) {
+	// latest is resolved by GKE, so the version is not known here
+	if d.ClusterVersion == "latest" {
+		return "", d.NodeVersion
+	}
+	node = d.NodeVersion
+	if node == "" {
+		node = d.ClusterVersion
+	}
+	return d.ClusterVersion, node
+}
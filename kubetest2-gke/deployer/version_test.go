@@ -18,6 +18,8 @@ package deployer
 
 import (
 	"testing"
+
+	"sigs.k8s.io/kubetest2/kubetest2-gke/deployer/options"
 )
 
 func TestValidateVersion(t *testing.T) {
@@ -182,3 +184,41 @@ func TestIsClusterVersionMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionSkew(t *testing.T) {
+	testCases := []struct {
+		desc                 string
+		clusterVersion       string
+		nodeVersion          string
+		expectedControlPlane string
+		expectedNode         string
+	}{
+		{
+			desc:                 "nodes follow the control plane by default",
+			clusterVersion:       "1.30.2-gke.1000",
+			expectedControlPlane: "1.30.2-gke.1000",
+			expectedNode:         "1.30.2-gke.1000",
+		},
+		{
+			desc:                 "skewed nodes",
+			clusterVersion:       "1.30.2-gke.1000",
+			nodeVersion:          "1.29.4-gke.1043002",
+			expectedControlPlane: "1.30.2-gke.1000",
+			expectedNode:         "1.29.4-gke.1043002",
+		},
+		{
+			desc:           "latest control plane is unknown",
+			clusterVersion: "latest",
+			nodeVersion:    "1.29.4-gke.1043002",
+			expectedNode:   "1.29.4-gke.1043002",
+		},
+	}
+
+	for _, tc := range testCases {
+		d := &Deployer{ClusterOptions: &options.ClusterOptions{ClusterVersion: tc.clusterVersion, NodeVersion: tc.nodeVersion}}
+		controlPlane, node := d.VersionSkew()
+		if controlPlane != tc.expectedControlPlane || node != tc.expectedNode {
+			t.Errorf("%s: expected %q and %q, got %q and %q", tc.desc, tc.expectedControlPlane, tc.expectedNode, controlPlane, node)
+		}
+	}
+}
@@ -17,11 +17,27 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"sigs.k8s.io/kubetest2/pkg/app"
 
 	"sigs.k8s.io/kubetest2/kubetest2-gke/deployer"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		subcommands := map[string]func([]string) error{
+			deployer.JanitorCommand: deployer.Janitor,
+			deployer.RestoreCommand: deployer.Restore,
+		}
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 	app.Main(deployer.Name, deployer.New)
 }
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	auditPolicyDir  = "/etc/kubernetes/audit"
+	auditLogDir     = "/var/log/kubernetes"
+	auditLogFile    = "audit.log"
+	auditPolicyFile = "policy.yaml"
+
+	// defaultAuditPolicy logs the metadata of all requests.
+	defaultAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: Metadata
+`
+
+	// auditKubeadmConfigPatch configures the apiserver to write audit logs
+	// with the policy mounted into the control plane nodes.
+	auditKubeadmConfigPatch = `kind: ClusterConfiguration
+apiServer:
+  extraArgs:
+    audit-log-path: ` + auditLogDir + "/" + auditLogFile + `
+    audit-policy-file: ` + auditPolicyDir + "/" + auditPolicyFile + `
+  extraVolumes:
+  - name: audit-policy
+    hostPath: ` + auditPolicyDir + `
+    mountPath: ` + auditPolicyDir + `
+    readOnly: true
+    pathType: DirectoryOrCreate
+  - name: audit-logs
+    hostPath: ` + auditLogDir + `
+    mountPath: ` + auditLogDir + `
+    pathType: DirectoryOrCreate
+`
+)
+
+// auditPolicyPath returns the absolute path of the audit policy to mount
+// into the control plane nodes, writing the default policy to the run dir
+// if no policy was given.
+func (d *deployer) auditPolicyPath() (string, error) {
+	path := d.AuditPolicyPath
+	if path == "" {
+		path = filepath.Join(d.commonOptions.RunDir(), "audit-policy.yaml")
+		if err := os.WriteFile(path, []byte(defaultAuditPolicy), 0644); err != nil {
+			return "", fmt.Errorf("failed to write audit policy: %w", err)
+		}
+	}
+	return filepath.Abs(path)
+}
+
+// configureAuditLogging mounts the audit policy into the control plane
+// nodes of config and configures the apiserver to use it.
+func (d *deployer) configureAuditLogging(config map[string]interface{}) error {
+	policyPath, err := d.auditPolicyPath()
+	if err != nil {
+		return err
+	}
+	if _, ok := config["nodes"].([]interface{}); !ok {
+		config["nodes"] = kindNodes(1, 0)
+	}
+	for _, node := range config["nodes"].([]interface{}) {
+		nodeMap, ok := node.(map[string]interface{})
+		if !ok || nodeMap["role"] != "control-plane" {
+			continue
+		}
+		appendToList(nodeMap, "extraMounts", map[string]interface{}{
+			"hostPath":      policyPath,
+			"containerPath": auditPolicyDir + "/" + auditPolicyFile,
+			"readOnly":      true,
+		})
+	}
+	appendToList(config, "kubeadmConfigPatches", auditKubeadmConfigPatch)
+	return nil
+}
+
+// exportAuditLogs copies the audit logs of the control plane nodes of the
+// named cluster into logsDir, alongside the logs exported by kind for each node.
+func (d *deployer) exportAuditLogs(name, logsDir string) error {
+	nodes, err := d.nodes(name)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if !strings.Contains(node, "control-plane") {
+			continue
+		}
+		nodeLogsDir := filepath.Join(logsDir, node)
+		if err := os.MkdirAll(nodeLogsDir, os.ModePerm); err != nil {
+			return err
+		}
+		klog.V(1).Infof("exporting audit logs of %s", node)
+		if err := exec.Command(d.runtime(), "cp",
+			node+":"+auditLogDir+"/"+auditLogFile,
+			filepath.Join(nodeLogsDir, auditLogFile)).Run(); err != nil {
+			return fmt.Errorf("failed to copy audit logs of %s: %w", node, err)
+		}
+	}
+	return nil
+}
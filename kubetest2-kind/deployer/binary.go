@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const kindReleaseURL = "https://github.com/kubernetes-sigs/kind/releases/download"
+
+// kind returns the kind binary to run. If --kind-version is set, the release
+// is downloaded into the run dir on first use, otherwise kind from PATH is used.
+func (d *deployer) kind() (string, error) {
+	if d.KindVersion == "" {
+		return "kind", nil
+	}
+	if d.kindPath != "" {
+		return d.kindPath, nil
+	}
+	path := filepath.Join(d.commonOptions.RunDir(), "kind-"+d.KindVersion)
+	url := fmt.Sprintf("%s/%s/kind-%s-%s", kindReleaseURL, d.KindVersion, runtime.GOOS, runtime.GOARCH)
+	if err := ensureKind(url, path); err != nil {
+		return "", fmt.Errorf("failed to get kind %s: %w", d.KindVersion, err)
+	}
+	d.kindPath = path
+	return path, nil
+}
+
+// ensureKind downloads the kind binary at url to path, unless path already
+// holds it, and verifies it against the checksum published with the release.
+func ensureKind(url, path string) error {
+	expectedSHA, err := fetchSHA256(url + ".sha256sum")
+	if err != nil {
+		return err
+	}
+
+	if actualSHA, err := sha256sum(path); err == nil {
+		if actualSHA == expectedSHA {
+			klog.V(0).Infof("Using existing kind at %v", path)
+			return nil
+		}
+		klog.Warningf("sha256 of existing kind at %v does not match, downloading again", path)
+	}
+
+	klog.V(0).Infof("Downloading kind from %v", url)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := download(url, path); err != nil {
+		return err
+	}
+	actualSHA, err := sha256sum(path)
+	if err != nil {
+		return fmt.Errorf("failed to compute sha256 for %q: %v", path, err)
+	}
+	if actualSHA != expectedSHA {
+		os.Remove(path)
+		return fmt.Errorf("sha256 of %s does not match: expected %s, got %s", url, expectedSHA, actualSHA)
+	}
+	return os.Chmod(path, 0755)
+}
+
+// fetchSHA256 returns the checksum in a sha256sum formatted file at url.
+func fetchSHA256(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to get sha256 from %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get sha256 from %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read sha256 from %s: %v", url, err)
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty sha256 from %s", url)
+	}
+	return fields[0], nil
+}
+
+func download(url, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	return f.Close()
+}
+
+func sha256sum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
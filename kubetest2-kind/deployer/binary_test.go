@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureKind(t *testing.T) {
+	binary := []byte("kind binary")
+	sum := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(sum[:])
+
+	cases := []struct {
+		name     string
+		checksum string
+		existing []byte
+		expectDL bool
+		expectOK bool
+	}{
+		{
+			name:     "download",
+			checksum: checksum,
+			expectDL: true,
+			expectOK: true,
+		},
+		{
+			name:     "existing binary matches",
+			checksum: checksum,
+			existing: binary,
+			expectOK: true,
+		},
+		{
+			name:     "existing binary does not match",
+			checksum: checksum,
+			existing: []byte("stale"),
+			expectDL: true,
+			expectOK: true,
+		},
+		{
+			name:     "checksum mismatch",
+			checksum: "0000",
+			expectDL: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			downloaded := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/kind-linux-amd64.sha256sum":
+					w.Write([]byte(tc.checksum + "  kind-linux-amd64\n"))
+				case "/kind-linux-amd64":
+					downloaded = true
+					w.Write(binary)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "kind")
+			if tc.existing != nil {
+				if err := os.WriteFile(path, tc.existing, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := ensureKind(server.URL+"/kind-linux-amd64", path)
+			if tc.expectOK && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.expectOK {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed", path)
+				}
+			}
+			if downloaded != tc.expectDL {
+				t.Errorf("expected download %v, got %v", tc.expectDL, downloaded)
+			}
+		})
+	}
+}
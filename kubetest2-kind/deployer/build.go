@@ -17,8 +17,6 @@ limitations under the License.
 package deployer
 
 import (
-	"os"
-
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/build"
@@ -26,12 +24,19 @@ import (
 )
 
 func (d *deployer) Build() error {
+	kind, err := d.kind()
+	if err != nil {
+		return err
+	}
 	args := []string{
 		"build", "node-image",
 	}
 	if d.BuildType != "" {
 		args = append(args, "--type", d.BuildType)
 	}
+	if d.Arch != "" {
+		args = append(args, "--arch", d.Arch)
+	}
 	if d.KubeRoot != "" {
 		args = append(args, "--kube-root", d.KubeRoot)
 	}
@@ -45,7 +50,7 @@ func (d *deployer) Build() error {
 
 	klog.V(0).Infof("Build(): building kind node image...\n")
 	// we want to see the output so use process.ExecJUnit
-	if err := process.ExecJUnit("kind", args, os.Environ()); err != nil {
+	if err := process.ExecJUnitTimeout(d.CommandTimeout, kind, args, d.kindEnv()); err != nil {
 		return err
 	}
 	build.StoreCommonBinaries(d.KubeRoot, d.commonOptions.RunDir())
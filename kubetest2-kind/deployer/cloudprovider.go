@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	cloudProviderName         = "kubetest2-cloud-provider-kind"
+	defaultCloudProviderImage = "registry.k8s.io/cloud-provider-kind/cloud-controller-manager:v0.4.0"
+	// nodes with this label are not used as load balancer backends,
+	// which leaves single node clusters without any
+	excludeFromLoadBalancersLabel = "node.kubernetes.io/exclude-from-external-load-balancers"
+)
+
+// startCloudProvider starts cloud-provider-kind unless it is running already.
+// A single cloud-provider-kind serves the LoadBalancer services of all the
+// clusters on the kind network.
+func (d *deployer) startCloudProvider() error {
+	running, err := exec.Output(exec.Command(d.runtime(), "inspect", "-f", "{{.State.Running}}", cloudProviderName))
+	if err == nil && strings.TrimSpace(string(running)) == "true" {
+		klog.V(1).Infof("cloud-provider-kind %s is already running", cloudProviderName)
+		return nil
+	}
+	klog.V(0).Infof("Up(): starting cloud-provider-kind...\n")
+	cmd := exec.Command(d.runtime(), "run", "-d", "--restart=always",
+		"--network", "kind",
+		"-v", "/var/run/docker.sock:/var/run/docker.sock",
+		"--name", cloudProviderName,
+		d.CloudProviderImage)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to start cloud-provider-kind: %w", err)
+	}
+	return nil
+}
+
+// includeNodesInLoadBalancers makes all nodes of the cluster of kubeconfig
+// eligible as load balancer backends.
+func includeNodesInLoadBalancers(kubeconfig string) error {
+	args := []string{"label", "nodes", "--all", excludeFromLoadBalancersLabel + "-"}
+	if kubeconfig != "" {
+		args = append([]string{"--kubeconfig=" + kubeconfig}, args...)
+	}
+	cmd := exec.Command("kubectl", args...)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove %s label from nodes: %w", excludeFromLoadBalancersLabel, err)
+	}
+	return nil
+}
+
+// deleteCloudProvider removes the cloud-provider-kind container.
+func (d *deployer) deleteCloudProvider() error {
+	cmd := exec.Command(d.runtime(), "rm", "-f", cloudProviderName)
+	exec.InheritOutput(cmd)
+	return cmd.Run()
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kindConfigKind       = "Cluster"
+	kindConfigAPIVersion = "kind.x-k8s.io/v1alpha4"
+)
+
+var validIPFamilies = []string{"ipv4", "ipv6", "dual"}
+
+// clusterConfig returns the kind cluster config built from --config and the
+// flags of the deployer, with the --config-patch patches applied on top.
+// It returns nil if there is nothing to configure, so kind's defaults apply.
+func (d *deployer) clusterConfig() (map[string]interface{}, error) {
+	config := map[string]interface{}{
+		"kind":       kindConfigKind,
+		"apiVersion": kindConfigAPIVersion,
+	}
+	configured := false
+
+	if d.ConfigPath != "" {
+		contents, err := os.ReadFile(d.ConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kind config: %w", err)
+		}
+		if err := yaml.Unmarshal(contents, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse kind config %s: %w", d.ConfigPath, err)
+		}
+		configured = true
+	}
+
+	if d.ControlPlaneNodes > 0 || d.WorkerNodes > 0 {
+		if d.ConfigPath != "" {
+			return nil, fmt.Errorf("--control-plane-nodes and --worker-nodes cannot be used with --config, set the nodes in the config instead")
+		}
+		config["nodes"] = kindNodes(d.ControlPlaneNodes, d.WorkerNodes)
+		configured = true
+	}
+
+	if d.ControlPlaneVersion != "" || d.NodeVersion != "" {
+		if err := d.configureNodeImages(config); err != nil {
+			return nil, err
+		}
+		configured = true
+	}
+
+	if d.IPFamily != "" {
+		if !slices.Contains(validIPFamilies, d.IPFamily) {
+			return nil, fmt.Errorf("--ip-family must be one of %v, got %q", validIPFamilies, d.IPFamily)
+		}
+		setNested(config, d.IPFamily, "networking", "ipFamily")
+		configured = true
+	}
+
+	if d.FeatureGates != "" {
+		featureGates, err := parseKeyValues(d.FeatureGates)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --feature-gates: %w", err)
+		}
+		for gate, value := range featureGates {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --feature-gates value for %s: %w", gate, err)
+			}
+			setNested(config, enabled, "featureGates", gate)
+		}
+		configured = true
+	}
+
+	if d.RuntimeConfig != "" {
+		runtimeConfig, err := parseKeyValues(d.RuntimeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --runtime-config: %w", err)
+		}
+		for api, value := range runtimeConfig {
+			setNested(config, value, "runtimeConfig", api)
+		}
+		configured = true
+	}
+
+	for _, patch := range d.KubeadmConfigPatches {
+		// the patch may be given inline or as the path to a file
+		if contents, err := os.ReadFile(patch); err == nil {
+			patch = string(contents)
+		}
+		appendToList(config, "kubeadmConfigPatches", patch)
+		configured = true
+	}
+
+	if d.AuditLoggingEnabled {
+		if err := d.configureAuditLogging(config); err != nil {
+			return nil, err
+		}
+		configured = true
+	}
+
+	if d.LocalRegistryPort > 0 {
+		appendToList(config, "containerdConfigPatches", d.localRegistryContainerdPatch())
+		configured = true
+	}
+
+	for _, patchPath := range d.ConfigPatches {
+		patch, err := os.ReadFile(patchPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kind config patch: %w", err)
+		}
+		if config, err = applyPatch(config, patch); err != nil {
+			return nil, fmt.Errorf("failed to apply kind config patch %s: %w", patchPath, err)
+		}
+		configured = true
+	}
+
+	if !configured {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// parseKeyValues parses a comma separated list of key=value pairs.
+func parseKeyValues(s string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("%q is not in the format key=value", pair)
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values, nil
+}
+
+// setNested sets value at the path of keys of obj, creating maps as needed.
+func setNested(obj map[string]interface{}, value interface{}, keys ...string) {
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := obj[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[key] = child
+		}
+		obj = child
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+// appendToList appends value to the list at key of obj, creating the list if needed.
+func appendToList(obj map[string]interface{}, key string, value interface{}) {
+	list, _ := obj[key].([]interface{})
+	obj[key] = append(list, value)
+}
+
+// kindNodes returns the nodes of a kind config with the given number of
+// control plane and worker nodes. There is always at least one control plane.
+func kindNodes(controlPlanes, workers int) []interface{} {
+	if controlPlanes < 1 {
+		controlPlanes = 1
+	}
+	nodes := make([]interface{}, 0, controlPlanes+workers)
+	for i := 0; i < controlPlanes; i++ {
+		nodes = append(nodes, map[string]interface{}{"role": "control-plane"})
+	}
+	for i := 0; i < workers; i++ {
+		nodes = append(nodes, map[string]interface{}{"role": "worker"})
+	}
+	return nodes
+}
+
+// writeClusterConfig writes the kind cluster config to the run dir and
+// returns its path, or an empty path if there is no config.
+func (d *deployer) writeClusterConfig() (string, error) {
+	config, err := d.clusterConfig()
+	if err != nil || config == nil {
+		return "", err
+	}
+	contents, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal kind config: %w", err)
+	}
+	path := filepath.Join(d.commonOptions.RunDir(), "kind-config.yaml")
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		return "", fmt.Errorf("failed to write kind config: %w", err)
+	}
+	klog.V(2).Infof("kind config written to %s:\n%s", path, contents)
+	return path, nil
+}
@@ -19,8 +19,11 @@ package deployer
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/octago/sflags/gen/gpflag"
 	"github.com/spf13/pflag"
@@ -37,10 +40,11 @@ var GitTag string
 
 // New implements deployer.New for kind
 func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
-	// create a deployer object and set fields that are not flag controlled
+	// create a deployer object and set defaults and fields that are not flag controlled
 	d := &deployer{
-		commonOptions: opts,
-		logsDir:       filepath.Join(artifacts.BaseDir(), "logs"),
+		commonOptions:      opts,
+		CloudProviderImage: defaultCloudProviderImage,
+		logsDir:            filepath.Join(artifacts.BaseDir(), "logs"),
 	}
 	// register flags and return
 	return d, bindFlags(d)
@@ -49,21 +53,65 @@ func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
 // assert that New implements types.NewDeployer
 var _ types.NewDeployer = New
 
+// assert that deployer implements types.DeployerWithTestArgs
+var _ types.DeployerWithTestArgs = &deployer{}
+
+// assert that deployer implements types.DeployerWithState
+var _ types.DeployerWithState = &deployer{}
+
+// assert that deployer implements types.DeployerWithFeatures
+var _ types.DeployerWithFeatures = &deployer{}
+
 type deployer struct {
 	// generic parts
 	commonOptions types.Options
 	// kind specific details
-	NodeImage      string `flag:"image-name" desc:"the image name to use for build and up"`
-	ClusterName    string `flag:"cluster-name" desc:"the kind cluster --name"`
-	BuildType      string `desc:"--type for kind build node-image"`
-	ConfigPath     string `flag:"config" desc:"--config for kind create cluster"`
-	KubeconfigPath string `flag:"kubeconfig" desc:"--kubeconfig flag for kind create cluster"`
-	KubeRoot       string `desc:"--kube-root for kind build node-image"`
+	NodeImage            string        `flag:"image-name" desc:"the image name to use for build and up"`
+	NodeImageTarball     string        `desc:"path to a node image tarball to load into the container runtime before up, so that no node image is pulled. The loaded image is used unless --image-name is set"`
+	ClusterName          string        `flag:"cluster-name" desc:"the kind cluster --name"`
+	BuildType            string        `desc:"--type for kind build node-image"`
+	Arch                 string        `desc:"--arch for kind build node-image, e.g. arm64 for arm64 nodes. Defaults to the architecture of the host, which the nodes run on"`
+	ConfigPath           string        `flag:"config" desc:"--config for kind create cluster"`
+	ConfigPatches        []string      `flag:"config-patch" desc:"path to a patch applied on top of the kind cluster config, can be repeated. A YAML/JSON object is applied as a merge patch, merging the nodes by index, and a list of operations as a JSON6902 patch"`
+	ControlPlaneNodes    int           `desc:"number of control plane nodes of the kind cluster, used when --config is not set"`
+	WorkerNodes          int           `desc:"number of worker nodes of the kind cluster, used when --config is not set"`
+	ControlPlaneVersion  string        `desc:"kubernetes version of the control plane nodes, e.g. v1.30.0 for the kindest/node:v1.30.0 node image, or a node image. Overrides --image-name for these nodes"`
+	NodeVersion          string        `desc:"kubernetes version of the worker nodes, e.g. v1.29.4 for a kubelet one minor version behind the control plane, or a node image. Overrides --image-name for these nodes, and adds a worker node if the cluster would have none"`
+	Provider             string        `desc:"the node provider for kind, one of docker, podman or nerdctl. Defaults to kind's auto-detection"`
+	IPFamily             string        `desc:"the IP family of the cluster networking, one of ipv4, ipv6 or dual"`
+	FeatureGates         string        `desc:"comma separated list of feature gates to set in the kind config, e.g. SomeGate=true,OtherGate=false"`
+	RuntimeConfig        string        `desc:"comma separated list of API runtime config to set in the kind config, e.g. api/alpha=true"`
+	LocalRegistryPort    int           `flag:"with-local-registry" desc:"if set, start a local registry on this port of the host and configure the nodes to pull from it, e.g. --with-local-registry or --with-local-registry=5002. The endpoint is exported to the tester in KUBETEST2_LOCAL_REGISTRY"`
+	CloudProviderEnabled bool          `flag:"with-cloud-provider-kind" desc:"if set, run cloud-provider-kind after up so that Services of type LoadBalancer get an external IP. Requires the docker provider"`
+	CloudProviderImage   string        `flag:"cloud-provider-kind-image" desc:"the cloud-provider-kind image to run with --with-cloud-provider-kind"`
+	LoadImages           []string      `flag:"load-image" desc:"image reference or image archive path to load into the cluster nodes after up, can be repeated"`
+	AuditLoggingEnabled  bool          `flag:"enable-audit-logging" desc:"if set, enable apiserver audit logging and export the audit logs with the cluster logs"`
+	AuditPolicyPath      string        `flag:"audit-policy" desc:"path to the audit policy to use with --enable-audit-logging, defaults to logging the metadata of all requests"`
+	NumClusters          int           `desc:"number of kind clusters to create, named after --cluster-name or the run id with an index suffix. Each cluster gets its own kubeconfig in the run dir"`
+	KindVersion          string        `desc:"kind release to download into the run dir and use, e.g. v0.20.0. Defaults to kind from PATH"`
+	CommandTimeout       time.Duration `desc:"timeout of each kind command, e.g. 20m. A command that times out is killed along with its child processes. 0 means no timeout"`
+	EnvAllowlist         []string      `desc:"if set, kind is run with only the environment variables with these names instead of the whole environment, e.g. DOCKER_HOST or KIND_*. A trailing * matches any suffix. Common variables such as PATH, HOME and proxy settings are always passed"`
+	KubeconfigPath       string        `flag:"kubeconfig" desc:"--kubeconfig flag for kind create cluster"`
+	KubeRoot             string        `desc:"--kube-root for kind build node-image"`
+
+	// bound in bindFlags, as patches may contain commas
+	KubeadmConfigPatches []string `flag:"-"`
 
 	logsDir string
+	// the downloaded kind binary when --kind-version is set
+	kindPath string
+	// whether this run started the local registry, which down only deletes then
+	localRegistryCreated bool
 }
 
 func (d *deployer) Kubeconfig() (string, error) {
+	if d.NumClusters > 1 {
+		var kubeconfigs []string
+		for _, name := range d.clusterNames() {
+			kubeconfigs = append(kubeconfigs, d.clusterKubeconfig(name))
+		}
+		return strings.Join(kubeconfigs, string(os.PathListSeparator)), nil
+	}
 	if d.KubeconfigPath != "" {
 		return d.KubeconfigPath, nil
 	}
@@ -74,10 +122,74 @@ func (d *deployer) Kubeconfig() (string, error) {
 	return filepath.Join(home, ".kube", "config"), nil
 }
 
+// TestArgs returns the e2e.test arguments for the cluster, implementing
+// types.DeployerWithTestArgs
+func (d *deployer) TestArgs() ([]string, error) {
+	// kind clusters have no cloud provider, only the skeleton one applies
+	args := []string{"--provider=skeleton"}
+	if d.ConfigPath == "" && d.WorkerNodes > 0 {
+		args = append(args, fmt.Sprintf("--num-nodes=%d", d.WorkerNodes))
+	}
+	return args, nil
+}
+
+// Features returns the features of the kind clusters, whose default storage
+// class provisions local volumes, implementing types.DeployerWithFeatures
+func (d *deployer) Features() []string {
+	features := []string{types.FeaturePersistentVolumes}
+	if d.CloudProviderEnabled {
+		features = append(features, types.FeatureLoadBalancer)
+	}
+	switch d.IPFamily {
+	case "ipv6":
+		features = append(features, types.FeatureIPv6)
+	case "dual":
+		features = append(features, types.FeatureDualStack)
+	}
+	return features
+}
+
 func (d *deployer) Version() string {
 	return GitTag
 }
 
+// clusterNames returns the names of the kind clusters of the run.
+// With --num-clusters, the names are derived from --cluster-name if set,
+// or from the run id otherwise.
+func (d *deployer) clusterNames() []string {
+	if d.NumClusters <= 1 {
+		return []string{d.ClusterName}
+	}
+	prefix := d.ClusterName
+	if prefix == "" {
+		prefix = "kt2-" + d.commonOptions.RunID()
+	}
+	names := make([]string, d.NumClusters)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", prefix, i+1)
+	}
+	return names
+}
+
+// clusterKubeconfig returns the kubeconfig path for the named cluster,
+// or an empty path for kind's default. With --num-clusters, each cluster
+// has its own kubeconfig in the run dir.
+func (d *deployer) clusterKubeconfig(name string) string {
+	if d.NumClusters > 1 {
+		return filepath.Join(d.commonOptions.RunDir(), "kubeconfig-"+name)
+	}
+	return d.KubeconfigPath
+}
+
+// clusterLogsDir returns the directory the logs of the named cluster are
+// exported to.
+func (d *deployer) clusterLogsDir(name string) string {
+	if d.NumClusters > 1 {
+		return filepath.Join(d.logsDir, name)
+	}
+	return d.logsDir
+}
+
 // helper used to create & bind a flagset to the deployer
 func bindFlags(d *deployer) *pflag.FlagSet {
 	flags, err := gpflag.Parse(d)
@@ -86,6 +198,11 @@ func bindFlags(d *deployer) *pflag.FlagSet {
 		return nil
 	}
 
+	flags.StringArrayVar(&d.KubeadmConfigPatches, "kubeadm-config-patch", nil, "kubeadm config patch, or the path to a file containing one, to add to the kubeadmConfigPatches of the kind config, can be repeated")
+
+	// allow --with-local-registry without a port
+	flags.Lookup("with-local-registry").NoOptDefVal = defaultLocalRegistryPort
+
 	flags.AddGoFlagSet(flag.CommandLine)
 
 	return flags
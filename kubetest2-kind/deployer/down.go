@@ -17,20 +17,51 @@ limitations under the License.
 package deployer
 
 import (
-	"os"
+	"errors"
+	"fmt"
 
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/process"
 )
 
+// Down deletes every cluster best-effort, along with cloud-provider-kind and
+// the local registry it started, so that a failure does not leak the rest.
 func (d *deployer) Down() error {
-	args := []string{
-		"delete", "cluster",
-		"--name", d.ClusterName,
+	kind, err := d.kind()
+	if err != nil {
+		return err
 	}
+	var errs []error
+	for _, name := range d.clusterNames() {
+		// the audit logs are lost with the nodes, so save them first
+		if d.AuditLoggingEnabled {
+			if err := d.exportAuditLogs(name, d.clusterLogsDir(name)); err != nil {
+				klog.Warningf("Down(): failed to export audit logs: %v", err)
+			}
+		}
 
-	klog.V(0).Infof("Down(): deleting kind cluster...%s\n", d.ClusterName)
-	// we want to see the output so use process.ExecJUnit
-	return process.ExecJUnit("kind", args, os.Environ())
+		args := []string{
+			"delete", "cluster",
+			"--name", name,
+		}
+		klog.V(0).Infof("Down(): deleting kind cluster...%s\n", name)
+		// we want to see the output so use process.ExecJUnit
+		if err := process.ExecJUnitTimeout(d.CommandTimeout, kind, args, d.kindEnv()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete kind cluster %s: %w", name, err))
+		}
+	}
+
+	if d.CloudProviderEnabled {
+		if err := d.deleteCloudProvider(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete cloud-provider-kind: %w", err))
+		}
+	}
+	// the registry may be in use by other runs unless this one started it
+	if d.LocalRegistryPort > 0 && d.localRegistryCreated {
+		if err := d.deleteLocalRegistry(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete local registry: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -17,21 +17,126 @@ limitations under the License.
 package deployer
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"k8s.io/klog/v2"
 
+	"sigs.k8s.io/kubetest2/pkg/exec"
 	"sigs.k8s.io/kubetest2/pkg/process"
 )
 
+// dumpParallelism is the number of nodes dumped at once
+const dumpParallelism = 10
+
+// nodeDumps are the commands run on each node by dumpNodes, keyed by the file
+// their output is written to in the directory of the node, mirroring the
+// per-node layout of the GCE cluster logs.
+var nodeDumps = []struct {
+	file    string
+	command []string
+}{
+	{"crictl-pods.log", []string{"crictl", "pods"}},
+	{"crictl-ps.log", []string{"crictl", "ps", "-a"}},
+	{"crictl-images.log", []string{"crictl", "images"}},
+	{"containerd.log", []string{"journalctl", "--no-pager", "-u", "containerd"}},
+	{"iptables.log", []string{"iptables-save"}},
+	{"ip6tables.log", []string{"ip6tables-save"}},
+	{"nftables.log", []string{"nft", "list", "ruleset"}},
+}
+
 func (d *deployer) DumpClusterLogs() error {
-	args := []string{
-		"export", "logs",
-		"--name", d.ClusterName,
-		d.logsDir,
+	kind, err := d.kind()
+	if err != nil {
+		return err
+	}
+	for _, name := range d.clusterNames() {
+		logsDir := d.clusterLogsDir(name)
+		args := []string{
+			"export", "logs",
+			"--name", name,
+			logsDir,
+		}
+
+		klog.V(0).Infof("DumpClusterLogs(): exporting kind cluster logs...%s\n", name)
+		// we want to see the output so use process.ExecJUnit
+		if err := process.ExecJUnitTimeout(d.CommandTimeout, kind, args, d.kindEnv()); err != nil {
+			return err
+		}
+		if d.AuditLoggingEnabled {
+			if err := d.exportAuditLogs(name, logsDir); err != nil {
+				return err
+			}
+		}
+		if err := d.dumpNodeContainers(name, logsDir); err != nil {
+			return err
+		}
+		if err := d.dumpNodes(name, logsDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodes returns the names of the node containers of the named cluster.
+func (d *deployer) nodes(name string) ([]string, error) {
+	kind, err := d.kind()
+	if err != nil {
+		return nil, err
 	}
+	nodes, err := exec.OutputLines(exec.Command(kind, "get", "nodes", "--name", name).SetEnv(d.kindEnv()...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kind nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+// dumpNodes writes the container runtime inspection of each node of the named
+// cluster and the output of nodeDumps run on it into logsDir/<node>, dumping
+// up to dumpParallelism nodes at once.
+// Failing commands are only logged, as not all of them exist in every node image.
+func (d *deployer) dumpNodes(name, logsDir string) error {
+	nodes, err := d.nodes(name)
+	if err != nil {
+		return err
+	}
+	var tasks []process.Task
+	for _, node := range nodes {
+		node := node
+		tasks = append(tasks, process.Task{
+			Name: "dump " + node,
+			Run: func() error {
+				nodeLogsDir := filepath.Join(logsDir, node)
+				if err := os.MkdirAll(nodeLogsDir, os.ModePerm); err != nil {
+					return err
+				}
+				klog.V(1).Infof("dumping state of %s", node)
+				d.dumpToFile(filepath.Join(nodeLogsDir, d.runtime()+"-inspect.json"), "inspect", node)
+				for _, dump := range nodeDumps {
+					args := append([]string{"exec", node}, dump.command...)
+					d.dumpToFile(filepath.Join(nodeLogsDir, dump.file), args...)
+				}
+				return nil
+			},
+		})
+	}
+	return process.ExecParallel(dumpParallelism, tasks)
+}
 
-	klog.V(0).Infof("DumpClusterLogs(): exporting kind cluster logs...\n")
-	// we want to see the output so use process.ExecJUnit
-	return process.ExecJUnit("kind", args, os.Environ())
+// dumpToFile writes the combined output of running the container runtime
+// with args to path, logging any failure.
+func (d *deployer) dumpToFile(path string, args ...string) {
+	f, err := os.Create(path)
+	if err != nil {
+		klog.Warningf("failed to create %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+	cmd := exec.Command(d.runtime(), args...)
+	cmd.SetStdout(f)
+	cmd.SetStderr(f)
+	if err := cmd.Run(); err != nil {
+		klog.Warningf("failed to dump %s: %v", filepath.Base(path), err)
+	}
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+// loadImages loads the --load-image images into the nodes of the named cluster.
+// Paths of existing files are loaded as image archives, anything else as
+// image references of the container runtime.
+func (d *deployer) loadImages(name string) error {
+	kind, err := d.kind()
+	if err != nil {
+		return err
+	}
+	for _, image := range d.LoadImages {
+		args := []string{"load", "docker-image", image}
+		if info, err := os.Stat(image); err == nil && info.Mode().IsRegular() {
+			args = []string{"load", "image-archive", image}
+		}
+		args = append(args, "--name", name)
+
+		klog.V(0).Infof("Up(): loading image %s into the kind cluster...\n", image)
+		// we want to see the output so use process.ExecJUnit
+		if err := process.ExecJUnitTimeout(d.CommandTimeout, kind, args, d.kindEnv()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadNodeImageTarball loads the --node-image-tarball into the container
+// runtime, so that creating the cluster does not need to pull the node image.
+// Unless --image-name is set, the loaded image is used for the nodes.
+func (d *deployer) loadNodeImageTarball() error {
+	if d.commonOptions.ShouldBuild() {
+		return fmt.Errorf("--node-image-tarball cannot be used with --build")
+	}
+	klog.V(0).Infof("Up(): loading node image tarball %s...\n", d.NodeImageTarball)
+	lines, err := exec.CombinedOutputLines(exec.Command(d.runtime(), "load", "-i", d.NodeImageTarball))
+	if err != nil {
+		return fmt.Errorf("failed to load node image tarball %s: %v, output: %q", d.NodeImageTarball, err, lines)
+	}
+	if d.NodeImage != "" {
+		return nil
+	}
+	image := loadedImage(lines)
+	if image == "" {
+		return fmt.Errorf("failed to find the image loaded from %s, set --image-name, output: %q", d.NodeImageTarball, lines)
+	}
+	klog.V(0).Infof("Up(): using node image %s\n", image)
+	d.NodeImage = image
+	return nil
+}
+
+// loadedImage returns the reference of the image from the output of
+// "<runtime> load", or an empty string if it was loaded untagged.
+func loadedImage(lines []string) string {
+	for _, line := range lines {
+		for _, prefix := range []string{"Loaded image:", "Loaded image(s):"} {
+			if image, ok := strings.CutPrefix(line, prefix); ok {
+				return strings.TrimSpace(image)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import "testing"
+
+func TestLoadedImage(t *testing.T) {
+	cases := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{
+			name:     "docker",
+			lines:    []string{"Loaded image: kindest/node:v1.27.3"},
+			expected: "kindest/node:v1.27.3",
+		},
+		{
+			name:     "podman",
+			lines:    []string{"Getting image source signatures", "Loaded image(s): docker.io/kindest/node:v1.27.3"},
+			expected: "docker.io/kindest/node:v1.27.3",
+		},
+		{
+			name:  "untagged",
+			lines: []string{"Loaded image ID: sha256:0123"},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := loadedImage(tc.lines); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// mergeKeys are the fields identifying the entries of the lists of the kind
+// Cluster config, which a merge patch merges entry by entry. The nodes have
+// no identifying field and are merged by index, e.g. a patch with
+// nodes: [{}, {labels: {...}}] only changes the second node.
+var mergeKeys = map[string]string{
+	"extraMounts":       "containerPath",
+	"extraPortMappings": "containerPort",
+}
+
+// indexMergedLists are the lists of the kind Cluster config whose entries a
+// merge patch merges by index
+var indexMergedLists = map[string]bool{
+	"nodes": true,
+}
+
+// applyPatch applies a YAML or JSON patch document to obj.
+// An object is applied as a merge patch, where maps are merged recursively,
+// null values delete keys, the lists of kind Cluster entries are merged as
+// described by mergeKeys and indexMergedLists, and any other value replaces
+// the original.
+// A list is applied as a JSON6902 patch (RFC 6902).
+func applyPatch(obj map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(patch, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+	switch p := doc.(type) {
+	case map[string]interface{}:
+		return mergePatch(obj, p), nil
+	case []interface{}:
+		var result interface{} = obj
+		for i, op := range p {
+			opMap, ok := op.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("operation %d of JSON6902 patch is not an object", i)
+			}
+			var err error
+			if result, err = applyOperation(result, opMap); err != nil {
+				return nil, fmt.Errorf("operation %d of JSON6902 patch failed: %w", i, err)
+			}
+		}
+		resultMap, ok := result.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("JSON6902 patch did not result in an object")
+		}
+		return resultMap, nil
+	default:
+		return nil, fmt.Errorf("patch must be an object or a list of JSON6902 operations")
+	}
+}
+
+func mergePatch(obj, patch map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		obj = map[string]interface{}{}
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(obj, k)
+			continue
+		}
+		patchMap, isMap := v.(map[string]interface{})
+		objMap, objIsMap := obj[k].(map[string]interface{})
+		patchList, isList := v.([]interface{})
+		objList, objIsList := obj[k].([]interface{})
+		if isList && objIsList && indexMergedLists[k] {
+			obj[k] = mergeListByIndex(objList, patchList)
+		} else if isList && objIsList && mergeKeys[k] != "" {
+			obj[k] = mergeListByKey(objList, patchList, mergeKeys[k])
+		} else if isMap && objIsMap {
+			obj[k] = mergePatch(objMap, patchMap)
+		} else if isMap {
+			obj[k] = mergePatch(nil, patchMap)
+		} else {
+			obj[k] = v
+		}
+	}
+	return obj
+}
+
+// mergeListByIndex merges the objects of patch into those of list at the same
+// index, appending the extra ones
+func mergeListByIndex(list, patch []interface{}) []interface{} {
+	for i, p := range patch {
+		if i >= len(list) {
+			list = append(list, p)
+			continue
+		}
+		patchMap, isMap := p.(map[string]interface{})
+		objMap, objIsMap := list[i].(map[string]interface{})
+		if isMap && objIsMap {
+			list[i] = mergePatch(objMap, patchMap)
+		} else {
+			list[i] = p
+		}
+	}
+	return list
+}
+
+// mergeListByKey merges the objects of patch into those of list with the same
+// value of key, appending the others
+func mergeListByKey(list, patch []interface{}, key string) []interface{} {
+	for _, p := range patch {
+		patchMap, isMap := p.(map[string]interface{})
+		merged := false
+		for i, o := range list {
+			objMap, objIsMap := o.(map[string]interface{})
+			if isMap && objIsMap && patchMap[key] != nil && reflect.DeepEqual(objMap[key], patchMap[key]) {
+				list[i] = mergePatch(objMap, patchMap)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+func applyOperation(doc interface{}, op map[string]interface{}) (interface{}, error) {
+	path, _ := op["path"].(string)
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	switch op["op"] {
+	case "add":
+		return setAtPointer(doc, tokens, op["value"], true)
+	case "replace":
+		return setAtPointer(doc, tokens, op["value"], false)
+	case "remove":
+		return removeAtPointer(doc, tokens)
+	case "test":
+		value, err := getAtPointer(doc, tokens)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op["value"]) {
+			return nil, fmt.Errorf("value at %q is %v, not %v", path, value, op["value"])
+		}
+		return doc, nil
+	case "move", "copy":
+		from, _ := op["from"].(string)
+		fromTokens, err := parsePointer(from)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getAtPointer(doc, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+		if op["op"] == "copy" {
+			return setAtPointer(doc, tokens, deepCopy(value), true)
+		}
+		if strings.HasPrefix(path+"/", from+"/") && path != from {
+			return nil, fmt.Errorf("cannot move %q into one of its children %q", from, path)
+		}
+		if doc, err = removeAtPointer(doc, fromTokens); err != nil {
+			return nil, err
+		}
+		return setAtPointer(doc, tokens, value, true)
+	default:
+		return nil, fmt.Errorf("unsupported operation %v", op["op"])
+	}
+}
+
+// getAtPointer returns the value at the location of tokens
+func getAtPointer(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			child, exists := d[token]
+			if !exists {
+				return nil, fmt.Errorf("key %q does not exist", token)
+			}
+			doc = child
+		case []interface{}:
+			i, err := listIndex(token, len(d), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("cannot traverse into %T at %q", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// deepCopy copies the maps and lists of a parsed document
+func deepCopy(v interface{}) interface{} {
+	switch d := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(d))
+		for k, v := range d {
+			c[k] = deepCopy(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(d))
+		for i, v := range d {
+			c[i] = deepCopy(v)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// parsePointer splits a JSON pointer into its unescaped reference tokens.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %q must start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// setAtPointer sets the value at the location of tokens. For add, lists are
+// inserted into and - appends, for replace the location must exist.
+func setAtPointer(doc interface{}, tokens []string, value interface{}, add bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token, rest := tokens[0], tokens[1:]
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, exists := d[token]
+		if len(rest) == 0 {
+			if !add && !exists {
+				return nil, fmt.Errorf("key %q does not exist", token)
+			}
+			d[token] = value
+			return d, nil
+		}
+		if !exists {
+			return nil, fmt.Errorf("key %q does not exist", token)
+		}
+		updated, err := setAtPointer(child, rest, value, add)
+		if err != nil {
+			return nil, err
+		}
+		d[token] = updated
+		return d, nil
+	case []interface{}:
+		if len(rest) == 0 && add && token == "-" {
+			return append(d, value), nil
+		}
+		i, err := listIndex(token, len(d), len(rest) == 0 && add)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			if add {
+				d = append(d, nil)
+				copy(d[i+1:], d[i:])
+			}
+			d[i] = value
+			return d, nil
+		}
+		updated, err := setAtPointer(d[i], rest, value, add)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = updated
+		return d, nil
+	default:
+		return nil, fmt.Errorf("cannot traverse into %T at %q", doc, token)
+	}
+}
+
+func removeAtPointer(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	token, rest := tokens[0], tokens[1:]
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, exists := d[token]
+		if !exists {
+			return nil, fmt.Errorf("key %q does not exist", token)
+		}
+		if len(rest) == 0 {
+			delete(d, token)
+			return d, nil
+		}
+		updated, err := removeAtPointer(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		d[token] = updated
+		return d, nil
+	case []interface{}:
+		i, err := listIndex(token, len(d), false)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			return append(d[:i], d[i+1:]...), nil
+		}
+		updated, err := removeAtPointer(d[i], rest)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = updated
+		return d, nil
+	default:
+		return nil, fmt.Errorf("cannot traverse into %T at %q", doc, token)
+	}
+}
+
+// listIndex parses a list index token, allowing the index past the end
+// of the list if inserting.
+func listIndex(token string, length int, inserting bool) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid list index %q", token)
+	}
+	last := length - 1
+	if inserting {
+		last = length
+	}
+	if i < 0 || i > last {
+		return 0, fmt.Errorf("list index %d out of range", i)
+	}
+	return i, nil
+}
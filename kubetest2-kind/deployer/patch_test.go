@@ -0,0 +1,293 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const base = `
+kind: Cluster
+nodes:
+- role: control-plane
+networking:
+  ipFamily: ipv4
+  podSubnet: 10.244.0.0/16
+`
+	testCases := []struct {
+		name          string
+		patch         string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "merge patch",
+			patch: `
+networking:
+  ipFamily: ipv6
+  podSubnet: null
+featureGates:
+  SomeGate: true
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+networking:
+  ipFamily: ipv6
+featureGates:
+  SomeGate: true
+`,
+		},
+		{
+			name: "JSON6902 patch",
+			patch: `
+- op: add
+  path: /nodes/-
+  value:
+    role: worker
+- op: replace
+  path: /networking/ipFamily
+  value: dual
+- op: remove
+  path: /networking/podSubnet
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: worker
+networking:
+  ipFamily: dual
+`,
+		},
+		{
+			name: "JSON6902 replace of a missing key",
+			patch: `
+- op: replace
+  path: /containerdConfigPatches
+  value: []
+`,
+			expectedError: true,
+		},
+		{
+			name: "JSON6902 move, copy and test",
+			patch: `
+- op: test
+  path: /networking/ipFamily
+  value: ipv4
+- op: copy
+  from: /nodes/0
+  path: /nodes/-
+- op: move
+  from: /networking/podSubnet
+  path: /networking/serviceSubnet
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: control-plane
+networking:
+  ipFamily: ipv4
+  serviceSubnet: 10.244.0.0/16
+`,
+		},
+		{
+			name: "JSON6902 failed test",
+			patch: `
+- op: test
+  path: /networking/ipFamily
+  value: ipv6
+`,
+			expectedError: true,
+		},
+		{
+			name: "JSON6902 move into a child",
+			patch: `
+- op: move
+  from: /networking
+  path: /networking/nested
+`,
+			expectedError: true,
+		},
+		{
+			name:          "scalar patch",
+			patch:         `foo`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var config map[string]interface{}
+			if err := yaml.Unmarshal([]byte(base), &config); err != nil {
+				t.Fatalf("failed to parse base config: %v", err)
+			}
+			got, err := applyPatch(config, []byte(tc.patch))
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+			var expected map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tc.expected), &expected); err != nil {
+				t.Fatalf("failed to parse expected config: %v", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("expected %v but got %v", expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyPatchMultiNode(t *testing.T) {
+	const base = `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: worker
+  extraMounts:
+  - containerPath: /data
+    hostPath: /tmp/data
+- role: worker
+`
+	testCases := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name: "merge patch of a single node",
+			patch: `
+nodes:
+- {}
+- labels:
+    tier: storage
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: worker
+  labels:
+    tier: storage
+  extraMounts:
+  - containerPath: /data
+    hostPath: /tmp/data
+- role: worker
+`,
+		},
+		{
+			name: "merge patch of the mounts of a node by container path",
+			patch: `
+nodes:
+- {}
+- extraMounts:
+  - containerPath: /data
+    readOnly: true
+  - containerPath: /cache
+    hostPath: /tmp/cache
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: worker
+  extraMounts:
+  - containerPath: /data
+    hostPath: /tmp/data
+    readOnly: true
+  - containerPath: /cache
+    hostPath: /tmp/cache
+- role: worker
+`,
+		},
+		{
+			name: "merge patch adding a node",
+			patch: `
+nodes:
+- {}
+- {}
+- {}
+- role: worker
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: worker
+  extraMounts:
+  - containerPath: /data
+    hostPath: /tmp/data
+- role: worker
+- role: worker
+`,
+		},
+		{
+			name: "JSON6902 patch of a single node",
+			patch: `
+- op: add
+  path: /nodes/2/labels
+  value:
+    tier: compute
+`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+- role: worker
+  extraMounts:
+  - containerPath: /data
+    hostPath: /tmp/data
+- role: worker
+  labels:
+    tier: compute
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var config map[string]interface{}
+			if err := yaml.Unmarshal([]byte(base), &config); err != nil {
+				t.Fatalf("failed to parse base config: %v", err)
+			}
+			got, err := applyPatch(config, []byte(tc.patch))
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+			var expected map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tc.expected), &expected); err != nil {
+				t.Fatalf("failed to parse expected config: %v", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("expected %v but got %v", expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/utils/strings/slices"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+var validProviders = []string{"docker", "podman", "nerdctl"}
+
+// kindEnv returns the environment kind is run with, selecting the node
+// provider if one was requested.
+func (d *deployer) kindEnv() []string {
+	env := os.Environ()
+	if len(d.EnvAllowlist) > 0 {
+		allowlist := append(append([]string{}, process.DefaultEnvAllowlist...), d.EnvAllowlist...)
+		env = process.AllowedEnv(env, allowlist)
+	}
+	if d.Provider != "" {
+		env = append(env, "KIND_EXPERIMENTAL_PROVIDER="+d.Provider)
+	}
+	return env
+}
+
+// runtime returns the container runtime CLI the nodes are run with.
+func (d *deployer) runtime() string {
+	if d.Provider != "" {
+		return d.Provider
+	}
+	return "docker"
+}
+
+// verifyProvider validates the provider, that the add-ons support it and
+// that its runtime is usable.
+func (d *deployer) verifyProvider() error {
+	if d.Provider != "" {
+		if !slices.Contains(validProviders, d.Provider) {
+			return fmt.Errorf("--provider must be one of %v, got %q", validProviders, d.Provider)
+		}
+	}
+	// checked before up creates anything, cloud-provider-kind talks to the docker socket
+	if d.CloudProviderEnabled && d.runtime() != "docker" {
+		return fmt.Errorf("--with-cloud-provider-kind requires the docker provider, got %s", d.runtime())
+	}
+	if out, err := exec.CombinedOutputLines(exec.Command(d.runtime(), "info")); err != nil {
+		return fmt.Errorf("container runtime %s is not usable: %v, output: %q", d.runtime(), err, out)
+	}
+	return nil
+}
+
+// dumpNodeContainers writes the state of the node containers of the named
+// cluster as seen by the container runtime into logsDir.
+func (d *deployer) dumpNodeContainers(name, logsDir string) error {
+	out, err := exec.Output(exec.Command(d.runtime(), "ps", "-a",
+		"--filter", "label=io.x-k8s.kind.cluster="+name))
+	if err != nil {
+		return fmt.Errorf("failed to list node containers: %w", err)
+	}
+	if err := os.MkdirAll(logsDir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(logsDir, d.runtime()+"-ps.txt"), out, 0644)
+}
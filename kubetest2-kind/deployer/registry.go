@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+const (
+	defaultLocalRegistryPort = "5001"
+	localRegistryName        = "kubetest2-kind-registry"
+	localRegistryImage       = "registry:2"
+	// localRegistryEnv is the environment variable the local registry
+	// endpoint is exported in for the tester.
+	localRegistryEnv = "KUBETEST2_LOCAL_REGISTRY"
+)
+
+// localRegistryEndpoint returns the endpoint images are pushed to from the
+// host and pulled from by the nodes.
+func (d *deployer) localRegistryEndpoint() string {
+	return "localhost:" + strconv.Itoa(d.LocalRegistryPort)
+}
+
+// localRegistryContainerdPatch returns the containerd config patch making
+// the nodes pull images of the local registry endpoint from the registry
+// container.
+func (d *deployer) localRegistryContainerdPatch() string {
+	return fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".registry.mirrors.%q]
+  endpoint = ["http://%s:5000"]`, d.localRegistryEndpoint(), localRegistryName)
+}
+
+// startLocalRegistry starts the registry container unless it is running
+// already, e.g. started by another run, which then owns it.
+func (d *deployer) startLocalRegistry() error {
+	running, err := exec.Output(exec.Command(d.runtime(), "inspect", "-f", "{{.State.Running}}", localRegistryName))
+	if err == nil && strings.TrimSpace(string(running)) == "true" {
+		klog.V(1).Infof("local registry %s is already running", localRegistryName)
+		return nil
+	}
+	// set before starting it, as a failed start may leave the container behind
+	d.localRegistryCreated = true
+	klog.V(0).Infof("Up(): starting local registry on %s...\n", d.localRegistryEndpoint())
+	cmd := exec.Command(d.runtime(), "run", "-d", "--restart=always",
+		"-p", fmt.Sprintf("127.0.0.1:%d:5000", d.LocalRegistryPort),
+		"--name", localRegistryName,
+		localRegistryImage)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to start local registry: %w", err)
+	}
+	return nil
+}
+
+// connectLocalRegistry connects the registry to the network of the nodes,
+// documents it in the cluster of kubeconfig and exports its endpoint to the tester.
+func (d *deployer) connectLocalRegistry(kubeconfig string) error {
+	// connecting fails if the registry is connected already, e.g. by a previous run
+	if err := exec.Command(d.runtime(), "network", "connect", "kind", localRegistryName).Run(); err != nil {
+		klog.V(1).Infof("connecting local registry to the kind network: %v", err)
+	}
+
+	// https://github.com/kubernetes/enhancements/tree/master/keps/sig-cluster-lifecycle/generic/1755-communicating-a-local-registry
+	configMap := fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: local-registry-hosting
+  namespace: kube-public
+data:
+  localRegistryHosting.v1: |
+    host: "%s"
+    help: "https://kind.sigs.k8s.io/docs/user/local-registry/"
+`, d.localRegistryEndpoint())
+	cmd := exec.Command("kubectl", "apply", "-f", "-")
+	if kubeconfig != "" {
+		cmd = exec.Command("kubectl", "--kubeconfig="+kubeconfig, "apply", "-f", "-")
+	}
+	cmd.SetStdin(strings.NewReader(configMap))
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to document the local registry: %w", err)
+	}
+
+	if os.Getenv(localRegistryEnv) == d.localRegistryEndpoint() {
+		// exported already for another cluster
+		return nil
+	}
+	if err := os.Setenv(localRegistryEnv, d.localRegistryEndpoint()); err != nil {
+		return err
+	}
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "local-registry", d.localRegistryEndpoint())
+}
+
+// deleteLocalRegistry removes the registry container.
+func (d *deployer) deleteLocalRegistry() error {
+	cmd := exec.Command(d.runtime(), "rm", "-f", localRegistryName)
+	exec.InheritOutput(cmd)
+	return cmd.Run()
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const rootlessDocs = "https://kind.sigs.k8s.io/docs/user/rootless/"
+
+// controllers that must be delegated to the user for rootless nodes
+var rootlessControllers = []string{"cpu", "cpuset", "io", "memory", "pids"}
+
+// sysctls whose defaults are too low to run the kubelets of the nodes
+var requiredSysctls = []struct {
+	key string
+	min int
+}{
+	{"fs/inotify/max_user_watches", 524288},
+	{"fs/inotify/max_user_instances", 512},
+}
+
+// isRootless returns true if the container runtime runs in rootless mode.
+func (d *deployer) isRootless() (bool, error) {
+	format := "{{json .SecurityOptions}}"
+	if d.runtime() == "podman" {
+		format = "{{.Host.Security.Rootless}}"
+	}
+	out, err := exec.Output(exec.Command(d.runtime(), "info", "--format", format))
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s info: %w", d.runtime(), err)
+	}
+	info := strings.TrimSpace(string(out))
+	return info == "true" || strings.Contains(info, "name=rootless"), nil
+}
+
+// verifyRootless checks that the host is set up to run rootless nodes,
+// as misconfigurations otherwise only surface as kubelet failures.
+func (d *deployer) verifyRootless() error {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	rootless, err := d.isRootless()
+	if err != nil {
+		return err
+	}
+	if !rootless {
+		return nil
+	}
+	klog.V(0).Infof("Up(): %s is running in rootless mode, verifying host setup...\n", d.runtime())
+	if err := verifyCgroupDelegation("/sys/fs/cgroup", os.Getuid()); err != nil {
+		return fmt.Errorf("%v, see %s", err, rootlessDocs)
+	}
+	if err := verifySysctls("/proc/sys"); err != nil {
+		return fmt.Errorf("%v, see %s", err, rootlessDocs)
+	}
+	return nil
+}
+
+// verifyCgroupDelegation checks that cgroupRoot is a cgroup v2 hierarchy
+// and that the controllers needed by the nodes are delegated to uid.
+func verifyCgroupDelegation(cgroupRoot string, uid int) error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("rootless nodes require cgroup v2, boot the host with systemd.unified_cgroup_hierarchy=1")
+	}
+	userService := fmt.Sprintf("user.slice/user-%d.slice/user@%d.service", uid, uid)
+	controllers, err := os.ReadFile(filepath.Join(cgroupRoot, userService, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("failed to read the cgroup controllers delegated to uid %d: %v", uid, err)
+	}
+	delegated := strings.Fields(string(controllers))
+	var missing []string
+	for _, controller := range rootlessControllers {
+		if !slices.Contains(delegated, controller) {
+			missing = append(missing, controller)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cgroup controllers %v are not delegated to uid %d, "+
+			"add \"Delegate=%s\" to /etc/systemd/system/user@.service.d/delegate.conf "+
+			"and run \"systemctl daemon-reload\"",
+			missing, uid, strings.Join(rootlessControllers, " "))
+	}
+	return nil
+}
+
+// verifySysctls checks the required sysctls under procSysRoot.
+func verifySysctls(procSysRoot string) error {
+	var errs []string
+	for _, sysctl := range requiredSysctls {
+		name := strings.ReplaceAll(sysctl.key, "/", ".")
+		raw, err := os.ReadFile(filepath.Join(procSysRoot, sysctl.key))
+		if err != nil {
+			return fmt.Errorf("failed to read sysctl %s: %v", name, err)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+		if err != nil {
+			return fmt.Errorf("failed to parse sysctl %s: %v", name, err)
+		}
+		if value < sysctl.min {
+			errs = append(errs, fmt.Sprintf("%s is %d, run \"sysctl -w %s=%d\"", name, value, name, sysctl.min))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("sysctls are too low: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
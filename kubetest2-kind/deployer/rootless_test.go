@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyCgroupDelegation(t *testing.T) {
+	cases := []struct {
+		name        string
+		v2          bool
+		delegated   string
+		expectError bool
+	}{
+		{
+			name:        "cgroup v1",
+			expectError: true,
+		},
+		{
+			name:        "not delegated",
+			v2:          true,
+			delegated:   "memory pids",
+			expectError: true,
+		},
+		{
+			name:      "delegated",
+			v2:        true,
+			delegated: "cpuset cpu io memory pids\n",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			if tc.v2 {
+				writeFile(t, filepath.Join(root, "cgroup.controllers"), "cpuset cpu io memory pids")
+				writeFile(t, filepath.Join(root, "user.slice/user-1000.slice/user@1000.service/cgroup.controllers"), tc.delegated)
+			}
+			err := verifyCgroupDelegation(root, 1000)
+			if err == nil && tc.expectError {
+				t.Fatal("expected error but got none")
+			}
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifySysctls(t *testing.T) {
+	cases := []struct {
+		name        string
+		watches     string
+		instances   string
+		expectError bool
+	}{
+		{
+			name:      "sufficient",
+			watches:   "524288\n",
+			instances: "8192\n",
+		},
+		{
+			name:        "too low",
+			watches:     "8192\n",
+			instances:   "128\n",
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "fs/inotify/max_user_watches"), tc.watches)
+			writeFile(t, filepath.Join(root, "fs/inotify/max_user_instances"), tc.instances)
+			err := verifySysctls(root)
+			if err == nil && tc.expectError {
+				t.Fatal("expected error but got none")
+			}
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
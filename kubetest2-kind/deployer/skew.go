@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kindNodeImageRepository is the repository of the node images kind releases
+const kindNodeImageRepository = "kindest/node"
+
+// nodeImage returns the node image of version, which is either a kubernetes
+// version of the images kind releases or a node image already
+func nodeImage(version string) string {
+	if strings.ContainsAny(version, ":@/") {
+		return version
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return kindNodeImageRepository + ":" + version
+}
+
+// configureNodeImages sets the images of the nodes of config to those of
+// --control-plane-version and --node-version by role, so that the kubelets
+// of the workers can be skewed from the control plane.
+func (d *deployer) configureNodeImages(config map[string]interface{}) error {
+	if _, ok := config["nodes"]; !ok {
+		// skewed workers need workers to begin with
+		workers := 0
+		if d.NodeVersion != "" {
+			workers = 1
+		}
+		config["nodes"] = kindNodes(1, workers)
+	}
+	nodes, ok := config["nodes"].([]interface{})
+	if !ok {
+		return fmt.Errorf("nodes of the kind config are not a list")
+	}
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("node of the kind config is not an object: %v", n)
+		}
+		version := d.ControlPlaneVersion
+		// the role defaults to control-plane in kind
+		if role, _ := node["role"].(string); role == "worker" {
+			version = d.NodeVersion
+		}
+		if version != "" {
+			node["image"] = nodeImage(version)
+		}
+	}
+	return nil
+}
+
+// VersionSkew returns the versions of the control plane and the worker nodes,
+// implementing types.DeployerWithVersionSkew
+func (d *deployer) VersionSkew() (controlPlane, node string) {
+	controlPlane, node = d.ControlPlaneVersion, d.NodeVersion
+	// nodes without a version of their own run the default image
+	if controlPlane == "" {
+		controlPlane = d.NodeImage
+	}
+	if node == "" {
+		node = controlPlane
+	}
+	return controlPlane, node
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestConfigureNodeImages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		deployer deployer
+		config   string
+		expected string
+	}{
+		{
+			name:     "skewed worker added",
+			deployer: deployer{ControlPlaneVersion: "v1.30.0", NodeVersion: "1.29.4"},
+			config:   `kind: Cluster`,
+			expected: `
+kind: Cluster
+nodes:
+- role: control-plane
+  image: kindest/node:v1.30.0
+- role: worker
+  image: kindest/node:v1.29.4
+`,
+		},
+		{
+			name:     "nodes of the config by role",
+			deployer: deployer{NodeVersion: "localhost:5000/node:v1.28.0"},
+			config: `
+kind: Cluster
+nodes:
+- {}
+- role: worker
+- role: worker
+`,
+			expected: `
+kind: Cluster
+nodes:
+- {}
+- role: worker
+  image: localhost:5000/node:v1.28.0
+- role: worker
+  image: localhost:5000/node:v1.28.0
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var config, expected map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tc.config), &config); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tc.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.deployer.configureNodeImages(config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, expected) {
+				t.Errorf("expected config %v, got %v", expected, config)
+			}
+		})
+	}
+}
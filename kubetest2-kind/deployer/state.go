@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+)
+
+// state is what Down needs to delete the clusters of a previous run, see
+// types.DeployerWithState
+type state struct {
+	ClusterName          string `json:"clusterName,omitempty"`
+	NumClusters          int    `json:"numClusters,omitempty"`
+	KubeconfigPath       string `json:"kubeconfig,omitempty"`
+	Provider             string `json:"provider,omitempty"`
+	KindVersion          string `json:"kindVersion,omitempty"`
+	CloudProviderEnabled bool   `json:"cloudProviderKind,omitempty"`
+	LocalRegistryPort    int    `json:"localRegistryPort,omitempty"`
+	LocalRegistryCreated bool   `json:"localRegistryCreated,omitempty"`
+}
+
+func (d *deployer) State() ([]byte, error) {
+	return json.MarshalIndent(state{
+		ClusterName:          d.ClusterName,
+		NumClusters:          d.NumClusters,
+		KubeconfigPath:       d.KubeconfigPath,
+		Provider:             d.Provider,
+		KindVersion:          d.KindVersion,
+		CloudProviderEnabled: d.CloudProviderEnabled,
+		LocalRegistryPort:    d.LocalRegistryPort,
+		LocalRegistryCreated: d.localRegistryCreated,
+	}, "", "  ")
+}
+
+// RestoreState restores the clusters of a previous run, the flags that are set
+// take precedence.
+func (d *deployer) RestoreState(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if d.ClusterName == "" {
+		d.ClusterName = s.ClusterName
+	}
+	if d.NumClusters == 0 {
+		d.NumClusters = s.NumClusters
+	}
+	if d.KubeconfigPath == "" {
+		d.KubeconfigPath = s.KubeconfigPath
+	}
+	if d.Provider == "" {
+		d.Provider = s.Provider
+	}
+	if d.KindVersion == "" {
+		d.KindVersion = s.KindVersion
+	}
+	d.CloudProviderEnabled = d.CloudProviderEnabled || s.CloudProviderEnabled
+	if d.LocalRegistryPort == 0 {
+		d.LocalRegistryPort = s.LocalRegistryPort
+	}
+	d.localRegistryCreated = d.localRegistryCreated || s.LocalRegistryCreated
+	return nil
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package deployer
 
 import (
-	"os"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -28,20 +27,71 @@ import (
 )
 
 func (d *deployer) IsUp() (up bool, err error) {
-	// naively assume that if the api server reports nodes, the cluster is up
-	lines, err := exec.CombinedOutputLines(
-		exec.Command("kubectl", "get", "nodes", "-o=name"),
-	)
-	if err != nil {
-		return false, metadata.NewJUnitError(err, strings.Join(lines, "\n"))
+	for _, name := range d.clusterNames() {
+		args := []string{"get", "nodes", "-o=name"}
+		if kubeconfig := d.clusterKubeconfig(name); kubeconfig != "" {
+			args = append(args, "--kubeconfig", kubeconfig)
+		}
+		// naively assume that if the api server reports nodes, the cluster is up
+		lines, err := exec.CombinedOutputLines(
+			exec.Command("kubectl", args...),
+		)
+		if err != nil {
+			return false, metadata.NewJUnitError(err, strings.Join(lines, "\n"))
+		}
+		if len(lines) == 0 {
+			return false, nil
+		}
 	}
-	return len(lines) > 0, nil
+	return true, nil
 }
 
 func (d *deployer) Up() error {
+	if err := d.verifyProvider(); err != nil {
+		return err
+	}
+	if err := d.verifyRootless(); err != nil {
+		return err
+	}
+	if d.LocalRegistryPort > 0 {
+		if err := d.startLocalRegistry(); err != nil {
+			return err
+		}
+	}
+
+	if d.NodeImageTarball != "" {
+		if err := d.loadNodeImageTarball(); err != nil {
+			return err
+		}
+	}
+
+	configPath, err := d.writeClusterConfig()
+	if err != nil {
+		return err
+	}
+	for _, name := range d.clusterNames() {
+		if err := d.createCluster(name, configPath); err != nil {
+			return err
+		}
+	}
+
+	// started after the clusters, as it needs the kind network
+	if d.CloudProviderEnabled {
+		if err := d.startCloudProvider(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *deployer) createCluster(name, configPath string) error {
+	kind, err := d.kind()
+	if err != nil {
+		return err
+	}
 	args := []string{
 		"create", "cluster",
-		"--name", d.ClusterName,
+		"--name", name,
 	}
 
 	// set the explicitly specified image name if set
@@ -53,14 +103,29 @@ func (d *deployer) Up() error {
 		// we use the same logic / constant for Build()
 		args = append(args, "--image", kindDefaultBuiltImageName)
 	}
-	if d.ConfigPath != "" {
-		args = append(args, "--config", d.ConfigPath)
+	if configPath != "" {
+		args = append(args, "--config", configPath)
 	}
-	if d.KubeconfigPath != "" {
-		args = append(args, "--kubeconfig", d.KubeconfigPath)
+	kubeconfig := d.clusterKubeconfig(name)
+	if kubeconfig != "" {
+		args = append(args, "--kubeconfig", kubeconfig)
 	}
 
-	klog.V(0).Infof("Up(): creating kind cluster...\n")
+	klog.V(0).Infof("Up(): creating kind cluster...%s\n", name)
 	// we want to see the output so use process.ExecJUnit
-	return process.ExecJUnit("kind", args, os.Environ())
+	if err := process.ExecJUnitTimeout(d.CommandTimeout, kind, args, d.kindEnv()); err != nil {
+		return err
+	}
+
+	if d.LocalRegistryPort > 0 {
+		if err := d.connectLocalRegistry(kubeconfig); err != nil {
+			return err
+		}
+	}
+	if d.CloudProviderEnabled {
+		if err := includeNodesInLoadBalancers(kubeconfig); err != nil {
+			return err
+		}
+	}
+	return d.loadImages(name)
 }
@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deployer implements the kubetest2 namespace deployer, which
+// isolates a run in a namespace of a shared, long-lived cluster instead of
+// bringing up a cluster of its own
+package deployer
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/octago/sflags/gen/gpflag"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// Name is the name of the deployer
+const Name = "namespace"
+
+var GitTag string
+
+// New implements deployer.New for namespace
+func New(opts types.Options) (types.Deployer, *pflag.FlagSet) {
+	// create a deployer object and set fields that are not flag controlled
+	d := &deployer{
+		commonOptions:      opts,
+		NetworkIsolation:   true,
+		CredentialsTimeout: 24 * time.Hour,
+		DeleteTimeout:      10 * time.Minute,
+		logsDir:            filepath.Join(artifacts.BaseDir(), "logs"),
+	}
+	// register flags and return
+	return d, bindFlags(d)
+}
+
+// assert that New implements types.NewDeployer
+var _ types.NewDeployer = New
+
+type deployer struct {
+	// generic parts
+	commonOptions types.Options
+
+	KubeconfigPath     string        `flag:"kubeconfig" desc:"Absolute path to the kubeconfig of the shared cluster, defaults to $KUBECONFIG or ~/.kube/config"`
+	Namespace          string        `desc:"Name of the namespace of the run, defaults to one derived from the run id so that --down --run-id=<id> deletes that of another run"`
+	Quota              []string      `desc:"Hard limits of the ResourceQuota of the namespace, e.g. requests.cpu=8,requests.memory=32Gi,pods=100. No quota is created if unset"`
+	NetworkIsolation   bool          `desc:"Create a NetworkPolicy only allowing ingress to the pods of the namespace from the pods of the namespace"`
+	ScopedCredentials  bool          `desc:"Give the tester the credentials of a service account with the admin role in the namespace only, instead of those of --kubeconfig. Testers creating namespaces of their own, like the e2e framework, are forbidden to with them"`
+	CredentialsTimeout time.Duration `desc:"How long the credentials of --scoped-credentials are valid for"`
+	DeleteTimeout      time.Duration `desc:"How long to wait for the namespace to be deleted by down"`
+
+	logsDir string
+}
+
+func (d *deployer) Up() error {
+	// the run dir would upload the credentials with the artifacts
+	if d.ScopedCredentials && d.commonOptions.RundirInArtifacts() {
+		return fmt.Errorf("--scoped-credentials cannot be used with --rundir-in-artifacts")
+	}
+	namespace := d.namespace()
+	manifests, err := manifests(namespace, d.commonOptions.RunID(), d.Quota, d.NetworkIsolation, d.ScopedCredentials)
+	if err != nil {
+		return err
+	}
+	klog.V(0).Infof("Up(): creating namespace %s ...", namespace)
+	// create instead of apply, to never take over the namespace of another run
+	cmd := d.kubectl("create", "-f", "-")
+	cmd.SetStdin(bytes.NewReader(manifests))
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %v", namespace, err)
+	}
+	if !d.ScopedCredentials {
+		return nil
+	}
+	return d.writeScopedKubeconfig(namespace)
+}
+
+// writeScopedKubeconfig writes the kubeconfig of the service account of the
+// namespace to the run dir
+func (d *deployer) writeScopedKubeconfig(namespace string) error {
+	cluster, err := exec.Output(d.kubectl("config", "view", "--minify", "--flatten", "-o", "jsonpath={.clusters[0].cluster}"))
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster of the kubeconfig: %v", err)
+	}
+	// unrecorded, so that the token is not captured with the output
+	token, err := exec.Output(d.kubectl("create", "token", serviceAccountName,
+		"--namespace", namespace,
+		"--duration", d.CredentialsTimeout.String()).SetRecorded(false))
+	if err != nil {
+		return fmt.Errorf("failed to create a token for the service account of namespace %s: %v", namespace, err)
+	}
+	kubeconfig, err := scopedKubeconfig(cluster, namespace, strings.TrimSpace(string(token)))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.scopedKubeconfigPath(), kubeconfig, 0600)
+}
+
+func (d *deployer) Down() error {
+	namespace := d.namespace()
+	klog.V(0).Infof("Down(): deleting namespace %s ...", namespace)
+	cmd := d.kubectl("delete", "namespace", namespace,
+		"--ignore-not-found",
+		"--wait",
+		"--timeout", d.DeleteTimeout.String())
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete namespace %s: %v", namespace, err)
+	}
+	return nil
+}
+
+func (d *deployer) IsUp() (up bool, err error) {
+	lines, err := exec.OutputLines(d.kubectl("get", "namespace", d.namespace(), "--ignore-not-found", "-o", "name"))
+	if err != nil {
+		return false, err
+	}
+	return len(lines) > 0, nil
+}
+
+// DumpClusterLogs dumps the resources, the events and the logs of the pods
+// of the namespace, the rest of the shared cluster is not the run's
+func (d *deployer) DumpClusterLogs() error {
+	namespace := d.namespace()
+	if err := os.MkdirAll(d.logsDir, os.ModePerm); err != nil {
+		return err
+	}
+	dumps := map[string][]string{
+		"resources.yaml": {"get", "all,configmaps,persistentvolumeclaims,networkpolicies,resourcequotas", "-o", "yaml"},
+		"events.txt":     {"get", "events", "--sort-by=.lastTimestamp"},
+	}
+	pods, err := exec.OutputLines(d.kubectl("get", "pods", "--namespace", namespace, "-o", "name"))
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of namespace %s: %v", namespace, err)
+	}
+	for _, pod := range pods {
+		dumps[strings.TrimPrefix(pod, "pod/")+".log"] = []string{"logs", pod, "--all-containers", "--prefix"}
+	}
+	var errs []string
+	for file, args := range dumps {
+		out, err := exec.Output(d.kubectl(append(args, "--namespace", namespace)...))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(d.logsDir, file), out, 0644); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to dump the logs of namespace %s: %s", namespace, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (d *deployer) Build() error {
+	// the shared cluster is not built by the runs
+	return nil
+}
+
+// Kubeconfig returns the kubeconfig of the service account of the namespace
+// with --scoped-credentials, or that of the shared cluster
+func (d *deployer) Kubeconfig() (string, error) {
+	if d.ScopedCredentials {
+		if _, err := os.Stat(d.scopedKubeconfigPath()); err == nil {
+			return d.scopedKubeconfigPath(), nil
+		}
+	}
+	return d.sharedKubeconfig()
+}
+
+// sharedKubeconfig returns the kubeconfig of the shared cluster
+func (d *deployer) sharedKubeconfig() (string, error) {
+	if d.KubeconfigPath != "" {
+		return d.KubeconfigPath, nil
+	}
+	if kconfig, ok := os.LookupEnv("KUBECONFIG"); ok {
+		return kconfig, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+func (d *deployer) scopedKubeconfigPath() string {
+	return filepath.Join(d.commonOptions.RunDir(), "kubeconfig-"+d.namespace())
+}
+
+// namespace returns the name of the namespace of the run
+func (d *deployer) namespace() string {
+	if d.Namespace != "" {
+		return d.Namespace
+	}
+	return defaultNamespace(d.commonOptions.RunID())
+}
+
+// kubectl returns a kubectl command against the shared cluster
+func (d *deployer) kubectl(args ...string) exec.Cmd {
+	cmd := exec.Command("kubectl", args...)
+	if kubeconfig, err := d.sharedKubeconfig(); err == nil {
+		cmd.SetEnv(append(os.Environ(), "KUBECONFIG="+kubeconfig)...)
+	}
+	return cmd
+}
+
+func (d *deployer) Version() string {
+	return GitTag
+}
+
+// helper used to create & bind a flagset to the deployer
+func bindFlags(d *deployer) *pflag.FlagSet {
+	flags, err := gpflag.Parse(d)
+	if err != nil {
+		klog.Fatalf("unable to generate flags from deployer")
+		return nil
+	}
+
+	flags.AddGoFlagSet(flag.CommandLine)
+
+	return flags
+}
+
+// assert that deployer implements types.DeployerWithKubeconfig
+var _ types.DeployerWithKubeconfig = &deployer{}
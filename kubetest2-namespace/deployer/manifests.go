@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// serviceAccountName is the name of the service account of
+	// --scoped-credentials in the namespace
+	serviceAccountName = "kubetest2"
+	// runIDLabel labels the namespace with the run that created it
+	runIDLabel = "kubetest2.k8s.io/run-id"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// defaultNamespace returns the namespace of the run with the given id
+func defaultNamespace(runID string) string {
+	name := "kubetest2-" + invalidNameChars.ReplaceAllString(strings.ToLower(runID), "-")
+	// namespaces are DNS labels
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+type object map[string]interface{}
+
+// manifests returns the list of the objects isolating a run in namespace
+func manifests(namespace, runID string, quota []string, networkIsolation, scopedCredentials bool) ([]byte, error) {
+	labels := object{runIDLabel: invalidNameChars.ReplaceAllString(strings.ToLower(runID), "-")}
+	metadata := func(name string) object {
+		m := object{"name": name, "labels": labels}
+		if name != namespace {
+			m["namespace"] = namespace
+		}
+		return m
+	}
+	items := []object{{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   metadata(namespace),
+	}}
+	if len(quota) > 0 {
+		hard, err := parseQuota(quota)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, object{
+			"apiVersion": "v1",
+			"kind":       "ResourceQuota",
+			"metadata":   metadata("kubetest2"),
+			"spec":       object{"hard": hard},
+		})
+	}
+	if networkIsolation {
+		items = append(items, object{
+			"apiVersion": "networking.k8s.io/v1",
+			"kind":       "NetworkPolicy",
+			"metadata":   metadata("kubetest2-isolation"),
+			"spec": object{
+				"podSelector": object{},
+				"policyTypes": []string{"Ingress"},
+				"ingress": []object{{
+					"from": []object{{"podSelector": object{}}},
+				}},
+			},
+		})
+	}
+	if scopedCredentials {
+		items = append(items,
+			object{
+				"apiVersion": "v1",
+				"kind":       "ServiceAccount",
+				"metadata":   metadata(serviceAccountName),
+			},
+			object{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "RoleBinding",
+				"metadata":   metadata(serviceAccountName + "-admin"),
+				"roleRef": object{
+					"apiGroup": "rbac.authorization.k8s.io",
+					"kind":     "ClusterRole",
+					"name":     "admin",
+				},
+				"subjects": []object{{
+					"kind":      "ServiceAccount",
+					"name":      serviceAccountName,
+					"namespace": namespace,
+				}},
+			},
+		)
+	}
+	return json.MarshalIndent(object{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	}, "", "  ")
+}
+
+// parseQuota parses the key=value hard limits of --quota
+func parseQuota(quota []string) (map[string]string, error) {
+	hard := map[string]string{}
+	for _, q := range quota {
+		key, value, ok := strings.Cut(q, "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid quota %q, expected <resource>=<quantity>", q)
+		}
+		hard[key] = value
+	}
+	return hard, nil
+}
+
+// scopedKubeconfig returns a kubeconfig for the service account of namespace
+// given the json of the cluster entry of the shared cluster's kubeconfig
+func scopedKubeconfig(clusterJSON []byte, namespace, token string) ([]byte, error) {
+	var cluster object
+	if err := json.Unmarshal(clusterJSON, &cluster); err != nil {
+		return nil, fmt.Errorf("failed to parse the cluster of the kubeconfig: %v", err)
+	}
+	if _, ok := cluster["server"]; !ok {
+		return nil, fmt.Errorf("the cluster of the kubeconfig has no server")
+	}
+	return json.MarshalIndent(object{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": namespace,
+		"clusters":        []object{{"name": "shared", "cluster": cluster}},
+		"users":           []object{{"name": serviceAccountName, "user": object{"token": token}}},
+		"contexts": []object{{
+			"name": namespace,
+			"context": object{
+				"cluster":   "shared",
+				"user":      serviceAccountName,
+				"namespace": namespace,
+			},
+		}},
+	}, "", "  ")
+}
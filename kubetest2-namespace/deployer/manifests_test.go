@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultNamespace(t *testing.T) {
+	testCases := []struct {
+		runID    string
+		expected string
+	}{
+		{
+			runID:    "1234abcd",
+			expected: "kubetest2-1234abcd",
+		},
+		{
+			runID:    "Pull_Kubernetes.e2e",
+			expected: "kubetest2-pull-kubernetes-e2e",
+		},
+		{
+			runID:    strings.Repeat("a", 52) + "_b",
+			expected: "kubetest2-" + strings.Repeat("a", 52),
+		},
+	}
+
+	for _, tc := range testCases {
+		got := defaultNamespace(tc.runID)
+		if got != tc.expected {
+			t.Errorf("expected %q but got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	testCases := []struct {
+		quota       []string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			quota:    []string{"requests.cpu=8", "pods=100"},
+			expected: map[string]string{"requests.cpu": "8", "pods": "100"},
+		},
+		{
+			quota:       []string{"pods"},
+			expectError: true,
+		},
+		{
+			quota:       []string{"pods="},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseQuota(tc.quota)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("expected an error for %v", tc.quota)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", tc.quota, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("expected %v but got %v", tc.expected, got)
+		}
+	}
+}
+
+func TestManifests(t *testing.T) {
+	testCases := []struct {
+		quota             []string
+		networkIsolation  bool
+		scopedCredentials bool
+		expectedKinds     []string
+	}{
+		{
+			expectedKinds: []string{"Namespace"},
+		},
+		{
+			quota:             []string{"pods=10"},
+			networkIsolation:  true,
+			scopedCredentials: true,
+			expectedKinds:     []string{"Namespace", "ResourceQuota", "NetworkPolicy", "ServiceAccount", "RoleBinding"},
+		},
+	}
+
+	for _, tc := range testCases {
+		out, err := manifests("kubetest2-abc", "abc", tc.quota, tc.networkIsolation, tc.scopedCredentials)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		var list struct {
+			Items []struct {
+				Kind     string
+				Metadata struct {
+					Name      string
+					Namespace string
+					Labels    map[string]string
+				}
+			}
+		}
+		if err := json.Unmarshal(out, &list); err != nil {
+			t.Errorf("failed to parse manifests: %v", err)
+			continue
+		}
+		var kinds []string
+		for _, item := range list.Items {
+			kinds = append(kinds, item.Kind)
+			if item.Metadata.Labels[runIDLabel] != "abc" {
+				t.Errorf("expected %s to be labelled with the run id", item.Kind)
+			}
+			if item.Kind != "Namespace" && item.Metadata.Namespace != "kubetest2-abc" {
+				t.Errorf("expected %s to be in the namespace but got %q", item.Kind, item.Metadata.Namespace)
+			}
+		}
+		if !reflect.DeepEqual(kinds, tc.expectedKinds) {
+			t.Errorf("expected %v but got %v", tc.expectedKinds, kinds)
+		}
+	}
+}
+
+func TestScopedKubeconfig(t *testing.T) {
+	cluster := []byte(`{"server":"https://10.0.0.1","certificate-authority-data":"Y2E="}`)
+	out, err := scopedKubeconfig(cluster, "kubetest2-abc", "token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var config struct {
+		CurrentContext string `json:"current-context"`
+		Clusters       []struct {
+			Cluster map[string]string
+		}
+		Contexts []struct {
+			Context map[string]string
+		}
+		Users []struct {
+			User map[string]string
+		}
+	}
+	if err := json.Unmarshal(out, &config); err != nil {
+		t.Fatalf("failed to parse kubeconfig: %v", err)
+	}
+	if config.Clusters[0].Cluster["server"] != "https://10.0.0.1" {
+		t.Errorf("expected the server of the shared cluster but got %v", config.Clusters[0].Cluster)
+	}
+	if config.Contexts[0].Context["namespace"] != "kubetest2-abc" {
+		t.Errorf("expected the context to default to the namespace but got %v", config.Contexts[0].Context)
+	}
+	if config.Users[0].User["token"] != "token" {
+		t.Errorf("expected the token of the service account but got %v", config.Users[0].User)
+	}
+
+	if _, err := scopedKubeconfig([]byte(`{}`), "kubetest2-abc", "token"); err == nil {
+		t.Errorf("expected an error for a cluster without server")
+	}
+}
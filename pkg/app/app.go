@@ -17,17 +17,24 @@ limitations under the License.
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/kballard/go-shellquote"
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/artifacts"
 	"sigs.k8s.io/kubetest2/pkg/exec"
 	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/monitor"
+	"sigs.k8s.io/kubetest2/pkg/process"
+	"sigs.k8s.io/kubetest2/pkg/profiling"
 	"sigs.k8s.io/kubetest2/pkg/types"
 )
 
@@ -67,12 +74,70 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 	if err := os.MkdirAll(opts.RunDir(), os.ModePerm); err != nil {
 		return err
 	}
+	if err := artifacts.UpdateLatest(opts.RunDir()); err != nil {
+		klog.Warningf("failed to point to the latest run dir: %v", err)
+	}
+	if err := artifacts.StampRetention(opts.RunDir(), opts.RunID()); err != nil {
+		return err
+	}
+
+	// capture the output of every command run along the way
+	process.SetCaptureDir(filepath.Join(opts.RunDir(), "commands"))
 
 	// ensure the artifacts dir
 	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
 		return err
 	}
 
+	// put the artifacts to the sink after each step and once they are all written
+	if sink := artifacts.ConfiguredSink(); sink != nil {
+		// the cluster logs are put once compressed, not file by file by
+		// the syncs of the steps
+		syncer = &artifacts.Syncer{Sink: sink, Dir: artifacts.BaseDir(), Exclude: artifacts.LogsDirsToCompress()}
+		defer func() {
+			// put whatever is left, should the compression have failed
+			syncer.Exclude = nil
+			if err := syncer.Sync(); err != nil && result == nil {
+				result = err
+			}
+			syncer = nil
+		}()
+	}
+
+	// index the artifacts once all of them have been written
+	defer func() {
+		if err := artifacts.WriteIndex("kubetest2 run " + opts.RunID()); err != nil && result == nil {
+			result = err
+		}
+	}()
+
+	// compress the cluster logs once they have been dumped, which may
+	// happen as late as in down
+	defer func() {
+		if err := artifacts.CompressLogs(); err != nil && result == nil {
+			result = err
+		}
+	}()
+
+	// keep the logs within the size limits before compressing them, once
+	// all of their writers, including the build log, are closed
+	defer func() {
+		if err := artifacts.EnforceSizeLimits(); err != nil && result == nil {
+			result = err
+		}
+	}()
+
+	// lay the artifacts out like prow does when not run by it
+	finishProwMetadata, err := artifacts.StartProwMetadata()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := finishProwMetadata(result); err != nil && result == nil {
+			result = err
+		}
+	}()
+
 	if err := writeVersionToMetadataJSON(d); err != nil {
 		return err
 	}
@@ -85,6 +150,22 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 		return fmt.Errorf("could not create runner output: %w", err)
 	}
 	writer := metadata.NewWriter("kubetest2", junitRunner)
+	process.SetRecorder(writer)
+
+	// setup the audit log of the commands
+	commandsLog, err := os.Create(
+		filepath.Join(artifacts.BaseDir(), "commands.jsonl"),
+	)
+	if err != nil {
+		return fmt.Errorf("could not create commands audit log: %w", err)
+	}
+	process.SetAuditLog(commandsLog)
+	defer func() {
+		process.SetAuditLog(nil)
+		if err := commandsLog.Close(); err != nil && result == nil {
+			result = err
+		}
+	}()
 
 	done := make(chan bool)
 	defer func() { done <- true }()
@@ -99,10 +180,14 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 				if opts.ShouldUp() || opts.ShouldTest() {
 					if opts.ShouldDown() {
 						klog.Info("Captured ^C, gracefully attempting to cleanup resources..")
-						if err := writer.WrapStep("Down", d.Down); err != nil {
+						if err := wrapStep(writer, "Down", d.Down); err != nil {
 							result = err
 						}
 					}
+					// the deferred functions do not run on exit
+					if err := finishProwMetadata(fmt.Errorf("interrupted")); err != nil {
+						klog.Warningf("failed to write finished.json: %v", err)
+					}
 					os.Exit(0)
 				}
 			case <-done:
@@ -129,9 +214,29 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 
 	klog.Infof("ID for this run: %q", opts.RunID())
 
+	if err := verifyUpgradeFlags(opts, d); err != nil {
+		return err
+	}
+	if err := verifyMixedOSFlags(opts, d); err != nil {
+		return err
+	}
+
+	if opts.FIPS() {
+		if err := enableFIPS(); err != nil {
+			return err
+		}
+	}
+
+	// tear down the cluster of a previous run with its run id
+	if opts.ShouldDown() && !opts.ShouldUp() {
+		if err := restoreDeployerState(opts, d); err != nil {
+			return err
+		}
+	}
+
 	// build if specified
 	if opts.ShouldBuild() {
-		if err := writer.WrapStep("Build", d.Build); err != nil {
+		if err := wrapStep(writer, "Build", d.Build); err != nil {
 			// we do not continue to up / test etc. if build fails
 			return err
 		}
@@ -143,7 +248,10 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 		if opts.ShouldDown() {
 			// TODO(bentheelder): instead of keeping the first error, consider
 			// a multi-error type
-			if err := writer.WrapStep("Down", d.Down); err != nil && result == nil {
+			err := wrapStep(writer, "Down", d.Down)
+			if err == nil {
+				removeDeployerState(opts)
+			} else if result == nil {
 				result = err
 			}
 		}
@@ -152,41 +260,95 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 	// up a cluster
 	if opts.ShouldUp() {
 		// TODO(bentheelder): this should write out to JUnit
-		if err := writer.WrapStep("Up", d.Up); err != nil {
+		err := wrapStep(writer, "Up", d.Up)
+		// a failed Up may have created resources as well
+		saveDeployerState(opts, d)
+		if err != nil {
 			// we do not continue to test if build fails
 			return err
 		}
+		if dWithVersionSkew, ok := d.(types.DeployerWithVersionSkew); ok {
+			if err := writeVersionSkewToMetadataJSON(dWithVersionSkew); err != nil {
+				klog.Warningf("failed to record the version skew of the cluster: %v", err)
+			}
+		}
+		if dWithFeatures, ok := d.(types.DeployerWithFeatures); ok {
+			if err := metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "deployer-features", strings.Join(dWithFeatures.Features(), ",")); err != nil {
+				klog.Warningf("failed to record the features of the cluster: %v", err)
+			}
+		}
+		if opts.FIPS() {
+			if err := writeFIPSNodesToMetadataJSON(d); err != nil {
+				klog.Warningf("failed to record whether the nodes are FIPS enabled: %v", err)
+			}
+		}
+	}
+
+	// report what the test leaves behind, before tearing the cluster down
+	if opts.ShouldTest() && opts.ShouldCheckLeaks() {
+		if before := takeInventory(d, "before"); before != nil {
+			defer checkLeaks(d, writer, before)
+		}
+	}
+
+	// profile the cluster while it is being tested
+	if opts.ShouldTest() && opts.ProfileInterval() > 0 {
+		collector := &profiling.Collector{
+			Kubeconfig: deployerKubeconfig(d),
+			Dir:        filepath.Join(artifacts.BaseDir(), "profiles"),
+			Interval:   opts.ProfileInterval(),
+		}
+		defer collector.Start()()
+	}
+
+	// record the state of the cluster while it is being tested
+	if opts.ShouldTest() && opts.MonitorInterval() > 0 {
+		m := &monitor.Monitor{
+			Kubeconfig: deployerKubeconfig(d),
+			Dir:        filepath.Join(artifacts.BaseDir(), "monitoring"),
+			Interval:   opts.MonitorInterval(),
+		}
+		defer m.Start()()
 	}
 
 	// and finally test, if a test was specified
-	if opts.ShouldTest() {
-		test := exec.Command(tester.TesterPath, tester.TesterArgs...)
-		exec.InheritOutput(test)
-
-		envsForTester := os.Environ()
-		// We expose both ARIFACTS and KUBETEST2_RUN_DIR so we can more granular about caching vs output in future.
-		// also add run_dir to $PATH for locally built binaries
-		updatedPath := opts.RunDir() + string(filepath.ListSeparator) + os.Getenv("PATH")
-		envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "PATH", updatedPath))
-		envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "ARTIFACTS", artifacts.BaseDir()))
-		envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "KUBETEST2_RUN_DIR", opts.RunDir()))
-		envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "KUBETEST2_RUN_ID", opts.RunID()))
-		// If the deployer provides a kubeconfig pass it to the tester
-		// else assumes that it is handled offline by default methods like
-		// ~/.kube/config
-		if dWithKubeconfig, ok := d.(types.DeployerWithKubeconfig); ok {
-			if kconfig, err := dWithKubeconfig.Kubeconfig(); err == nil {
-				envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "KUBECONFIG", kconfig))
+	if opts.ShouldTest() && opts.ShouldTestMixedOS() {
+		testErr := runMixedOSTests(opts, d, tester, writer)
+		if dWithPostTester, ok := d.(types.DeployerWithPostTester); ok {
+			if err := dWithPostTester.PostTest(testErr); err != nil {
+				return err
 			}
-
 		}
-		test.SetEnv(envsForTester...)
+		return testErr
+	}
+	if opts.ShouldTest() && opts.UpgradeTo() != "" {
+		testErr := runUpgradeTests(opts, d, tester, writer)
+		if dWithPostTester, ok := d.(types.DeployerWithPostTester); ok {
+			if err := dWithPostTester.PostTest(testErr); err != nil {
+				return err
+			}
+		}
+		return testErr
+	}
+	if opts.ShouldTest() {
+		testErr := runTest(opts, d, tester, writer, "Test", artifacts.BaseDir())
 
-		var testErr error
-		if !opts.SkipTestJUnitReport() {
-			testErr = writer.WrapStep("Test", test.Run)
-		} else {
-			testErr = test.Run()
+		// if the deployer supports upgrades, upgrade the cluster step by step
+		// and re-run the tester after each step
+		if dWithUpgrades, ok := d.(types.DeployerWithUpgrades); ok && testErr == nil {
+			for _, step := range dWithUpgrades.UpgradeSteps() {
+				if testErr = wrapStep(writer, step.Name, step.Run); testErr != nil {
+					break
+				}
+				// keep the results of each re-run apart from the previous ones
+				stepArtifacts := filepath.Join(artifacts.BaseDir(), step.Name)
+				if err := os.MkdirAll(stepArtifacts, os.ModePerm); err != nil {
+					return err
+				}
+				if testErr = runTest(opts, d, tester, writer, "Test after "+step.Name, stepArtifacts); testErr != nil {
+					break
+				}
+			}
 		}
 
 		if dWithPostTester, ok := d.(types.DeployerWithPostTester); ok {
@@ -202,6 +364,140 @@ func RealMain(opts types.Options, d types.Deployer, tester types.Tester) (result
 	return nil
 }
 
+// runTest runs the tester as the named step, with its results written to
+// the given artifacts directory.
+func runTest(opts types.Options, d types.Deployer, tester types.Tester, writer *metadata.Writer, name, artifactsDir string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	test := exec.CommandContext(ctx, tester.TesterPath, tester.TesterArgs...)
+	exec.InheritOutput(test)
+
+	envsForTester := os.Environ()
+	// We expose both ARIFACTS and KUBETEST2_RUN_DIR so we can more granular about caching vs output in future.
+	// also add run_dir to $PATH for locally built binaries
+	updatedPath := opts.RunDir() + string(filepath.ListSeparator) + os.Getenv("PATH")
+	envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "PATH", updatedPath))
+	envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "ARTIFACTS", artifactsDir))
+	envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "KUBETEST2_RUN_DIR", opts.RunDir()))
+	envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "KUBETEST2_RUN_ID", opts.RunID()))
+	if location := artifacts.SinkLocation(); location != "" {
+		envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", artifacts.SinkEnv, location))
+	}
+	// If the deployer provides a kubeconfig pass it to the tester
+	// else assumes that it is handled offline by default methods like
+	// ~/.kube/config
+	if dWithKubeconfig, ok := d.(types.DeployerWithKubeconfig); ok {
+		if kconfig, err := dWithKubeconfig.Kubeconfig(); err == nil {
+			envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", "KUBECONFIG", kconfig))
+		}
+
+	}
+	// the test args are optional for the tester, which may not use them
+	if dWithTestArgs, ok := d.(types.DeployerWithTestArgs); ok {
+		if testArgs, err := dWithTestArgs.TestArgs(); err != nil {
+			klog.Warningf("failed to get the test args of the deployer: %v", err)
+		} else {
+			envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", types.DeployerTestArgsEnv, shellquote.Join(testArgs...)))
+		}
+	}
+	if dWithFeatures, ok := d.(types.DeployerWithFeatures); ok {
+		envsForTester = append(envsForTester, fmt.Sprintf("%s=%s", types.DeployerFeaturesEnv, strings.Join(dWithFeatures.Features(), ",")))
+	}
+	envsForTester = append(envsForTester, tester.TesterEnv...)
+	test.SetEnv(envsForTester...)
+
+	// stop the tester if the lease of the resources of the cluster is lost,
+	// so that the cluster is torn down before they are cleaned up under it
+	var leaseLost <-chan struct{}
+	if dWithLease, ok := d.(types.DeployerWithLease); ok {
+		leaseLost = dWithLease.LeaseLost()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-leaseLost:
+			klog.Errorf("The lease of the resources of the cluster was lost, stopping the tester")
+			close(stopped)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	runTester := func() error {
+		err := test.Run()
+		select {
+		case <-stopped:
+			return fmt.Errorf("the tester was stopped as the lease of the resources of the cluster was lost: %v", err)
+		default:
+			return err
+		}
+	}
+
+	defer mergeTestResults(artifactsDir)
+	if !opts.SkipTestJUnitReport() {
+		return wrapStep(writer, name, runTester)
+	}
+	process.SetPhase(name)
+	return runTester()
+}
+
+// mergeTestResults merges the JUnit results written by the tester into
+// artifactsDir into merged-junit.xml, with retried testcases deduplicated,
+// and reports the flakes. It is named so as not to be picked up again as
+// results of their own.
+func mergeTestResults(artifactsDir string) {
+	paths, err := filepath.Glob(filepath.Join(artifactsDir, "junit*.xml"))
+	if err != nil {
+		return
+	}
+	var results []string
+	for _, path := range paths {
+		if filepath.Base(path) != "junit_runner.xml" {
+			results = append(results, path)
+		}
+	}
+	if len(results) == 0 {
+		return
+	}
+	merged, err := os.Create(filepath.Join(artifactsDir, "merged-junit.xml"))
+	if err != nil {
+		klog.Warningf("failed to merge test results: %v", err)
+		return
+	}
+	defer merged.Close()
+	summary, err := artifacts.MergeJUnit("kubetest2", results, merged)
+	if err != nil {
+		klog.Warningf("failed to merge test results: %v", err)
+		return
+	}
+	klog.Infof("Test results: %d tests, %d failed, %d flaked, %d skipped",
+		summary.Tests, summary.Failures, summary.Flakes, summary.Skipped)
+}
+
+// syncer puts the artifacts to --artifacts-sink, if set
+var syncer *artifacts.Syncer
+
+// syncInterval is how often the artifacts are put to the sink during a step
+const syncInterval = time.Minute
+
+// wrapStep runs the named step with the output of its commands captured
+// under that phase, putting the artifacts to the sink while it runs and
+// afterwards.
+func wrapStep(writer *metadata.Writer, name string, fn func() error) error {
+	process.SetPhase(name)
+	stopSync := func() {}
+	if syncer != nil {
+		stopSync = syncer.SyncEvery(syncInterval)
+	}
+	err := writer.WrapStep(name, fn)
+	stopSync()
+	if syncer != nil {
+		if err := syncer.Sync(); err != nil {
+			klog.Warningf("failed to put the artifacts of %s to %s: %v", name, artifacts.SinkLocation(), err)
+		}
+	}
+	return err
+}
+
 func writeVersionToMetadataJSON(d types.Deployer) error {
 	// setup the json metadata writer
 	metadataJSON, err := os.Create(
@@ -234,3 +530,29 @@ func writeVersionToMetadataJSON(d types.Deployer) error {
 	}
 	return metadataJSON.Close()
 }
+
+// writeVersionSkewToMetadataJSON records the versions of the control plane and
+// the nodes of the cluster, and the skew between them if both are known
+func writeVersionSkewToMetadataJSON(d types.DeployerWithVersionSkew) error {
+	path := filepath.Join(artifacts.BaseDir(), "metadata.json")
+	controlPlane, node := d.VersionSkew()
+	if controlPlane != "" {
+		if err := metadata.AddToFile(path, "control-plane-version", controlPlane); err != nil {
+			return err
+		}
+	}
+	if node != "" {
+		if err := metadata.AddToFile(path, "node-version", node); err != nil {
+			return err
+		}
+	}
+	if controlPlane == "" || node == "" {
+		return nil
+	}
+	skew, err := metadata.VersionSkew(controlPlane, node)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Nodes at %s are %s to the control plane at %s", node, skew, controlPlane)
+	return metadata.AddToFile(path, "version-skew", skew)
+}
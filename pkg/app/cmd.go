@@ -18,10 +18,12 @@ package app
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
@@ -30,6 +32,7 @@ import (
 	"sigs.k8s.io/kubetest2/pkg/app/shim"
 	"sigs.k8s.io/kubetest2/pkg/artifacts"
 	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/flagschema"
 	"sigs.k8s.io/kubetest2/pkg/types"
 )
 
@@ -72,6 +75,14 @@ func runE(
 	// parse arguments, splitting out test args (after the `--`)
 	deployerArgs, testerArgs := splitArgs(args)
 
+	// describe-flags --json describes the flags instead of running
+	describeFlags, deployerArgs, err := splitDescribeFlags(deployerArgs)
+	if err != nil {
+		incorrectUsageString := fmt.Sprintf("Error: %s", err)
+		cmd.Print(incorrectUsageString)
+		return types.NewIncorrectUsage(incorrectUsageString)
+	}
+
 	// setup usage metadata for deffered usage printing
 	usage := &usage{
 		deployerName:   deployerName,
@@ -85,12 +96,22 @@ func runE(
 
 	// now that we've parsed flags we can look up the tester
 	tester := types.Tester{}
-	if opts.test != "" {
+	var testerSchema *flagschema.Plugin
+	// with --remote the tester only needs to be installed on the remote host
+	if opts.test != "" && (opts.remote == "" || describeFlags || opts.HelpRequested()) {
 		testerPath, err := shim.FindTester(opts.test)
 		if err != nil {
 			return fmt.Errorf("unable to find tester %v: %v", opts.test, err)
 		}
 
+		if describeFlags {
+			flags, err := describeTesterFlags(testerPath)
+			if err != nil {
+				return fmt.Errorf("unable to describe the flags of tester %v: %v", opts.test, err)
+			}
+			testerSchema = &flagschema.Plugin{Name: opts.test, Flags: flags}
+		}
+
 		// Get tester usage by running it with --help
 		var helpArgs []string
 		helpArgs = append(helpArgs, "--help")
@@ -135,6 +156,14 @@ func runE(
 		parseError = err
 	}
 
+	if describeFlags {
+		return flagschema.Write(cmd.OutOrStdout(), flagschema.Schema{
+			Kubetest2: flagschema.FromFlagSet(kubetest2Flags),
+			Deployer:  flagschema.Plugin{Name: deployerName, Flags: flagschema.FromFlagSet(deployerFlags)},
+			Tester:    testerSchema,
+		})
+	}
+
 	// print usage and return if no args are provided, or help is explicitly requested
 	if len(args) == 0 || opts.HelpRequested() {
 		cmd.Print(usage.String())
@@ -161,6 +190,10 @@ func runE(
 		return parseError
 	}
 
+	if opts.remote != "" {
+		return runRemote(opts, deployerName, deployerArgs, testerArgs)
+	}
+
 	// run RealMain, which contains all of the logic beyond the CLI boilerplate
 	return RealMain(opts, deployer, tester)
 }
@@ -181,6 +214,50 @@ func splitArgs(args []string) ([]string, []string) {
 	return args, testArgs
 }
 
+const describeFlagsCommand = "describe-flags"
+
+// splitDescribeFlags removes the describe-flags subcommand and its --json
+// flag from args, returning whether it was found
+func splitDescribeFlags(args []string) (bool, []string, error) {
+	found, jsonFormat := false, false
+	rest := []string{}
+	for _, arg := range args {
+		switch arg {
+		case describeFlagsCommand:
+			found = true
+		case "--json":
+			jsonFormat = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if !found {
+		return false, args, nil
+	}
+	if !jsonFormat {
+		return false, nil, fmt.Errorf("%s requires --json, the only supported format", describeFlagsCommand)
+	}
+	return true, rest, nil
+}
+
+// describeTesterFlags returns the flags of the tester, which describes them
+// as JSON when run with --help and flagschema.FormatEnv set
+func describeTesterFlags(testerPath string) ([]flagschema.Flag, error) {
+	cmd := exec.Command(testerPath, "--help")
+	cmd.SetEnv(append(os.Environ(), flagschema.FormatEnv+"=json")...)
+	var stderr bytes.Buffer
+	cmd.SetStderr(&stderr)
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, stderr.String())
+	}
+	var flags []flagschema.Flag
+	if err := json.Unmarshal(out, &flags); err != nil {
+		return nil, fmt.Errorf("the tester does not support describing its flags: %v", err)
+	}
+	return flags, nil
+}
+
 // options holds flag values and implements deployer.Options
 type options struct {
 	help                bool
@@ -191,6 +268,17 @@ type options struct {
 	skipTestJUnitReport bool
 	runid               string
 	rundirInArtifacts   bool
+	upgradeTo           string
+	downgrade           bool
+	leakCheck           bool
+	profileInterval     time.Duration
+	monitorInterval     time.Duration
+	remote              string
+	remoteDir           string
+	mixedOS             bool
+	windowsTest         string
+	windowsTestArgs     string
+	fips                bool
 }
 
 // bindFlags registers all first class kubetest2 flags
@@ -211,6 +299,24 @@ func (o *options) bindFlags(flags *pflag.FlagSet) {
 	}
 	flags.StringVar(&o.runid, "run-id", defaultRunID, "unique identifier for a kubetest2 run")
 	flags.BoolVar(&o.rundirInArtifacts, "rundir-in-artifacts", false, `if true, the test binaries and run specific metadata will be in the ARTIFACTS`)
+	flags.StringVar(&o.upgradeTo, "upgrade-to", "", "if set, upgrade the cluster to this version after the first test pass and test it again, "+
+		"with the results of each pass in a subdirectory of the artifacts. Requires a deployer supporting in place upgrades")
+	flags.BoolVar(&o.downgrade, "downgrade", false, "if true, downgrade the cluster back to its initial version after the test pass following --upgrade-to and test it again")
+	flags.BoolVar(&o.leakCheck, "leak-check", false, "if true, take an inventory of the namespaces, persistent volumes, LoadBalancer services and cloud resources of the cluster "+
+		"before and after the test, and report those left behind as a failed JUnit test case")
+	flags.DurationVar(&o.profileInterval, "profile-interval", 0, "if set, take CPU and heap pprof profiles of the apiserver and the kubelets at this interval while testing, "+
+		"into the profiles directory of the artifacts, e.g. 10m. etcd is not profiled")
+	flags.DurationVar(&o.monitorInterval, "monitor-interval", 0, "if set, record the events, the apiserver request metrics and the node conditions of the cluster at this interval while testing, "+
+		"into the monitoring directory of the artifacts, e.g. 30s")
+	flags.BoolVar(&o.mixedOS, "mixed-os", false, "if true and the deployer has Windows nodes, run a test pass for the Linux nodes and another for the Windows nodes, "+
+		"with the results of each in the linux and windows subdirectories of the artifacts. The tester gets the operating system in $"+types.NodeOSEnv)
+	flags.StringVar(&o.windowsTest, "windows-test", "", "tester of the Windows test pass of --mixed-os, defaults to --test")
+	flags.StringVar(&o.windowsTestArgs, "windows-test-args", "", "shell quoted arguments of the tester of the Windows test pass of --mixed-os, defaults to the TesterArgs")
+	flags.BoolVar(&o.fips, "fips", false, "if true, build with the boringcrypto FIPS 140 module (GOEXPERIMENT=boringcrypto) and deploy FIPS enabled nodes if the deployer supports it, "+
+		"with the GOEXPERIMENT recorded in the metadata. Only the binaries built with cgo use boringcrypto")
+	flags.StringVar(&o.remote, "remote", "", "if set, run the phases on this [user@]host over ssh instead, e.g. a builder or a bastion host: the current directory is synced to it with rsync "+
+		"and the artifacts are synced back. kubetest2, the deployer and the tester must be installed on the remote host")
+	flags.StringVar(&o.remoteDir, "remote-dir", "", "directory of the --remote host the current directory is synced to and the phases are run in, defaults to kubetest2/<name of the current directory> in the home directory")
 }
 
 // assert that options implements deployer options
@@ -267,6 +373,42 @@ func (o *options) RundirInArtifacts() bool {
 	return o.rundirInArtifacts
 }
 
+func (o *options) UpgradeTo() string {
+	return o.upgradeTo
+}
+
+func (o *options) ShouldDowngrade() bool {
+	return o.downgrade
+}
+
+func (o *options) ShouldCheckLeaks() bool {
+	return o.leakCheck
+}
+
+func (o *options) ProfileInterval() time.Duration {
+	return o.profileInterval
+}
+
+func (o *options) MonitorInterval() time.Duration {
+	return o.monitorInterval
+}
+
+func (o *options) ShouldTestMixedOS() bool {
+	return o.mixedOS
+}
+
+func (o *options) WindowsTest() string {
+	return o.windowsTest
+}
+
+func (o *options) WindowsTestArgs() string {
+	return o.windowsTestArgs
+}
+
+func (o *options) FIPS() bool {
+	return o.fips
+}
+
 // metadata used for CLI usage string
 type usage struct {
 	kubetest2Flags *pflag.FlagSet
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// boringCryptoExperiment is the GOEXPERIMENT building with the boringcrypto
+// FIPS 140 module instead of the go crypto
+const boringCryptoExperiment = "boringcrypto"
+
+// enableFIPS sets up the --fips profile: the builds of the run inherit the
+// boringcrypto GOEXPERIMENT, which is recorded in the metadata. Only the
+// binaries built with cgo use boringcrypto, the static ones fall back to the
+// go crypto, so the metadata does not claim a crypto mode.
+func enableFIPS() error {
+	experiments := os.Getenv("GOEXPERIMENT")
+	if !slices.Contains(strings.Split(experiments, ","), boringCryptoExperiment) {
+		if experiments != "" {
+			experiments += ","
+		}
+		experiments += boringCryptoExperiment
+		if err := os.Setenv("GOEXPERIMENT", experiments); err != nil {
+			return err
+		}
+	}
+	klog.Warningf("--fips: binaries built with CGO_ENABLED=0, as most kubernetes binaries are, use the go crypto instead of boringcrypto, " +
+		"list them in KUBE_CGO_OVERRIDES to build them with cgo")
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "goexperiment", experiments)
+}
+
+// writeFIPSNodesToMetadataJSON records whether the nodes of the cluster run a
+// FIPS enabled image with --fips, which depends on the deployer
+func writeFIPSNodesToMetadataJSON(d types.Deployer) error {
+	fipsNodes := false
+	if dWithFIPS, ok := d.(types.DeployerWithFIPS); ok {
+		fipsNodes = dWithFIPS.FIPSNodes()
+	}
+	if !fipsNodes {
+		klog.Warningf("--fips is set but the deployer did not deploy FIPS enabled nodes")
+	}
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "fips-nodes", strconv.FormatBool(fipsNodes))
+}
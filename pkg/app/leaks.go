@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/inventory"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+const (
+	// leakGracePeriod is how long the resources the tester deleted are
+	// given to go away, e.g. namespaces being finalized
+	leakGracePeriod = 5 * time.Minute
+	leakPollPeriod  = 15 * time.Second
+)
+
+// takeInventory takes the named inventory of the cluster of d, written to
+// the inventory directory of the artifacts. It returns nil if it fails, as
+// the leak check should not fail the run.
+func takeInventory(d types.Deployer, name string) *inventory.Snapshot {
+	snapshot, err := inventory.Take(deployerKubeconfig(d))
+	if err != nil {
+		klog.Warningf("failed to take the %s inventory of the cluster: %v", name, err)
+		return nil
+	}
+	if dWithCloudInventory, ok := d.(types.DeployerWithCloudInventory); ok {
+		if snapshot.CloudResources, err = dWithCloudInventory.CloudResources(); err != nil {
+			klog.Warningf("failed to take the %s inventory of the cloud resources: %v", name, err)
+			return nil
+		}
+	}
+	dir := filepath.Join(artifacts.BaseDir(), "inventory")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		klog.Warningf("failed to write the %s inventory: %v", name, err)
+		return snapshot
+	}
+	if err := snapshot.Write(filepath.Join(dir, name+".json")); err != nil {
+		klog.Warningf("failed to write the %s inventory: %v", name, err)
+	}
+	return snapshot
+}
+
+// deployerKubeconfig returns the kubeconfig of the cluster of d, or an empty
+// path for that of the environment if it has none
+func deployerKubeconfig(d types.Deployer) string {
+	if dWithKubeconfig, ok := d.(types.DeployerWithKubeconfig); ok {
+		if kubeconfig, err := dWithKubeconfig.Kubeconfig(); err == nil {
+			return kubeconfig
+		}
+	}
+	return ""
+}
+
+// checkLeaks reports the resources of the cluster that were not there before
+// the test as the failure of a JUnit test case. Leaks are reported only once
+// they outlive leakGracePeriod, and do not fail the run.
+func checkLeaks(d types.Deployer, writer *metadata.Writer, before *inventory.Snapshot) {
+	_ = wrapStep(writer, "Check for leaked resources", func() error {
+		var leaks []string
+		for deadline := time.Now().Add(leakGracePeriod); ; time.Sleep(leakPollPeriod) {
+			after := takeInventory(d, "after")
+			if after == nil {
+				return fmt.Errorf("failed to take the inventory of the cluster after the test")
+			}
+			if leaks = inventory.Leaks(before, after); len(leaks) == 0 {
+				return nil
+			}
+			if time.Now().After(deadline) {
+				break
+			}
+			klog.V(2).Infof("Waiting for %d resources left by the test to go away", len(leaks))
+		}
+		klog.Warningf("The test left %d resources behind:\n%s", len(leaks), strings.Join(leaks, "\n"))
+		return fmt.Errorf("the test left %d resources behind: %s", len(leaks), strings.Join(leaks, ", "))
+	})
+}
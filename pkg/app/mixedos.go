@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	"sigs.k8s.io/kubetest2/pkg/app/shim"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// verifyMixedOSFlags fails early, before anything is built or brought up, if
+// --mixed-os cannot be honored
+func verifyMixedOSFlags(opts types.Options, d types.Deployer) error {
+	if !opts.ShouldTestMixedOS() {
+		if opts.WindowsTest() != "" || opts.WindowsTestArgs() != "" {
+			return fmt.Errorf("--windows-test and --windows-test-args require --mixed-os")
+		}
+		return nil
+	}
+	if !opts.ShouldTest() {
+		return fmt.Errorf("--mixed-os requires --test")
+	}
+	if opts.UpgradeTo() != "" {
+		return fmt.Errorf("--mixed-os and --upgrade-to are mutually exclusive")
+	}
+	if _, ok := d.(types.DeployerWithNodeOS); !ok {
+		return fmt.Errorf("--mixed-os is not supported by the deployer, which does not report the operating systems of its nodes")
+	}
+	if _, err := shellquote.Split(opts.WindowsTestArgs()); err != nil {
+		return fmt.Errorf("error parsing --windows-test-args: %v", err)
+	}
+	return nil
+}
+
+// windowsTester returns the tester of the Windows test pass, which is tester
+// unless --windows-test or --windows-test-args are set
+func windowsTester(opts types.Options, tester types.Tester) (types.Tester, error) {
+	if opts.WindowsTest() != "" {
+		testerPath, err := shim.FindTester(opts.WindowsTest())
+		if err != nil {
+			return tester, fmt.Errorf("unable to find tester %v: %v", opts.WindowsTest(), err)
+		}
+		tester.TesterPath = testerPath
+	}
+	if opts.WindowsTestArgs() != "" {
+		args, err := shellquote.Split(opts.WindowsTestArgs())
+		if err != nil {
+			return tester, fmt.Errorf("error parsing --windows-test-args: %v", err)
+		}
+		tester.TesterArgs = args
+	}
+	return tester, nil
+}
+
+// runMixedOSTests runs a test pass per operating system of the nodes, the
+// Linux one with tester and the Windows one with the windowsTester. The
+// results of each test pass are written to a subdirectory of the artifacts
+// named after the operating system. All of the passes are run, the first
+// failure is returned.
+func runMixedOSTests(opts types.Options, d types.Deployer, tester types.Tester, writer *metadata.Writer) error {
+	nodeOSes := d.(types.DeployerWithNodeOS).NodeOS()
+	if err := metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "node-os", strings.Join(nodeOSes, ",")); err != nil {
+		klog.Warningf("failed to record the operating systems of the nodes: %v", err)
+	}
+
+	testers := map[string]types.Tester{types.NodeOSLinux: tester}
+	if slices.Contains(nodeOSes, types.NodeOSWindows) {
+		var err error
+		if testers[types.NodeOSWindows], err = windowsTester(opts, tester); err != nil {
+			return err
+		}
+	} else {
+		klog.Warningf("--mixed-os is set but the cluster has no Windows nodes, only testing the Linux nodes")
+	}
+
+	var testErr error
+	for _, nodeOS := range []string{types.NodeOSLinux, types.NodeOSWindows} {
+		osTester, ok := testers[nodeOS]
+		if !ok {
+			continue
+		}
+		osTester.TesterEnv = append(osTester.TesterEnv, fmt.Sprintf("%s=%s", types.NodeOSEnv, nodeOS))
+		if err := runOSTest(opts, d, osTester, writer, nodeOS); err != nil && testErr == nil {
+			testErr = err
+		}
+	}
+	return testErr
+}
+
+// runOSTest runs the test pass for the nodes of the operating system os
+func runOSTest(opts types.Options, d types.Deployer, tester types.Tester, writer *metadata.Writer, nodeOS string) error {
+	osArtifacts := filepath.Join(artifacts.BaseDir(), nodeOS)
+	if err := os.MkdirAll(osArtifacts, os.ModePerm); err != nil {
+		return err
+	}
+	return runTest(opts, d, tester, writer, "Test "+nodeOS, osArtifacts)
+}
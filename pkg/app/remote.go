@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/app/shim"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// runRemote runs the phases on the --remote host instead: the current
+// directory is synced to --remote-dir with rsync, kubetest2 is run there over
+// ssh with the same arguments, and the artifacts are synced back.
+func runRemote(opts *options, deployerName string, deployerArgs, testerArgs []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	remoteDir := opts.remoteDir
+	if remoteDir == "" {
+		remoteDir = filepath.Join("kubetest2", filepath.Base(wd))
+	}
+	remoteDir = strings.TrimSuffix(remoteDir, "/")
+
+	klog.V(0).Infof("Syncing %s to %s:%s", wd, opts.remote, remoteDir)
+	if err := runCommand(exec.Command("ssh", opts.remote, "mkdir -p "+shellquote.Join(remoteDir))); err != nil {
+		return fmt.Errorf("failed to create %s on %s: %v", remoteDir, opts.remote, err)
+	}
+	// the outputs of previous runs on the remote host are kept, e.g. for
+	// --down --run-id=<id> of a run in the remote run dir
+	if err := runCommand(exec.Command("rsync", "-az", "--delete",
+		"--exclude=/_artifacts", "--exclude=/_rundir",
+		wd+"/", opts.remote+":"+remoteDir+"/")); err != nil {
+		return fmt.Errorf("failed to sync %s to %s: %v", wd, opts.remote, err)
+	}
+
+	klog.V(0).Infof("Running kubetest2 %s on %s", deployerName, opts.remote)
+	runErr := runCommand(exec.Command("ssh", opts.remote, remoteCommand(remoteDir, deployerName, opts.RunID(), deployerArgs, testerArgs)))
+
+	// the artifacts are synced back whether the run succeeded or not
+	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
+		return err
+	}
+	if err := runCommand(exec.Command("rsync", "-az",
+		opts.remote+":"+remoteDir+"/_artifacts/", artifacts.BaseDir()+"/")); err != nil {
+		klog.Errorf("failed to sync the artifacts back from %s: %v", opts.remote, err)
+		if runErr == nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// remoteCommand returns the shell command running kubetest2 in remoteDir on
+// the remote host. The run id is passed along, as it is generated by default,
+// and the artifacts go to remoteDir to be synced back.
+func remoteCommand(remoteDir, deployerName, runID string, deployerArgs, testerArgs []string) string {
+	args := append([]string{shim.BinaryName, deployerName}, deployerArgs...)
+	// the last value of a flag wins, so these override the local ones
+	args = append(args, "--run-id="+runID, "--remote=")
+	command := fmt.Sprintf(`cd %s && %s --artifacts="$PWD/_artifacts"`, shellquote.Join(remoteDir), shellquote.Join(args...))
+	if len(testerArgs) > 0 {
+		command += " -- " + shellquote.Join(testerArgs...)
+	}
+	return command
+}
+
+func runCommand(cmd exec.Cmd) error {
+	exec.InheritOutput(cmd)
+	return cmd.Run()
+}
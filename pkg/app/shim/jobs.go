@@ -0,0 +1,299 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+// JobsConfig is the config file of --jobs, listing the deployer and tester
+// combinations to run in a single invocation
+type JobsConfig struct {
+	// Parallelism is the number of jobs run at once, 1 if unset
+	Parallelism int `json:"parallelism,omitempty"`
+	// Jobs are the jobs to run
+	Jobs []Job `json:"jobs"`
+}
+
+// Job is a kubetest2 invocation of a JobsConfig
+type Job struct {
+	// Name identifies the job, it names the directory of its artifacts and
+	// suffixes its run id
+	Name string `json:"name"`
+	// Deployer is the deployer of the job, e.g. kind
+	Deployer string `json:"deployer"`
+	// Args are the arguments of the deployer, e.g.
+	// [--up, --down, --test=ginkgo, --, --focus-regex=\[Conformance\]]
+	Args []string `json:"args,omitempty"`
+	// Timeout is how long the job may run before it is killed, e.g. 2h,
+	// no timeout if unset
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// JobResult is the outcome of a job, as written to jobs-summary.json
+type JobResult struct {
+	Name     string  `json:"name"`
+	Deployer string  `json:"deployer"`
+	RunID    string  `json:"run-id"`
+	Passed   bool    `json:"passed"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration-seconds"`
+	// the test results of the job, if it ran tests
+	Tests    int `json:"tests"`
+	Failures int `json:"failures"`
+	Flakes   int `json:"flakes"`
+	Skipped  int `json:"skipped"`
+}
+
+var jobNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// isJobsInvocation returns true if args run the jobs of a config file
+// instead of a deployer, i.e. start with --jobs
+func isJobsInvocation(args []string) bool {
+	return len(args) > 0 && (args[0] == "--jobs" || strings.HasPrefix(args[0], "--jobs="))
+}
+
+// runJobs runs the jobs of the config file of --jobs, with at most
+// --parallelism of them at once. The artifacts of each job are written to a
+// subdirectory of the artifacts named after the job, along with a combined
+// junit_jobs.xml and jobs-summary.json. It fails if any job failed.
+func runJobs(cmd *cobra.Command, args []string) error {
+	flags := pflag.NewFlagSet(BinaryName, pflag.ContinueOnError)
+	configPath := flags.String("jobs", "", "path to the config file of the jobs to run")
+	parallelism := flags.Int("parallelism", 0, "number of jobs to run at once, overriding the parallelism of the config file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments with --jobs: %v", flags.Args())
+	}
+	config, err := loadJobsConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *parallelism > 0 {
+		config.Parallelism = *parallelism
+	}
+
+	deployers := map[string]string{}
+	for _, job := range config.Jobs {
+		if _, ok := deployers[job.Deployer]; ok {
+			continue
+		}
+		deployer, err := FindDeployer(job.Deployer)
+		if err != nil {
+			return fmt.Errorf("could not find kubetest2 deployer %#v of job %s: %v", job.Deployer, job.Name, err)
+		}
+		deployers[job.Deployer] = deployer
+	}
+
+	baseDir := artifacts.BaseDir()
+	if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
+		return err
+	}
+	junitJobs, err := os.Create(filepath.Join(baseDir, "junit_jobs.xml"))
+	if err != nil {
+		return fmt.Errorf("could not create junit_jobs.xml: %v", err)
+	}
+	defer junitJobs.Close()
+	writer := metadata.NewWriter("kubetest2-jobs", junitJobs)
+
+	baseRunID := os.Getenv("PROW_JOB_ID")
+	if baseRunID == "" {
+		baseRunID = uuid.New().String()
+	}
+	var mu sync.Mutex
+	results := make([]JobResult, len(config.Jobs))
+	tasks := make([]process.Task, len(config.Jobs))
+	for i, job := range config.Jobs {
+		i, job := i, job
+		tasks[i] = process.Task{
+			Name: job.Name,
+			Run: func() error {
+				return writer.WrapStep(job.Name, func() error {
+					mu.Lock()
+					cmd.Printf("Starting job %s\n", job.Name)
+					mu.Unlock()
+					results[i] = runJob(job, deployers[job.Deployer], baseRunID, filepath.Join(baseDir, job.Name))
+					mu.Lock()
+					defer mu.Unlock()
+					if !results[i].Passed {
+						cmd.Printf("Job %s failed after %s: %s\n", job.Name, seconds(results[i].Duration), results[i].Error)
+						return fmt.Errorf("%s", results[i].Error)
+					}
+					cmd.Printf("Job %s passed after %s\n", job.Name, seconds(results[i].Duration))
+					return nil
+				})
+			},
+		}
+	}
+	jobsErr := process.ExecParallel(config.Parallelism, tasks)
+
+	if err := writer.Finish(); err != nil {
+		cmd.Printf("Error: could not write junit_jobs.xml: %v\n", err)
+	}
+	if err := writeJobsSummary(filepath.Join(baseDir, "jobs-summary.json"), results); err != nil {
+		cmd.Printf("Error: could not write jobs-summary.json: %v\n", err)
+	}
+	cmd.Println()
+	printJobsSummary(cmd.OutOrStdout(), results)
+	return jobsErr
+}
+
+// loadJobsConfig reads and validates the JobsConfig at path, in YAML or JSON
+func loadJobsConfig(path string) (*JobsConfig, error) {
+	if path == "" {
+		return nil, fmt.Errorf("--jobs requires the path to a config file")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the jobs config: %v", err)
+	}
+	config := &JobsConfig{}
+	if err := yaml.UnmarshalStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("could not parse the jobs config %s: %v", path, err)
+	}
+	if len(config.Jobs) == 0 {
+		return nil, fmt.Errorf("the jobs config %s lists no jobs", path)
+	}
+	names := map[string]bool{}
+	for i, job := range config.Jobs {
+		if !jobNameRegex.MatchString(job.Name) {
+			return nil, fmt.Errorf("job %d has an invalid name %q, it must match %s", i, job.Name, jobNameRegex)
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("job %s is listed more than once", job.Name)
+		}
+		names[job.Name] = true
+		if job.Deployer == "" {
+			return nil, fmt.Errorf("job %s has no deployer", job.Name)
+		}
+		if job.Timeout != "" {
+			if _, err := time.ParseDuration(job.Timeout); err != nil {
+				return nil, fmt.Errorf("job %s has an invalid timeout: %v", job.Name, err)
+			}
+		}
+	}
+	return config, nil
+}
+
+// runJob runs job with the deployer binary at deployerPath, with its output
+// written to build-log.txt among its artifacts in artifactsDir
+func runJob(job Job, deployerPath, baseRunID, artifactsDir string) JobResult {
+	result := JobResult{
+		Name:     job.Name,
+		Deployer: job.Deployer,
+		RunID:    baseRunID + "-" + job.Name,
+	}
+	start := time.Now()
+	err := func() error {
+		if err := os.MkdirAll(artifactsDir, os.ModePerm); err != nil {
+			return err
+		}
+		log, err := os.Create(filepath.Join(artifactsDir, "build-log.txt"))
+		if err != nil {
+			return err
+		}
+		defer log.Close()
+
+		env := append(os.Environ(),
+			fmt.Sprintf("KUBETEST2_VERSION=kubetest2 version %s", GitTag),
+			"ARTIFACTS="+artifactsDir,
+		)
+		// the timeout was validated by loadJobsConfig
+		timeout, _ := time.ParseDuration(job.Timeout)
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return process.ExecContextWithOutput(ctx, deployerPath, jobArgs(job.Args, "--run-id="+result.RunID), env, log, log)
+	}()
+	result.Duration = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Passed = err == nil
+
+	if _, err := os.Stat(filepath.Join(artifactsDir, "merged-junit.xml")); err == nil {
+		if summary, err := artifacts.MergeJUnit(job.Name, []string{filepath.Join(artifactsDir, "merged-junit.xml")}, io.Discard); err == nil {
+			result.Tests, result.Failures, result.Skipped = summary.Tests, summary.Failures, summary.Skipped
+			result.Flakes = summary.Flakes
+		}
+	}
+	return result
+}
+
+// jobArgs returns args with the kubetest2 flags extra added before the
+// tester arguments following --, if any
+func jobArgs(args []string, extra ...string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return append(append(append([]string{}, args[:i]...), extra...), args[i:]...)
+		}
+	}
+	return append(append([]string{}, args...), extra...)
+}
+
+func writeJobsSummary(path string, results []JobResult) error {
+	raw, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+// printJobsSummary prints a line per job with its outcome and test results
+func printJobsSummary(out io.Writer, results []JobResult) {
+	passed := 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tDEPLOYER\tRESULT\tDURATION\tTESTS\tFAILED\tFLAKED\tSKIPPED")
+	for _, r := range results {
+		outcome := "FAILED"
+		if r.Passed {
+			outcome = "PASSED"
+			passed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n", r.Name, r.Deployer, outcome, seconds(r.Duration), r.Tests, r.Failures, r.Flakes, r.Skipped)
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\n%d of %d jobs passed\n", passed, len(results))
+}
+
+func seconds(s float64) string {
+	return time.Duration(s * float64(time.Second)).Round(time.Second).String()
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoadJobsConfig(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expected  *JobsConfig
+		expectErr string
+	}{
+		{
+			name: "valid",
+			config: `parallelism: 2
+jobs:
+- name: kind-conformance
+  deployer: kind
+  args: [--up, --down, --test=ginkgo, --, --focus-regex=Conformance]
+  timeout: 2h
+- name: gce.default
+  deployer: gce
+`,
+			expected: &JobsConfig{
+				Parallelism: 2,
+				Jobs: []Job{
+					{Name: "kind-conformance", Deployer: "kind", Args: []string{"--up", "--down", "--test=ginkgo", "--", "--focus-regex=Conformance"}, Timeout: "2h"},
+					{Name: "gce.default", Deployer: "gce"},
+				},
+			},
+		},
+		{
+			name:      "no jobs",
+			config:    "parallelism: 2\n",
+			expectErr: "lists no jobs",
+		},
+		{
+			name:      "unknown field",
+			config:    "jobs:\n- name: a\n  deployer: kind\n  tester: ginkgo\n",
+			expectErr: "could not parse",
+		},
+		{
+			name:      "invalid name",
+			config:    "jobs:\n- name: ../a\n  deployer: kind\n",
+			expectErr: "invalid name",
+		},
+		{
+			name:      "duplicate name",
+			config:    "jobs:\n- name: a\n  deployer: kind\n- name: a\n  deployer: gce\n",
+			expectErr: "more than once",
+		},
+		{
+			name:      "no deployer",
+			config:    "jobs:\n- name: a\n",
+			expectErr: "has no deployer",
+		},
+		{
+			name:      "invalid timeout",
+			config:    "jobs:\n- name: a\n  deployer: kind\n  timeout: 2 hours\n",
+			expectErr: "invalid timeout",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jobs.yaml")
+			if err := os.WriteFile(path, []byte(tc.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := loadJobsConfig(path)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, config)
+			}
+		})
+	}
+}
+
+func TestJobArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "no args",
+			expected: []string{"--run-id=1234"},
+		},
+		{
+			name:     "no tester args",
+			args:     []string{"--up", "--down"},
+			expected: []string{"--up", "--down", "--run-id=1234"},
+		},
+		{
+			name:     "before the tester args",
+			args:     []string{"--up", "--test=ginkgo", "--", "--focus-regex=Conformance"},
+			expected: []string{"--up", "--test=ginkgo", "--run-id=1234", "--", "--focus-regex=Conformance"},
+		},
+		{
+			name:     "before the first --",
+			args:     []string{"--test=exec", "--", "sh", "-c", "--", "true"},
+			expected: []string{"--test=exec", "--run-id=1234", "--", "sh", "-c", "--", "true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{}, tc.args...)
+			if got := jobArgs(args, "--run-id=1234"); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+			if !reflect.DeepEqual(args, append([]string{}, tc.args...)) {
+				t.Errorf("expected the args to be left as is, got %v", args)
+			}
+		})
+	}
+}
+
+func TestPrintJobsSummary(t *testing.T) {
+	var out bytes.Buffer
+	printJobsSummary(&out, []JobResult{
+		{Name: "kind", Deployer: "kind", Passed: true, Duration: 61, Tests: 10, Skipped: 2},
+		{Name: "gce", Deployer: "gce", Passed: false, Duration: 3600, Tests: 5, Failures: 1, Flakes: 1},
+	})
+	expected := `JOB   DEPLOYER  RESULT  DURATION  TESTS  FAILED  FLAKED  SKIPPED
+kind  kind      PASSED  1m1s      10     0       0       2
+gce   gce       FAILED  1h0m0s    5      1       1       0
+
+1 of 2 jobs passed
+`
+	if out.String() != expected {
+		t.Errorf("expected summary:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestRunJobs(t *testing.T) {
+	// fake deployers, the passing one reporting test results
+	bin := t.TempDir()
+	deployers := map[string]string{
+		"pass": "#!/bin/sh\n" +
+			`echo '<testsuites><testsuite name="e2e" tests="2" failures="1"><testcase name="a"></testcase><testcase name="b"><failure>failed</failure></testcase></testsuite></testsuites>' > "$ARTIFACTS/merged-junit.xml"` + "\n" +
+			`echo "$@"` + "\n",
+		"fail": "#!/bin/sh\nexit 1\n",
+	}
+	for name, script := range deployers {
+		if err := os.WriteFile(filepath.Join(bin, BinaryName+"-"+name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("PROW_JOB_ID", "1234")
+
+	testCases := []struct {
+		name      string
+		jobs      string
+		expectErr bool
+		expected  []JobResult
+		// the expected output of the deployer of job a
+		expectedLog string
+	}{
+		{
+			name: "all passed",
+			jobs: "jobs:\n- name: a\n  deployer: pass\n  args: [--up, --, --focus]\n- name: b\n  deployer: pass\n",
+			expected: []JobResult{
+				{Name: "a", Deployer: "pass", RunID: "1234-a", Passed: true, Tests: 2, Failures: 1},
+				{Name: "b", Deployer: "pass", RunID: "1234-b", Passed: true, Tests: 2, Failures: 1},
+			},
+			expectedLog: "--up --run-id=1234-a -- --focus\n",
+		},
+		{
+			name:      "a job failed",
+			jobs:      "parallelism: 2\njobs:\n- name: a\n  deployer: pass\n- name: b\n  deployer: fail\n",
+			expectErr: true,
+			expected: []JobResult{
+				{Name: "a", Deployer: "pass", RunID: "1234-a", Passed: true, Tests: 2, Failures: 1},
+				{Name: "b", Deployer: "fail", RunID: "1234-b", Passed: false, Error: "exit status 1"},
+			},
+			expectedLog: "--run-id=1234-a\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			artifactsDir := t.TempDir()
+			t.Setenv("ARTIFACTS", artifactsDir)
+			path := filepath.Join(t.TempDir(), "jobs.yaml")
+			if err := os.WriteFile(path, []byte(tc.jobs), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+			if err := runJobs(cmd, []string{"--jobs=" + path}); (err != nil) != tc.expectErr {
+				t.Fatalf("expected error: %v, got %v", tc.expectErr, err)
+			}
+
+			raw, err := os.ReadFile(filepath.Join(artifactsDir, "jobs-summary.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var results []JobResult
+			if err := json.Unmarshal(raw, &results); err != nil {
+				t.Fatal(err)
+			}
+			for i := range results {
+				results[i].Duration = 0
+			}
+			if !reflect.DeepEqual(results, tc.expected) {
+				t.Errorf("expected results %+v, got %+v", tc.expected, results)
+			}
+			if _, err := os.Stat(filepath.Join(artifactsDir, "junit_jobs.xml")); err != nil {
+				t.Errorf("expected junit_jobs.xml: %v", err)
+			}
+			log, err := os.ReadFile(filepath.Join(artifactsDir, "a", "build-log.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(log) != tc.expectedLog {
+				t.Errorf("expected the deployer of job a to output %q, got %q", tc.expectedLog, log)
+			}
+		})
+	}
+}
@@ -47,6 +47,9 @@ It orchestrates creating clusters, building kubernetes, deleting clusters, runni
 
 kubetest2 should be called with a deployer like: 'kubetest2 kind --help'
 
+Several deployer and tester combinations can be run at once from a config file
+with: 'kubetest2 --jobs=jobs.yaml [--parallelism=N]'
+
 For more information see: https://github.com/kubernetes-sigs/kubetest2`
 
 // NewCommand returns a new cobra.Command for building the base image
@@ -93,6 +96,14 @@ func runE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if isJobsInvocation(args) {
+		err := runJobs(cmd, args)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+		}
+		return err
+	}
+
 	// otherwise find and execute the deployer with the remaining arguments
 	deployerName := args[0]
 	deployer, err := FindDeployer(deployerName)
@@ -123,6 +134,7 @@ func usage(cmd *cobra.Command) {
 	deployers := FindDeployers()
 	cmd.Println("Usage:")
 	cmd.Printf("  %s [deployer] [flags]\n", BinaryName)
+	cmd.Printf("  %s --jobs=<config> [--parallelism=N]\n", BinaryName)
 	cmd.Println()
 	cmd.Println("Detected Deployers:")
 	for deployer := range deployers {
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// saveDeployerState persists the state of d into the run dir, if supported,
+// so that the run can be torn down by another one with the same run id.
+func saveDeployerState(opts types.Options, d types.Deployer) {
+	dWithState, ok := d.(types.DeployerWithState)
+	if !ok {
+		return
+	}
+	state, err := dWithState.State()
+	if err == nil {
+		err = os.WriteFile(filepath.Join(opts.RunDir(), types.DeployerStateFile), state, 0644)
+	}
+	if err != nil {
+		klog.Warningf("failed to persist the deployer state, the run cannot be torn down by its run id: %v", err)
+	}
+}
+
+// restoreDeployerState restores the state of a previous run with the same run
+// id into d, if there is one.
+func restoreDeployerState(opts types.Options, d types.Deployer) error {
+	dWithState, ok := d.(types.DeployerWithState)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(opts.RunDir(), types.DeployerStateFile)
+	state, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	klog.Infof("Restoring the deployer state of run %q from %s", opts.RunID(), path)
+	if err := dWithState.RestoreState(state); err != nil {
+		return fmt.Errorf("failed to restore the deployer state from %s: %v", path, err)
+	}
+	return nil
+}
+
+// removeDeployerState removes the state persisted by saveDeployerState once
+// the run was torn down, so that its run dir may expire.
+func removeDeployerState(opts types.Options) {
+	path := filepath.Join(opts.RunDir(), types.DeployerStateFile)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("failed to remove the deployer state %s: %v", path, err)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// upgradeStage is a test pass of --upgrade-to, after moving the cluster to
+// version if set
+type upgradeStage struct {
+	// name is that of the subdirectory of the artifacts of the test pass
+	name    string
+	step    string
+	version string
+}
+
+// verifyUpgradeFlags fails early, before anything is built or brought up, if
+// --upgrade-to or --downgrade cannot be honored
+func verifyUpgradeFlags(opts types.Options, d types.Deployer) error {
+	if opts.ShouldDowngrade() && opts.UpgradeTo() == "" {
+		return fmt.Errorf("--downgrade requires --upgrade-to")
+	}
+	if opts.UpgradeTo() == "" {
+		return nil
+	}
+	if !opts.ShouldTest() {
+		return fmt.Errorf("--upgrade-to requires --test")
+	}
+	dWithUpgrades, ok := d.(types.DeployerWithUpgrades)
+	if !ok {
+		return fmt.Errorf("--upgrade-to is not supported by the deployer, which cannot upgrade the cluster in place")
+	}
+	if steps := dWithUpgrades.UpgradeSteps(); len(steps) > 0 {
+		return fmt.Errorf("--upgrade-to and the upgrade steps configured by the deployer flags, e.g. --upgrade-target-version, are mutually exclusive")
+	}
+	return nil
+}
+
+// upgradeStages returns the test passes of an upgrade from the version from
+// to the version to, and back if downgrade is true
+func upgradeStages(from, to string, downgrade bool) []upgradeStage {
+	stages := []upgradeStage{
+		{name: "before-upgrade"},
+		{name: "after-upgrade", step: "Upgrade to " + to, version: to},
+	}
+	if downgrade {
+		stages = append(stages, upgradeStage{name: "after-downgrade", step: "Downgrade to " + from, version: from})
+	}
+	return stages
+}
+
+// runUpgradeTests runs the tester against the cluster at its initial version,
+// after upgrading it to --upgrade-to and, with --downgrade, after downgrading
+// it back, stopping at the first failure. The results of each test pass are
+// written to a subdirectory of the artifacts named after the stage.
+func runUpgradeTests(opts types.Options, d types.Deployer, tester types.Tester, writer *metadata.Writer) error {
+	dWithUpgrade := d.(types.DeployerWithUpgrades)
+	from, err := dWithUpgrade.CurrentVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get the version of the cluster to upgrade: %v", err)
+	}
+	stages := upgradeStages(from, opts.UpgradeTo(), opts.ShouldDowngrade())
+
+	path := []string{from}
+	for _, stage := range stages[1:] {
+		path = append(path, stage.version)
+	}
+	if err := metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "upgrade-path", strings.Join(path, " -> ")); err != nil {
+		klog.Warningf("failed to record the upgrade path: %v", err)
+	}
+
+	for _, stage := range stages {
+		if stage.version != "" {
+			version := stage.version
+			if err := wrapStep(writer, stage.step, func() error { return dWithUpgrade.Upgrade(version) }); err != nil {
+				return err
+			}
+		}
+		stageArtifacts := filepath.Join(artifacts.BaseDir(), stage.name)
+		if err := os.MkdirAll(stageArtifacts, os.ModePerm); err != nil {
+			return err
+		}
+		if err := runTest(opts, d, tester, writer, "Test "+stage.name, stageArtifacts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
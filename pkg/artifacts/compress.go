@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+)
+
+// logsDirs are the directories under BaseDir the deployers dump the cluster logs into
+var logsDirs = []string{"cluster-logs", "logs"}
+
+var compressLogs bool
+
+// LogsDirsToCompress returns the cluster logs directories under BaseDir
+// that CompressLogs replaces, i.e. none unless --compress-cluster-logs is set
+func LogsDirsToCompress() []string {
+	if !compressLogs {
+		return nil
+	}
+	return logsDirs
+}
+
+// CompressLogs replaces the cluster logs directories under BaseDir with
+// gzipped tarballs if --compress-cluster-logs is set, as uploading many
+// small files is a lot slower than uploading a single large one.
+func CompressLogs() error {
+	if !compressLogs {
+		return nil
+	}
+	for _, dir := range logsDirs {
+		path := filepath.Join(BaseDir(), dir)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := CompressDir(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompressDir writes the contents of dir into dir.tar.gz and removes dir.
+func CompressDir(dir string) error {
+	tarball := dir + ".tar.gz"
+	files, size, err := writeTarball(dir, tarball+".tmp")
+	if err != nil {
+		os.Remove(tarball + ".tmp")
+		return fmt.Errorf("failed to compress %s: %v", dir, err)
+	}
+	if err := os.Rename(tarball+".tmp", tarball); err != nil {
+		return err
+	}
+	info, err := os.Stat(tarball)
+	if err != nil {
+		return err
+	}
+	klog.V(0).Infof("Compressed %d files (%s) in %s into %s (%s)",
+		files, formatSize(size), dir, filepath.Base(tarball), formatSize(info.Size()))
+	return os.RemoveAll(dir)
+}
+
+// writeTarball writes dir into a gzipped tarball at path, with the paths
+// relative to the parent of dir. It returns the number and total size of
+// the files written.
+func writeTarball(dir, path string) (int, int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+
+	var files int
+	var size int64
+	parent := filepath.Dir(dir)
+	err = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(parent, p)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		n, err := io.Copy(tw, src)
+		files++
+		size += n
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := tw.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := gzw.Close(); err != nil {
+		return 0, 0, err
+	}
+	return files, size, f.Close()
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
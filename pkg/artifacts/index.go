@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxIndexFiles is the number of files listed in the index at most
+const maxIndexFiles = 1000
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+.failed { color: #c00; }
+.passed { color: #080; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Suites}}
+<h2><a href="{{.File}}">{{.Name}}</a>: {{.Tests}} tests, <span class="{{if .Failures}}failed{{else}}passed{{end}}">{{.Failures}} failed</span></h2>
+<table>
+<tr><th>Name</th><th>Result</th><th>Time (s)</th></tr>
+{{range .Cases}}<tr><td>{{.Name}}</td><td class="{{.Result}}">{{.Result}}</td><td>{{printf "%.1f" .Time}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Files</h2>
+<ul>
+{{range .Files}}<li><a href="{{.}}">{{.}}</a></li>
+{{end}}</ul>
+{{if .Truncated}}<p>Only the first {{len .Files}} files are listed.</p>{{end}}
+</body>
+</html>
+`))
+
+type index struct {
+	Title     string
+	Suites    []indexSuite
+	Files     []string
+	Truncated bool
+}
+
+type indexSuite struct {
+	File     string
+	Name     string
+	Tests    int
+	Failures int
+	Cases    []indexCase
+}
+
+type indexCase struct {
+	Name   string
+	Result string
+	Time   float64
+}
+
+// WriteIndex writes an index.html into BaseDir, summarizing the JUnit
+// results of the run (e.g. the phases in junit_runner.xml) and linking to
+// the files, so that uploaded artifacts can be browsed.
+func WriteIndex(title string) error {
+	dir := BaseDir()
+	idx := index{Title: title}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "index.html" {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), "junit") && strings.HasSuffix(entry.Name(), ".xml") {
+			idx.Suites = append(idx.Suites, readJUnit(path, rel)...)
+		}
+		if len(idx.Files) == maxIndexFiles {
+			idx.Truncated = true
+			return nil
+		}
+		idx.Files = append(idx.Files, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// the phases of the run come first
+	sort.SliceStable(idx.Suites, func(i, j int) bool {
+		return idx.Suites[i].File == "junit_runner.xml" && idx.Suites[j].File != "junit_runner.xml"
+	})
+
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	if err := indexTemplate.Execute(f, idx); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readJUnit returns the summaries of the suites in the JUnit file at path.
+// Unparsable files are skipped.
+func readJUnit(path, rel string) []indexSuite {
+	root, err := readJUnitFile(path)
+	if err != nil {
+		return nil
+	}
+	var suites []indexSuite
+	var add func(s *junitSuite)
+	add = func(s *junitSuite) {
+		if len(s.Cases) > 0 {
+			suite := indexSuite{File: rel, Name: s.Name, Tests: len(s.Cases)}
+			for _, c := range s.Cases {
+				result := "passed"
+				switch {
+				case c.failed():
+					result = "failed"
+					suite.Failures++
+				case c.Skipped != nil:
+					result = "skipped"
+				}
+				suite.Cases = append(suite.Cases, indexCase{Name: c.Name, Result: result, Time: c.Time})
+			}
+			if suite.Name == "" {
+				suite.Name = rel
+			}
+			suites = append(suites, suite)
+		}
+		for i := range s.Suites {
+			add(&s.Suites[i])
+		}
+	}
+	add(root)
+	return suites
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteIndex(t *testing.T) {
+	baseDir = t.TempDir()
+	defer func() { baseDir = "" }()
+
+	runner := `<?xml version="1.0" encoding="UTF-8"?><testsuite name="kubetest2" failures="1" tests="2" time="3">
+    <testcase name="Up" classname="kubetest2" time="1"></testcase>
+    <testcase name="Test" classname="kubetest2" time="2">
+        <failure>exit status 1</failure>
+    </testcase>
+</testsuite>`
+	if err := os.WriteFile(filepath.Join(baseDir, "junit_runner.xml"), []byte(runner), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(baseDir, "cluster-logs"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "cluster-logs", "kubelet.log"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteIndex("kubetest2 run 1234"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(baseDir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := string(raw)
+	for _, expected := range []string{
+		"<title>kubetest2 run 1234</title>",
+		`<a href="junit_runner.xml">kubetest2</a>: 2 tests`,
+		`<td>Test</td><td class="failed">failed</td>`,
+		`<a href="cluster-logs/kubelet.log">`,
+	} {
+		if !strings.Contains(index, expected) {
+			t.Errorf("expected index to contain %q, got:\n%s", expected, index)
+		}
+	}
+}
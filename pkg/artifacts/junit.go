@@ -0,0 +1,328 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// junitSuite is a JUnit testsuite, which may be nested. It is also used to
+// read files with a testsuites root, whose suites end up in Suites.
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr,omitempty"`
+	Time       float64         `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+	Suites     []junitSuite    `xml:"testsuite"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func (c *junitCase) failed() bool {
+	return c.Failure != nil || c.Error != nil
+}
+
+func (c *junitCase) message() string {
+	m := c.Failure
+	if m == nil {
+		m = c.Error
+	}
+	if m == nil {
+		return ""
+	}
+	if m.Message != "" {
+		return m.Message
+	}
+	return strings.TrimSpace(m.Text)
+}
+
+// retriedAttemptRegex matches the failed attempts ginkgo v2 notes in the
+// output of a spec it retried, whereas ginkgo v1 reports each attempt as a
+// testcase of its own
+var retriedAttemptRegex = regexp.MustCompile(`Attempt #\d+ Failed`)
+
+// retriedAttempts returns the number of failed attempts noted in the
+// output of c
+func (c *junitCase) retriedAttempts() int {
+	return len(retriedAttemptRegex.FindAllString(c.SystemOut+c.SystemErr, -1))
+}
+
+// allCases returns the testcases of s and of the suites nested in it
+func (s *junitSuite) allCases() []junitCase {
+	cases := s.Cases
+	for i := range s.Suites {
+		cases = append(cases, s.Suites[i].allCases()...)
+	}
+	return cases
+}
+
+func readJUnitFile(path string) (*junitSuite, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suite junitSuite
+	if err := xml.Unmarshal(raw, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return &suite, nil
+}
+
+// JUnitSummary summarizes the testcases merged by MergeJUnit
+type JUnitSummary struct {
+	Tests    int
+	Failures int
+	Skipped  int
+	// Flakes are the testcases that failed before passing
+	Flakes int
+}
+
+// MergeJUnit merges the testcases of the JUnit files at paths into a single
+// testsuite written to out. The testcases with the same classname and name,
+// e.g. those of retried tests, are merged into one: a testcase that passed
+// in any attempt passed, with the failures of the other attempts noted in
+// its system-out as a flake, otherwise the last failure is kept. The flaked
+// and failed testcases are also listed as properties of the testsuite.
+func MergeJUnit(name string, paths []string, out io.Writer) (JUnitSummary, error) {
+	keys, attempts, err := groupAttempts(paths)
+	if err != nil {
+		return JUnitSummary{}, err
+	}
+
+	merged := junitSuite{Name: name}
+	var summary JUnitSummary
+	for _, key := range keys {
+		c, flake := mergeAttempts(attempts[key])
+		merged.Tests++
+		merged.Time += c.Time
+		switch {
+		case c.failed():
+			merged.Failures++
+		case c.Skipped != nil:
+			merged.Skipped++
+		}
+		if flake {
+			summary.Flakes++
+			merged.Properties = append(merged.Properties, junitProperty{Name: "flaked", Value: key})
+		} else if c.failed() {
+			merged.Properties = append(merged.Properties, junitProperty{Name: "failed", Value: key})
+		}
+		merged.Cases = append(merged.Cases, c)
+	}
+	summary.Tests, summary.Failures, summary.Skipped = merged.Tests, merged.Failures, merged.Skipped
+
+	_, _ = io.WriteString(out, xml.Header)
+	e := xml.NewEncoder(out)
+	e.Indent("", "    ")
+	if err := e.EncodeElement(merged, xml.StartElement{Name: xml.Name{Local: "testsuite"}}); err != nil {
+		return JUnitSummary{}, err
+	}
+	return summary, nil
+}
+
+// groupAttempts reads the testcases of the JUnit files at paths, grouping
+// those with the same classname and name, returned in the order of keys
+func groupAttempts(paths []string) ([]string, map[string][]junitCase, error) {
+	var keys []string
+	attempts := map[string][]junitCase{}
+	for _, path := range paths {
+		suite, err := readJUnitFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range suite.allCases() {
+			key := c.ClassName + "/" + c.Name
+			if _, ok := attempts[key]; !ok {
+				keys = append(keys, key)
+			}
+			attempts[key] = append(attempts[key], c)
+		}
+	}
+	return keys, attempts, nil
+}
+
+// mergeAttempts merges the attempts of a testcase, returning whether it is
+// a flake, i.e. failed before passing.
+func mergeAttempts(attempts []junitCase) (junitCase, bool) {
+	var passed, failed, skipped []junitCase
+	for _, c := range attempts {
+		switch {
+		case c.failed():
+			failed = append(failed, c)
+		case c.Skipped != nil:
+			skipped = append(skipped, c)
+		default:
+			passed = append(passed, c)
+		}
+	}
+	switch {
+	case len(passed) > 0 && len(failed) > 0:
+		c := passed[len(passed)-1]
+		var notes []string
+		for _, f := range failed {
+			notes = append(notes, f.message())
+		}
+		c.SystemOut = fmt.Sprintf("flake: failed %d of %d attempts:\n%s\n%s",
+			len(failed), len(attempts), strings.Join(notes, "\n"), c.SystemOut)
+		return c, true
+	case len(passed) > 0:
+		c := passed[len(passed)-1]
+		if retried := c.retriedAttempts(); retried > 0 {
+			c.SystemOut = fmt.Sprintf("flake: failed %d of %d attempts\n%s", retried, retried+1, c.SystemOut)
+			return c, true
+		}
+		return c, false
+	case len(failed) > 0:
+		c := failed[len(failed)-1]
+		if len(failed) > 1 {
+			c.SystemOut = fmt.Sprintf("failed all %d attempts\n%s", len(failed), c.SystemOut)
+		}
+		return c, false
+	default:
+		return skipped[len(skipped)-1], false
+	}
+}
+
+// FlakeReport tells apart the testcases that failed in every attempt from
+// those that passed when retried
+type FlakeReport struct {
+	Failed []ReportedCase `json:"failed"`
+	Flaked []ReportedCase `json:"flaked"`
+}
+
+// ReportedCase is a testcase of a FlakeReport
+type ReportedCase struct {
+	Name      string `json:"name"`
+	ClassName string `json:"classname"`
+	Attempts  int    `json:"attempts"`
+	// Failures are the messages of the failed attempts, when reported
+	Failures []string `json:"failures,omitempty"`
+}
+
+// ReadFlakeReport reads the FlakeReport of the JUnit files at paths, whose
+// testcases are merged as by MergeJUnit
+func ReadFlakeReport(paths []string) (*FlakeReport, error) {
+	keys, attempts, err := groupAttempts(paths)
+	if err != nil {
+		return nil, err
+	}
+	report := &FlakeReport{
+		Failed: []ReportedCase{},
+		Flaked: []ReportedCase{},
+	}
+	for _, key := range keys {
+		c, flake := mergeAttempts(attempts[key])
+		if !flake && !c.failed() {
+			continue
+		}
+		reported := ReportedCase{Name: c.Name, ClassName: c.ClassName}
+		for _, a := range attempts[key] {
+			reported.Attempts += 1 + a.retriedAttempts()
+			if a.failed() {
+				reported.Failures = append(reported.Failures, a.message())
+			}
+		}
+		if flake {
+			report.Flaked = append(report.Flaked, reported)
+		} else {
+			report.Failed = append(report.Failed, reported)
+		}
+	}
+	return report, nil
+}
+
+// JUnitDurations returns the durations in seconds of the testcases of the
+// JUnit files at paths by name, skipped testcases excepted. The longest
+// attempt of retried testcases is kept.
+func JUnitDurations(paths []string) (map[string]float64, error) {
+	durations := map[string]float64{}
+	for _, path := range paths {
+		suite, err := readJUnitFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range suite.allCases() {
+			if c.Skipped != nil {
+				continue
+			}
+			if d, ok := durations[c.Name]; !ok || c.Time > d {
+				durations[c.Name] = c.Time
+			}
+		}
+	}
+	return durations, nil
+}
+
+// JUnitFailure is a testcase which failed in every attempt
+type JUnitFailure struct {
+	Name    string
+	Message string
+	// Output is the tail of the output of the last failed attempt
+	Output string
+}
+
+// JUnitFailures returns the testcases of the JUnit files at paths which
+// failed, merged as by MergeJUnit, with up to outputLines of their output
+func JUnitFailures(paths []string, outputLines int) ([]JUnitFailure, error) {
+	keys, attempts, err := groupAttempts(paths)
+	if err != nil {
+		return nil, err
+	}
+	var failures []JUnitFailure
+	for _, key := range keys {
+		c, _ := mergeAttempts(attempts[key])
+		if !c.failed() {
+			continue
+		}
+		output := strings.TrimSpace(c.SystemErr)
+		if output == "" {
+			output = strings.TrimSpace(c.SystemOut)
+		}
+		if lines := strings.Split(output, "\n"); len(lines) > outputLines {
+			output = strings.Join(lines[len(lines)-outputLines:], "\n")
+		}
+		failures = append(failures, JUnitFailure{Name: c.Name, Message: c.message(), Output: output})
+	}
+	return failures, nil
+}
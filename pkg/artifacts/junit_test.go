@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeJUnit(t *testing.T) {
+	dir := t.TempDir()
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"junit_01.xml", `<testsuite name="e2e">
+    <testcase name="passes" classname="e2e" time="1"></testcase>
+    <testcase name="flakes" classname="e2e" time="1"><failure message="timed out"></failure></testcase>
+    <testcase name="fails" classname="e2e" time="1"><failure message="boom"></failure></testcase>
+</testsuite>`},
+		{"junit_02.xml", `<testsuites><testsuite name="e2e">
+    <testcase name="flakes" classname="e2e" time="2"></testcase>
+    <testcase name="fails" classname="e2e" time="1"><failure message="boom again"></failure></testcase>
+    <testcase name="skipped" classname="e2e" time="0"><skipped></skipped></testcase>
+</testsuite></testsuites>`},
+	}
+	var paths []string
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	var out bytes.Buffer
+	summary, err := MergeJUnit("merged", paths, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := JUnitSummary{Tests: 4, Failures: 1, Skipped: 1, Flakes: 1}
+	if summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+	for _, s := range []string{
+		`<testsuite name="merged" tests="4" failures="1" skipped="1"`,
+		"flake: failed 1 of 2 attempts:",
+		`<failure message="boom again">`,
+		"failed all 2 attempts",
+		`<property name="flaked" value="e2e/flakes"></property>`,
+		`<property name="failed" value="e2e/fails"></property>`,
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected merged output to contain %q, got:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestReadFlakeReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit_01.xml")
+	content := `<testsuites><testsuite name="e2e">
+    <testcase name="passes" classname="e2e" time="1"></testcase>
+    <testcase name="flakes" classname="e2e" time="2"><system-err>Attempt #1 Failed.  Retrying</system-err></testcase>
+    <testcase name="fails" classname="e2e" time="1"><failure message="boom"></failure></testcase>
+</testsuite></testsuites>`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ReadFlakeReport([]string{path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &FlakeReport{
+		Failed: []ReportedCase{{Name: "fails", ClassName: "e2e", Attempts: 1, Failures: []string{"boom"}}},
+		Flaked: []ReportedCase{{Name: "flakes", ClassName: "e2e", Attempts: 2}},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected report %+v, got %+v", expected, report)
+	}
+}
+
+func TestJUnitDurations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit_01.xml")
+	content := `<testsuite name="e2e">
+    <testcase name="slow" classname="e2e" time="30"></testcase>
+    <testcase name="fast" classname="e2e" time="1.5"><failure message="boom"></failure></testcase>
+    <testcase name="fast" classname="e2e" time="2"></testcase>
+    <testcase name="skipped" classname="e2e" time="0"><skipped></skipped></testcase>
+</testsuite>`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	durations, err := JUnitDurations([]string{path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]float64{"slow": 30, "fast": 2}
+	if !reflect.DeepEqual(durations, expected) {
+		t.Errorf("expected durations %v, got %v", expected, durations)
+	}
+}
+
+func TestJUnitFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit_01.xml")
+	content := `<testsuite name="e2e">
+    <testcase name="passes" classname="e2e" time="1"></testcase>
+    <testcase name="fails" classname="e2e" time="1"><failure message="boom"></failure><system-err>one
+two
+three</system-err></testcase>
+</testsuite>`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	failures, err := JUnitFailures([]string{path}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []JUnitFailure{{Name: "fails", Message: "boom", Output: "two\nthree"}}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("expected failures %+v, got %+v", expected, failures)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+)
+
+// LatestName is the name of the pointer to the most recent run dir, next to it
+const LatestName = "latest"
+
+// UpdateLatest atomically points the latest symlink next to runDir at it,
+// so that the most recent run can be found without knowing its run id.
+// A file holding the path of runDir is written instead where symlinks are
+// not supported.
+func UpdateLatest(runDir string) error {
+	parent := filepath.Dir(runDir)
+	latest := filepath.Join(parent, LatestName)
+	tmp := fmt.Sprintf("%s.%d", latest, os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(runDir), tmp); err != nil {
+		klog.V(1).Infof("failed to symlink %s, writing its path instead: %v", latest, err)
+		if err := os.WriteFile(tmp, []byte(runDir+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, latest); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to update %s: %v", latest, err)
+	}
+	return nil
+}
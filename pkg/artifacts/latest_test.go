@@ -0,0 +1,46 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateLatest(t *testing.T) {
+	dir := t.TempDir()
+	for _, runID := range []string{"run-1", "run-2"} {
+		runDir := filepath.Join(dir, runID)
+		if err := os.MkdirAll(runDir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(runDir, "kubeconfig"), []byte(runID), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateLatest(runDir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		kubeconfig, err := os.ReadFile(filepath.Join(dir, LatestName, "kubeconfig"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(kubeconfig) != runID {
+			t.Errorf("expected latest to point at %s, got %s", runID, kubeconfig)
+		}
+	}
+}
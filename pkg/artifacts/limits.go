@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/klog/v2"
+)
+
+// minTruncatedSize is the size files are never truncated below to meet the
+// total size limit
+const minTruncatedSize = 64 << 10
+
+var maxFileSizeMB, maxTotalSizeMB int64
+
+// truncatable are the extensions of the text logs that are truncated, other
+// files, e.g. JUnit results, profiles or archives, would not be readable
+// anymore
+var truncatable = map[string]bool{".log": true, ".txt": true}
+
+// untruncatable are the text logs that are never truncated, as they are
+// read by other tools in full
+var untruncatable = map[string]bool{"build-log.txt": true}
+
+// EnforceSizeLimits truncates the text logs under BaseDir to the limits set
+// by --artifacts-max-file-size-mb and --artifacts-max-total-size-mb, keeping
+// the head and tail of each truncated file. It must only be called once the
+// logs are finished, as the files are replaced.
+func EnforceSizeLimits() error {
+	return enforceSizeLimits(BaseDir(), maxFileSizeMB<<20, maxTotalSizeMB<<20)
+}
+
+type sizedFile struct {
+	path string
+	size int64
+}
+
+func enforceSizeLimits(dir string, maxFileSize, maxTotalSize int64) error {
+	var files []sizedFile
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		if truncatable[filepath.Ext(path)] && !untruncatable[entry.Name()] {
+			files = append(files, sizedFile{path: path, size: info.Size()})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the sizes the files are truncated to, so that each is truncated once
+	targets := make([]int64, len(files))
+	for i, f := range files {
+		targets[i] = f.size
+		if maxFileSize > 0 && f.size > maxFileSize {
+			targets[i] = maxFileSize
+			total -= f.size - maxFileSize
+		}
+	}
+	if maxTotalSize > 0 && total > maxTotalSize {
+		// cut the largest files first
+		order := make([]int, len(files))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return targets[order[i]] > targets[order[j]] })
+		excess := total - maxTotalSize
+		for _, i := range order {
+			if excess <= 0 || targets[i] <= minTruncatedSize {
+				break
+			}
+			cut := targets[i] - minTruncatedSize
+			if cut > excess {
+				cut = excess
+			}
+			targets[i] -= cut
+			excess -= cut
+		}
+		if excess > 0 {
+			klog.Warningf("artifacts in %s exceed the total size limit by %s", dir, formatSize(excess))
+		}
+	}
+
+	for i, f := range files {
+		if targets[i] < f.size {
+			if err := truncateFile(f.path, f.size, targets[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// truncateFile truncates the file at path of the given size to about max
+// bytes, keeping its head and tail with a marker in between.
+func truncateFile(path string, size, max int64) error {
+	klog.Warningf("Truncating %s from %s to %s", path, formatSize(size), formatSize(max))
+	marker := fmt.Sprintf("\n\n... truncated %d bytes by kubetest2 ...\n\n", size-max)
+	head := max / 2
+	tail := max - head
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".truncated"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = func() error {
+		if _, err := io.CopyN(dst, src, head); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(dst, marker); err != nil {
+			return err
+		}
+		if _, err := src.Seek(size-tail, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(dst, src, tail); err != nil {
+			return err
+		}
+		return dst.Close()
+	}()
+	if err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to truncate %s: %v", path, err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnforceSizeLimits(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		content := "head" + strings.Repeat("x", size-8) + "tail"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	huge := write("crashloop.log", 4<<20)
+	large := write("kubelet.log", 512<<10)
+	small := write("small.log", 100)
+	junit := write("junit_runner.xml", 2<<20)
+
+	if err := enforceSizeLimits(dir, 1<<20, 2<<20+1<<20); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	size := func(path string) int64 {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	content, err := os.ReadFile(huge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "head") || !strings.HasSuffix(string(content), "tail") ||
+		!strings.Contains(string(content), "truncated") {
+		t.Errorf("expected the head and tail to be kept with a marker")
+	}
+	if size(junit) != 2<<20 {
+		t.Errorf("expected %s not to be truncated", junit)
+	}
+	if size(small) != 100 {
+		t.Errorf("expected %s not to be truncated", small)
+	}
+	// the per-file limit leaves 1MiB+512KiB+100B of logs, cut from the largest
+	if total := size(huge) + size(large) + size(small) + size(junit); total > 3<<20+1<<10 {
+		t.Errorf("expected the total size to be about 3MiB, got %d", total)
+	}
+}
+
+func TestEnforceSizeLimitsOnlyTruncatesLogs(t *testing.T) {
+	dir := t.TempDir()
+	content := "head" + strings.Repeat("x", 4<<10) + "tail"
+	for _, name := range []string{"test.log", "build-log.txt", "commands.jsonl", "cpu.pprof"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enforceSizeLimits(dir, 1<<10, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, truncated := range map[string]bool{"test.log": true, "build-log.txt": false, "commands.jsonl": false, "cpu.pprof": false} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (info.Size() < int64(len(content))) != truncated {
+			t.Errorf("expected %s to be truncated: %v, got size %d", name, truncated, info.Size())
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"k8s.io/klog/v2"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 var baseDir string
@@ -99,7 +100,12 @@ func BindFlags(flags *pflag.FlagSet) error {
 		return err
 	}
 	flags.StringVar(&baseDir, "artifacts", defaultArtifacts, `top-level directory to put artifacts under for each kubetest2 run, defaulting to "${ARTIFACTS:-./_artifacts}". If using the ginkgo tester, this must be an absolute path.`)
+	flags.BoolVar(&compressLogs, "compress-cluster-logs", os.Getenv("CI") == "true", `if true, the cluster logs dumped into the artifacts are compressed into a tarball at the end of the run, defaulting to true in CI ($CI=true).`)
+	flags.Int64Var(&maxFileSizeMB, "artifacts-max-file-size-mb", 1024, `files in the artifacts larger than this are truncated at the end of the run, keeping their head and tail. 0 means no limit.`)
+	flags.Int64Var(&maxTotalSizeMB, "artifacts-max-total-size-mb", 0, `if the artifacts are larger than this in total at the end of the run, the largest files are truncated, keeping their head and tail. 0 means no limit.`)
+	flags.StringVar(&sinkLocation, "artifacts-sink", "", `if set, the artifacts are also written to this gs:// or s3:// URL, http(s):// URL to PUT them under or directory after each step of the run. The tester gets it in $KUBETEST2_ARTIFACTS_SINK.`)
 	flags.StringVar(&RunDirFlag, "rundir", "", `directory to put run related test binaries like e2e.test, ginkgo, kubectl for each kubetest2 run, defaulting to "${KUBETEST2_RUN_DIR:-./_rundir}". If using the ginkgo tester, this must be an absolute path.`)
+	flags.DurationVar(&retention, "rundir-retention", 7*24*time.Hour, `how long the run dir is kept, after which it may be removed by artifacts.Expire, e.g. by janitors.`)
 	return nil
 }
 
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// started and finished follow started.json and finished.json of the prow
+// pod utilities, see https://docs.prow.k8s.io/docs/metadata-artifacts/
+type started struct {
+	Timestamp int64 `json:"timestamp"`
+}
+
+type finished struct {
+	Timestamp int64  `json:"timestamp"`
+	Passed    bool   `json:"passed"`
+	Result    string `json:"result"`
+}
+
+// StartProwMetadata writes started.json into BaseDir and tees the logs of
+// kubetest2 into build-log.txt, as the prow pod utilities do, so that the
+// artifacts render in Spyglass when uploaded as the root of a job. The
+// output of the commands run is captured by process.CaptureOutput instead.
+// finish writes the error the run failed with, if any, to build-log.txt and
+// finished.json with the result of the run, and stops the tee. It may be
+// called more than once, e.g. on an interrupt, only the first call counts.
+// Nothing is done when running in a prow job, where the pod utilities
+// take care of it.
+func StartProwMetadata() (finish func(result error) error, err error) {
+	if _, ok := os.LookupEnv("PROW_JOB_ID"); ok {
+		return func(error) error { return nil }, nil
+	}
+	if err := writeJSON("started.json", started{Timestamp: time.Now().Unix()}); err != nil {
+		return nil, err
+	}
+	log, stopTee, err := teeLogs(filepath.Join(BaseDir(), "build-log.txt"))
+	if err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	var finishErr error
+	return func(result error) error {
+		once.Do(func() {
+			if result != nil {
+				fmt.Fprintf(log, "Error: %v\n", result)
+			}
+			stopErr := stopTee()
+			passed, outcome := result == nil, "FAILURE"
+			if passed {
+				outcome = "SUCCESS"
+			}
+			if err := writeJSON("finished.json", finished{Timestamp: time.Now().Unix(), Passed: passed, Result: outcome}); err != nil {
+				finishErr = err
+				return
+			}
+			finishErr = stopErr
+		})
+		return finishErr
+	}, nil
+}
+
+func writeJSON(name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(BaseDir(), name), b, 0644)
+}
+
+// teeLogs tees the klog output of the process into the file at path, which
+// is returned to add to it, while still logging to stderr. Unlike replacing
+// os.Stdout and os.Stderr, this leaves the file descriptors inherited by the
+// commands run alone. If klog was explicitly set up to log to files, the
+// logs are left alone. stop restores the klog settings and closes the file.
+func teeLogs(path string) (log io.Writer, stop func() error, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the logs and the final error are written concurrently
+	locked := &lockedWriter{w: f}
+
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	if flags.Lookup("logtostderr").Value.String() != "true" {
+		return locked, f.Close, nil
+	}
+	alsoToStderr := flags.Lookup("alsologtostderr").Value.String()
+	oneOutput := flags.Lookup("one_output").Value.String()
+	set := func(name, value string) {
+		_ = flags.Set(name, value)
+	}
+	// log each line once to the file and to stderr
+	klog.SetOutput(locked)
+	set("one_output", "true")
+	set("alsologtostderr", "true")
+	set("logtostderr", "false")
+	return locked, func() error {
+		klog.Flush()
+		set("logtostderr", "true")
+		set("alsologtostderr", alsoToStderr)
+		set("one_output", oneOutput)
+		return f.Close()
+	}, nil
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestStartProwMetadata(t *testing.T) {
+	if jobID, ok := os.LookupEnv("PROW_JOB_ID"); ok {
+		os.Unsetenv("PROW_JOB_ID")
+		defer os.Setenv("PROW_JOB_ID", jobID)
+	}
+	baseDir = t.TempDir()
+	defer func() { baseDir = "" }()
+
+	finish, err := StartProwMetadata()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	klog.Info("hello from the run")
+	if err := finish(fmt.Errorf("boom")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// later calls, e.g. on an interrupt, do not overwrite the result
+	if err := finish(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	klog.Info("after the run")
+
+	if _, err := os.Stat(filepath.Join(baseDir, "started.json")); err != nil {
+		t.Errorf("expected started.json: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(baseDir, "finished.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f finished
+	if err := json.Unmarshal(raw, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Passed || f.Result != "FAILURE" || f.Timestamp == 0 {
+		t.Errorf("unexpected finished.json %s", raw)
+	}
+	log, err := os.ReadFile(filepath.Join(baseDir, "build-log.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "hello from the run") || lines[1] != "Error: boom" {
+		t.Errorf("unexpected build-log.txt %q", log)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// RetentionFile is the file in each run dir holding its Retention
+const RetentionFile = "retention.json"
+
+var retention time.Duration
+
+// Retention is the metadata stamped in a run dir to tell whom it belongs to
+// and when it may be removed, see Expire
+type Retention struct {
+	RunID string `json:"runID"`
+	Owner string `json:"owner"`
+	// Job is the CI job of the run, if any
+	Job     string    `json:"job,omitempty"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// StampRetention writes the Retention of the run into runDir, expiring
+// after --rundir-retention.
+func StampRetention(runDir, runID string) error {
+	now := time.Now()
+	r := Retention{
+		RunID:   runID,
+		Owner:   owner(),
+		Job:     os.Getenv("JOB_NAME"),
+		Created: now,
+		Expires: now.Add(retention),
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(runDir, RetentionFile), b, 0644)
+}
+
+func owner() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// ReadRetention reads the Retention stamped in runDir
+func ReadRetention(runDir string) (*Retention, error) {
+	b, err := os.ReadFile(filepath.Join(runDir, RetentionFile))
+	if err != nil {
+		return nil, err
+	}
+	var r Retention
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Expire removes the run dirs in dir that expired by now, returning their
+// paths. Run dirs without retention metadata are left alone, and so are the
+// ones holding the deployer state of a run that was not torn down, which is
+// needed to tear it down by its run id.
+func Expire(dir string, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var expired []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		runDir := filepath.Join(dir, entry.Name())
+		r, err := ReadRetention(runDir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				klog.Warningf("skipping %s with unreadable retention: %v", runDir, err)
+			}
+			continue
+		}
+		if now.Before(r.Expires) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(runDir, types.DeployerStateFile)); err == nil {
+			klog.Warningf("keeping expired run dir %s of %s, which holds the deployer state of a run that was not torn down", runDir, r.Owner)
+			continue
+		}
+		klog.V(1).Infof("removing run dir %s of %s, expired at %v", runDir, r.Owner, r.Expires)
+		if err := os.RemoveAll(runDir); err != nil {
+			return expired, err
+		}
+		expired = append(expired, runDir)
+	}
+	return expired, nil
+}
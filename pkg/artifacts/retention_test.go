@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+func TestExpire(t *testing.T) {
+	dir := t.TempDir()
+	retention = time.Hour
+	defer func() { retention = 0 }()
+	for _, runID := range []string{"old", "leaked", "new", "unstamped"} {
+		if err := os.MkdirAll(filepath.Join(dir, runID), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, runID := range []string{"old", "leaked"} {
+		if err := StampRetention(filepath.Join(dir, runID), runID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the leaked run was not torn down
+	if err := os.WriteFile(filepath.Join(dir, "leaked", types.DeployerStateFile), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	retention = 2 * time.Hour
+	if err := StampRetention(filepath.Join(dir, "new"), "new"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReadRetention(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RunID != "new" || r.Owner == "" {
+		t.Errorf("unexpected retention %+v", r)
+	}
+
+	expired, err := Expire(dir, time.Now().Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{filepath.Join(dir, "old")}; !reflect.DeepEqual(expired, expected) {
+		t.Errorf("expected %v to expire, got %v", expected, expired)
+	}
+	for _, runID := range []string{"leaked", "new", "unstamped"} {
+		if _, err := os.Stat(filepath.Join(dir, runID)); err != nil {
+			t.Errorf("expected %s to be kept: %v", runID, err)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// SinkEnv is set to --artifacts-sink for the tester, so that it can write
+// through the same sink
+const SinkEnv = "KUBETEST2_ARTIFACTS_SINK"
+
+var sinkLocation string
+
+// Sink is where artifacts are written to, so that they can be uploaded as
+// they are produced during the run rather than all at once at the end.
+type Sink interface {
+	// Put writes the contents of r as the artifact at the slash separated
+	// path relative to the root of the sink
+	Put(path string, r io.Reader) error
+}
+
+// DirSyncer is implemented by sinks that can put all the new and modified
+// files of a directory at once, more efficiently than one Put per file.
+type DirSyncer interface {
+	// SyncDir puts the files of dir that are new or modified in the sink,
+	// except those under the exclude subdirectories of dir
+	SyncDir(dir string, exclude []string) error
+}
+
+// NewSink returns the sink at location, which is a gs:// or s3:// URL,
+// a http(s):// URL artifacts are PUT under, or a local directory.
+func NewSink(location string) Sink {
+	location = strings.TrimSuffix(location, "/")
+	switch {
+	case strings.HasPrefix(location, "gs://"):
+		return &GCSSink{Location: location}
+	case strings.HasPrefix(location, "s3://"):
+		return &S3Sink{Location: location}
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		return &HTTPSink{URL: location}
+	default:
+		return &DirSink{Dir: location}
+	}
+}
+
+// ConfiguredSink returns the sink set by --artifacts-sink, or nil if unset
+func ConfiguredSink() Sink {
+	if sinkLocation == "" {
+		return nil
+	}
+	return NewSink(sinkLocation)
+}
+
+// SinkLocation returns --artifacts-sink
+func SinkLocation() string {
+	return sinkLocation
+}
+
+// DirSink writes artifacts into a local directory
+type DirSink struct {
+	Dir string
+}
+
+func (s *DirSink) Put(p string, r io.Reader) error {
+	dest := filepath.Join(s.Dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %v", dest, err)
+	}
+	return f.Close()
+}
+
+// GCSSink uploads artifacts under a gs://<bucket>/<prefix> location with gcloud
+type GCSSink struct {
+	Location string
+}
+
+func (s *GCSSink) Put(p string, r io.Reader) error {
+	return putWithCLI(r, s.Location+"/"+p, "gcloud", "storage", "cp", "-", s.Location+"/"+p)
+}
+
+func (s *GCSSink) SyncDir(dir string, exclude []string) error {
+	args := []string{"storage", "rsync", "--recursive", dir, s.Location}
+	if len(exclude) > 0 {
+		// a regular expression matched against the relative paths
+		quoted := make([]string, len(exclude))
+		for i, e := range exclude {
+			quoted[i] = regexp.QuoteMeta(filepath.ToSlash(e))
+		}
+		args = append(args, "--exclude", "^("+strings.Join(quoted, "|")+")/")
+	}
+	return syncWithCLI(dir, s.Location, "gcloud", args...)
+}
+
+// S3Sink uploads artifacts under a s3://<bucket>/<prefix> location with the aws CLI
+type S3Sink struct {
+	Location string
+	// Endpoint overrides the S3 endpoint, e.g. for MinIO
+	Endpoint string
+}
+
+func (s *S3Sink) Put(p string, r io.Reader) error {
+	args := []string{"s3", "cp", "-", s.Location + "/" + p}
+	if s.Endpoint != "" {
+		args = append(args, "--endpoint-url", s.Endpoint)
+	}
+	return putWithCLI(r, s.Location+"/"+p, "aws", args...)
+}
+
+func (s *S3Sink) SyncDir(dir string, exclude []string) error {
+	args := []string{"s3", "sync", dir, s.Location}
+	for _, e := range exclude {
+		args = append(args, "--exclude", filepath.ToSlash(e)+"/*")
+	}
+	if s.Endpoint != "" {
+		args = append(args, "--endpoint-url", s.Endpoint)
+	}
+	return syncWithCLI(dir, s.Location, "aws", args...)
+}
+
+// the sink commands are unrecorded, so that the periodic syncs are not
+// captured and recorded as commands of the step, which would add artifacts
+// of their own for the next sync to put
+
+func putWithCLI(r io.Reader, dest, name string, args ...string) error {
+	klog.V(2).Infof("uploading %s ...", dest)
+	cmd := exec.Command(name, args...).SetRecorded(false)
+	cmd.SetStdin(r)
+	if out, err := exec.CombinedOutputLines(cmd); err != nil {
+		return fmt.Errorf("failed to upload %s: %v, output: %q", dest, err, out)
+	}
+	return nil
+}
+
+func syncWithCLI(dir, dest, name string, args ...string) error {
+	klog.V(2).Infof("syncing %s to %s ...", dir, dest)
+	cmd := exec.Command(name, args...).SetRecorded(false)
+	if out, err := exec.CombinedOutputLines(cmd); err != nil {
+		return fmt.Errorf("failed to sync %s to %s: %v, output: %q", dir, dest, err, out)
+	}
+	return nil
+}
+
+// HTTPSink uploads artifacts with a PUT request to <URL>/<path>
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *HTTPSink) Put(p string, r io.Reader) error {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := s.URL + "/" + path.Clean(p)
+	req, err := http.NewRequest(http.MethodPut, url, r)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to upload %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// Syncer incrementally puts the files of a directory to a sink, e.g. after
+// each step of the run
+type Syncer struct {
+	Sink Sink
+	Dir  string
+	// Exclude are the subdirectories of Dir that are not put
+	Exclude []string
+	// mu serializes the syncs of SyncEvery with the others
+	mu sync.Mutex
+	// synced holds the modification time of the files already put
+	synced map[string]time.Time
+}
+
+// SyncEvery syncs every interval until stop is called, so that the artifacts
+// of long steps, e.g. the logs of the tests, are put while they are written.
+func (s *Syncer) SyncEvery(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Sync(); err != nil {
+					klog.Warningf("failed to put the artifacts to the sink: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// Sync puts the files of Dir that are new or modified since the last Sync.
+func (s *Syncer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dirSyncer, ok := s.Sink.(DirSyncer); ok {
+		return dirSyncer.SyncDir(s.Dir, s.Exclude)
+	}
+	if s.synced == nil {
+		s.synced = map[string]time.Time{}
+	}
+	var failed []string
+	excluded := map[string]bool{}
+	for _, e := range s.Exclude {
+		excluded[filepath.ToSlash(e)] = true
+	}
+	err := filepath.WalkDir(s.Dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() && excluded[rel] {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if synced, ok := s.synced[rel]; ok && !info.ModTime().After(synced) {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := s.Sink.Put(rel, f); err != nil {
+			failed = append(failed, err.Error())
+			return nil
+		}
+		s.synced[rel] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to sync %d artifacts: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewSink(t *testing.T) {
+	cases := []struct {
+		location string
+		expected Sink
+	}{
+		{"gs://bucket/logs/", &GCSSink{Location: "gs://bucket/logs"}},
+		{"s3://bucket/logs", &S3Sink{Location: "s3://bucket/logs"}},
+		{"https://example.com/upload", &HTTPSink{URL: "https://example.com/upload"}},
+		{"/tmp/artifacts", &DirSink{Dir: "/tmp/artifacts"}},
+	}
+	for _, tc := range cases {
+		if sink := NewSink(tc.location); !reflect.DeepEqual(sink, tc.expected) {
+			t.Errorf("expected %#v for %s, got %#v", tc.expected, tc.location, sink)
+		}
+	}
+}
+
+func TestSyncer(t *testing.T) {
+	var mu sync.Mutex
+	uploads := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		uploads[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	write := func(name, content string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("junit_runner.xml", "build", start)
+	write("logs/up.log", "up", start)
+
+	syncer := &Syncer{Sink: NewSink(server.URL + "/run"), Dir: dir}
+	if err := syncer.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"/run/junit_runner.xml": "build", "/run/logs/up.log": "up"}
+	if !reflect.DeepEqual(uploads, expected) {
+		t.Errorf("expected uploads %v, got %v", expected, uploads)
+	}
+
+	// only the new and modified files are put again
+	uploads = map[string]string{}
+	write("junit_runner.xml", "build, up", start.Add(time.Minute))
+	write("logs/down.log", "down", start.Add(time.Minute))
+	if err := syncer.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = map[string]string{"/run/junit_runner.xml": "build, up", "/run/logs/down.log": "down"}
+	if !reflect.DeepEqual(uploads, expected) {
+		t.Errorf("expected uploads %v, got %v", expected, uploads)
+	}
+}
+
+func TestSyncEvery(t *testing.T) {
+	dir, dest := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "build-log.txt"), []byte("testing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	syncer := &Syncer{Sink: NewSink(dest), Dir: dir}
+	stop := syncer.SyncEvery(10 * time.Millisecond)
+	defer stop()
+
+	// the log is put while the step is still running
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if content, err := os.ReadFile(filepath.Join(dest, "build-log.txt")); err == nil && string(content) == "testing" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the artifacts to be put periodically")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyncerExclude(t *testing.T) {
+	dir, dest := t.TempDir(), t.TempDir()
+	for _, name := range []string{"build-log.txt", "cluster-logs/node/kubelet.log"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("testing"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	syncer := &Syncer{Sink: NewSink(dest), Dir: dir, Exclude: []string{"cluster-logs"}}
+	if err := syncer.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "build-log.txt")); err != nil {
+		t.Errorf("expected build-log.txt to be put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "cluster-logs")); !os.IsNotExist(err) {
+		t.Errorf("expected cluster-logs to be excluded, got %v", err)
+	}
+}
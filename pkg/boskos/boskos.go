@@ -18,6 +18,7 @@ package boskos
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -47,58 +48,69 @@ func NewClient(boskosLocation string) (*client.Client, error) {
 
 // Acquire acquires a resource for the given type and starts a heartbeat goroutine to keep the resource reserved.
 func Acquire(boskosClient *client.Client, resourceType string, timeout, heartbeatInterval time.Duration, heartbeatClose chan struct{}) (*common.Resource, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	boskosResource, err := boskosClient.AcquireWait(ctx, resourceType, "free", "busy")
+	resources, err := AcquireN(boskosClient, resourceType, 1, timeout, heartbeatInterval, heartbeatClose)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get a %q from boskos: %s", resourceType, err)
+		return nil, err
 	}
-	if boskosResource == nil {
-		return nil, fmt.Errorf("boskos had no %s available", resourceType)
+	return resources[0], nil
+}
+
+// AcquireN acquires n resources for the given type, waiting up to timeout for each of them, and starts a
+// heartbeat goroutine to keep all of them reserved. If not all of them can be acquired, the ones acquired
+// so far are released.
+func AcquireN(boskosClient *client.Client, resourceType string, n int, timeout, heartbeatInterval time.Duration, heartbeatClose chan struct{}) ([]*common.Resource, error) {
+	return acquireN(boskosClient, resourceType, n, timeout, heartbeatInterval, heartbeatClose, newHeartbeatMonitor(false))
+}
+
+func acquireN(boskosClient *client.Client, resourceType string, n int, timeout, heartbeatInterval time.Duration, heartbeatClose chan struct{}, monitor *heartbeatMonitor) ([]*common.Resource, error) {
+	var resources []*common.Resource
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		boskosResource, err := boskosClient.AcquireWait(ctx, resourceType, "free", "busy")
+		cancel()
+		if err == nil && boskosResource == nil {
+			err = fmt.Errorf("boskos had no %s available", resourceType)
+		}
+		if err != nil {
+			var names []string
+			for _, resource := range resources {
+				names = append(names, resource.Name)
+			}
+			if releaseErr := release(boskosClient, names); releaseErr != nil {
+				klog.Warningf("failed to release the %d %q acquired: %v", len(names), resourceType, releaseErr)
+			}
+			return nil, fmt.Errorf("failed to get %q %d of %d from boskos: %s", resourceType, i+1, n, err)
+		}
+		resources = append(resources, boskosResource)
 	}
 
 	if heartbeatInterval != 0 {
 		startBoskosHeartbeat(
 			boskosClient,
-			boskosResource,
+			resources,
 			heartbeatInterval,
 			heartbeatClose,
+			monitor,
 		)
 	}
 
-	return boskosResource, nil
+	return resources, nil
 }
 
-// startBoskosHeartbeat starts a goroutine that sends periodic updates to boskos
-// about the provided resource until the channel is closed. This prevents
-// reaper from taking the resource from the deployer while it is still in use.
-func startBoskosHeartbeat(boskosClient *client.Client, resource *common.Resource, interval time.Duration, close chan struct{}) {
-	go func(c *client.Client, resource *common.Resource) {
-		klog.V(2).Info("boskos hearbeat starting")
-
-		for {
-			select {
-			case <-close:
-				klog.V(2).Info("Boskos heartbeat func received signal to close")
-				return
-			case <-time.NewTicker(interval).C:
-				klog.V(2).Info("Sending heartbeat to Boskos")
-				if err := c.UpdateOne(resource.Name, "busy", nil); err != nil {
-					klog.Warningf("[Boskos] Update of %s failed with %v", resource.Name, err)
-				}
-			}
-		}
-	}(boskosClient, resource)
+// Release releases the resources and stops their heartbeats.
+// All the resources are attempted to be released even if some of them fail.
+func Release(client *client.Client, resourceNames []string, heartbeatClose chan struct{}) error {
+	err := release(client, resourceNames)
+	close(heartbeatClose)
+	return err
 }
 
-// Release releases a resource.
-func Release(client *client.Client, resourceNames []string, heartbeatClose chan struct{}) error {
+func release(client *client.Client, resourceNames []string) error {
+	var errs []error
 	for _, name := range resourceNames {
 		if err := client.Release(name, "dirty"); err != nil {
-			return fmt.Errorf("failed to release %s: %s", name, err)
+			errs = append(errs, fmt.Errorf("failed to release %s: %s", name, err))
 		}
 	}
-	close(heartbeatClose)
-	return nil
+	return errors.Join(errs...)
 }
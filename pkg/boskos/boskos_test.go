@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/boskos/common"
+)
+
+// fakeBoskos serves the acquire and release requests of the boskos client
+// from a pool of free resources
+type fakeBoskos struct {
+	mu       sync.Mutex
+	free     []string
+	released []string
+}
+
+func (f *fakeBoskos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/acquire":
+		if len(f.free) == 0 {
+			http.Error(w, "no resources available", http.StatusNotFound)
+			return
+		}
+		name := f.free[0]
+		f.free = f.free[1:]
+		_ = json.NewEncoder(w).Encode(common.Resource{Name: name, Type: r.URL.Query().Get("type"), State: "busy"})
+	case "/release":
+		f.released = append(f.released, r.URL.Query().Get("name"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAcquireN(t *testing.T) {
+	testCases := []struct {
+		name             string
+		free             []string
+		n                int
+		expectErr        bool
+		expectedAcquired []string
+		expectedReleased []string
+	}{
+		{
+			name:             "all acquired",
+			free:             []string{"project-a", "project-b", "project-c"},
+			n:                2,
+			expectedAcquired: []string{"project-a", "project-b"},
+		},
+		{
+			name:             "the acquired ones are released if not all are",
+			free:             []string{"project-a", "project-b"},
+			n:                3,
+			expectErr:        true,
+			expectedReleased: []string{"project-a", "project-b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeBoskos{free: tc.free}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			boskosClient, err := NewClient(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resources, err := AcquireN(boskosClient, "gce-project", tc.n, 10*time.Millisecond, 0, make(chan struct{}))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error: %v, got %v", tc.expectErr, err)
+			}
+			var acquired []string
+			for _, resource := range resources {
+				acquired = append(acquired, resource.Name)
+			}
+			if !reflect.DeepEqual(acquired, tc.expectedAcquired) {
+				t.Errorf("expected %v to be acquired, got %v", tc.expectedAcquired, acquired)
+			}
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			sort.Strings(fake.released)
+			if !reflect.DeepEqual(fake.released, tc.expectedReleased) {
+				t.Errorf("expected %v to be released, got %v", tc.expectedReleased, fake.released)
+			}
+		})
+	}
+}
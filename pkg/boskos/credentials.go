@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/boskos/common"
+)
+
+// Credentials describes the credentials of a cloud account resource in its
+// user data, for deployers leasing accounts instead of GCP projects.
+type Credentials struct {
+	// ResourceType is the suffix of the boskos resource types holding the credentials
+	ResourceType string
+	// Env maps the user data keys of the credentials to the env vars
+	// they are exported as
+	Env map[string]string
+}
+
+var (
+	// AWSAccount are the credentials of aws-account resources, as set by
+	// the boskos aws janitor
+	AWSAccount = Credentials{
+		ResourceType: "aws-account",
+		Env: map[string]string{
+			"access-key-id":     "AWS_ACCESS_KEY_ID",
+			"secret-access-key": "AWS_SECRET_ACCESS_KEY",
+		},
+	}
+	// AzureSubscription are the credentials of the service principal of
+	// azure-subscription resources
+	AzureSubscription = Credentials{
+		ResourceType: "azure-subscription",
+		Env: map[string]string{
+			"subscription-id": "AZURE_SUBSCRIPTION_ID",
+			"tenant-id":       "AZURE_TENANT_ID",
+			"client-id":       "AZURE_CLIENT_ID",
+			"client-secret":   "AZURE_CLIENT_SECRET",
+		},
+	}
+)
+
+// ExportCredentials exports the credentials in the user data of the resource
+// to the environment of the deployer, and so the tester.
+func ExportCredentials(resource *common.Resource, credentials Credentials) error {
+	env, err := credentials.env(resource)
+	if err != nil {
+		return err
+	}
+	for key, value := range env {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to export %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// env returns the env vars of the credentials in the user data of the resource.
+func (c Credentials) env(resource *common.Resource) (map[string]string, error) {
+	if !strings.HasSuffix(resource.Type, c.ResourceType) {
+		return nil, fmt.Errorf("resource %s of type %q does not hold %s credentials", resource.Name, resource.Type, c.ResourceType)
+	}
+	userData := common.UserDataMap{}
+	if resource.UserData != nil {
+		userData = resource.UserData.ToMap()
+	}
+	env := map[string]string{}
+	var missing []string
+	for key, envVar := range c.Env {
+		value, ok := userData[key]
+		if !ok || value == "" {
+			missing = append(missing, key)
+			continue
+		}
+		env[envVar] = value
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("resource %s is missing %v in its user data", resource.Name, missing)
+	}
+	return env, nil
+}
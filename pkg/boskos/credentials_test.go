@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/boskos/common"
+)
+
+func TestCredentialsEnv(t *testing.T) {
+	cases := []struct {
+		name        string
+		resource    *common.Resource
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name: "aws account",
+			resource: &common.Resource{
+				Name: "account-1",
+				Type: "aws-account",
+				UserData: common.UserDataFromMap(common.UserDataMap{
+					"access-key-id":     "id",
+					"secret-access-key": "secret",
+				}),
+			},
+			expected: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "id",
+				"AWS_SECRET_ACCESS_KEY": "secret",
+			},
+		},
+		{
+			name: "missing key",
+			resource: &common.Resource{
+				Name: "account-1",
+				Type: "aws-account",
+				UserData: common.UserDataFromMap(common.UserDataMap{
+					"access-key-id": "id",
+				}),
+			},
+			expectError: true,
+		},
+		{
+			name: "no user data",
+			resource: &common.Resource{
+				Name: "account-1",
+				Type: "aws-account",
+			},
+			expectError: true,
+		},
+		{
+			name: "wrong type",
+			resource: &common.Resource{
+				Name: "project-1",
+				Type: "gce-project",
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := AWSAccount.env(tc.resource)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error but got %v", env)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(env, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, env)
+			}
+		})
+	}
+}
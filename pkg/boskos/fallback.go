@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// fallbackLockTTL is the age after which the lock of a fallback project is
+// considered stale, e.g. when a run was killed before releasing it
+const fallbackLockTTL = 24 * time.Hour
+
+// DefaultLockDir is the directory the locks of the fallback projects are
+// taken in by default, shared by the runs on the host
+var DefaultLockDir = filepath.Join(os.TempDir(), "kubetest2-project-locks")
+
+// Locker takes the locks of a pool of projects shared between runs, as a
+// lightweight alternative to boskos.
+type Locker interface {
+	// Lock takes the lock of the project, returning false if it is taken
+	Lock(project string) (bool, error)
+	// Unlock releases the lock of the project
+	Unlock(project string) error
+}
+
+// NewLocker returns the Locker taking the locks at location, which is either
+// a gs://bucket/path to share the projects between hosts, or a local directory.
+func NewLocker(location string) Locker {
+	if location == "" {
+		location = DefaultLockDir
+	}
+	if strings.HasPrefix(location, gcsPrefix) {
+		return &GCSLocker{Location: strings.TrimSuffix(location, "/")}
+	}
+	return &DirLocker{Dir: location}
+}
+
+// AcquireFallback acquires n of the projects for when boskos is unreachable
+// or not used, by taking their locks.
+func AcquireFallback(locker Locker, projects []string, n int) ([]string, error) {
+	var acquired []string
+	for _, project := range projects {
+		if len(acquired) == n {
+			break
+		}
+		ok, err := locker.Lock(project)
+		if err != nil {
+			ReleaseFallback(locker, acquired)
+			return nil, err
+		}
+		if ok {
+			klog.V(1).Infof("Got fallback project %s", project)
+			acquired = append(acquired, project)
+		}
+	}
+	if len(acquired) < n {
+		ReleaseFallback(locker, acquired)
+		return nil, fmt.Errorf("only %d of %d fallback projects %v are free", len(acquired), n, projects)
+	}
+	return acquired, nil
+}
+
+// ReleaseFallback releases the fallback projects.
+func ReleaseFallback(locker Locker, projects []string) error {
+	var errs []error
+	for _, project := range projects {
+		if err := locker.Unlock(project); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release fallback project %s: %v", project, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DirLocker takes the locks of the projects as files in Dir.
+type DirLocker struct {
+	Dir string
+}
+
+// Lock takes the lock of project, if it is free or stale.
+func (l *DirLocker) Lock(project string) (bool, error) {
+	if err := os.MkdirAll(l.Dir, os.ModePerm); err != nil {
+		return false, fmt.Errorf("failed to create project lock dir: %v", err)
+	}
+	path := l.path(project)
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > fallbackLockTTL {
+		klog.Warningf("Taking over stale lock of fallback project %s from %v", project, info.ModTime())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	// O_EXCL makes the creation of the lock atomic between the runs
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to lock fallback project %s: %v", project, err)
+	}
+	_, err = f.WriteString(lockOwner())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return true, err
+}
+
+// Unlock removes the lock of project.
+func (l *DirLocker) Unlock(project string) error {
+	if err := os.Remove(l.path(project)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *DirLocker) path(project string) string {
+	return filepath.Join(l.Dir, project+".lock")
+}
+
+// lockOwner returns the content of the locks, identifying the run holding them
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s %s %d\n", boskosOwner, hostname, os.Getpid())
+}
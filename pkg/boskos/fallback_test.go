@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAcquireFallback(t *testing.T) {
+	locker := &DirLocker{Dir: t.TempDir()}
+	projects := []string{"project-a", "project-b", "project-c"}
+
+	first, err := AcquireFallback(locker, projects, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"project-a", "project-b"}; !reflect.DeepEqual(first, expected) {
+		t.Errorf("expected %v, got %v", expected, first)
+	}
+	if _, err := AcquireFallback(locker, projects, 2); err == nil {
+		t.Error("expected error acquiring more projects than free")
+	}
+	second, err := AcquireFallback(locker, projects, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"project-c"}; !reflect.DeepEqual(second, expected) {
+		t.Errorf("expected %v, got %v", expected, second)
+	}
+
+	if err := ReleaseFallback(locker, first); err != nil {
+		t.Fatalf("unexpected error releasing: %v", err)
+	}
+	// a stale lock is taken over
+	stale := time.Now().Add(-2 * fallbackLockTTL)
+	if err := os.Chtimes(locker.path("project-c"), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	third, err := AcquireFallback(locker, projects, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(third, projects) {
+		t.Errorf("expected %v, got %v", projects, third)
+	}
+}
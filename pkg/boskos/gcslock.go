@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const gcsPrefix = "gs://"
+
+// GCSLocker takes the locks of the projects as objects under Location in GCS,
+// using generation preconditions to make taking them atomic between hosts.
+type GCSLocker struct {
+	Location string
+}
+
+// Lock takes the lock of project, if it is free or stale.
+func (l *GCSLocker) Lock(project string) (bool, error) {
+	object := l.object(project)
+	if err := l.removeStale(object); err != nil {
+		return false, err
+	}
+
+	f, err := os.CreateTemp("", "kubetest2-lock")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(lockOwner())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// generation 0 only matches objects which do not exist yet
+	cmd := exec.Command("gcloud", "storage", "cp", "--if-generation-match=0", f.Name(), object)
+	out, err := exec.CombinedOutputLines(cmd)
+	if err != nil {
+		if preconditionFailed(out) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock fallback project %s: %v: %s", project, err, strings.Join(out, "\n"))
+	}
+	return true, nil
+}
+
+// Unlock removes the lock of project.
+func (l *GCSLocker) Unlock(project string) error {
+	out, err := exec.CombinedOutputLines(exec.Command("gcloud", "storage", "rm", l.object(project)))
+	if err != nil && !notFound(out) {
+		return fmt.Errorf("%v: %s", err, strings.Join(out, "\n"))
+	}
+	return nil
+}
+
+// removeStale removes the lock object if it is older than fallbackLockTTL,
+// unless it was taken again meanwhile.
+func (l *GCSLocker) removeStale(object string) error {
+	cmd := exec.Command("gcloud", "storage", "objects", "describe", object, "--format=value(update_time,generation)")
+	out, err := exec.OutputLines(cmd)
+	if err != nil || len(out) == 0 {
+		// there is no lock, or taking it fails if there is one
+		return nil
+	}
+	fields := strings.Fields(out[0])
+	if len(fields) != 2 {
+		return fmt.Errorf("failed to parse lock %s: %q", object, out[0])
+	}
+	updated, err := time.Parse(time.RFC3339, fields[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse update time of lock %s: %v", object, err)
+	}
+	if time.Since(updated) <= fallbackLockTTL {
+		return nil
+	}
+	klog.Warningf("Taking over stale lock %s from %v", object, updated)
+	cmd = exec.Command("gcloud", "storage", "rm", "--if-generation-match="+fields[1], object)
+	if lines, err := exec.CombinedOutputLines(cmd); err != nil && !preconditionFailed(lines) && !notFound(lines) {
+		return fmt.Errorf("failed to remove stale lock %s: %v: %s", object, err, strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+func (l *GCSLocker) object(project string) string {
+	return l.Location + "/" + project + ".lock"
+}
+
+// preconditionFailed returns true if the gcloud output is of a failed
+// generation precondition, i.e. the lock is taken
+func preconditionFailed(out []string) bool {
+	output := strings.Join(out, "\n")
+	return strings.Contains(output, "412") || strings.Contains(output, "PreconditionException")
+}
+
+// notFound returns true if the gcloud output is of a missing object
+func notFound(out []string) bool {
+	output := strings.Join(out, "\n")
+	return strings.Contains(output, "404") || strings.Contains(output, "No URLs matched")
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/boskos/client"
+	"sigs.k8s.io/boskos/common"
+)
+
+const (
+	// heartbeatAttempts is the number of attempts of each heartbeat
+	heartbeatAttempts = 3
+	// heartbeatBackoff is the backoff before the first retry of a heartbeat,
+	// doubled for each further retry
+	heartbeatBackoff = time.Second
+	// heartbeatJitter is the fraction the heartbeat interval is jittered by,
+	// so that the heartbeats of concurrent runs are spread out
+	heartbeatJitter = 0.1
+	// leaseLostHeartbeats is the number of heartbeats failing in a row after
+	// which the lease of a resource is considered lost
+	leaseLostHeartbeats = 3
+)
+
+// heartbeatMonitor counts the heartbeats of the resources of a lease and
+// tracks whether the lease of any of them was lost.
+type heartbeatMonitor struct {
+	sent   atomic.Int64
+	failed atomic.Int64
+	lost   atomic.Int64
+
+	// failOnLeaseLost closes lostCh when a lease is lost instead of warning
+	failOnLeaseLost bool
+	lostOnce        sync.Once
+	lostCh          chan struct{}
+}
+
+func newHeartbeatMonitor(failOnLeaseLost bool) *heartbeatMonitor {
+	return &heartbeatMonitor{
+		failOnLeaseLost: failOnLeaseLost,
+		lostCh:          make(chan struct{}),
+	}
+}
+
+// leaseLost records that the lease of the named resource was lost. With
+// failOnLeaseLost, the owner of the lease is notified through lostCh so that
+// it can stop using the resource and tear down what runs on it.
+func (m *heartbeatMonitor) leaseLost(name string) {
+	m.lost.Add(1)
+	if !m.failOnLeaseLost {
+		klog.Errorf("[Boskos] The lease of %s may be lost after %d failed heartbeats, it may be cleaned up during the run", name, leaseLostHeartbeats)
+		return
+	}
+	klog.Errorf("[Boskos] Lost the lease of %s after %d failed heartbeats", name, leaseLostHeartbeats)
+	m.lostOnce.Do(func() { close(m.lostCh) })
+}
+
+// startBoskosHeartbeat starts a goroutine that sends periodic updates to boskos
+// about the provided resources until the channel is closed. This prevents
+// reaper from taking the resources from the deployer while they are still in use.
+// The heartbeats are counted by monitor.
+func startBoskosHeartbeat(boskosClient *client.Client, resources []*common.Resource, interval time.Duration, close chan struct{}, monitor *heartbeatMonitor) {
+	go func(c *client.Client, resources []*common.Resource) {
+		for _, resource := range resources {
+			klog.V(2).Infof("boskos hearbeat starting for %s", resource.Name)
+		}
+		// heartbeats failed in a row of each resource
+		failures := map[string]int{}
+
+		timer := time.NewTimer(jitter(interval))
+		defer timer.Stop()
+		for {
+			select {
+			case <-close:
+				for _, resource := range resources {
+					klog.V(2).Infof("Boskos heartbeat func for %s received signal to close", resource.Name)
+				}
+				return
+			case <-timer.C:
+				klog.V(2).Info("Sending heartbeat to Boskos")
+				for _, resource := range resources {
+					monitor.sent.Add(1)
+					if err := heartbeat(c, resource.Name, close); err != nil {
+						monitor.failed.Add(1)
+						failures[resource.Name]++
+						klog.Warningf("[Boskos] Update of %s failed %d times in a row with %v", resource.Name, failures[resource.Name], err)
+						if failures[resource.Name] == leaseLostHeartbeats {
+							monitor.leaseLost(resource.Name)
+						}
+						continue
+					}
+					failures[resource.Name] = 0
+				}
+				timer.Reset(jitter(interval))
+			}
+		}
+	}(boskosClient, resources)
+}
+
+// heartbeat updates the resource, retrying with backoff until the channel is closed.
+func heartbeat(c *client.Client, name string, close chan struct{}) error {
+	backoff := heartbeatBackoff
+	var err error
+	for attempt := 1; attempt <= heartbeatAttempts; attempt++ {
+		if err = c.UpdateOne(name, "busy", nil); err == nil {
+			return nil
+		}
+		if attempt == heartbeatAttempts {
+			break
+		}
+		klog.V(2).Infof("[Boskos] Update of %s failed with %v, retrying in %v", name, err, backoff)
+		select {
+		case <-close:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+// jitter returns interval randomly adjusted by up to heartbeatJitter of it
+func jitter(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * (1 + heartbeatJitter*(2*rand.Float64()-1)))
+}
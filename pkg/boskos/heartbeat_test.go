@@ -0,0 +1,62 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"testing"
+)
+
+func TestHeartbeatMonitorLeaseLost(t *testing.T) {
+	testCases := []struct {
+		name            string
+		failOnLeaseLost bool
+		expectLost      bool
+	}{
+		{
+			name:       "warn only",
+			expectLost: false,
+		},
+		{
+			name:            "fail on lease lost",
+			failOnLeaseLost: true,
+			expectLost:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := newHeartbeatMonitor(tc.failOnLeaseLost)
+			// losing several leases must not close the channel twice
+			m.leaseLost("project-a")
+			m.leaseLost("project-b")
+			if got := m.lost.Load(); got != 2 {
+				t.Errorf("expected 2 leases lost, got %d", got)
+			}
+			select {
+			case <-m.lostCh:
+				if !tc.expectLost {
+					t.Errorf("did not expect the lease loss to be reported")
+				}
+			default:
+				if tc.expectLost {
+					t.Errorf("expected the lease loss to be reported")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/boskos/client"
+	"sigs.k8s.io/boskos/common"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+// DefaultLocation is the location of the boskos server in the prow clusters
+const DefaultLocation = "http://boskos.test-pods.svc.cluster.local."
+
+// Options are the flags of the deployers and testers acquiring resources
+// from boskos, to embed in their flags.
+type Options struct {
+	BoskosLocation                 string `flag:"~boskos-location" desc:"If set, manually specifies the location of the boskos server. If unset and boskos is needed, defaults to http://boskos.test-pods.svc.cluster.local."`
+	BoskosAcquireTimeoutSeconds    int    `flag:"~boskos-acquire-timeout-seconds" desc:"How long (in seconds) to hang on a request to Boskos to acquire a resource before erroring. When several resources are requested, each of them is waited for this long."`
+	BoskosHeartbeatIntervalSeconds int    `flag:"~boskos-heartbeat-interval-seconds" desc:"How often (in seconds) to send a heartbeat to Boskos to hold the acquired resource. 0 means no heartbeat."`
+	BoskosFailOnLeaseLost          bool   `flag:"~boskos-fail-on-lease-lost" desc:"If set, stop the tests and tear down the cluster when the heartbeats of an acquired resource keep failing instead of warning, as the resource may be cleaned up during the run."`
+}
+
+// NewOptions returns the default boskos options
+func NewOptions() *Options {
+	return &Options{
+		BoskosLocation:                 DefaultLocation,
+		BoskosAcquireTimeoutSeconds:    5 * 60,
+		BoskosHeartbeatIntervalSeconds: 5 * 60,
+	}
+}
+
+// Lease holds the resources acquired from boskos, which are heartbeated
+// until released together.
+type Lease struct {
+	client         *client.Client
+	options        *Options
+	heartbeatClose chan struct{}
+	monitor        *heartbeatMonitor
+	resources      []*common.Resource
+	released       bool
+}
+
+// NewLease returns an empty lease from the boskos server of the options.
+func (o *Options) NewLease() (*Lease, error) {
+	boskosClient, err := NewClient(o.BoskosLocation)
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{
+		client:         boskosClient,
+		options:        o,
+		heartbeatClose: make(chan struct{}),
+		monitor:        newHeartbeatMonitor(o.BoskosFailOnLeaseLost),
+	}, nil
+}
+
+// Acquire acquires n more resources of the given type into the lease. If not
+// all of them can be acquired, none of them are added to the lease.
+func (l *Lease) Acquire(resourceType string, n int) ([]*common.Resource, error) {
+	resources, err := acquireN(
+		l.client,
+		resourceType,
+		n,
+		time.Duration(l.options.BoskosAcquireTimeoutSeconds)*time.Second,
+		time.Duration(l.options.BoskosHeartbeatIntervalSeconds)*time.Second,
+		l.heartbeatClose,
+		l.monitor,
+	)
+	if err != nil {
+		return nil, err
+	}
+	l.resources = append(l.resources, resources...)
+	return resources, nil
+}
+
+// Names returns the names of the resources in the lease.
+func (l *Lease) Names() []string {
+	var names []string
+	for _, resource := range l.resources {
+		names = append(names, resource.Name)
+	}
+	return names
+}
+
+// Lost returns a channel closed when the lease of any of its resources is
+// lost with --boskos-fail-on-lease-lost, after which the resources may be
+// cleaned up by the janitor and should not be used anymore.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.monitor.lostCh
+}
+
+// Release releases the resources in the lease and stops their heartbeats,
+// which are recorded in the metadata of the run.
+func (l *Lease) Release() error {
+	if l.released {
+		return nil
+	}
+	l.released = true
+	err := Release(l.client, l.Names(), l.heartbeatClose)
+	l.writeHeartbeatsToMetadataJSON()
+	return err
+}
+
+// writeHeartbeatsToMetadataJSON records the heartbeats of the lease in the
+// metadata of the run
+func (l *Lease) writeHeartbeatsToMetadataJSON() {
+	path := filepath.Join(artifacts.BaseDir(), "metadata.json")
+	for key, value := range map[string]int64{
+		"boskos-heartbeats-sent":   l.monitor.sent.Load(),
+		"boskos-heartbeats-failed": l.monitor.failed.Load(),
+		"boskos-leases-lost":       l.monitor.lost.Load(),
+	} {
+		if err := metadata.AddToFile(path, key, strconv.FormatInt(value, 10)); err != nil {
+			klog.Warningf("failed to record the boskos heartbeats: %v", err)
+			return
+		}
+	}
+}
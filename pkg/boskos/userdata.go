@@ -0,0 +1,53 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"sigs.k8s.io/boskos/common"
+)
+
+// User data keys of the setup pre-provisioned in project resources
+const (
+	UserDataNetwork        = "network"
+	UserDataSubnetwork     = "subnetwork"
+	UserDataServiceAccount = "service-account"
+)
+
+// ProjectUserData is the setup of a project resource pre-provisioned by the
+// boskos pool, which deployers use instead of creating their own.
+type ProjectUserData struct {
+	// Network is the VPC network to create the cluster in
+	Network string
+	// Subnetwork is the subnetwork of Network to create the cluster in
+	Subnetwork string
+	// ServiceAccount is the service account of the nodes
+	ServiceAccount string
+}
+
+// ParseProjectUserData returns the setup in the user data of the resource,
+// which is empty where the pool does not pre-provision any.
+func ParseProjectUserData(resource *common.Resource) ProjectUserData {
+	if resource.UserData == nil {
+		return ProjectUserData{}
+	}
+	userData := resource.UserData.ToMap()
+	return ProjectUserData{
+		Network:        userData[UserDataNetwork],
+		Subnetwork:     userData[UserDataSubnetwork],
+		ServiceAccount: userData[UserDataServiceAccount],
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"testing"
+
+	"sigs.k8s.io/boskos/common"
+)
+
+func TestParseProjectUserData(t *testing.T) {
+	resource := &common.Resource{
+		Name: "project-1",
+		Type: "gce-project",
+		UserData: common.UserDataFromMap(common.UserDataMap{
+			UserDataNetwork:    "e2e",
+			UserDataSubnetwork: "e2e-us-central1",
+		}),
+	}
+	expected := ProjectUserData{Network: "e2e", Subnetwork: "e2e-us-central1"}
+	if userData := ParseProjectUserData(resource); userData != expected {
+		t.Errorf("expected %+v, got %+v", expected, userData)
+	}
+	if userData := ParseProjectUserData(&common.Resource{Name: "project-2"}); userData != (ProjectUserData{}) {
+		t.Errorf("expected empty user data, got %+v", userData)
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// SBOMFile is the name of the SPDX SBOM of the build outputs
+	SBOMFile = "sbom.spdx"
+	// ProvenanceFile is the name of the SLSA provenance of the build outputs
+	ProvenanceFile = "provenance.intoto.json"
+
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	slsaProvenanceType  = "https://slsa.dev/provenance/v1"
+	kubetest2BuildType  = "https://sigs.k8s.io/kubetest2/build/v1"
+	kubetest2BuilderID  = "https://sigs.k8s.io/kubetest2"
+)
+
+// Attestor generates an SBOM and a provenance attestation of the build outputs,
+// i.e. the release tars, binaries and images left in the repo root and the
+// images published with ko.
+type Attestor struct {
+	RepoRoot        string
+	Strategy        string
+	TargetBuildArch string
+	Version         string
+	StartedOn       time.Time
+	// Images are the references of published images, keyed by their source
+	Images map[string]string
+	// OutputDir is where the SBOM and provenance are written to
+	OutputDir string
+}
+
+// outputs returns the files built, relative to the repo root, and the
+// image archives among them.
+func (a *Attestor) outputs() (files []string, imageArchives []string, err error) {
+	for _, dir := range []string{releaseTarsOutput, binariesOutput, PackagesOutput, releaseImagesOutput} {
+		root := filepath.Join(a.RepoRoot, dir)
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(a.RepoRoot, path)
+			if err != nil {
+				return err
+			}
+			if dir == releaseImagesOutput {
+				imageArchives = append(imageArchives, rel)
+			} else {
+				files = append(files, rel)
+			}
+			return nil
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return files, imageArchives, nil
+}
+
+// GenerateSBOM writes an SPDX SBOM of the build outputs with bom.
+func (a *Attestor) GenerateSBOM() error {
+	files, imageArchives, err := a.outputs()
+	if err != nil {
+		return fmt.Errorf("failed to list build outputs: %v", err)
+	}
+	if err := os.MkdirAll(a.OutputDir, os.ModePerm); err != nil {
+		return err
+	}
+	output := filepath.Join(a.OutputDir, SBOMFile)
+	args := []string{"generate",
+		"--namespace", kubetest2BuilderID + "/" + a.Version,
+		"--output", output,
+	}
+	for _, file := range files {
+		args = append(args, "--file", file)
+	}
+	for _, archive := range imageArchives {
+		args = append(args, "--image-archive", archive)
+	}
+	for _, ref := range sortedValues(a.Images) {
+		args = append(args, "--image", ref)
+	}
+	klog.V(0).Infof("Generating SBOM of the build to %s ...", output)
+	cmd := exec.Command("bom", args...)
+	cmd.SetDir(a.RepoRoot)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to generate SBOM: %v", err)
+	}
+	return nil
+}
+
+type inTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []inTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     slsaProvenance  `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type resourceDescriptor struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaProvenance struct {
+	BuildDefinition struct {
+		BuildType            string               `json:"buildType"`
+		ExternalParameters   map[string]string    `json:"externalParameters"`
+		ResolvedDependencies []resourceDescriptor `json:"resolvedDependencies,omitempty"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			StartedOn  string `json:"startedOn"`
+			FinishedOn string `json:"finishedOn"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+// GenerateProvenance writes a SLSA v1 provenance statement with the
+// digests of the build outputs and the commit they were built from.
+func (a *Attestor) GenerateProvenance() error {
+	files, imageArchives, err := a.outputs()
+	if err != nil {
+		return fmt.Errorf("failed to list build outputs: %v", err)
+	}
+	statement := inTotoStatement{
+		Type:          inTotoStatementType,
+		PredicateType: slsaProvenanceType,
+	}
+	for _, file := range append(files, imageArchives...) {
+		digest, err := sha256sum(filepath.Join(a.RepoRoot, file))
+		if err != nil {
+			return fmt.Errorf("failed to compute sha256 for %q: %v", file, err)
+		}
+		statement.Subject = append(statement.Subject, inTotoSubject{
+			Name:   filepath.ToSlash(file),
+			Digest: map[string]string{"sha256": digest},
+		})
+	}
+	for _, ref := range sortedValues(a.Images) {
+		if name, digest, ok := strings.Cut(ref, "@sha256:"); ok {
+			statement.Subject = append(statement.Subject, inTotoSubject{
+				Name:   name,
+				Digest: map[string]string{"sha256": digest},
+			})
+		}
+	}
+
+	predicate := &statement.Predicate
+	predicate.BuildDefinition.BuildType = kubetest2BuildType
+	predicate.BuildDefinition.ExternalParameters = map[string]string{
+		"strategy":        a.Strategy,
+		"targetBuildArch": a.TargetBuildArch,
+		"version":         a.Version,
+	}
+	if source := a.source(); source != nil {
+		predicate.BuildDefinition.ResolvedDependencies = []resourceDescriptor{*source}
+	}
+	predicate.RunDetails.Builder.ID = kubetest2BuilderID
+	predicate.RunDetails.Metadata.StartedOn = a.StartedOn.UTC().Format(time.RFC3339)
+	predicate.RunDetails.Metadata.FinishedOn = time.Now().UTC().Format(time.RFC3339)
+
+	raw, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(a.OutputDir, os.ModePerm); err != nil {
+		return err
+	}
+	output := filepath.Join(a.OutputDir, ProvenanceFile)
+	klog.V(0).Infof("Writing provenance of the build to %s ...", output)
+	return os.WriteFile(output, raw, 0644)
+}
+
+// source returns the git commit the build is from, or nil if unknown.
+func (a *Attestor) source() *resourceDescriptor {
+	commitCmd := exec.Command("git", "rev-parse", "HEAD")
+	commitCmd.SetDir(a.RepoRoot)
+	commit, err := exec.OutputLines(commitCmd)
+	if err != nil || len(commit) == 0 {
+		klog.Warningf("failed to get the commit of %s: %v", a.RepoRoot, err)
+		return nil
+	}
+	uri := "git+file://" + a.RepoRoot
+	remoteCmd := exec.Command("git", "remote", "get-url", "origin")
+	remoteCmd.SetDir(a.RepoRoot)
+	if remote, err := exec.OutputLines(remoteCmd); err == nil && len(remote) > 0 {
+		uri = "git+" + remote[0]
+	}
+	return &resourceDescriptor{
+		URI:    uri,
+		Digest: map[string]string{"gitCommit": commit[0]},
+	}
+}
+
+func sortedValues(m map[string]string) []string {
+	var values []string
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func sha256sum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -18,16 +18,37 @@ package build
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/fs"
 )
 
+const (
+	// bazelReleaseTarsTarget builds the release tars used by the deployers
+	bazelReleaseTarsTarget = "//build/release-tars"
+	// releaseTarsOutput is where make quick-release leaves the release tars
+	releaseTarsOutput = "_output/release-tars"
+	// binariesOutput is where the dockerized make build leaves the binaries
+	binariesOutput = "_output/dockerized/bin"
+)
+
+// bazelTestTargets build the binaries used by the testers, see CommonTestBinaries
+var bazelTestTargets = []string{
+	"//cmd/kubectl",
+	"//test/e2e:e2e.test",
+	"//vendor/github.com/onsi/ginkgo/v2/ginkgo",
+}
+
 type Bazel struct {
 	RepoRoot      string
 	StageLocation string
 	ImageLocation string
+	RemoteCache   string
 }
 
 var _ Builder = &Bazel{}
@@ -42,15 +63,56 @@ func (b *Bazel) Stage(version string) error {
 	return cmd.Run()
 }
 
+// Build builds the release tars and test binaries with bazel and copies
+// them to where the make build leaves them, so that deployers and stagers
+// find them regardless of the build strategy.
 func (b *Bazel) Build() (string, error) {
 	klog.V(0).Infof("Building kubernetes from %s ...", b.RepoRoot)
 	version, err := sourceVersion(b.RepoRoot)
 	if err != nil {
 		return "", fmt.Errorf("failed to get version: %v", err)
 	}
-	cmd := exec.Command("bazel", "build", "//build/release-tars")
+	targets := append([]string{bazelReleaseTarsTarget}, bazelTestTargets...)
+	args := append([]string{"build"}, BazelRemoteCacheArgs(b.RemoteCache)...)
+	cmd := exec.Command("bazel", append(args, targets...)...)
 	cmd = cmd.SetDir(b.RepoRoot)
 	setSourceDateEpoch(b.RepoRoot, cmd)
 	exec.InheritOutput(cmd)
-	return version, cmd.Run()
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	if err := b.copyOutputs(bazelReleaseTarsTarget, filepath.Join(b.RepoRoot, releaseTarsOutput)); err != nil {
+		return "", err
+	}
+	binaries := filepath.Join(b.RepoRoot, binariesOutput, runtime.GOOS, runtime.GOARCH)
+	for _, target := range bazelTestTargets {
+		if err := b.copyOutputs(target, binaries); err != nil {
+			return "", err
+		}
+	}
+	return version, nil
+}
+
+// copyOutputs copies the output files of the bazel target to dir.
+func (b *Bazel) copyOutputs(target, dir string) error {
+	cmd := exec.Command("bazel", "cquery", "--output=files", target)
+	cmd.SetDir(b.RepoRoot)
+	outputs, err := exec.OutputLines(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get outputs of %s: %v", target, err)
+	}
+	for _, output := range outputs {
+		source := filepath.Join(b.RepoRoot, output)
+		dest := filepath.Join(dir, filepath.Base(output))
+		klog.V(2).Infof("copying %s to %s ...", source, dest)
+		// bazel outputs are read-only, so replace rather than overwrite
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+		if err := fs.CopyFile(source, dest); err != nil {
+			return fmt.Errorf("failed to copy %s to %s: %v", source, dest, err)
+		}
+	}
+	return nil
 }
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
 	"sigs.k8s.io/kubetest2/pkg/exec"
 	"sigs.k8s.io/kubetest2/pkg/fs"
 )
@@ -81,10 +82,71 @@ var (
 	}
 )
 
+// WindowsNodeBinaries are the node binaries built for windows platforms
+var WindowsNodeBinaries = []string{
+	"kubelet.exe",
+	"kube-proxy.exe",
+}
+
+// Platforms returns the platforms of a comma or space separated target
+// build arch, e.g. "linux/amd64,windows/amd64"
+func Platforms(targetBuildArch string) []string {
+	return strings.FieldsFunc(targetBuildArch, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// WindowsPlatforms returns the windows platforms of platforms
+func WindowsPlatforms(platforms []string) []string {
+	var windows []string
+	for _, platform := range platforms {
+		if strings.HasPrefix(platform, "windows/") {
+			windows = append(windows, platform)
+		}
+	}
+	return windows
+}
+
+// LinuxPlatforms returns the linux platforms of platforms, those of the
+// control plane and the linux nodes, or the linux platform of the host
+// architecture if there are none
+func LinuxPlatforms(platforms []string) []string {
+	var linux []string
+	for _, platform := range platforms {
+		if strings.HasPrefix(platform, "linux/") {
+			linux = append(linux, platform)
+		}
+	}
+	if len(linux) == 0 {
+		return []string{"linux/" + runtime.GOARCH}
+	}
+	return linux
+}
+
+// NodeArch returns the architecture the cluster is built for with the
+// target build arch, that of its first linux platform, e.g. arm64 for
+// linux/arm64. Deployers select matching machine types and node images.
+func NodeArch(targetBuildArch string) string {
+	return platformArch(LinuxPlatforms(Platforms(targetBuildArch))[0])
+}
+
+// testBinaryPlatforms returns the platforms whose CommonTestBinaries are
+// staged, those of the host to run the tests from and the linux platforms
+// built, so that they can be run from a host of the architecture of the
+// cluster too
+func testBinaryPlatforms(platforms []string) []string {
+	staged := []string{runtime.GOOS + "/" + runtime.GOARCH}
+	for _, platform := range LinuxPlatforms(platforms) {
+		if !slices.Contains(staged, platform) {
+			staged = append(staged, platform)
+		}
+	}
+	return staged
+}
+
 // StoreCommonBinaries will best effort try to store commonly built binaries
 // to the output directory
 func StoreCommonBinaries(kuberoot string, outroot string) {
-	const dockerizedOutput = "_output/dockerized"
 	root := filepath.Join(kuberoot, dockerizedOutput, "bin", runtime.GOOS, runtime.GOARCH)
 	for _, binary := range CommonTestBinaries {
 		source := filepath.Join(root, binary)
@@ -101,18 +163,52 @@ func StoreCommonBinaries(kuberoot string, outroot string) {
 }
 
 // setSourceDateEpoch sets the SOURCE_DATE_EPOCH env to the commit timestamp of the latest commit in the
-// kubernetes repository, specified under kubeRoot, for reproducible builds
+// kubernetes repository, specified under kubeRoot, for reproducible builds, along with extraEnv
 // https://github.com/kubernetes/kubernetes/blob/7eae33cb0e1ead51c80ad517bc670113d77fa28d/build/README.md#reproducibility
-func setSourceDateEpoch(kubeRoot string, cmd exec.Cmd) {
-	if os.Getenv("SOURCE_DATE_EPOCH") != "" {
-		return
+func setSourceDateEpoch(kubeRoot string, cmd exec.Cmd, extraEnv ...string) {
+	env := append(os.Environ(), extraEnv...)
+	cmd.SetEnv(append(env, sourceDateEpochEnv(kubeRoot)...)...)
+}
+
+// sourceDateEpochEnv returns the SOURCE_DATE_EPOCH env, computed as in
+// setSourceDateEpoch unless it is set already.
+func sourceDateEpochEnv(kubeRoot string) []string {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		return []string{"SOURCE_DATE_EPOCH=" + epoch}
 	}
 	gitCmd := exec.Command("git", "log", "-1", "--pretty=%ct")
 	gitCmd.SetDir(kubeRoot)
-	if output, err := exec.CombinedOutputLines(gitCmd); err == nil {
-		env := append(os.Environ(), fmt.Sprintf("SOURCE_DATE_EPOCH=%s", output[0]))
-		cmd.SetEnv(env...)
-	} else {
+	output, err := exec.CombinedOutputLines(gitCmd)
+	if err != nil {
 		klog.Warningf("failed to compute SOURCE_DATE_EPOCH from kubernetes repository: %v", err)
+		return nil
+	}
+	return []string{fmt.Sprintf("SOURCE_DATE_EPOCH=%s", output[0])}
+}
+
+// GoCacheProgEnv returns the environment to use the Go cache program
+// goCacheProg, e.g. a remote build cache, or none if it is empty.
+func GoCacheProgEnv(goCacheProg string) []string {
+	if goCacheProg == "" {
+		return nil
+	}
+	return []string{"GOCACHEPROG=" + goCacheProg}
+}
+
+// goExperimentEnv returns the GOEXPERIMENT of the environment to pass to
+// build containers, e.g. boringcrypto with --fips, or none if it is unset.
+func goExperimentEnv() []string {
+	if experiment := os.Getenv("GOEXPERIMENT"); experiment != "" {
+		return []string{"GOEXPERIMENT=" + experiment}
+	}
+	return nil
+}
+
+// BazelRemoteCacheArgs returns the bazel arguments to use the remote cache
+// at remoteCache, or none if it is empty.
+func BazelRemoteCacheArgs(remoteCache string) []string {
+	if remoteCache == "" {
+		return nil
 	}
+	return []string{"--remote_cache=" + remoteCache}
 }
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"k8s.io/utils/strings/slices"
+)
+
+func TestLinuxPlatforms(t *testing.T) {
+	testCases := []struct {
+		name            string
+		targetBuildArch string
+		expected        []string
+		expectedArch    string
+	}{
+		{
+			name:            "arm64",
+			targetBuildArch: "linux/arm64",
+			expected:        []string{"linux/arm64"},
+			expectedArch:    "arm64",
+		},
+		{
+			name:            "windows nodes",
+			targetBuildArch: "linux/amd64,windows/amd64",
+			expected:        []string{"linux/amd64"},
+			expectedArch:    "amd64",
+		},
+		{
+			name:            "multi-arch",
+			targetBuildArch: "linux/arm64 linux/amd64",
+			expected:        []string{"linux/arm64", "linux/amd64"},
+			expectedArch:    "arm64",
+		},
+		{
+			name:         "unset",
+			expected:     []string{"linux/" + runtime.GOARCH},
+			expectedArch: runtime.GOARCH,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := LinuxPlatforms(Platforms(tc.targetBuildArch)); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected platforms %v but got %v", tc.expected, got)
+			}
+			if got := NodeArch(tc.targetBuildArch); got != tc.expectedArch {
+				t.Errorf("expected arch %q but got %q", tc.expectedArch, got)
+			}
+		})
+	}
+}
+
+func TestTestBinaryPlatforms(t *testing.T) {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	got := testBinaryPlatforms([]string{"linux/arm64", "linux/amd64", "windows/amd64"})
+	if got[0] != host {
+		t.Errorf("expected the host platform %s first but got %v", host, got)
+	}
+	for _, platform := range []string{"linux/arm64", "linux/amd64"} {
+		if !slices.Contains(got, platform) {
+			t.Errorf("expected %s in %v", platform, got)
+		}
+	}
+	if slices.Contains(got, "windows/amd64") {
+		t.Errorf("expected no windows platform in %v", got)
+	}
+	seen := map[string]bool{}
+	for _, platform := range got {
+		if seen[platform] {
+			t.Errorf("expected no duplicate platforms in %v", got)
+		}
+		seen[platform] = true
+	}
+}
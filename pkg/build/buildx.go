@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// DefaultImagePlatforms are the platforms multi-arch images are built for by default
+var DefaultImagePlatforms = []string{"linux/amd64", "linux/arm64"}
+
+// releaseImages are the component images built with the release tars
+var releaseImages = []string{
+	"kube-apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+	"kube-proxy",
+}
+
+// releaseImagesOutput is where the release scripts leave the image tars of each arch
+const releaseImagesOutput = "_output/release-images"
+
+// Buildx builds kubernetes for multiple platforms, which the release scripts
+// build the component images for with docker buildx, and stages the images
+// as multi-arch manifests.
+type Buildx struct {
+	RepoRoot      string
+	ImageLocation string
+	Platforms     []string
+	Targets       []string
+}
+
+var _ Builder = &Buildx{}
+var _ Stager = &Buildx{}
+
+// Build builds kubernetes for all platforms with the quick-release make target
+func (b *Buildx) Build() (string, error) {
+	return (&MakeBuilder{
+		RepoRoot:        b.RepoRoot,
+		TargetBuildArch: strings.Join(b.Platforms, " "),
+		Targets:         b.Targets,
+	}).Build()
+}
+
+// Stage pushes the images of each platform and a multi-arch manifest
+// referencing them to the image location. Unlike krel, it does not upload
+// the release tars, as deployers consume them from the repo root.
+func (b *Buildx) Stage(version string) error {
+	if b.ImageLocation == "" {
+		return fmt.Errorf("an image location is required to stage multi-arch images")
+	}
+	// image tags can't contain +
+	tag := strings.ReplaceAll(version, "+", "_")
+	for _, image := range releaseImages {
+		manifest := fmt.Sprintf("%s/%s:%s", b.ImageLocation, image, tag)
+		var archImages []string
+		for _, platform := range b.Platforms {
+			arch := platformArch(platform)
+			archImage := fmt.Sprintf("%s/%s-%s:%s", b.ImageLocation, image, arch, tag)
+			tar := filepath.Join(b.RepoRoot, releaseImagesOutput, arch, image+".tar")
+			if err := pushImageTar(tar, archImage); err != nil {
+				return err
+			}
+			archImages = append(archImages, archImage)
+		}
+
+		klog.V(0).Infof("Pushing multi-arch manifest %s ...", manifest)
+		cmd := exec.Command("docker", append([]string{"buildx", "imagetools", "create", "-t", manifest}, archImages...)...)
+		exec.InheritOutput(cmd)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to push multi-arch manifest %s: %v", manifest, err)
+		}
+	}
+	return nil
+}
+
+// pushImageTar loads the image in tar and pushes it as ref.
+func pushImageTar(tar, ref string) error {
+	lines, err := exec.CombinedOutputLines(exec.Command("docker", "load", "-i", tar))
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %v, output: %q", tar, err, lines)
+	}
+	loaded := ""
+	for _, line := range lines {
+		if image, ok := strings.CutPrefix(line, "Loaded image:"); ok {
+			loaded = strings.TrimSpace(image)
+		}
+	}
+	if loaded == "" {
+		return fmt.Errorf("failed to find the image loaded from %s, output: %q", tar, lines)
+	}
+	klog.V(0).Infof("Pushing %s as %s ...", loaded, ref)
+	if err := exec.Command("docker", "tag", loaded, ref).Run(); err != nil {
+		return fmt.Errorf("failed to tag %s as %s: %v", loaded, ref, err)
+	}
+	cmd := exec.Command("docker", "push", ref)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to push %s: %v", ref, err)
+	}
+	return nil
+}
+
+// platformArch returns the architecture of a platform such as linux/arm64
+func platformArch(platform string) string {
+	_, arch, _ := strings.Cut(platform, "/")
+	return arch
+}
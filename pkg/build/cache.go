@@ -0,0 +1,176 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/fs"
+)
+
+// BuildCache skips rebuilding a tree that was built before with the same
+// options, by saving the build outputs under Dir keyed by a hash of both.
+type BuildCache struct { //nolint:revive
+	Dir      string
+	RepoRoot string
+	// Options are the build options the outputs depend on
+	Options []string
+	// Outputs are the output directories relative to RepoRoot
+	Outputs []string
+}
+
+// Restore restores the outputs of a previous build saved in entry into the
+// repo root, and returns its version, if there is one.
+func (c *BuildCache) Restore(entry string) (string, bool, error) {
+	version, err := os.ReadFile(filepath.Join(entry, "version"))
+	if os.IsNotExist(err) {
+		klog.V(0).Infof("no cached build at %s", entry)
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	klog.V(0).Infof("restoring cached build from %s", entry)
+	for _, output := range c.Outputs {
+		dest := filepath.Join(c.RepoRoot, output)
+		if err := os.RemoveAll(dest); err != nil {
+			return "", false, err
+		}
+		cached := filepath.Join(entry, output)
+		if _, err := os.Stat(cached); os.IsNotExist(err) {
+			continue
+		}
+		if err := fs.CopyDir(cached, dest); err != nil {
+			return "", false, fmt.Errorf("failed to restore %s: %v", output, err)
+		}
+	}
+	return string(version), true, nil
+}
+
+// Save saves the outputs of the build of version in entry
+func (c *BuildCache) Save(entry, version string) error {
+	// populate the entry aside, so that a failed save is not restored
+	tmp := entry + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	for _, output := range c.Outputs {
+		src := filepath.Join(c.RepoRoot, output)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := fs.CopyDir(src, filepath.Join(tmp, output)); err != nil {
+			return fmt.Errorf("failed to cache %s: %v", output, err)
+		}
+	}
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "version"), []byte(version), 0644); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(entry); err != nil {
+		return err
+	}
+	klog.V(0).Infof("caching build in %s", entry)
+	return os.Rename(tmp, entry)
+}
+
+// entry returns the directory of the cache entry of the current tree, to be
+// computed before building as the build may change the tree
+func (c *BuildCache) entry() (string, error) {
+	tree, err := treeHash(c.RepoRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the repo tree: %v", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(tree))
+	for _, option := range c.Options {
+		h.Write([]byte("\x00" + option))
+	}
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// treeHash returns a hash of the working tree of repoRoot, including
+// uncommitted and untracked changes. It only reads the repo: changed files are
+// hashed without being written to the git object store.
+func treeHash(repoRoot string) (string, error) {
+	// the blobs of the index
+	indexCmd := exec.Command("git", "ls-files", "--stage", "-z")
+	indexCmd.SetDir(repoRoot)
+	index, err := exec.Output(indexCmd)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(index)
+
+	// and the files that differ from it
+	changedCmd := exec.Command("git", "ls-files", "--modified", "--others", "--exclude-standard", "-z")
+	changedCmd.SetDir(repoRoot)
+	changedOut, err := exec.Output(changedCmd)
+	if err != nil {
+		return "", err
+	}
+	var changed []string
+	for _, path := range strings.Split(string(changedOut), "\x00") {
+		if path == "" {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(repoRoot, path))
+		if os.IsNotExist(err) {
+			h.Write([]byte("\x00deleted\x00" + path))
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		h.Write([]byte(fmt.Sprintf("\x00%v\x00%s", info.Mode(), path)))
+		changed = append(changed, path)
+	}
+	if len(changed) > 0 {
+		// without -w, so nothing is written to .git/objects
+		hashCmd := exec.Command("git", "hash-object", "--no-filters", "--stdin-paths")
+		hashCmd.SetDir(repoRoot)
+		hashCmd.SetStdin(strings.NewReader(strings.Join(changed, "\n") + "\n"))
+		blobs, err := exec.Output(hashCmd)
+		if err != nil {
+			return "", err
+		}
+		h.Write(blobs)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// headVersion returns the description of HEAD by the version tags, which the
+// kubernetes build scripts derive the version of the build from. The long
+// format includes the commit even when HEAD is tagged.
+func headVersion(repoRoot string) (string, error) {
+	cmd := exec.Command("git", "describe", "--tags", "--match=v*", "--abbrev=14", "--always", "--long", "HEAD")
+	cmd.SetDir(repoRoot)
+	lines, err := exec.OutputLines(cmd)
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("failed to describe HEAD: %v", err)
+	}
+	return lines[0], nil
+}
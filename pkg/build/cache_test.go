@@ -0,0 +1,192 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo returns a git repo with a source file, committed if commit is set
+func initRepo(t *testing.T, commit bool) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, "main.go"), "package main")
+	writeFile(t, filepath.Join(repo, ".gitignore"), "_output/")
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v, output: %s", args, err, out)
+		}
+	}
+	git("init", "--quiet")
+	if commit {
+		git("add", "--all")
+		git("commit", "--quiet", "-m", "initial")
+	}
+	return repo
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countObjects returns the count of the loose objects of repo
+func countObjects(t *testing.T, repo string) string {
+	t.Helper()
+	cmd := exec.Command("git", "count-objects")
+	cmd.Dir = repo
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git count-objects failed: %v", err)
+	}
+	return string(out)
+}
+
+func TestTreeHash(t *testing.T) {
+	testCases := []struct {
+		name   string
+		commit bool
+	}{
+		{
+			name:   "committed tree",
+			commit: true,
+		},
+		{
+			name:   "no git index",
+			commit: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := initRepo(t, tc.commit)
+			objectsBefore := countObjects(t, repo)
+			before, err := treeHash(repo)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if again, err := treeHash(repo); err != nil || again != before {
+				t.Errorf("expected the same hash %q for the same tree but got %q, %v", before, again, err)
+			}
+			// untracked changes are part of the tree
+			writeFile(t, filepath.Join(repo, "new.go"), "package main")
+			after, err := treeHash(repo)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after == before {
+				t.Errorf("expected the hash to change with an untracked file")
+			}
+			// modified files are part of the tree too
+			writeFile(t, filepath.Join(repo, "main.go"), "package main\n")
+			if modified, err := treeHash(repo); err != nil || modified == after {
+				t.Errorf("expected the hash to change with a modified file, got %q, %v", modified, err)
+			}
+			// nothing is written to the repo
+			if objects := countObjects(t, repo); objects != objectsBefore {
+				t.Errorf("expected the git objects to be untouched, got %q, before %q", objects, objectsBefore)
+			}
+			// the index of the repo is untouched
+			if _, err := os.Stat(filepath.Join(repo, ".git", "index")); tc.commit == os.IsNotExist(err) {
+				t.Errorf("expected the git index to exist: %v, got %v", tc.commit, err)
+			}
+		})
+	}
+}
+
+func TestBuildCache(t *testing.T) {
+	repo := initRepo(t, true)
+	writeFile(t, filepath.Join(repo, "_output", "bin", "kubectl"), "kubectl")
+	cache := &BuildCache{
+		Dir:      t.TempDir(),
+		RepoRoot: repo,
+		Options:  []string{"strategy=make"},
+		Outputs:  []string{"_output/bin", "_output/release-tars"},
+	}
+
+	entry, err := cache.entry()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, err := cache.Restore(entry); err != nil || ok {
+		t.Fatalf("expected no cached build, got %v, %v", ok, err)
+	}
+	if err := cache.Save(entry, "v1.30.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a stale output is replaced by the cached one
+	writeFile(t, filepath.Join(repo, "_output", "bin", "kubectl"), "stale")
+	version, ok, err := cache.Restore(entry)
+	if err != nil || !ok {
+		t.Fatalf("expected a cached build, got %v, %v", ok, err)
+	}
+	if version != "v1.30.0" {
+		t.Errorf("expected version v1.30.0 but got %q", version)
+	}
+	if content, err := os.ReadFile(filepath.Join(repo, "_output", "bin", "kubectl")); err != nil || string(content) != "kubectl" {
+		t.Errorf("expected the cached kubectl to be restored, got %q, %v", content, err)
+	}
+
+	// other options are other builds
+	other := *cache
+	other.Options = []string{"strategy=bazel"}
+	otherEntry, err := other.entry()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, err := other.Restore(otherEntry); err != nil || ok {
+		t.Errorf("expected no cached build for other options, got %v, %v", ok, err)
+	}
+}
+
+func TestHeadVersion(t *testing.T) {
+	repo := initRepo(t, true)
+	untagged, err := headVersion(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the same commit, tagged, is another version
+	cmd := exec.Command("git", "tag", "v1.30.0")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git tag failed: %v, output: %s", err, out)
+	}
+	tagged, err := headVersion(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tagged == untagged || !strings.HasPrefix(tagged, "v1.30.0-0-g") {
+		t.Errorf("expected HEAD to be described by the tag, got %q, untagged %q", tagged, untagged)
+	}
+}
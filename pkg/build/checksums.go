@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChecksumsFile is the name of the checksum manifest staged with the
+// artifacts, in the format of sha256sum
+const ChecksumsFile = "SHA256SUMS"
+
+// WriteChecksums writes a checksum manifest of files, a map of the names of
+// the files in the manifest to their local paths, to path.
+func WriteChecksums(path string, files map[string]string) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifest bytes.Buffer
+	for _, name := range names {
+		sum, err := sha256sum(files[name])
+		if err != nil {
+			return fmt.Errorf("failed to compute sha256 for %q: %v", files[name], err)
+		}
+		fmt.Fprintf(&manifest, "%s  %s\n", sum, name)
+	}
+	return os.WriteFile(path, manifest.Bytes(), 0644)
+}
+
+// writeDirChecksums writes a checksum manifest of the files in dir into dir.
+func writeDirChecksums(dir string) error {
+	files := map[string]string{}
+	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if name != ChecksumsFile {
+			files[filepath.ToSlash(name)] = path
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return WriteChecksums(filepath.Join(dir, ChecksumsFile), files)
+}
+
+// ParseChecksums parses a checksum manifest into a map of names to checksums.
+func ParseChecksums(manifest []byte) (map[string]string, error) {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum line: %q", line)
+		}
+		// sha256sum marks names of files read in binary mode with a *
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		checksums[name] = sum
+	}
+	return checksums, scanner.Err()
+}
+
+// VerifyChecksum verifies the file at path against the checksum of name in checksums.
+func VerifyChecksum(checksums map[string]string, name, path string) error {
+	expected, ok := checksums[name]
+	if !ok {
+		return fmt.Errorf("no checksum for %s in %s", name, ChecksumsFile)
+	}
+	actual, err := sha256sum(path)
+	if err != nil {
+		return fmt.Errorf("failed to compute sha256 for %q: %v", path, err)
+	}
+	if actual != expected {
+		return fmt.Errorf("sha256 of %s does not match %s: expected %s, got %s", path, ChecksumsFile, expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kubernetes.tar.gz":       filepath.Join(dir, "kubernetes.tar.gz"),
+		"bin/linux/amd64/kubectl": filepath.Join(dir, "kubectl"),
+	}
+	for _, path := range files {
+		if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifestPath := filepath.Join(dir, ChecksumsFile)
+	if err := WriteChecksums(manifestPath, files); err != nil {
+		t.Fatalf("unexpected error writing checksums: %v", err)
+	}
+	manifest, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksums, err := ParseChecksums(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error parsing checksums: %v", err)
+	}
+	if len(checksums) != len(files) {
+		t.Fatalf("expected %d checksums, got %v", len(files), checksums)
+	}
+	for name, path := range files {
+		if err := VerifyChecksum(checksums, name, path); err != nil {
+			t.Errorf("unexpected error verifying %s: %v", name, err)
+		}
+	}
+
+	// a truncated file
+	if err := os.WriteFile(files["kubernetes.tar.gz"], []byte("kuber"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(checksums, "kubernetes.tar.gz", files["kubernetes.tar.gz"]); err == nil {
+		t.Error("expected error verifying a truncated file but got none")
+	}
+	if err := VerifyChecksum(checksums, "missing", files["kubernetes.tar.gz"]); err == nil {
+		t.Error("expected error verifying a file missing from the manifest but got none")
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	checksums, err := ParseChecksums([]byte("abc  kubernetes.tar.gz\ndef *bin/kubectl\n\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"kubernetes.tar.gz": "abc", "bin/kubectl": "def"}
+	for name, sum := range expected {
+		if checksums[name] != sum {
+			t.Errorf("expected %s for %s, got %s", sum, name, checksums[name])
+		}
+	}
+	if _, err := ParseChecksums([]byte("invalid")); err == nil {
+		t.Error("expected error but got none")
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/kballard/go-shellquote"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// crossVersionFile holds the version of the kube-cross image used by the
+// kubernetes build container
+const crossVersionFile = "build/build-image/cross/VERSION"
+
+// Container runs build commands in a container with the repo root mounted at
+// the same path, so that the host only needs a container runtime instead of
+// the exact toolchain of the kubernetes version.
+type Container struct {
+	Image    string
+	RepoRoot string
+	// Mounts are host paths mounted read-only at the same path
+	Mounts []string
+	// DockerSocket mounts the docker socket of the host, for builds of images
+	DockerSocket bool
+}
+
+// DefaultBuildImage returns the kube-cross image the kubernetes build
+// container of the kubernetes version in repoRoot is based on.
+func DefaultBuildImage(repoRoot string) (string, error) {
+	version, err := os.ReadFile(filepath.Join(repoRoot, crossVersionFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the kube-cross version, set the build image: %v", err)
+	}
+	return "registry.k8s.io/build-image/kube-cross:" + strings.TrimSpace(string(version)), nil
+}
+
+// Command returns a command running name with args in the container with env.
+// The command runs as the current user, so that the outputs are owned by it.
+func (c *Container) Command(env []string, name string, args ...string) (exec.Cmd, error) {
+	runArgs := []string{"run", "--rm",
+		"-v", c.RepoRoot + ":" + c.RepoRoot,
+		"-w", c.RepoRoot,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		// the current user has no home in the image
+		"-e", "HOME=/tmp",
+	}
+	for _, m := range c.Mounts {
+		runArgs = append(runArgs, "-v", m+":"+m+":ro")
+	}
+	if c.DockerSocket {
+		info, err := os.Stat(dockerSocket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the docker socket to mount: %v", err)
+		}
+		runArgs = append(runArgs, "-v", dockerSocket+":"+dockerSocket)
+		// the current user needs the group of the socket to use it
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			runArgs = append(runArgs, "--group-add", fmt.Sprintf("%d", stat.Gid))
+		}
+	}
+	for _, e := range env {
+		runArgs = append(runArgs, "-e", e)
+	}
+	runArgs = append(runArgs, c.Image, name)
+	return exec.Command("docker", append(runArgs, args...)...), nil
+}
+
+// dockerSocket is the docker socket of the host
+const dockerSocket = "/var/run/docker.sock"
+
+// containerGoCacheProg returns the Go cache program goCacheProg with the
+// program resolved to an absolute path on the host, and that path, which is
+// mounted so that the container can run it. The program should be static, as
+// it runs with the libraries of the image.
+func containerGoCacheProg(goCacheProg string) (string, string, error) {
+	words, err := shellquote.Split(goCacheProg)
+	if err != nil || len(words) == 0 {
+		return "", "", fmt.Errorf("failed to parse the Go cache program %q: %v", goCacheProg, err)
+	}
+	path, err := osexec.LookPath(words[0])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find the Go cache program: %v", err)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", "", fmt.Errorf("failed to get the absolute path of the Go cache program: %v", err)
+	}
+	return shellquote.Join(append([]string{path}, words[1:]...)...), path, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// defaultUploadParallelism is the number of concurrent uploads by default
+	defaultUploadParallelism = 8
+	// uploadAttempts is the number of times an upload is attempted
+	uploadAttempts = 3
+)
+
+// uploadBackoff is the delay before the first retry of an upload, doubled for each retry
+var uploadBackoff = 5 * time.Second
+
+// uploadDir uploads the files of dir to the gs:// URL dest with parallelism
+// concurrent uploads, retrying failed uploads. gcloud storage verifies the
+// checksum of each upload and resumes interrupted uploads of large files
+// where they left off.
+func uploadDir(dir, dest string, parallelism int) error {
+	if parallelism <= 0 {
+		parallelism = defaultUploadParallelism
+	}
+	var files []string
+	if err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		files = append(files, p)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to list files to upload in %s: %v", dir, err)
+	}
+
+	klog.V(0).Infof("Uploading %d files from %s to %s ...", len(files), dir, dest)
+	sem := make(chan struct{}, parallelism)
+	errs := make(chan error, len(files))
+	var wg sync.WaitGroup
+	for _, file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(source, dest string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs <- uploadFile(source, dest)
+		}(file, dest+"/"+path.Clean(filepath.ToSlash(rel)))
+	}
+	wg.Wait()
+	close(errs)
+
+	var failed []error
+	for err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to upload %d of %d files to %s: %v", len(failed), len(files), dest, failed)
+	}
+	return nil
+}
+
+// uploadFile uploads source to dest, retrying with exponential backoff.
+func uploadFile(source, dest string) error {
+	backoff := uploadBackoff
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		klog.V(2).Infof("uploading %s to %s (attempt %d) ...", source, dest, attempt)
+		var out []string
+		out, err = exec.CombinedOutputLines(exec.Command("gcloud", "storage", "cp", source, dest))
+		if err == nil {
+			return nil
+		}
+		err = fmt.Errorf("failed to upload %s to %s: %v, output: %q", source, dest, err, out)
+		if attempt < uploadAttempts {
+			klog.Warningf("%v, retrying in %v", err, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+// KoImagesEnv is the environment variable the references of the images
+// published with ko are exported in for the tester.
+const KoImagesEnv = "KUBETEST2_KO_IMAGES"
+
+// Ko builds and publishes images of Go main packages with ko,
+// e.g. test images or operators under test.
+type Ko struct {
+	// ImportPaths are the Go import paths of the main packages
+	ImportPaths []string
+	// Dir is the Go module the import paths are built in
+	Dir string
+	// DockerRepo is the repository the images are published to
+	DockerRepo string
+	// Platforms to build the images for, defaults to the host platform
+	Platforms []string
+}
+
+// Publish builds and pushes the images and returns their references, keyed by import path.
+func (k *Ko) Publish() (map[string]string, error) {
+	refs := map[string]string{}
+	for _, importPath := range k.ImportPaths {
+		args := []string{"build", "--base-import-paths"}
+		if len(k.Platforms) > 0 {
+			args = append(args, "--platform="+strings.Join(k.Platforms, ","))
+		}
+		args = append(args, importPath)
+
+		klog.V(0).Infof("Publishing %s to %s with ko ...", importPath, k.DockerRepo)
+		cmd := exec.Command("ko", args...)
+		cmd.SetDir(k.Dir)
+		cmd.SetEnv(append(os.Environ(), "KO_DOCKER_REPO="+k.DockerRepo)...)
+		cmd.SetStderr(os.Stderr)
+		// ko prints the reference of the published image last
+		lines, err := exec.OutputLines(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish %s with ko: %v", importPath, err)
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("publishing %s with ko had no output", importPath)
+		}
+		refs[importPath] = lines[len(lines)-1]
+	}
+	return refs, nil
+}
+
+// exportKoImages exports the image references to the tester environment
+// and the metadata as a comma separated list of <import path>=<reference>.
+func exportKoImages(refs map[string]string) error {
+	var images []string
+	for importPath, ref := range refs {
+		images = append(images, importPath+"="+ref)
+	}
+	sort.Strings(images)
+	value := strings.Join(images, ",")
+	if err := os.Setenv(KoImagesEnv, value); err != nil {
+		return err
+	}
+	return metadata.AddToFile(filepath.Join(artifacts.BaseDir(), "metadata.json"), "ko-images", value)
+}
@@ -18,10 +18,13 @@ package build
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	rbuild "k8s.io/release/pkg/build"
+	"k8s.io/release/pkg/release"
 )
 
 type Krel struct {
@@ -30,12 +33,17 @@ type Krel struct {
 	RepoRoot        string
 	StageExtraFiles bool
 	UpdateLatest    bool
+	// UploadParallelism is the number of concurrent uploads to GCS
+	UploadParallelism int
 }
 
 var _ Stager = &Krel{}
 
-// Stage stages the build to GCS using
-// essentially release/push-build.sh --bucket=B --ci --gcs-suffix=S --noupdatelatest
+// Stage stages the build to GCS like
+// release/push-build.sh --bucket=B --ci --gcs-suffix=S --noupdatelatest
+// with krel staging the artifacts locally and pushing the images, but with
+// the artifacts uploaded concurrently and retried by kubetest2, as a single
+// rsync of a full release is slow and fails on transient errors.
 func (rpb *Krel) Stage(version string) error {
 	if !strings.HasPrefix(version, "v") {
 		version = "v" + version
@@ -46,9 +54,10 @@ func (rpb *Krel) Stage(version string) error {
 		return fmt.Errorf("invalid stage location: %v. Use gs://<bucket>/<ci|devel>/<optional-suffix>", rpb.StageLocation)
 	}
 
-	if err := rbuild.NewInstance(&rbuild.Options{
+	opts := &rbuild.Options{
 		Bucket:          mat[1],
 		GCSRoot:         mat[3],
+		BuildDir:        filepath.Join(rpb.RepoRoot, release.BuildDir),
 		AllowDup:        true,
 		CI:              mat[2] == "ci",
 		NoUpdateLatest:  !rpb.UpdateLatest,
@@ -56,9 +65,36 @@ func (rpb *Krel) Stage(version string) error {
 		Version:         version,
 		StageExtraFiles: rpb.StageExtraFiles,
 		RepoRoot:        rpb.RepoRoot,
-	}).Push(); err != nil {
+	}
+	// sets the defaults of opts, e.g. the GCS root
+	bi := rbuild.NewInstance(opts)
+	if err := bi.CheckReleaseBucket(); err != nil {
+		return fmt.Errorf("stage via krel: check release bucket access: %w", err)
+	}
+	if err := bi.StageLocalArtifacts(); err != nil {
+		return fmt.Errorf("stage via krel: staging local artifacts: %w", err)
+	}
+	if err := bi.PushContainerImages(); err != nil {
+		return fmt.Errorf("stage via krel: push container images: %w", err)
+	}
 
-		return fmt.Errorf("stage via krel push: %w", err)
+	stageDir := filepath.Join(opts.BuildDir, release.GCSStagePath, version)
+	if err := writeDirChecksums(stageDir); err != nil {
+		return fmt.Errorf("stage via krel: write checksums: %w", err)
+	}
+	dest := "gs://" + path.Join(opts.Bucket, opts.GCSRoot, version)
+	if err := uploadDir(stageDir, dest, rpb.UploadParallelism); err != nil {
+		return fmt.Errorf("stage via krel: push release artifacts: %w", err)
+	}
+
+	if !opts.CI || opts.NoUpdateLatest {
+		return nil
+	}
+	if err := release.NewPublisher().PublishVersion(
+		opts.BuildType, version, opts.BuildDir, opts.Bucket, opts.GCSRoot,
+		nil, false, false,
+	); err != nil {
+		return fmt.Errorf("stage via krel: publish version: %w", err)
 	}
 	return nil
 }
@@ -18,31 +18,65 @@ package build
 
 import (
 	"fmt"
+	"strings"
 
 	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
 	"sigs.k8s.io/kubetest2/pkg/exec"
 )
 
 type MakeBuilder struct {
-	RepoRoot        string
+	RepoRoot string
+	// TargetBuildArch are the comma or space separated platforms to build,
+	// windows platforms build the windows node binaries
 	TargetBuildArch string
+	GoCacheProg     string
+	// Targets are the components to build instead of the quick-release,
+	// see BuildTargetPath
+	Targets []string
+	// Container runs the build in a container instead of the kubernetes
+	// build container, if set
+	Container *Container
 }
 
 var _ Builder = &MakeBuilder{}
 
 const (
 	target = "quick-release"
+	// containerTarget builds the release when already in a container
+	containerTarget = "release-in-a-container"
+	// dockerizedOutput is where the kubernetes build container leaves the outputs
+	dockerizedOutput = "_output/dockerized"
 )
 
-// Build builds kubernetes with the quick-release make target
+// Build builds kubernetes with the quick-release make target,
+// or only the selected targets in the build container
 func (m *MakeBuilder) Build() (string, error) {
 	version, err := sourceVersion(m.RepoRoot)
 	if err != nil {
 		return "", fmt.Errorf("failed to get version: %v", err)
 	}
-	cmd := exec.Command("make", target,
-		fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", m.TargetBuildArch))
-	klog.Infof("running build %s using: KUBE_BUILD_PLATFORMS=%s", target, m.TargetBuildArch)
+	// the build scripts expect space separated platforms
+	platforms := strings.Join(Platforms(m.TargetBuildArch), " ")
+	var cmd exec.Cmd
+	if m.Container != nil {
+		if cmd, err = m.containerCommand(platforms); err != nil {
+			return "", err
+		}
+	} else if m.GoCacheProg != "" {
+		// the kubernetes build container does not forward GOCACHEPROG
+		return "", fmt.Errorf("the Go cache program is only supported when building in a container, set --build-in-container")
+	} else if m.selective() {
+		// like quick-release, build/run.sh leaves the binaries in _output/dockerized
+		cmd = exec.Command("build/run.sh", "make", "all",
+			fmt.Sprintf("WHAT=%s", m.what()),
+			fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", platforms))
+		klog.Infof("running build of %v using: KUBE_BUILD_PLATFORMS=%s", m.Targets, platforms)
+	} else {
+		cmd = exec.Command("make", target,
+			fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", platforms))
+		klog.Infof("running build %s using: KUBE_BUILD_PLATFORMS=%s", target, platforms)
+	}
 	cmd.SetDir(m.RepoRoot)
 	setSourceDateEpoch(m.RepoRoot, cmd)
 	exec.InheritOutput(cmd)
@@ -51,3 +85,67 @@ func (m *MakeBuilder) Build() (string, error) {
 	}
 	return version, nil
 }
+
+// containerCommand returns the command building in m.Container, which runs
+// make directly instead of in the kubernetes build container. The outputs
+// are left where the kubernetes build container leaves them.
+func (m *MakeBuilder) containerCommand(platforms string) (exec.Cmd, error) {
+	container := *m.Container
+	env := sourceDateEpochEnv(m.RepoRoot)
+	if m.GoCacheProg != "" {
+		goCacheProg, path, err := containerGoCacheProg(m.GoCacheProg)
+		if err != nil {
+			return nil, err
+		}
+		container.Mounts = append(container.Mounts, path)
+		env = append(env, GoCacheProgEnv(goCacheProg)...)
+	}
+	env = append(env, gitVersionEnvs()...)
+	env = append(env, goExperimentEnv()...)
+	env = append(env, "KUBE_OUTPUT_SUBPATH="+dockerizedOutput)
+	if m.selective() {
+		klog.Infof("running build of %v in %s using: KUBE_BUILD_PLATFORMS=%s", m.Targets, container.Image, platforms)
+		return container.Command(env, "make", "all",
+			fmt.Sprintf("WHAT=%s", m.what()),
+			fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", platforms))
+	}
+	// the release builds the component images with docker
+	container.DockerSocket = true
+	klog.Infof("running build %s in %s using: KUBE_BUILD_PLATFORMS=%s", containerTarget, container.Image, platforms)
+	return container.Command(env, "make", containerTarget,
+		fmt.Sprintf("KUBE_BUILD_PLATFORMS=%s", platforms))
+}
+
+// selective returns true if only the selected targets are built
+func (m *MakeBuilder) selective() bool {
+	return len(m.Targets) > 0 && !slices.Contains(m.Targets, ReleaseTarsTarget)
+}
+
+// what returns the WHAT to build the selected targets with
+func (m *MakeBuilder) what() string {
+	var what []string
+	for _, t := range m.Targets {
+		what = append(what, BuildTargetPath(t))
+	}
+	return strings.Join(what, " ")
+}
+
+// ReleaseTarsTarget is the build target for the full quick-release,
+// which includes the release tars
+const ReleaseTarsTarget = "release-tars"
+
+// buildTargetPaths are the package paths of the build targets outside of cmd/
+var buildTargetPaths = map[string]string{
+	"e2e.test":      "test/e2e/e2e.test",
+	"e2e_node.test": "test/e2e_node/e2e_node.test",
+	"ginkgo":        "vendor/github.com/onsi/ginkgo/v2/ginkgo",
+}
+
+// BuildTargetPath returns the package path of a build target in the
+// kubernetes repository, e.g. cmd/kubelet for kubelet
+func BuildTargetPath(target string) string {
+	if p, ok := buildTargetPaths[target]; ok {
+		return p
+	}
+	return "cmd/" + target
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// ociStagePrefix selects the OCI stager for a stage location
+	ociStagePrefix = "oci://"
+
+	ociArtifactType        = "application/vnd.kubernetes.release.v1"
+	ociReleaseTarMediaType = "application/vnd.kubernetes.release.tar.v1+gzip"
+	ociBinaryMediaType     = "application/vnd.kubernetes.binary.v1"
+)
+
+// OCI stages the release tars and test binaries as an OCI artifact with oras,
+// e.g. for clusters that can pull from a registry but not from GCS.
+// The files keep their paths relative to the repo root, so that
+// "oras pull" restores the layout of a local build.
+type OCI struct {
+	RepoRoot string
+	// StageLocation is the repository to push to, as oci://<registry>/<repository>
+	StageLocation string
+	// Platforms are the platforms built, whose test binaries and windows node
+	// binaries are pushed too
+	Platforms []string
+}
+
+var _ Stager = &OCI{}
+
+// Stage pushes the build outputs as <repository>:<version>
+func (o *OCI) Stage(version string) error {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	// image tags can't contain +
+	ref := strings.TrimPrefix(o.StageLocation, ociStagePrefix) + ":" + strings.ReplaceAll(version, "+", "_")
+
+	files, err := o.files()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("found no build outputs to stage in %s", o.RepoRoot)
+	}
+
+	klog.V(0).Infof("Staging builds to %s ...", ref)
+	cmd := exec.Command("oras", append([]string{"push", "--artifact-type", ociArtifactType, ref}, files...)...)
+	cmd.SetDir(o.RepoRoot)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to stage builds to %s: %v", ref, err)
+	}
+	return nil
+}
+
+// files returns the build outputs to push as oras file references
+// relative to the repo root, with their media types.
+func (o *OCI) files() ([]string, error) {
+	var files []string
+	tars, err := filepath.Glob(filepath.Join(o.RepoRoot, releaseTarsOutput, "*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+	for _, tar := range tars {
+		files = append(files, filepath.Join(releaseTarsOutput, filepath.Base(tar))+":"+ociReleaseTarMediaType)
+	}
+	for _, platform := range testBinaryPlatforms(o.Platforms) {
+		for _, binary := range CommonTestBinaries {
+			path := filepath.Join(binariesOutput, platform, binary)
+			if _, err := os.Stat(filepath.Join(o.RepoRoot, path)); err != nil {
+				klog.Warningf("could not find %s: %v", path, err)
+				continue
+			}
+			files = append(files, path+":"+ociBinaryMediaType)
+		}
+	}
+	for _, platform := range WindowsPlatforms(o.Platforms) {
+		for _, binary := range WindowsNodeBinaries {
+			files = append(files, filepath.Join(binariesOutput, platform, binary)+":"+ociBinaryMediaType)
+		}
+	}
+	return files, nil
+}
@@ -18,6 +18,15 @@ package build
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/strings/slices"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
 )
 
 // ignore package name stutter
@@ -28,49 +37,344 @@ const (
 	bazelStrategy BuildAndStageStrategy = "bazel"
 	// MakeStrategy builds using make and (optionally) stages using krel
 	MakeStrategy BuildAndStageStrategy = "make"
+	// BuildxStrategy builds for multiple platforms using make, with images
+	// built by docker buildx, and (optionally) stages multi-arch images
+	BuildxStrategy BuildAndStageStrategy = "buildx"
 )
 
 type Options struct {
-	Strategy           string `flag:"~strategy" desc:"Determines the build strategy to use either make or bazel."`
-	StageLocation      string `flag:"~stage" desc:"Upload binaries to gs://bucket/ci/job-suffix if set"`
-	RepoRoot           string `flag:"-"`
-	ImageLocation      string `flag:"~image-location" desc:"Image registry where built images are stored."`
-	StageExtraGCPFiles bool   `flag:"-"`
-	VersionSuffix      string `flag:"-"`
-	UpdateLatest       bool   `flag:"~update-latest" desc:"Whether should upload the build number to the GCS"`
-	TargetBuildArch    string `flag:"~target-build-arch" desc:"Target architecture for the test artifacts for dockerized build"`
+	Strategy           string   `flag:"~strategy" desc:"Determines the build strategy to use either make, bazel or buildx."`
+	StageLocation      string   `flag:"~stage" desc:"Upload binaries to gs://bucket/ci/job-suffix or s3://bucket/prefix, or push them as an OCI artifact to oci://registry/repository, if set"`
+	StageParallelism   int      `flag:"~stage-parallelism" desc:"Number of concurrent uploads when staging to gs:// locations, defaults to 8."`
+	StageEndpoint      string   `flag:"~stage-endpoint" desc:"Endpoint to use for s3:// stage locations instead of AWS S3, e.g. a MinIO server."`
+	RepoRoot           string   `flag:"-"`
+	ImageLocation      string   `flag:"~image-location" desc:"Image registry where built images are stored."`
+	StageExtraGCPFiles bool     `flag:"-"`
+	VersionSuffix      string   `flag:"-"`
+	UpdateLatest       bool     `flag:"~update-latest" desc:"Whether should upload the build number to the GCS"`
+	TargetBuildArch    string   `flag:"~target-build-arch" desc:"Target architecture for the test artifacts for dockerized build. Comma separated platforms build several, e.g. linux/amd64,windows/amd64 for the windows node binaries"`
+	ImagePlatforms     []string `flag:"~image-platforms" desc:"Platforms to build and stage multi-arch images for with the buildx strategy, defaults to linux/amd64,linux/arm64."`
+	BazelRemoteCache   string   `flag:"~bazel-remote-cache" desc:"Remote cache endpoint for bazel builds, e.g. grpcs://cache.example.com, passed as --remote_cache."`
+	GoCacheProg        string   `flag:"~go-cache-prog" desc:"Go cache program for make builds with --build-in-container, e.g. a client of a remote build cache, passed as GOCACHEPROG."`
+	BuildTargets       []string `flag:"~build-targets" desc:"Components to build instead of the full quick-release with the make strategy, e.g. kubelet,kubectl,e2e.test. release-tars builds the full release."`
+	// RequiredBuildTargets are the build targets the deployer needs, which
+	// --build-targets must include
+	RequiredBuildTargets []string `flag:"-"`
+	Packages             []string `flag:"~packages" desc:"Package formats to package the built kubeadm, kubelet and kubectl as, deb and/or rpm. The packages are left in _output/packages/<format> of the repo root."`
+	GenerateSBOM         bool     `flag:"~generate-sbom" desc:"Generate an SPDX SBOM of the build outputs into build/sbom.spdx of the artifacts."`
+	GenerateProvenance   bool     `flag:"~generate-provenance" desc:"Generate a SLSA provenance attestation of the build outputs into build/provenance.intoto.json of the artifacts."`
+	KoImages             []string `flag:"~ko-image" desc:"Go import path of a main package to build and publish with ko after the build, can be repeated. The image references are exported to the tester in KUBETEST2_KO_IMAGES."`
+	KoDir                string   `flag:"~ko-dir" desc:"Directory of the Go module the --ko-image import paths are built in, defaults to the current directory."`
+	KoDockerRepo         string   `flag:"~ko-docker-repo" desc:"Repository to publish the --ko-image images to, defaults to --image-location."`
+	Version              string   `flag:"~build-version" desc:"Version to embed into the build instead of the one of the repo, e.g. v1.31.0-myfeature.1. A version starting with - or + is appended to the one of the repo, e.g. -myfeature.1."`
+	BuildCacheDir        string   `flag:"~build-cache-dir" desc:"Directory to cache the build outputs in, keyed by the repo tree and build flags. Builds of a tree already in the cache are skipped and the outputs restored instead."`
+	BuildInContainer     bool     `flag:"~build-in-container" desc:"Run the make build strategy in --build-image with docker, so that the host does not need the kubernetes toolchain."`
+	BuildImage           string   `flag:"~build-image" desc:"Image to build in with --build-in-container, defaults to the kube-cross image of the kubernetes version."`
 	Builder
 	Stager
 }
 
 func (o *Options) Validate() error {
+	platforms := Platforms(o.TargetBuildArch)
+	if len(WindowsPlatforms(platforms)) > 0 && len(WindowsPlatforms(platforms)) == len(platforms) {
+		return fmt.Errorf("--target-build-arch %q must include a linux platform for the control plane", o.TargetBuildArch)
+	}
+	if err := o.validateBuildTargets(); err != nil {
+		return err
+	}
+	for _, format := range o.Packages {
+		if !slices.Contains(PackageFormats, format) {
+			return fmt.Errorf("--packages must be one of %v, got %q", PackageFormats, format)
+		}
+	}
+	if len(o.Packages) > 0 && len(o.BuildTargets) > 0 && !slices.Contains(o.BuildTargets, ReleaseTarsTarget) {
+		for _, binary := range PackagedBinaries {
+			if !slices.Contains(o.BuildTargets, binary) {
+				return fmt.Errorf("--packages requires --build-targets to include %v", PackagedBinaries)
+			}
+		}
+	}
+	if o.BuildInContainer && BuildAndStageStrategy(o.Strategy) != MakeStrategy {
+		return fmt.Errorf("--build-in-container is not supported with the %s build strategy", o.Strategy)
+	}
+	if o.GoCacheProg != "" && BuildAndStageStrategy(o.Strategy) == BuildxStrategy {
+		return fmt.Errorf("--go-cache-prog is not supported with the %s build strategy, which builds in the kubernetes build container", o.Strategy)
+	}
+	if o.BuildCacheDir != "" && BuildAndStageStrategy(o.Strategy) == bazelStrategy {
+		return fmt.Errorf("--build-cache-dir is not supported with the %s build strategy, use --bazel-remote-cache", o.Strategy)
+	}
+	for _, prefix := range []string{ociStagePrefix, s3StagePrefix} {
+		if strings.HasPrefix(o.StageLocation, prefix) && BuildAndStageStrategy(o.Strategy) == bazelStrategy {
+			return fmt.Errorf("--stage %s is not supported with the %s build strategy, which stages to GCS", prefix, o.Strategy)
+		}
+	}
+	if o.BuildImage != "" && !o.BuildInContainer {
+		return fmt.Errorf("--build-image requires --build-in-container")
+	}
+	if len(o.KoImages) > 0 && o.koDockerRepo() == "" {
+		return fmt.Errorf("--ko-image requires --ko-docker-repo or --image-location")
+	}
 	return o.implementationFromStrategy()
 }
 
+func (o *Options) validateBuildTargets() error {
+	if len(o.BuildTargets) == 0 || slices.Contains(o.BuildTargets, ReleaseTarsTarget) {
+		return nil
+	}
+	switch BuildAndStageStrategy(o.Strategy) {
+	case MakeStrategy, BuildxStrategy:
+	default:
+		return fmt.Errorf("--build-targets is not supported with the %s build strategy", o.Strategy)
+	}
+	if o.StageLocation != "" {
+		return fmt.Errorf("--stage requires the %s build target", ReleaseTarsTarget)
+	}
+	var missing []string
+	for _, t := range o.RequiredBuildTargets {
+		if !slices.Contains(o.BuildTargets, t) {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("--build-targets must include %v, required by the deployer", missing)
+	}
+	return nil
+}
+
+// Build builds with the builder of the strategy, then makes the packages,
+// publishes the ko images and generates the attestations if requested.
+func (o *Options) Build() (string, error) {
+	startedOn := time.Now()
+	if o.Version != "" {
+		version, err := overrideVersion(o.RepoRoot, o.Version)
+		if err != nil {
+			return "", err
+		}
+		klog.V(2).Infof("overriding build version with %s", version)
+	}
+	version, err := o.build()
+	if err != nil {
+		return "", err
+	}
+	if len(o.Packages) > 0 {
+		packager := &Packager{
+			RepoRoot:  o.RepoRoot,
+			Formats:   o.Packages,
+			Platforms: Platforms(o.TargetBuildArch),
+		}
+		if err := packager.Package(version); err != nil {
+			return "", err
+		}
+	}
+	var images map[string]string
+	if len(o.KoImages) > 0 {
+		ko := &Ko{
+			ImportPaths: o.KoImages,
+			Dir:         o.KoDir,
+			DockerRepo:  o.koDockerRepo(),
+			Platforms:   o.ImagePlatforms,
+		}
+		if images, err = ko.Publish(); err != nil {
+			return "", err
+		}
+		if err := exportKoImages(images); err != nil {
+			return "", err
+		}
+	}
+	if o.GenerateSBOM || o.GenerateProvenance {
+		attestor := &Attestor{
+			RepoRoot:        o.RepoRoot,
+			Strategy:        o.Strategy,
+			TargetBuildArch: o.TargetBuildArch,
+			Version:         version,
+			StartedOn:       startedOn,
+			Images:          images,
+			OutputDir:       filepath.Join(artifacts.BaseDir(), "build"),
+		}
+		if o.GenerateSBOM {
+			if err := attestor.GenerateSBOM(); err != nil {
+				return "", err
+			}
+		}
+		if o.GenerateProvenance {
+			if err := attestor.GenerateProvenance(); err != nil {
+				return "", err
+			}
+		}
+	}
+	return version, nil
+}
+
+func (o *Options) koDockerRepo() string {
+	if o.KoDockerRepo != "" {
+		return o.KoDockerRepo
+	}
+	return o.ImageLocation
+}
+
+// build builds with the Builder, unless the build is cached
+func (o *Options) build() (string, error) {
+	if o.BuildCacheDir == "" {
+		return o.Builder.Build()
+	}
+	cache, err := o.buildCache()
+	if err != nil {
+		return "", err
+	}
+	// keyed by the tree before the build changes it
+	entry, err := cache.entry()
+	if err != nil {
+		return "", err
+	}
+	version, ok, err := cache.Restore(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to restore cached build: %v", err)
+	}
+	if ok {
+		return version, nil
+	}
+	if version, err = o.Builder.Build(); err != nil {
+		return "", err
+	}
+	if err := cache.Save(entry, version); err != nil {
+		// the build itself succeeded
+		klog.Warningf("failed to cache build: %v", err)
+	}
+	return version, nil
+}
+
+// buildCache returns the cache of the outputs of the Builder
+func (o *Options) buildCache() (*BuildCache, error) {
+	head, err := headVersion(o.RepoRoot)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []string{binariesOutput}
+	if len(o.BuildTargets) == 0 || slices.Contains(o.BuildTargets, ReleaseTarsTarget) {
+		outputs = append(outputs, releaseTarsOutput)
+	}
+	if BuildAndStageStrategy(o.Strategy) == BuildxStrategy {
+		outputs = append(outputs, releaseImagesOutput)
+	}
+	return &BuildCache{
+		Dir:      o.BuildCacheDir,
+		RepoRoot: o.RepoRoot,
+		Options: []string{
+			"strategy=" + o.Strategy,
+			"target-build-arch=" + o.TargetBuildArch,
+			"build-targets=" + strings.Join(o.BuildTargets, ","),
+			"image-platforms=" + strings.Join(o.ImagePlatforms, ","),
+			"image-location=" + o.ImageLocation,
+			"build-image=" + o.BuildImage,
+			// e.g. boringcrypto with --fips
+			"goexperiment=" + os.Getenv("GOEXPERIMENT"),
+			// the resolved --build-version
+			"version=" + os.Getenv(gitVersionEnv),
+			// otherwise the version of the build, from the tags
+			"head=" + head,
+		},
+		Outputs: outputs,
+	}, nil
+}
+
+// buildContainer returns the container to build in, if --build-in-container is set
+func (o *Options) buildContainer() (*Container, error) {
+	if !o.BuildInContainer {
+		return nil, nil
+	}
+	image := o.BuildImage
+	if image == "" {
+		var err error
+		if image, err = DefaultBuildImage(o.RepoRoot); err != nil {
+			return nil, err
+		}
+	}
+	// docker only mounts absolute paths
+	repoRoot, err := filepath.Abs(o.RepoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the absolute path of the repo root: %v", err)
+	}
+	return &Container{Image: image, RepoRoot: repoRoot}, nil
+}
+
 func (o *Options) implementationFromStrategy() error {
+	// the platforms built, whose test binaries are staged
+	platforms := Platforms(o.TargetBuildArch)
 	switch BuildAndStageStrategy(o.Strategy) {
 	case bazelStrategy:
 		bazel := &Bazel{
 			RepoRoot:      o.RepoRoot,
 			StageLocation: o.StageLocation,
 			ImageLocation: o.ImageLocation,
+			RemoteCache:   o.BazelRemoteCache,
 		}
 		o.Builder = bazel
 		o.Stager = bazel
 	case MakeStrategy:
+		container, err := o.buildContainer()
+		if err != nil {
+			return err
+		}
 		o.Builder = &MakeBuilder{
 			RepoRoot:        o.RepoRoot,
 			TargetBuildArch: o.TargetBuildArch,
+			GoCacheProg:     o.GoCacheProg,
+			Targets:         o.BuildTargets,
+			Container:       container,
 		}
 		o.Stager = &Krel{
-			RepoRoot:        o.RepoRoot,
-			StageLocation:   o.StageLocation,
-			ImageLocation:   o.ImageLocation,
-			StageExtraFiles: o.StageExtraGCPFiles,
-			UpdateLatest:    o.UpdateLatest,
+			RepoRoot:          o.RepoRoot,
+			StageLocation:     o.StageLocation,
+			ImageLocation:     o.ImageLocation,
+			StageExtraFiles:   o.StageExtraGCPFiles,
+			UpdateLatest:      o.UpdateLatest,
+			UploadParallelism: o.StageParallelism,
+		}
+	case BuildxStrategy:
+		platforms = o.ImagePlatforms
+		if len(platforms) == 0 {
+			platforms = DefaultImagePlatforms
 		}
+		buildx := &Buildx{
+			RepoRoot:      o.RepoRoot,
+			ImageLocation: o.ImageLocation,
+			Platforms:     platforms,
+			Targets:       o.BuildTargets,
+		}
+		o.Builder = buildx
+		o.Stager = buildx
 	default:
 		return fmt.Errorf("unknown build strategy: %v", o.Strategy)
 	}
+	if stager := o.locationStager(platforms); stager != nil {
+		if BuildAndStageStrategy(o.Strategy) == BuildxStrategy {
+			// the multi-arch images are still pushed to the image location
+			o.Stager = MultiStager{stager, o.Stager}
+		} else {
+			o.Stager = stager
+		}
+	}
+	return nil
+}
+
+// locationStager returns the stager of the release tars and test binaries of
+// platforms selected by the scheme of the stage location, if not GCS
+func (o *Options) locationStager(platforms []string) Stager {
+	switch {
+	case strings.HasPrefix(o.StageLocation, ociStagePrefix):
+		return &OCI{
+			RepoRoot:      o.RepoRoot,
+			Platforms:     platforms,
+			StageLocation: o.StageLocation,
+		}
+	case strings.HasPrefix(o.StageLocation, s3StagePrefix):
+		return &S3{
+			RepoRoot:      o.RepoRoot,
+			Platforms:     platforms,
+			StageLocation: o.StageLocation,
+			Endpoint:      o.StageEndpoint,
+			UpdateLatest:  o.UpdateLatest,
+		}
+	}
 	return nil
 }
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateStager(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       Options
+		expected      Stager
+		expectedError string
+	}{
+		{
+			name: "buildx staged to s3",
+			options: Options{
+				Strategy:      "buildx",
+				StageLocation: "s3://bucket/ci",
+				ImageLocation: "registry.example.com/k8s",
+			},
+			// the images are still staged
+			expected: MultiStager{
+				&S3{StageLocation: "s3://bucket/ci", Platforms: DefaultImagePlatforms},
+				&Buildx{ImageLocation: "registry.example.com/k8s", Platforms: DefaultImagePlatforms},
+			},
+		},
+		{
+			name: "buildx staged to oci",
+			options: Options{
+				Strategy:       "buildx",
+				StageLocation:  "oci://registry.example.com/k8s/release",
+				ImageLocation:  "registry.example.com/k8s",
+				ImagePlatforms: []string{"linux/arm64"},
+			},
+			expected: MultiStager{
+				&OCI{StageLocation: "oci://registry.example.com/k8s/release", Platforms: []string{"linux/arm64"}},
+				&Buildx{ImageLocation: "registry.example.com/k8s", Platforms: []string{"linux/arm64"}},
+			},
+		},
+		{
+			name: "make staged to s3",
+			options: Options{
+				Strategy:        "make",
+				StageLocation:   "s3://bucket/ci",
+				TargetBuildArch: "linux/arm64",
+			},
+			expected: &S3{StageLocation: "s3://bucket/ci", Platforms: []string{"linux/arm64"}},
+		},
+		{
+			name: "bazel staged to s3",
+			options: Options{
+				Strategy:      "bazel",
+				StageLocation: "s3://bucket/ci",
+			},
+			expectedError: "not supported with the bazel build strategy",
+		},
+		{
+			name: "bazel staged to oci",
+			options: Options{
+				Strategy:      "bazel",
+				StageLocation: "oci://registry.example.com/k8s/release",
+			},
+			expectedError: "not supported with the bazel build strategy",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := tc.options
+			err := o.Validate()
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected an error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(o.Stager, tc.expected) {
+				t.Errorf("expected stager %#v, got %#v", tc.expected, o.Stager)
+			}
+		})
+	}
+}
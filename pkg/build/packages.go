@@ -0,0 +1,187 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// PackagesOutput is where the packages are left, in a directory per format
+const PackagesOutput = "_output/packages"
+
+// PackageFormats are the supported package formats
+var PackageFormats = []string{"deb", "rpm"}
+
+// PackagedBinaries are the binaries packaged, each in a package of the same name
+var PackagedBinaries = []string{"kubeadm", "kubelet", "kubectl"}
+
+const kubeletService = `[Unit]
+Description=kubelet: The Kubernetes Node Agent
+Documentation=https://kubernetes.io/docs/
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=/usr/bin/kubelet
+Restart=always
+StartLimitInterval=0
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`
+
+const kubeadmKubeletDropIn = `# Note: This dropin only works with kubeadm and kubelet v1.11+
+[Service]
+Environment="KUBELET_KUBECONFIG_ARGS=--bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubelet.conf --kubeconfig=/etc/kubernetes/kubelet.conf"
+Environment="KUBELET_CONFIG_ARGS=--config=/var/lib/kubelet/config.yaml"
+# This is a file that "kubeadm init" and "kubeadm join" generates at runtime, populating the KUBELET_KUBEADM_ARGS variable dynamically
+EnvironmentFile=-/var/lib/kubelet/kubeadm-flags.env
+# This is a file that the user can use for overrides of the kubelet args as a last resort. Preferably, the user should use
+# the .NodeRegistration.KubeletExtraArgs object in the configuration files instead. KUBELET_EXTRA_ARGS should be sourced from this file.
+EnvironmentFile=-/etc/default/kubelet
+ExecStart=
+ExecStart=/usr/bin/kubelet $KUBELET_KUBECONFIG_ARGS $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS $KUBELET_EXTRA_ARGS
+`
+
+// Packager packages the built kubeadm, kubelet and kubectl binaries
+// as deb and rpm packages with nfpm, so that they can be installed the same
+// way as released packages.
+type Packager struct {
+	RepoRoot string
+	Formats  []string
+	// Platforms are the platforms built, packages are made for the linux ones.
+	// Defaults to the host platform, like the build.
+	Platforms []string
+}
+
+// nfpmContent is a file of an nfpm package
+type nfpmContent struct {
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+	Type string `json:"type,omitempty"`
+}
+
+// nfpmConfig is the subset of the nfpm configuration used for the packages,
+// written as JSON as nfpm accepts any YAML
+type nfpmConfig struct {
+	Name        string        `json:"name"`
+	Arch        string        `json:"arch"`
+	Platform    string        `json:"platform"`
+	Version     string        `json:"version"`
+	Maintainer  string        `json:"maintainer"`
+	Description string        `json:"description"`
+	Homepage    string        `json:"homepage"`
+	License     string        `json:"license"`
+	Depends     []string      `json:"depends,omitempty"`
+	Contents    []nfpmContent `json:"contents"`
+}
+
+// Package packages the binaries of version into PackagesOutput/<format>.
+func (p *Packager) Package(version string) error {
+	workDir, err := os.MkdirTemp("", "kubetest2-packages")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	for name, content := range map[string]string{
+		"kubelet.service": kubeletService,
+		"10-kubeadm.conf": kubeadmKubeletDropIn,
+	} {
+		if err := os.WriteFile(filepath.Join(workDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	platforms := p.Platforms
+	if len(platforms) == 0 {
+		platforms = []string{runtime.GOOS + "/" + runtime.GOARCH}
+	}
+	for _, platform := range platforms {
+		goos, arch, _ := strings.Cut(platform, "/")
+		if goos != "linux" {
+			continue
+		}
+		for _, binary := range PackagedBinaries {
+			config := p.packageConfig(binary, arch, version, workDir)
+			configPath := filepath.Join(workDir, fmt.Sprintf("%s-%s.json", binary, arch))
+			raw, err := json.Marshal(config)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(configPath, raw, 0644); err != nil {
+				return err
+			}
+			for _, format := range p.Formats {
+				target := filepath.Join(p.RepoRoot, PackagesOutput, format)
+				if err := os.MkdirAll(target, os.ModePerm); err != nil {
+					return err
+				}
+				klog.V(0).Infof("Packaging %s %s for %s as %s ...", binary, version, platform, format)
+				cmd := exec.Command("nfpm", "package", "--config", configPath, "--packager", format, "--target", target)
+				exec.InheritOutput(cmd)
+				if err := cmd.Run(); err != nil {
+					return fmt.Errorf("failed to package %s for %s as %s: %v", binary, platform, format, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// packageConfig returns the nfpm configuration of the package of binary.
+func (p *Packager) packageConfig(binary, arch, version, workDir string) *nfpmConfig {
+	config := &nfpmConfig{
+		Name:        binary,
+		Arch:        arch,
+		Platform:    "linux",
+		Version:     strings.TrimPrefix(version, "v"),
+		Maintainer:  "Kubernetes Authors <dev@kubernetes.io>",
+		Description: binary + " built from source by kubetest2",
+		Homepage:    "https://kubernetes.io",
+		License:     "Apache-2.0",
+		Contents: []nfpmContent{{
+			Src: filepath.Join(p.RepoRoot, binariesOutput, "linux", arch, binary),
+			Dst: "/usr/bin/" + binary,
+		}},
+	}
+	switch binary {
+	case "kubelet":
+		config.Contents = append(config.Contents, nfpmContent{
+			Src:  filepath.Join(workDir, "kubelet.service"),
+			Dst:  "/lib/systemd/system/kubelet.service",
+			Type: "config",
+		})
+	case "kubeadm":
+		config.Depends = []string{"kubelet", "kubectl"}
+		config.Contents = append(config.Contents, nfpmContent{
+			Src:  filepath.Join(workDir, "10-kubeadm.conf"),
+			Dst:  "/usr/lib/systemd/system/kubelet.service.d/10-kubeadm.conf",
+			Type: "config",
+		})
+	}
+	return config
+}
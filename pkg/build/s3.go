@@ -0,0 +1,136 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// s3StagePrefix selects the S3 stager for a stage location
+const s3StagePrefix = "s3://"
+
+// S3 stages the release tars and test binaries to S3 with the aws CLI,
+// with the same layout as the GCS staging:
+// <location>/v<version>/kubernetes*.tar.gz and <location>/v<version>/bin/<os>/<arch>/<binary>,
+// for the host and each linux platform built, including the windows node
+// binaries of windows platforms, and a SHA256SUMS manifest of them to verify
+// downloads against.
+type S3 struct {
+	RepoRoot string
+	// StageLocation is where to stage to, as s3://<bucket>/<prefix>
+	StageLocation string
+	// Endpoint overrides the S3 endpoint, e.g. for MinIO
+	Endpoint     string
+	UpdateLatest bool
+	// Platforms are the platforms built, whose test binaries and windows node
+	// binaries are staged too
+	Platforms []string
+}
+
+var _ Stager = &S3{}
+
+func (s *S3) Stage(version string) error {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	location := strings.TrimSuffix(s.StageLocation, "/")
+	klog.V(0).Infof("Staging builds to %s/%s ...", location, version)
+
+	// the files to stage, keyed by their path relative to the version
+	files := map[string]string{}
+	tars, err := filepath.Glob(filepath.Join(s.RepoRoot, releaseTarsOutput, "*.tar.gz"))
+	if err != nil {
+		return err
+	}
+	if len(tars) == 0 {
+		return fmt.Errorf("found no release tars to stage in %s", filepath.Join(s.RepoRoot, releaseTarsOutput))
+	}
+	for _, tar := range tars {
+		files[filepath.Base(tar)] = tar
+	}
+	for _, platform := range testBinaryPlatforms(s.Platforms) {
+		for _, binary := range CommonTestBinaries {
+			source := filepath.Join(s.RepoRoot, binariesOutput, platform, binary)
+			if _, err := os.Stat(source); err != nil {
+				klog.Warningf("could not find %s: %v", source, err)
+				continue
+			}
+			files[path.Join("bin", platform, binary)] = source
+		}
+	}
+	for _, platform := range WindowsPlatforms(s.Platforms) {
+		for _, binary := range WindowsNodeBinaries {
+			files[path.Join("bin", platform, binary)] = filepath.Join(s.RepoRoot, binariesOutput, platform, binary)
+		}
+	}
+
+	for name, source := range files {
+		if err := s.copy(source, path.Join(location, version, name)); err != nil {
+			return err
+		}
+	}
+	// staged last, so that a manifest only exists for complete stagings
+	checksums := filepath.Join(s.RepoRoot, releaseTarsOutput, ChecksumsFile)
+	if err := WriteChecksums(checksums, files); err != nil {
+		return err
+	}
+	if err := s.copy(checksums, path.Join(location, version, ChecksumsFile)); err != nil {
+		return err
+	}
+
+	if s.UpdateLatest {
+		marker, err := os.CreateTemp("", "latest")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(marker.Name())
+		if _, err := marker.WriteString(version); err != nil {
+			marker.Close()
+			return err
+		}
+		if err := marker.Close(); err != nil {
+			return err
+		}
+		if err := s.copy(marker.Name(), path.Join(location, "latest.txt")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copy uploads source to the s3:// URL dest.
+func (s *S3) copy(source, dest string) error {
+	args := []string{"s3", "cp", source, dest}
+	if s.Endpoint != "" {
+		args = append(args, "--endpoint-url", s.Endpoint)
+	}
+	klog.V(2).Infof("copying %s to %s ...", source, dest)
+	cmd := exec.Command("aws", args...)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %v", source, dest, err)
+	}
+	return nil
+}
@@ -29,3 +29,18 @@ var _ Stager = &NoopStager{}
 func (n *NoopStager) Stage(string) error {
 	return nil
 }
+
+// MultiStager stages with each of its stagers in turn, e.g. the release tars
+// and the images to different locations
+type MultiStager []Stager
+
+var _ Stager = MultiStager{}
+
+func (m MultiStager) Stage(version string) error {
+	for _, stager := range m {
+		if err := stager.Stage(version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// gitVersionEnv overrides the version the kubernetes build scripts embed
+// into the binaries and name the release with
+const gitVersionEnv = "KUBE_GIT_VERSION"
+
+// gitVersionRegex matches the versions hack/lib/version.sh accepts
+var gitVersionRegex = regexp.MustCompile(`^v([0-9]+)\.([0-9]+)(\.[0-9]+)?([-].*)?([+].*)?$`)
+
+// resolveVersion returns the version to build with for override, which
+// replaces version if it is a full version, or is appended to it if it
+// starts with - or +, e.g. -myfeature.1
+func resolveVersion(version, override string) (string, error) {
+	if strings.HasPrefix(override, "-") || strings.HasPrefix(override, "+") {
+		// drop the build metadata of the source version, as the version may
+		// have only one
+		version, _, _ = strings.Cut(version, "+")
+		override = version + override
+	}
+	if !gitVersionRegex.MatchString(override) {
+		return "", fmt.Errorf("invalid version %q, expected e.g. v1.31.0-myfeature.1", override)
+	}
+	return override, nil
+}
+
+// overrideVersion sets the version the builds embed, see resolveVersion
+func overrideVersion(kubeRoot, override string) (string, error) {
+	version := ""
+	if strings.HasPrefix(override, "-") || strings.HasPrefix(override, "+") {
+		var err error
+		if version, err = sourceVersion(kubeRoot); err != nil {
+			return "", fmt.Errorf("failed to get version to append %q to: %v", override, err)
+		}
+	}
+	version, err := resolveVersion(version, override)
+	if err != nil {
+		return "", err
+	}
+	// the build scripts, and sourceVersion with them, take the version from the env
+	if err := os.Setenv(gitVersionEnv, version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// gitVersionEnvs returns the version override env to pass on to builds
+// which do not inherit the environment
+func gitVersionEnvs() []string {
+	if version := os.Getenv(gitVersionEnv); version != "" {
+		return []string{gitVersionEnv + "=" + version}
+	}
+	return nil
+}
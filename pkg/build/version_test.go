@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import "testing"
+
+func TestResolveVersion(t *testing.T) {
+	cases := []struct {
+		name        string
+		version     string
+		override    string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "replace",
+			version:  "v1.31.0-alpha.1.20+0123456789abcd",
+			override: "v1.31.0-myfeature.1",
+			expected: "v1.31.0-myfeature.1",
+		},
+		{
+			name:     "append prerelease",
+			version:  "v1.31.0",
+			override: "-myfeature.1",
+			expected: "v1.31.0-myfeature.1",
+		},
+		{
+			name:     "append build metadata",
+			version:  "v1.31.0-alpha.1.20+0123456789abcd",
+			override: "+myfeature",
+			expected: "v1.31.0-alpha.1.20+myfeature",
+		},
+		{
+			name:        "invalid",
+			override:    "1.31.0",
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			version, err := resolveVersion(tc.version, tc.override)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error but got version %q", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tc.expected {
+				t.Errorf("expected version %q, got %q", tc.expected, version)
+			}
+		})
+	}
+}
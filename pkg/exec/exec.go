@@ -35,6 +35,10 @@ type Cmd interface {
 	SetStdout(io.Writer) Cmd
 	SetStderr(io.Writer) Cmd
 	SetDir(string) Cmd
+	// SetRecorded sets whether the output of the command is captured and the
+	// command recorded, true by default. Background pollers and commands
+	// printing credentials opt out.
+	SetRecorded(bool) Cmd
 }
 
 // Cmder abstracts over creating commands
@@ -23,11 +23,15 @@ import (
 	"strings"
 
 	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/process"
 )
 
 // LocalCmd wraps os/exec.Cmd, implementing the exec.Cmd interface
 type LocalCmd struct {
 	*osexec.Cmd
+	// unrecorded is set by SetRecorded(false)
+	unrecorded bool
 }
 
 var _ Cmd = &LocalCmd{}
@@ -82,7 +86,24 @@ func (cmd *LocalCmd) SetDir(dir string) Cmd {
 	return cmd
 }
 
-// Run runs
+// SetRecorded sets whether Run captures and records the command
+func (cmd *LocalCmd) SetRecorded(recorded bool) Cmd {
+	cmd.unrecorded = !recorded
+	return cmd
+}
+
+// Run runs, capturing the output with process.CaptureOutput and recording
+// the command with process.RecordCommand, unless SetRecorded(false)
 func (cmd *LocalCmd) Run() error {
-	return cmd.Cmd.Run()
+	if cmd.unrecorded {
+		return cmd.Cmd.Run()
+	}
+	var done func()
+	cmd.Stdout, cmd.Stderr, done = process.CaptureOutput(cmd.Path, cmd.Stdout, cmd.Stderr)
+	defer done()
+	var record func(error)
+	cmd.Stdout, cmd.Stderr, record = process.RecordCommand(cmd.Cmd, cmd.Stdout, cmd.Stderr)
+	err := cmd.Cmd.Run()
+	record(err)
+	return err
 }
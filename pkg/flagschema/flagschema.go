@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flagschema describes flags in a machine-readable form, so that
+// tooling can be built on top of kubetest2 without scraping the --help text.
+package flagschema
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+// FormatEnv is set to "json" when kubetest2 runs a tester with --help to
+// describe its flags, in which case the tester prints its Flags as JSON
+// instead of its usage.
+const FormatEnv = "KUBETEST2_FLAGS_FORMAT"
+
+// Flag describes a single flag.
+type Flag struct {
+	Name        string `json:"name"`
+	Shorthand   string `json:"shorthand,omitempty"`
+	Type        string `json:"type"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	Deprecated  string `json:"deprecated,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+}
+
+// Plugin describes the flags of a deployer or a tester.
+type Plugin struct {
+	Name  string `json:"name"`
+	Flags []Flag `json:"flags"`
+}
+
+// Schema describes all of the flags of a kubetest2 invocation.
+type Schema struct {
+	Kubetest2 []Flag  `json:"kubetest2"`
+	Deployer  Plugin  `json:"deployer"`
+	Tester    *Plugin `json:"tester,omitempty"`
+}
+
+// FromFlagSet returns the flags of fs, sorted by name.
+func FromFlagSet(fs *pflag.FlagSet) []Flag {
+	flags := []Flag{}
+	if fs == nil {
+		return flags
+	}
+	fs.VisitAll(func(f *pflag.Flag) {
+		flags = append(flags, Flag{
+			Name:        f.Name,
+			Shorthand:   f.Shorthand,
+			Type:        f.Value.Type(),
+			Default:     f.DefValue,
+			Description: f.Usage,
+			Deprecated:  f.Deprecated,
+			Hidden:      f.Hidden,
+		})
+	})
+	return flags
+}
+
+// Requested returns true if the flags should be described as JSON, see
+// FormatEnv.
+func Requested() bool {
+	return os.Getenv(FormatEnv) == "json"
+}
+
+// Write writes v as indented JSON to w.
+func Write(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
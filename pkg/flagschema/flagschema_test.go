@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagschema
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestFromFlagSet(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.BoolP("help", "h", false, "display help")
+	fs.Duration("timeout", time.Minute, "timeout of the test")
+	fs.StringSlice("env", nil, "environment variables")
+	fs.String("old", "", "an old flag")
+	if err := fs.MarkDeprecated("old", "use --new instead"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Flag{
+		{Name: "env", Type: "stringSlice", Default: "[]", Description: "environment variables"},
+		{Name: "help", Shorthand: "h", Type: "bool", Default: "false", Description: "display help"},
+		{Name: "old", Type: "string", Description: "an old flag", Deprecated: "use --new instead", Hidden: true},
+		{Name: "timeout", Type: "duration", Default: "1m0s", Description: "timeout of the test"},
+	}
+	if got := FromFlagSet(fs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected flags %+v but got %+v", expected, got)
+	}
+	if got := FromFlagSet(nil); len(got) != 0 {
+		t.Errorf("expected no flags for a nil flag set but got %v", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	schema := Schema{
+		Kubetest2: []Flag{{Name: "up", Type: "bool", Default: "false", Description: "provision the test cluster"}},
+		Deployer:  Plugin{Name: "noop", Flags: []Flag{}},
+	}
+	if err := Write(&buf, schema); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "kubetest2": [
+    {
+      "name": "up",
+      "type": "bool",
+      "default": "false",
+      "description": "provision the test cluster"
+    }
+  ],
+  "deployer": {
+    "name": "noop",
+    "flags": []
+  }
+}
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("expected output:\n%s\nbut got:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// gcpResources are the kinds of the resources kubernetes creates in a GCP
+// project on behalf of tests, with the gcloud filter selecting them
+var gcpResources = []struct {
+	kind   string
+	filter string
+}{
+	{kind: "forwarding-rules"},
+	{kind: "target-pools"},
+	{kind: "addresses"},
+	{kind: "backend-services"},
+	// the firewall rules of LoadBalancer services
+	{kind: "firewall-rules", filter: "name ~ ^k8s-"},
+	// the disks of dynamically provisioned volumes, not those of the nodes
+	{kind: "disks", filter: "name ~ ^(pvc-|kubernetes-dynamic-pvc-)"},
+}
+
+// GCPResources returns the compute resources of project that kubernetes
+// creates on behalf of tests, e.g. the forwarding rules of LoadBalancer
+// services, each named as kind/name.
+func GCPResources(project string) ([]string, error) {
+	var resources []string
+	for _, r := range gcpResources {
+		args := []string{"compute", r.kind, "list", "--project=" + project, "--format=value(name)"}
+		if r.filter != "" {
+			args = append(args, "--filter="+r.filter)
+		}
+		names, err := exec.OutputLines(exec.Command("gcloud", args...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the %s of project %s: %v", r.kind, project, err)
+		}
+		for _, name := range names {
+			if name != "" {
+				resources = append(resources, r.kind+"/"+name)
+			}
+		}
+	}
+	return resources, nil
+}
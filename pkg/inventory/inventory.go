@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory takes inventories of the resources of a cluster that
+// tests are expected to clean up, so that the leaks of a test run can be
+// found by comparing the inventories before and after it.
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// Snapshot is an inventory of the resources of a cluster, each named as
+// kind/name, or kind/namespace/name for namespaced ones
+type Snapshot struct {
+	Namespaces        []string `json:"namespaces"`
+	PersistentVolumes []string `json:"persistentVolumes"`
+	LoadBalancers     []string `json:"loadBalancers"`
+	// CloudResources are those of the deployer, e.g. forwarding rules
+	CloudResources []string `json:"cloudResources,omitempty"`
+}
+
+// Take takes the inventory of the cluster of kubeconfig, which may be empty
+// to use that of the environment.
+func Take(kubeconfig string) (*Snapshot, error) {
+	kubectl := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("kubectl", args...)
+		if kubeconfig != "" {
+			cmd.SetEnv(append(os.Environ(), "KUBECONFIG="+kubeconfig)...)
+		}
+		out, err := exec.Output(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("kubectl %s failed: %v", strings.Join(args, " "), err)
+		}
+		return out, nil
+	}
+
+	s := &Snapshot{}
+	out, err := kubectl("get", "namespaces", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+	s.Namespaces = parseNames(out)
+	if out, err = kubectl("get", "persistentvolumes", "-o", "name"); err != nil {
+		return nil, err
+	}
+	s.PersistentVolumes = parseNames(out)
+	if out, err = kubectl("get", "services", "--all-namespaces", "-o", "json"); err != nil {
+		return nil, err
+	}
+	if s.LoadBalancers, err = parseLoadBalancers(out); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseNames parses the output of kubectl get -o name, e.g. namespace/default
+func parseNames(out []byte) []string {
+	names := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseLoadBalancers returns the services of type LoadBalancer in the
+// output of kubectl get services -o json
+func parseLoadBalancers(out []byte) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Type string `json:"type"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse services: %v", err)
+	}
+	names := []string{}
+	for _, item := range list.Items {
+		if item.Spec.Type == "LoadBalancer" {
+			names = append(names, "service/"+item.Metadata.Namespace+"/"+item.Metadata.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Leaks returns the resources of after that are not in before, sorted
+func Leaks(before, after *Snapshot) []string {
+	var leaks []string
+	for _, pair := range [][2][]string{
+		{before.Namespaces, after.Namespaces},
+		{before.PersistentVolumes, after.PersistentVolumes},
+		{before.LoadBalancers, after.LoadBalancers},
+		{before.CloudResources, after.CloudResources},
+	} {
+		existing := map[string]bool{}
+		for _, name := range pair[0] {
+			existing[name] = true
+		}
+		for _, name := range pair[1] {
+			if !existing[name] {
+				leaks = append(leaks, name)
+			}
+		}
+	}
+	sort.Strings(leaks)
+	return leaks
+}
+
+// Write writes the snapshot to path as JSON
+func (s *Snapshot) Write(path string) error {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
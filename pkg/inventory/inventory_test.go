@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLoadBalancers(t *testing.T) {
+	out := []byte(`{"items":[
+{"metadata":{"namespace":"default","name":"kubernetes"},"spec":{"type":"ClusterIP"}},
+{"metadata":{"namespace":"e2e-1","name":"lb"},"spec":{"type":"LoadBalancer"}},
+{"metadata":{"namespace":"app","name":"ingress"},"spec":{"type":"LoadBalancer"}}]}`)
+	lbs, err := parseLoadBalancers(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"service/app/ingress", "service/e2e-1/lb"}
+	if !reflect.DeepEqual(lbs, expected) {
+		t.Errorf("expected %v, got %v", expected, lbs)
+	}
+	if _, err := parseLoadBalancers([]byte("error: the server doesn't have a resource type")); err == nil {
+		t.Errorf("expected an error for output that is not json")
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	before := &Snapshot{
+		Namespaces:        parseNames([]byte("namespace/default\nnamespace/kube-system\n")),
+		PersistentVolumes: []string{},
+		LoadBalancers:     []string{"service/app/ingress"},
+		CloudResources:    []string{"forwarding-rules/a1"},
+	}
+	after := &Snapshot{
+		Namespaces:        parseNames([]byte("namespace/kube-system\nnamespace/e2e-volumes-123\nnamespace/default\n")),
+		PersistentVolumes: []string{"persistentvolume/pv-1"},
+		LoadBalancers:     []string{"service/e2e-lb-9/lb"},
+		CloudResources:    []string{"forwarding-rules/a1", "forwarding-rules/b2"},
+	}
+	expected := []string{
+		"forwarding-rules/b2",
+		"namespace/e2e-volumes-123",
+		"persistentvolume/pv-1",
+		"service/e2e-lb-9/lb",
+	}
+	if leaks := Leaks(before, after); !reflect.DeepEqual(leaks, expected) {
+		t.Errorf("expected leaks %v, got %v", expected, leaks)
+	}
+	if leaks := Leaks(after, after); len(leaks) != 0 {
+		t.Errorf("expected no leaks, got %v", leaks)
+	}
+}
@@ -53,7 +53,8 @@ func NewJUnitError(inner error, systemOut string) error {
 
 // testSuite holds a slice of TestCase and other summary metadata.
 //
-// A build (column in testgrid) is composed of one or more TestSuites.
+// A build (column in testgrid) is composed of one or more TestSuites,
+// which may be nested.
 type testSuite struct {
 	XMLName  xml.Name `xml:"testsuite"`
 	Name     string   `xml:"name,attr"`
@@ -61,6 +62,7 @@ type testSuite struct {
 	Tests    int      `xml:"tests,attr"`
 	Time     float64  `xml:"time,attr"`
 	Cases    []testCase
+	Suites   []testSuite
 }
 
 func (t *testSuite) Write(writer io.Writer) error {
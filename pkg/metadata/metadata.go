@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 )
 
 type CustomJSON struct {
@@ -58,3 +59,38 @@ func (m *CustomJSON) Write(writer io.Writer) error {
 	}
 	return err
 }
+
+// AddToFile adds the key value pair to the custom metadata JSON file at path,
+// preserving any existing entries. The file is created if it does not exist.
+func AddToFile(path, key, value string) error {
+	var meta *CustomJSON
+	if existing, err := os.Open(path); err == nil {
+		meta, err = NewCustomJSON(existing)
+		existing.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse existing metadata at %s: %w", path, err)
+		}
+	} else if os.IsNotExist(err) {
+		meta = &CustomJSON{}
+	} else {
+		return err
+	}
+
+	if err := meta.Add(key, value); err != nil {
+		return err
+	}
+
+	metadataJSON, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := meta.Write(metadataJSON); err != nil {
+		metadataJSON.Close()
+		return err
+	}
+	if err := metadataJSON.Sync(); err != nil {
+		metadataJSON.Close()
+		return err
+	}
+	return metadataJSON.Close()
+}
@@ -19,6 +19,8 @@ package metadata
 import (
 	"bytes"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -63,3 +65,33 @@ func TestNewCustomJSON(t *testing.T) {
 		t.Errorf("mismatched metadata bytes, got: %v, want: %v", meta.data, expectedData)
 	}
 }
+
+func TestAddToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	if err := AddToFile(path, "foo", "bar"); err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if err := AddToFile(path, "baz", "qwe"); err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if err := AddToFile(path, "foo", "again"); err == nil {
+		t.Errorf("expected an error when adding a duplicate key")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	defer f.Close()
+	meta, err := NewCustomJSON(f)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	expectedData := map[string]string{
+		"foo": "bar",
+		"baz": "qwe",
+	}
+	if !reflect.DeepEqual(meta.data, expectedData) {
+		t.Errorf("mismatched metadata, got: %v, want: %v", meta.data, expectedData)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// minorVersionRegex matches the major and minor of a kubernetes version in
+// any of the forms deployers take, e.g. v1.30.2, 1.30.2-gke.1000 or a node
+// image tag such as kindest/node:v1.30.2
+var minorVersionRegex = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.\d+)?[^:/]*$`)
+
+// VersionSkew returns the skew of the node version behind the control plane
+// version, in the n-1 notation of the version skew policy, e.g. n-1 for a
+// kubelet one minor version older than the apiserver or n for none.
+func VersionSkew(controlPlane, node string) (string, error) {
+	cpMajor, cpMinor, err := parseMinorVersion(controlPlane)
+	if err != nil {
+		return "", err
+	}
+	nodeMajor, nodeMinor, err := parseMinorVersion(node)
+	if err != nil {
+		return "", err
+	}
+	if cpMajor != nodeMajor {
+		return "", fmt.Errorf("control plane version %s and node version %s have different major versions", controlPlane, node)
+	}
+	switch skew := nodeMinor - cpMinor; {
+	case skew == 0:
+		return "n", nil
+	case skew < 0:
+		return fmt.Sprintf("n%d", skew), nil
+	default:
+		return fmt.Sprintf("n+%d", skew), nil
+	}
+}
+
+func parseMinorVersion(version string) (major, minor int, err error) {
+	match := minorVersionRegex.FindStringSubmatch(version)
+	if match == nil {
+		return 0, 0, fmt.Errorf("no kubernetes version in %q", version)
+	}
+	major, _ = strconv.Atoi(match[1])
+	minor, _ = strconv.Atoi(match[2])
+	return major, minor, nil
+}
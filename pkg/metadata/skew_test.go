@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"testing"
+)
+
+func TestVersionSkew(t *testing.T) {
+	testCases := []struct {
+		controlPlane string
+		node         string
+		expected     string
+		expectErr    bool
+	}{
+		{controlPlane: "v1.30.2", node: "v1.30.0", expected: "n"},
+		{controlPlane: "v1.30.2", node: "v1.29.5", expected: "n-1"},
+		{controlPlane: "1.30.2-gke.1000", node: "1.28.9-gke.2000", expected: "n-2"},
+		{controlPlane: "kindest/node:v1.30.0", node: "kindest/node:v1.27.13", expected: "n-3"},
+		{controlPlane: "localhost:5000/node:v1.29.0", node: "v1.30.0", expected: "n+1"},
+		{controlPlane: "v1.30.2", node: "latest", expectErr: true},
+		{controlPlane: "v2.0.0", node: "v1.30.0", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		skew, err := VersionSkew(tc.controlPlane, tc.node)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("VersionSkew(%q, %q): expected error: %v, got %v", tc.controlPlane, tc.node, tc.expectErr, err)
+			continue
+		}
+		if skew != tc.expected {
+			t.Errorf("VersionSkew(%q, %q): expected %q, got %q", tc.controlPlane, tc.node, tc.expected, skew)
+		}
+	}
+}
@@ -18,12 +18,16 @@ package metadata
 
 import (
 	"io"
+	"sync"
 	"time"
 )
 
 // Writer manages writing out kubetest2 metadata, namely JUnit
 type Writer struct {
-	suite     testSuite
+	mu    sync.Mutex
+	suite testSuite
+	// step holds the commands of the step being run
+	step      *testSuite
 	start     time.Time
 	runnerOut io.Writer
 	// for faking out time when testing
@@ -45,11 +49,34 @@ func NewWriter(suiteName string, runnerOut io.Writer) *Writer {
 
 // WrapStep executes doStep and captures the output to be written to the
 // kubetest2 runner metadata. If doStep returns a JUnitError this metadata
-// will be captured
+// will be captured. The commands recorded while doStep runs are written to
+// a testsuite of the step, nested in the kubetest2 one.
 func (w *Writer) WrapStep(name string, doStep func() error) error {
 	start := w.timeNow()
+	step := &testSuite{Name: name}
+	w.mu.Lock()
+	w.step = step
+	w.mu.Unlock()
 	err := doStep()
 	finish := w.timeNow()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.step == step {
+		w.step = nil
+	}
+	if step.Tests > 0 {
+		step.Time = finish.Sub(start).Seconds()
+		if err == nil {
+			// the step succeeded, so the commands failing along the way
+			// were expected to or were retried
+			step.Failures = 0
+			for i := range step.Cases {
+				step.Cases[i].Failure = ""
+			}
+		}
+		w.suite.Suites = append(w.suite.Suites, *step)
+	}
 	tc := testCase{
 		Name:      name,
 		ClassName: w.suite.Name,
@@ -65,8 +92,30 @@ func (w *Writer) WrapStep(name string, doStep func() error) error {
 	return err
 }
 
+// RecordCommand records a command run by the step being run as a testcase,
+// with its output if it failed. Commands run outside of steps are not recorded.
+func (w *Writer) RecordCommand(command string, duration time.Duration, err error, output string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.step == nil {
+		return
+	}
+	tc := testCase{
+		Name:      command,
+		ClassName: w.step.Name,
+		Time:      duration.Seconds(),
+	}
+	if err != nil {
+		tc.Failure = err.Error()
+		tc.SystemOut = output
+	}
+	w.step.AddTestCase(tc)
+}
+
 // Finish finalizes the metadata (time) and writes it out
 func (w *Writer) Finish() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.suite.Time = w.timeNow().Sub(w.start).Seconds()
 	return w.suite.Write(w.runnerOut)
 }
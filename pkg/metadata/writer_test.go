@@ -190,3 +190,53 @@ func TestWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestWriterCommands(t *testing.T) {
+	runnerOut := bytes.NewBuffer([]byte{})
+	w := NewWriter("kubetest2", runnerOut)
+	w.timeNow = makeFakeNow()
+	w.start = w.timeNow()
+
+	// not part of any step
+	w.RecordCommand("kind version", time.Second, nil, "")
+	_ = w.WrapStep("Up", func() error {
+		w.RecordCommand("gcloud compute networks describe", time.Second, errors.New("exit status 1"), "not found")
+		w.RecordCommand("gcloud compute networks create", 2*time.Second, nil, "created")
+		return nil
+	})
+	_ = w.WrapStep("Test", func() error {
+		w.RecordCommand("ginkgo", 3*time.Second, errors.New("exit status 1"), "tests failed")
+		return errors.New("tests failed")
+	})
+	_ = w.WrapStep("Down", func() error { return nil })
+	if err := w.Finish(); err != nil {
+		t.Fatalf("unexpected error for writer.Finish() %v", err)
+	}
+
+	expectedOutput := strings.TrimPrefix(
+		`
+<?xml version="1.0" encoding="UTF-8"?><testsuite name="kubetest2" failures="1" tests="3" time="7">
+    <testcase name="Up" classname="kubetest2" time="1"></testcase>
+    <testcase name="Test" classname="kubetest2" time="1">
+        <failure>tests failed</failure>
+    </testcase>
+    <testcase name="Down" classname="kubetest2" time="1"></testcase>
+    <testsuite name="Up" failures="0" tests="2" time="1">
+        <testcase name="gcloud compute networks describe" classname="Up" time="1">
+            <system-out>not found</system-out>
+        </testcase>
+        <testcase name="gcloud compute networks create" classname="Up" time="2"></testcase>
+    </testsuite>
+    <testsuite name="Test" failures="1" tests="1" time="1">
+        <testcase name="ginkgo" classname="Test" time="3">
+            <failure>exit status 1</failure>
+            <system-out>tests failed</system-out>
+        </testcase>
+    </testsuite>
+</testsuite>`,
+		"\n",
+	)
+	if output := runnerOut.String(); output != expectedOutput {
+		t.Errorf("runnerOut did not match expected \n%v\nVERSUS:\n %v", expectedOutput, output)
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package monitor records the events, the apiserver request metrics and the
+// node conditions of a cluster while it is being tested, so that disruptions
+// in the middle of a run can be investigated after the fact.
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// metricPrefixes are those of the apiserver metrics recorded
+var metricPrefixes = []string{
+	"apiserver_request_total",
+	"apiserver_request_duration_seconds_sum",
+	"apiserver_request_duration_seconds_count",
+	"apiserver_current_inflight_requests",
+	"apiserver_flowcontrol_rejected_requests_total",
+	"etcd_request_duration_seconds_sum",
+	"etcd_request_duration_seconds_count",
+}
+
+// Monitor records the state of the cluster of Kubeconfig every Interval into
+// Dir: the events not seen yet to events.jsonl, the changes of the node
+// conditions to node-conditions.jsonl and the apiserver metrics to a
+// timestamped file of the metrics directory.
+type Monitor struct {
+	Kubeconfig string
+	Dir        string
+	Interval   time.Duration
+
+	// resource versions of the events recorded, by uid
+	events map[string]string
+	// last node conditions recorded, by node and type
+	conditions map[string]nodeCondition
+}
+
+// event is a recorded event, with the time it was observed at
+type event struct {
+	Observed time.Time       `json:"observed"`
+	Event    json.RawMessage `json:"event"`
+}
+
+// nodeCondition is a recorded condition of a node
+type nodeCondition struct {
+	Observed time.Time `json:"observed"`
+	Node     string    `json:"node"`
+	Type     string    `json:"type"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Start starts recording every m.Interval until the returned function is
+// called, which waits for the recording in progress.
+func (m *Monitor) Start() (stop func()) {
+	m.events = map[string]string{}
+	m.conditions = map[string]nodeCondition{}
+	if err := os.MkdirAll(filepath.Join(m.Dir, "metrics"), os.ModePerm); err != nil {
+		klog.Warningf("failed to create the directory of the cluster monitoring: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			m.record(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// record records the state of the cluster once, logging failures as the
+// cluster may well be disrupted
+func (m *Monitor) record(ctx context.Context, now time.Time) {
+	for name, fn := range map[string]func(context.Context, time.Time) error{
+		"events":          m.recordEvents,
+		"node conditions": m.recordNodeConditions,
+		"metrics":         m.recordMetrics,
+	} {
+		if err := fn(ctx, now); err != nil && ctx.Err() == nil {
+			klog.V(2).Infof("failed to record the %s of the cluster: %v", name, err)
+		}
+	}
+}
+
+func (m *Monitor) recordEvents(ctx context.Context, now time.Time) error {
+	out, err := exec.Output(m.kubectl(ctx, "get", "events", "--all-namespaces", "-o", "json"))
+	if err != nil {
+		return err
+	}
+	events, err := m.newEvents(out, now)
+	if err != nil {
+		return err
+	}
+	return appendJSONLines(filepath.Join(m.Dir, "events.jsonl"), events)
+}
+
+// newEvents returns the events of the output of kubectl get events -o json
+// that are new or changed, e.g. repeated, since the last call
+func (m *Monitor) newEvents(out []byte, now time.Time) ([]interface{}, error) {
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %v", err)
+	}
+	var events []interface{}
+	for _, item := range list.Items {
+		var meta struct {
+			Metadata struct {
+				UID             string `json:"uid"`
+				ResourceVersion string `json:"resourceVersion"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(item, &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse event: %v", err)
+		}
+		if m.events[meta.Metadata.UID] == meta.Metadata.ResourceVersion {
+			continue
+		}
+		m.events[meta.Metadata.UID] = meta.Metadata.ResourceVersion
+		events = append(events, event{Observed: now, Event: item})
+	}
+	return events, nil
+}
+
+func (m *Monitor) recordNodeConditions(ctx context.Context, now time.Time) error {
+	out, err := exec.Output(m.kubectl(ctx, "get", "nodes", "-o", "json"))
+	if err != nil {
+		return err
+	}
+	conditions, err := m.changedConditions(out, now)
+	if err != nil {
+		return err
+	}
+	return appendJSONLines(filepath.Join(m.Dir, "node-conditions.jsonl"), conditions)
+}
+
+// changedConditions returns the node conditions of the output of kubectl get
+// nodes -o json whose status or reason changed since the last call
+func (m *Monitor) changedConditions(out []byte, now time.Time) ([]interface{}, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type    string `json:"type"`
+					Status  string `json:"status"`
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %v", err)
+	}
+	var changed []interface{}
+	for _, node := range list.Items {
+		for _, c := range node.Status.Conditions {
+			key := node.Metadata.Name + "/" + c.Type
+			last, seen := m.conditions[key]
+			if seen && last.Status == c.Status && last.Reason == c.Reason {
+				continue
+			}
+			condition := nodeCondition{
+				Observed: now,
+				Node:     node.Metadata.Name,
+				Type:     c.Type,
+				Status:   c.Status,
+				Reason:   c.Reason,
+				Message:  c.Message,
+			}
+			m.conditions[key] = condition
+			changed = append(changed, condition)
+		}
+	}
+	return changed, nil
+}
+
+func (m *Monitor) recordMetrics(ctx context.Context, now time.Time) error {
+	out, err := exec.Output(m.kubectl(ctx, "get", "--raw", "/metrics"))
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.Dir, "metrics", "apiserver-"+now.UTC().Format("20060102-150405")+".txt")
+	return os.WriteFile(path, filterMetrics(out), 0644)
+}
+
+// filterMetrics returns the samples of the metrics of metricPrefixes in the
+// prometheus text format
+func filterMetrics(out []byte) []byte {
+	var filtered bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, prefix := range metricPrefixes {
+			if strings.HasPrefix(line, prefix+"{") || strings.HasPrefix(line, prefix+" ") {
+				filtered.WriteString(line + "\n")
+				break
+			}
+		}
+	}
+	return filtered.Bytes()
+}
+
+// kubectl returns an unrecorded kubectl command, as the polls are not
+// commands of the step and would flood its captured output and results
+func (m *Monitor) kubectl(ctx context.Context, args ...string) exec.Cmd {
+	cmd := exec.CommandContext(ctx, "kubectl", args...).SetRecorded(false)
+	if m.Kubeconfig != "" {
+		cmd.SetEnv(append(os.Environ(), "KUBECONFIG="+m.Kubeconfig)...)
+	}
+	return cmd
+}
+
+// appendJSONLines appends values to the file at path, one JSON per line
+func appendJSONLines(path string, values []interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	for _, v := range values {
+		if err := encoder.Encode(v); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewEvents(t *testing.T) {
+	m := &Monitor{events: map[string]string{}}
+	now := time.Now()
+	first := []byte(`{"items":[
+{"metadata":{"uid":"a","resourceVersion":"1"},"reason":"Pulled"},
+{"metadata":{"uid":"b","resourceVersion":"2"},"reason":"BackOff","count":1}]}`)
+	events, err := m.newEvents(first, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected 2 new events, got %d", len(events))
+	}
+	second := []byte(`{"items":[
+{"metadata":{"uid":"a","resourceVersion":"1"},"reason":"Pulled"},
+{"metadata":{"uid":"b","resourceVersion":"5"},"reason":"BackOff","count":2}]}`)
+	events, err = m.newEvents(second, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || string(events[0].(event).Event) != `{"metadata":{"uid":"b","resourceVersion":"5"},"reason":"BackOff","count":2}` {
+		t.Errorf("expected the repeated event only, got %v", events)
+	}
+}
+
+func TestChangedConditions(t *testing.T) {
+	m := &Monitor{conditions: map[string]nodeCondition{}}
+	nodes := func(ready string) []byte {
+		return []byte(`{"items":[{"metadata":{"name":"node-a"},"status":{"conditions":[
+{"type":"MemoryPressure","status":"False","reason":"KubeletHasSufficientMemory"},
+{"type":"Ready","status":"` + ready + `","reason":"KubeletReady"}]}}]}`)
+	}
+	for _, step := range []struct {
+		ready    string
+		expected int
+	}{
+		{ready: "True", expected: 2},
+		{ready: "True", expected: 0},
+		{ready: "Unknown", expected: 1},
+	} {
+		changed, err := m.changedConditions(nodes(step.ready), time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(changed) != step.expected {
+			t.Errorf("Ready=%s: expected %d changed conditions, got %v", step.ready, step.expected, changed)
+		}
+	}
+}
+
+func TestFilterMetrics(t *testing.T) {
+	metrics := []byte(`# HELP apiserver_request_total Counter of apiserver requests
+# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",verb="GET"} 42
+apiserver_request_total_other 1
+apiserver_current_inflight_requests{request_kind="mutating"} 3
+go_goroutines 120
+`)
+	expected := `apiserver_request_total{code="200",verb="GET"} 42
+apiserver_current_inflight_requests{request_kind="mutating"} 3
+`
+	if filtered := string(filterMetrics(metrics)); filtered != expected {
+		t.Errorf("expected %q, got %q", expected, filtered)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// secretEnvRegex matches the names of variables whose values are redacted
+// in the audit log
+var secretEnvRegex = regexp.MustCompile(`(?i)secret|token|password|passwd|credential|_key$|^key$`)
+
+var audit struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// auditEntry is a line of the audit log, recording an executed command
+type auditEntry struct {
+	Argv []string `json:"argv"`
+	Dir  string   `json:"dir,omitempty"`
+	// Env holds the variables set or changed relative to the environment
+	// of kubetest2, UnsetEnv the ones that were not passed on
+	Env      []string  `json:"env,omitempty"`
+	UnsetEnv []string  `json:"unsetEnv,omitempty"`
+	Phase    string    `json:"phase,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration float64   `json:"durationSeconds"`
+	ExitCode int       `json:"exitCode"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// SetAuditLog makes every command executed from now on be recorded in w as
+// a line of JSON, with its arguments, environment, timing and exit code.
+func SetAuditLog(w io.Writer) {
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+	audit.w = w
+}
+
+func auditCommand(cmd *exec.Cmd, start time.Time, err error) {
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+	if audit.w == nil {
+		return
+	}
+	end := time.Now()
+	entry := auditEntry{
+		Argv:     cmd.Args,
+		Dir:      cmd.Dir,
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start).Seconds(),
+		ExitCode: exitCode(err),
+	}
+	if cmd.Env != nil {
+		entry.Env, entry.UnsetEnv = envDelta(os.Environ(), cmd.Env)
+	}
+	capture.mu.Lock()
+	entry.Phase = capture.phase
+	capture.mu.Unlock()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		klog.Warningf("failed to audit command %v: %v", cmd.Args, err)
+		return
+	}
+	if _, err := audit.w.Write(append(line, '\n')); err != nil {
+		klog.Warningf("failed to audit command %v: %v", cmd.Args, err)
+	}
+}
+
+// exitCode returns the exit code of a command that exited with err, or -1
+// if it did not exit by itself
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// envDelta returns the variables of env that are not in base, with secret
+// values redacted, and the names of those of base missing from env.
+func envDelta(base, env []string) ([]string, []string) {
+	baseValues := map[string]string{}
+	for _, kv := range base {
+		name, value, _ := strings.Cut(kv, "=")
+		baseValues[name] = value
+	}
+	var set, unset []string
+	names := map[string]bool{}
+	for _, kv := range SanitizeEnv(env) {
+		name, value, _ := strings.Cut(kv, "=")
+		names[name] = true
+		if baseValue, ok := baseValues[name]; ok && baseValue == value {
+			continue
+		}
+		if secretEnvRegex.MatchString(name) {
+			kv = name + "=REDACTED"
+		}
+		set = append(set, kv)
+	}
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if !names[name] {
+			unset = append(unset, name)
+		}
+	}
+	return set, unset
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestEnvDelta(t *testing.T) {
+	base := []string{"PATH=/bin", "HOME=/root", "LANG=C"}
+	env := []string{"PATH=/usr/bin", "HOME=/root", "KUBECONFIG=/tmp/kubeconfig", "AWS_SECRET_ACCESS_KEY=hunter2"}
+	set, unset := envDelta(base, env)
+	expectedSet := []string{"PATH=/usr/bin", "KUBECONFIG=/tmp/kubeconfig", "AWS_SECRET_ACCESS_KEY=REDACTED"}
+	if !reflect.DeepEqual(set, expectedSet) {
+		t.Errorf("expected set %q, got %q", expectedSet, set)
+	}
+	if expectedUnset := []string{"LANG"}; !reflect.DeepEqual(unset, expectedUnset) {
+		t.Errorf("expected unset %q, got %q", expectedUnset, unset)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	var log bytes.Buffer
+	SetAuditLog(&log)
+	defer SetAuditLog(nil)
+
+	if err := execCmdWithSignals(context.Background(), exec.Command("sh", "-c", "exit 3")); err == nil {
+		t.Fatal("expected error but got none")
+	}
+	var entry auditEntry
+	if err := json.Unmarshal(log.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse audit log %q: %v", log.String(), err)
+	}
+	if !reflect.DeepEqual(entry.Argv, []string{"sh", "-c", "exit 3"}) {
+		t.Errorf("unexpected argv %q", entry.Argv)
+	}
+	if entry.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", entry.ExitCode)
+	}
+	if entry.End.Before(entry.Start) {
+		t.Errorf("expected end %v after start %v", entry.End, entry.Start)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// capture holds the state of capturing the output of the commands
+var capture struct {
+	mu    sync.Mutex
+	dir   string
+	phase string
+	seq   int
+}
+
+// SetCaptureDir makes the output of every command executed from now on also
+// be written to a file of its own in dir, see CaptureOutput.
+func SetCaptureDir(dir string) {
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	capture.dir = dir
+}
+
+// SetPhase sets the phase the output files of the following commands are
+// named by, e.g. Up.
+func SetPhase(phase string) {
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	capture.phase = phase
+}
+
+// CaptureOutput returns stdout and stderr teed into the output file of the
+// command argv0, named by the phase and sequence of the command, e.g.
+// up-0003-gcloud.log. done closes the file once the command has exited.
+// If capturing is not enabled, stdout and stderr are returned as is.
+func CaptureOutput(argv0 string, stdout, stderr io.Writer) (io.Writer, io.Writer, func()) {
+	f, err := captureFile(argv0)
+	if err != nil || f == nil {
+		return stdout, stderr, func() {}
+	}
+	// stdout and stderr may be written concurrently
+	out := &mutexWriter{writer: f}
+	return tee(stdout, out), tee(stderr, out), func() { _ = f.Close() }
+}
+
+func captureFile(argv0 string) (*os.File, error) {
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	if capture.dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(capture.dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	capture.seq++
+	phase := capture.phase
+	if phase == "" {
+		phase = "init"
+	}
+	name := fmt.Sprintf("%s-%04d-%s.log",
+		strings.ToLower(strings.ReplaceAll(phase, " ", "-")), capture.seq, filepath.Base(argv0))
+	// the output of commands may hold credentials
+	return os.OpenFile(filepath.Join(capture.dir, name), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+}
+
+func tee(w io.Writer, capture io.Writer) io.Writer {
+	if w == nil {
+		return capture
+	}
+	return io.MultiWriter(w, capture)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCaptureOutput(t *testing.T) {
+	dir := t.TempDir()
+	SetCaptureDir(dir)
+	SetPhase("Test after v1.2")
+	defer SetCaptureDir("")
+	defer SetPhase("")
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo out; echo err >&2")
+	cmd.Stdout = &stdout
+	if err := execCmdWithSignals(context.Background(), cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "out\n" {
+		t.Errorf("expected stdout to still be streamed, got %q", stdout.String())
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "test-after-v1.2-*-sh.log"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one output file, got %v (%v)", matches, err)
+	}
+	captured, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(captured) != "out\nerr\n" {
+		t.Errorf("unexpected captured output %q", captured)
+	}
+	info, err := os.Stat(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the output file to only be readable by the owner, got %v", info.Mode())
+	}
+}
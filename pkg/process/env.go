@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"strings"
+)
+
+// DefaultEnvAllowlist holds the variables most commands need to run,
+// see AllowedEnv
+var DefaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "SHELL", "TERM", "TMPDIR", "TZ", "LANG", "LC_*",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	"GOOGLE_APPLICATION_CREDENTIALS", "CLOUDSDK_*",
+}
+
+// AllowedEnv returns the sanitized entries of env with names in allowlist,
+// so that only those are passed on to a command instead of the whole
+// environment. A name ending in * matches any name with that prefix.
+func AllowedEnv(env []string, allowlist []string) []string {
+	var allowed []string
+	for _, kv := range SanitizeEnv(env) {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, pattern := range allowlist {
+			if matchEnv(name, pattern) {
+				allowed = append(allowed, kv)
+				break
+			}
+		}
+	}
+	return allowed
+}
+
+func matchEnv(name, pattern string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return name == pattern
+}
+
+// SanitizeEnv returns env without malformed entries, keeping only the last
+// entry of a variable set more than once, at the position of the first.
+func SanitizeEnv(env []string) []string {
+	index := map[string]int{}
+	var sanitized []string
+	for _, kv := range env {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			continue
+		}
+		if i, ok := index[name]; ok {
+			sanitized[i] = kv
+			continue
+		}
+		index[name] = len(sanitized)
+		sanitized = append(sanitized, kv)
+	}
+	return sanitized
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllowedEnv(t *testing.T) {
+	cases := []struct {
+		name      string
+		env       []string
+		allowlist []string
+		expected  []string
+	}{
+		{
+			name:      "names",
+			env:       []string{"PATH=/bin", "AWS_SECRET_ACCESS_KEY=secret", "HOME=/root"},
+			allowlist: []string{"PATH", "HOME"},
+			expected:  []string{"PATH=/bin", "HOME=/root"},
+		},
+		{
+			name:      "prefixes",
+			env:       []string{"KIND_EXPERIMENTAL_PROVIDER=podman", "KINDLE=1", "LC_ALL=C"},
+			allowlist: []string{"KIND_*", "LC_*"},
+			expected:  []string{"KIND_EXPERIMENTAL_PROVIDER=podman", "LC_ALL=C"},
+		},
+		{
+			name:      "sanitized",
+			env:       []string{"PATH=/bin", "malformed", "=x", "HOME=/root", "PATH=/usr/bin"},
+			allowlist: []string{"PATH", "HOME"},
+			expected:  []string{"PATH=/usr/bin", "HOME=/root"},
+		},
+		{
+			name:     "nothing allowed",
+			env:      []string{"PATH=/bin"},
+			expected: nil,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := AllowedEnv(tc.env, tc.allowlist); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -17,9 +17,14 @@ limitations under the License.
 package process
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strings"
+	"time"
 )
 
 // Exec generally mimics syscall.Exec behavior, but using a child process
@@ -34,10 +39,60 @@ func Exec(argv0 string, args []string, env []string) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	return execCmdWithSignals(cmd)
+	return execCmdWithSignals(context.Background(), cmd)
 }
 
-func execCmdWithSignals(cmd *exec.Cmd) error {
+// ExecContext is like Exec, except that the process is killed along with its
+// process group if ctx is done before it exits
+func ExecContext(ctx context.Context, argv0 string, args []string, env []string) error {
+	return ExecContextWithOutput(ctx, argv0, args, env, os.Stdout, os.Stderr)
+}
+
+// ExecContextWithOutput is like ExecContext, except that the output of the
+// process is written to stdout and stderr
+func ExecContextWithOutput(ctx context.Context, argv0 string, args []string, env []string, stdout, stderr io.Writer) error {
+	cmd := exec.Command(argv0, args...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return execCmdWithSignals(ctx, cmd)
+}
+
+// ExecTimeout is like ExecContext with a timeout, no timeout if it is 0
+func ExecTimeout(timeout time.Duration, argv0 string, args []string, env []string) error {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	return ExecContext(ctx, argv0, args, env)
+}
+
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func execCmdWithSignals(ctx context.Context, cmd *exec.Cmd) error {
+	var done func()
+	cmd.Stdout, cmd.Stderr, done = CaptureOutput(cmd.Path, cmd.Stdout, cmd.Stderr)
+	defer done()
+	var record func(error)
+	cmd.Stdout, cmd.Stderr, record = RecordCommand(cmd, cmd.Stdout, cmd.Stderr)
+	err := runCmdWithSignals(ctx, cmd)
+	record(err)
+	return err
+}
+
+func runCmdWithSignals(ctx context.Context, cmd *exec.Cmd) error {
+
+	// run in a process group of its own if it may be killed, so that the
+	// children of the process are killed with it
+	killable := ctx.Done() != nil
+	if killable {
+		setProcessGroup(cmd)
+	}
+
 	// setup listener to forward all signals
 	// TODO(bentheelder): what should this buffer size be?
 	signals := make(chan os.Signal, 5)
@@ -61,7 +116,15 @@ func execCmdWithSignals(cmd *exec.Cmd) error {
 		select {
 		case sig := <-signals:
 			// TODO(bentheelder): can this actually fail? should we log this?
-			_ = cmd.Process.Signal(sig)
+			if killable {
+				_ = signalProcessGroup(cmd, sig)
+			} else {
+				_ = cmd.Process.Signal(sig)
+			}
+		case <-ctx.Done():
+			_ = killProcessGroup(cmd)
+			<-wait
+			return fmt.Errorf("%s was killed: %w", strings.Join(cmd.Args, " "), ctx.Err())
 		case err := <-wait:
 			return err
 		}
@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExecJUnitTimeout(t *testing.T) {
+	start := time.Now()
+	// the background sleep keeps the output open unless the group is killed
+	err := ExecJUnitTimeout(100*time.Millisecond, "sh", []string{"-c", "sleep 10 & sleep 10"}, os.Environ())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to be killed after the timeout, took %v", elapsed)
+	}
+	if err := ExecJUnitTimeout(0, "true", nil, os.Environ()); err != nil {
+		t.Errorf("unexpected error without timeout: %v", err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"time"
 
 	"sigs.k8s.io/kubetest2/pkg/metadata"
 )
@@ -35,6 +36,10 @@ func (e *execJunitError) SystemOut() string {
 	return e.systemout
 }
 
+func (e *execJunitError) Unwrap() error {
+	return e.error
+}
+
 var _ metadata.JUnitError = &execJunitError{}
 
 // ExecJUnit is like Exec, except that it tees the output and captures it
@@ -42,29 +47,53 @@ var _ metadata.JUnitError = &execJunitError{}
 func ExecJUnit(argv0 string, args []string, env []string) error {
 	// construct command from inputs
 	cmd := exec.Command(argv0, args...)
-	return execJUnit(cmd, env)
+	return execJUnit(context.Background(), cmd, env)
 }
 
+// ExecJUnitContext is like ExecJUnit, except that the process is killed along
+// with its process group if ctx is done before it exits
 func ExecJUnitContext(ctx context.Context, argv0 string, args []string, env []string) error {
-	cmd := exec.CommandContext(ctx, argv0, args...)
-	return execJUnit(cmd, env)
+	cmd := exec.Command(argv0, args...)
+	return execJUnit(ctx, cmd, env)
 }
 
-func execJUnit(cmd *exec.Cmd, env []string) error {
+// ExecJUnitTimeout is like ExecJUnitContext with a timeout, no timeout if it is 0
+func ExecJUnitTimeout(timeout time.Duration, argv0 string, args []string, env []string) error {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	return ExecJUnitContext(ctx, argv0, args, env)
+}
+
+// ExecJUnitContextTee is like ExecJUnitContext, except that the output is
+// also written to w, e.g. to parse it
+func ExecJUnitContextTee(ctx context.Context, w io.Writer, argv0 string, args []string, env []string) error {
+	cmd := exec.Command(argv0, args...)
+	return execJUnitTee(ctx, cmd, env, w)
+}
+
+func execJUnit(ctx context.Context, cmd *exec.Cmd, env []string) error {
+	return execJUnitTee(ctx, cmd, env, nil)
+}
+
+func execJUnitTee(ctx context.Context, cmd *exec.Cmd, env []string, w io.Writer) error {
 	cmd.Env = env
 
 	// inherit some standard file descriptors, as if `syscall.Exec`ed
 	cmd.Stdin = os.Stdin
 	// ensure we also capture output
 	var systemout bytes.Buffer
+	var captured io.Writer = &systemout
+	if w != nil {
+		captured = io.MultiWriter(&systemout, w)
+	}
 	syncSystemOut := &mutexWriter{
-		writer: &systemout,
+		writer: captured,
 	}
 	cmd.Stdout = io.MultiWriter(syncSystemOut, os.Stdout)
 	cmd.Stderr = io.MultiWriter(syncSystemOut, os.Stderr)
 
 	// actually execute, return a JUnit error if the command errors
-	if err := execCmdWithSignals(cmd); err != nil {
+	if err := execCmdWithSignals(ctx, cmd); err != nil {
 		return &execJunitError{
 			error:     err,
 			systemout: systemout.String(),
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+// Task is a unit of work of ExecParallel, e.g. the commands run for a node
+type Task struct {
+	// Name identifies the task in the JUnit output and errors
+	Name string
+	Run  func() error
+}
+
+// ExecParallel runs tasks with at most parallelism of them at once, waiting
+// for all of them to finish. Each task is recorded with the Recorder set by
+// SetRecorder, if any, so that it gets a JUnit testcase of its own.
+// The failed tasks are returned as a metadata.JUnitError.
+func ExecParallel(parallelism int, tasks []Task) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	r := currentRecorder()
+	sem := make(chan struct{}, parallelism)
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task Task) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			errs[i] = task.Run()
+			if r != nil {
+				r.RecordCommand(task.Name, time.Since(start), errs[i], systemOut(errs[i]))
+			}
+		}(i, task)
+	}
+	wg.Wait()
+
+	var failed []string
+	var systemout strings.Builder
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", tasks[i].Name, err))
+		if out := systemOut(err); out != "" {
+			fmt.Fprintf(&systemout, "=== %s\n%s\n", tasks[i].Name, out)
+		}
+	}
+	if len(failed) > 0 {
+		return &execJunitError{
+			error:     fmt.Errorf("%d of %d tasks failed: %s", len(failed), len(tasks), strings.Join(failed, "; ")),
+			systemout: systemout.String(),
+		}
+	}
+	return nil
+}
+
+func systemOut(err error) string {
+	var junitErr metadata.JUnitError
+	if errors.As(err, &junitErr) {
+		return junitErr.SystemOut()
+	}
+	return ""
+}
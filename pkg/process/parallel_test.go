@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeRecorder struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (f *fakeRecorder) RecordCommand(command string, duration time.Duration, err error, output string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, command)
+}
+
+func TestExecParallel(t *testing.T) {
+	recorder := &fakeRecorder{}
+	SetRecorder(recorder)
+	defer SetRecorder(nil)
+
+	var running, maxRunning int32
+	var tasks []Task
+	for i := 0; i < 10; i++ {
+		i := i
+		tasks = append(tasks, Task{
+			Name: fmt.Sprintf("node-%d", i),
+			Run: func() error {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				if i%5 == 0 {
+					return errors.New("unreachable")
+				}
+				return nil
+			},
+		})
+	}
+
+	err := ExecParallel(3, tasks)
+	if err == nil {
+		t.Fatal("expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "2 of 10 tasks failed") ||
+		!strings.Contains(err.Error(), "node-0: unreachable") || !strings.Contains(err.Error(), "node-5: unreachable") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 tasks at once, got %d", maxRunning)
+	}
+	if len(recorder.commands) != len(tasks) {
+		t.Errorf("expected each task to be recorded, got %v", recorder.commands)
+	}
+}
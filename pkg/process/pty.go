@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// ExecWithPTY is like ExecJUnit, except that the command is run in a
+// pseudo-terminal, for tools that behave differently without a TTY, e.g.
+// gcloud prompts or ssh. Stdin is forwarded to the terminal and its output
+// is still captured and recorded, see CaptureOutput and RecordCommand.
+func ExecWithPTY(argv0 string, args []string, env []string) error {
+	return ExecWithPTYInDir("", argv0, args, env)
+}
+
+// ExecWithPTYInDir is like ExecWithPTY, except that the command runs in dir
+func ExecWithPTYInDir(dir, argv0 string, args []string, env []string) error {
+	ptm, pts, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("failed to open a pseudo-terminal for %s: %w", argv0, err)
+	}
+	defer ptm.Close()
+
+	cmd := exec.Command(argv0, args...)
+	cmd.Env = env
+	cmd.Dir = dir
+	cmd.Stdin = pts
+	cmd.Stdout = pts
+	cmd.Stderr = pts
+	setControllingTerminal(cmd)
+
+	// the command has to write to the terminal itself, so its output is
+	// teed here instead of in execCmdWithSignals
+	var systemout bytes.Buffer
+	out, _, done := CaptureOutput(cmd.Path, io.MultiWriter(os.Stdout, &systemout), nil)
+	defer done()
+	out, _, record := RecordCommand(cmd, out, nil)
+
+	stopStdin := forwardStdin(ptm)
+	copied := make(chan struct{})
+	go func() {
+		// reading fails once the command exited and the terminal is closed
+		_, _ = io.Copy(out, ptm)
+		close(copied)
+	}()
+
+	err = runCmdWithSignals(context.Background(), cmd)
+	stopStdin()
+	pts.Close()
+	<-copied
+	record(err)
+	if err != nil {
+		return &execJunitError{
+			error:     err,
+			systemout: systemout.String(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// openPTY opens a pseudo-terminal, returning its master and slave ends
+func openPTY() (*os.File, *os.File, error) {
+	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var unlock int32
+	if err := ioctl(ptm.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		ptm.Close()
+		return nil, nil, fmt.Errorf("failed to unlock pseudo-terminal: %w", err)
+	}
+	var n uint32
+	if err := ioctl(ptm.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		ptm.Close()
+		return nil, nil, fmt.Errorf("failed to get pseudo-terminal number: %w", err)
+	}
+	pts, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	// the size defaults to 0x0, which some tools do not cope with
+	size := struct{ rows, cols, x, y uint16 }{rows: 24, cols: 80}
+	_ = ioctl(pts.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&size)))
+	return ptm, pts, nil
+}
+
+// setControllingTerminal makes the stdin of cmd its controlling terminal,
+// in a session of its own
+func setControllingTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+}
+
+// forwardStdin copies stdin to w until stop is called. Stdin is read through
+// a non-blocking duplicate, so that stop interrupts the pending read instead
+// of leaving the copy blocked on stdin for the lifetime of the process.
+func forwardStdin(w io.Writer) (stop func()) {
+	fd, err := syscall.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return func() {}
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return func() {}
+	}
+	stdin := os.NewFile(uintptr(fd), "stdin")
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(w, stdin)
+		close(copied)
+	}()
+	return func() {
+		// regular files, which cannot have deadlines, do not block anyway
+		_ = stdin.SetReadDeadline(time.Now())
+		<-copied
+		stdin.Close()
+		// the duplicate shares the non-blocking flag with stdin
+		_ = syscall.SetNonblock(int(os.Stdin.Fd()), false)
+	}
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
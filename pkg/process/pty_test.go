@@ -0,0 +1,64 @@
+//go:build linux
+
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecWithPTY(t *testing.T) {
+	// fail to get the output recorded in the error
+	err := ExecWithPTY("sh", []string{"-c", "test -t 0 && test -t 1 && echo tty; exit 3"}, os.Environ())
+	junitErr, ok := err.(*execJunitError)
+	if !ok {
+		t.Fatalf("expected a JUnitError, got %v", err)
+	}
+	if !strings.Contains(junitErr.SystemOut(), "tty") {
+		t.Errorf("expected the command to run in a terminal, got output %q", junitErr.SystemOut())
+	}
+}
+
+func TestForwardStdinStops(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	var out bytes.Buffer
+	stop := forwardStdin(&out)
+	stopped := make(chan struct{})
+	go func() {
+		// nothing is written, so the copy is blocked reading stdin
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected stop to interrupt the copy of stdin")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecordedOutput is the number of bytes at the end of the output of
+// a failed command that are recorded
+const maxRecordedOutput = 1 << 20
+
+// Recorder records the commands executed, e.g. as JUnit testcases
+type Recorder interface {
+	RecordCommand(command string, duration time.Duration, err error, output string)
+}
+
+var recorder struct {
+	mu sync.Mutex
+	r  Recorder
+}
+
+// SetRecorder makes every command executed from now on be recorded with r,
+// see RecordCommand.
+func SetRecorder(r Recorder) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.r = r
+}
+
+func currentRecorder() Recorder {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.r
+}
+
+// RecordCommand returns stdout and stderr teed into a buffer keeping the
+// end of the output of cmd. done records the command with the Recorder set
+// by SetRecorder, if any, and in the audit log set by SetAuditLog, if any,
+// once it has exited with err.
+func RecordCommand(cmd *exec.Cmd, stdout, stderr io.Writer) (io.Writer, io.Writer, func(err error)) {
+	start := time.Now()
+	r := currentRecorder()
+	if r == nil {
+		return stdout, stderr, func(err error) {
+			auditCommand(cmd, start, err)
+		}
+	}
+	output := &tailBuffer{max: maxRecordedOutput}
+	out := &mutexWriter{writer: output}
+	return tee(stdout, out), tee(stderr, out), func(err error) {
+		r.RecordCommand(strings.Join(cmd.Args, " "), time.Since(start), err, string(output.buf))
+		auditCommand(cmd, start, err)
+	}
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(b []byte) (int, error) {
+	t.buf = append(t.buf, b...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(b), nil
+}
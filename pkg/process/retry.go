@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// RetryPolicy describes when and how often ExecWithRetry retries a command
+type RetryPolicy struct {
+	// Attempts is the number of times the command is run at most
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for each retry
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries, if set
+	MaxBackoff time.Duration
+	// RetryableExitCodes are the exit codes a failed command is retried on
+	RetryableExitCodes []int
+	// RetryableStderrPatterns are retried on if the stderr of a failed
+	// command matches any of them
+	RetryableStderrPatterns []*regexp.Regexp
+}
+
+// GCloudRetryPolicy retries gcloud and gsutil commands failing on transient
+// errors of the GCP APIs
+var GCloudRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Backoff:    10 * time.Second,
+	MaxBackoff: time.Minute,
+	RetryableStderrPatterns: []*regexp.Regexp{
+		regexp.MustCompile(`(?i)internal error`),
+		regexp.MustCompile(`(?i)try again`),
+		regexp.MustCompile(`(?i)rate limit`),
+		regexp.MustCompile(`(?i)quota exceeded`),
+		regexp.MustCompile(`\b(429|500|502|503|504)\b`),
+		regexp.MustCompile(`UNAVAILABLE|RESOURCE_EXHAUSTED|DEADLINE_EXCEEDED|backendError`),
+		regexp.MustCompile(`(?i)connection reset|i/o timeout|TLS handshake timeout`),
+	},
+}
+
+// retryable returns true if a command failing with err and stderr should be
+// retried. Any failure is retried if the policy matches neither exit codes
+// nor stderr.
+func (p RetryPolicy) retryable(err error, stderr string) bool {
+	if len(p.RetryableExitCodes) == 0 && len(p.RetryableStderrPatterns) == 0 {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		for _, code := range p.RetryableExitCodes {
+			if exitErr.ExitCode() == code {
+				return true
+			}
+		}
+	}
+	for _, pattern := range p.RetryableStderrPatterns {
+		if pattern.MatchString(stderr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecWithRetry is like ExecJUnit, except that the command is retried with
+// backoff while it fails in a way that is retryable according to policy.
+// The output of every attempt is recorded in the returned metadata.JUnitError.
+func ExecWithRetry(policy RetryPolicy, argv0 string, args []string, env []string) error {
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := policy.Backoff
+	command := strings.Join(append([]string{argv0}, args...), " ")
+
+	var systemout bytes.Buffer
+	for attempt := 1; ; attempt++ {
+		cmd := exec.Command(argv0, args...)
+		cmd.Env = env
+		cmd.Stdin = os.Stdin
+		var stderr bytes.Buffer
+		syncSystemOut := &mutexWriter{
+			writer: &systemout,
+		}
+		cmd.Stdout = io.MultiWriter(syncSystemOut, os.Stdout)
+		cmd.Stderr = io.MultiWriter(syncSystemOut, &stderr, os.Stderr)
+
+		fmt.Fprintf(&systemout, "=== attempt %d of %d: %s\n", attempt, attempts, command)
+		err := execCmdWithSignals(context.Background(), cmd)
+		if err == nil {
+			return nil
+		}
+		fmt.Fprintf(&systemout, "=== attempt %d of %d failed: %v\n", attempt, attempts, err)
+
+		if attempt == attempts || !policy.retryable(err, stderr.String()) {
+			return &execJunitError{
+				error:     fmt.Errorf("%s failed after %d attempt(s): %w", command, attempt, err),
+				systemout: systemout.String(),
+			}
+		}
+		klog.Warningf("%s failed: %v, retrying in %v", command, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
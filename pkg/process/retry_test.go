@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/kubetest2/pkg/metadata"
+)
+
+func TestExecWithRetry(t *testing.T) {
+	// fails with the given exit code and stderr until the nth attempt
+	script := `n=$(cat "$COUNT" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$COUNT"
+if [ $n -lt "$SUCCEED_AT" ]; then echo "$STDERR" >&2; exit "$CODE"; fi`
+
+	cases := []struct {
+		name           string
+		policy         RetryPolicy
+		code           string
+		stderr         string
+		succeedAt      string
+		expectAttempts int
+		expectError    bool
+	}{
+		{
+			name:           "succeeds after retries",
+			policy:         RetryPolicy{Attempts: 3},
+			code:           "1",
+			succeedAt:      "3",
+			expectAttempts: 3,
+		},
+		{
+			name:           "gives up after attempts",
+			policy:         RetryPolicy{Attempts: 2},
+			code:           "1",
+			succeedAt:      "5",
+			expectAttempts: 2,
+			expectError:    true,
+		},
+		{
+			name:           "retryable exit code",
+			policy:         RetryPolicy{Attempts: 3, RetryableExitCodes: []int{75}},
+			code:           "75",
+			succeedAt:      "2",
+			expectAttempts: 2,
+		},
+		{
+			name:           "non retryable exit code",
+			policy:         RetryPolicy{Attempts: 3, RetryableExitCodes: []int{75}},
+			code:           "1",
+			succeedAt:      "2",
+			expectAttempts: 1,
+			expectError:    true,
+		},
+		{
+			name: "retryable stderr",
+			policy: RetryPolicy{Attempts: 3, RetryableStderrPatterns: []*regexp.Regexp{
+				regexp.MustCompile("try again"),
+			}},
+			code:           "1",
+			stderr:         "backend unavailable, try again later",
+			succeedAt:      "2",
+			expectAttempts: 2,
+		},
+		{
+			name: "non retryable stderr",
+			policy: RetryPolicy{Attempts: 3, RetryableStderrPatterns: []*regexp.Regexp{
+				regexp.MustCompile("try again"),
+			}},
+			code:           "1",
+			stderr:         "permission denied",
+			succeedAt:      "2",
+			expectAttempts: 1,
+			expectError:    true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			count := filepath.Join(t.TempDir(), "count")
+			env := append(os.Environ(),
+				"COUNT="+count, "CODE="+tc.code, "STDERR="+tc.stderr, "SUCCEED_AT="+tc.succeedAt)
+			err := ExecWithRetry(tc.policy, "sh", []string{"-c", script}, env)
+			if err == nil && tc.expectError {
+				t.Fatal("expected error but got none")
+			}
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				junitErr, ok := err.(metadata.JUnitError)
+				if !ok {
+					t.Fatalf("expected a JUnitError, got %T", err)
+				}
+				if n := strings.Count(junitErr.SystemOut(), "failed: exit status"); n != tc.expectAttempts {
+					t.Errorf("expected the failed attempts to be recorded, got %q", junitErr.SystemOut())
+				}
+			}
+			attempts, err := os.ReadFile(count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(attempts)); got != strconv.Itoa(tc.expectAttempts) {
+				t.Errorf("expected %d attempts, got %s", tc.expectAttempts, got)
+			}
+		})
+	}
+}
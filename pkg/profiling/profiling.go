@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package profiling collects pprof profiles of the control plane and the
+// nodes of a cluster while it is being tested, to debug the performance
+// regressions only seen in e2e runs.
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// cpuProfileSeconds is how long each CPU profile samples for
+	cpuProfileSeconds = 10
+	// maxConcurrentProfiles bounds the profiles taken at once, as clusters
+	// may have many nodes
+	maxConcurrentProfiles = 10
+)
+
+// target is a component whose pprof endpoints are reachable through the
+// apiserver at path
+type target struct {
+	name string
+	path string
+}
+
+// profile is a kind of pprof profile, and the query taking it
+type profile struct {
+	name  string
+	query string
+}
+
+var profiles = []profile{
+	{name: "cpu", query: fmt.Sprintf("profile?seconds=%d", cpuProfileSeconds)},
+	{name: "heap", query: "heap"},
+}
+
+// targets returns the apiserver and the kubelets of nodes, whose debug
+// handlers are proxied by the apiserver. etcd is not profiled, as it only
+// serves pprof with --enable-pprof and on no port the apiserver proxies.
+func targets(nodes []string) []target {
+	t := []target{{name: "kube-apiserver", path: "/debug/pprof"}}
+	for _, node := range nodes {
+		t = append(t, target{name: "kubelet-" + node, path: "/api/v1/nodes/" + node + "/proxy/debug/pprof"})
+	}
+	return t
+}
+
+// profilePath returns the path of the profile of t taken at time at in dir
+func profilePath(dir string, t target, p profile, at time.Time) string {
+	return filepath.Join(dir, t.name, fmt.Sprintf("%s-%s.pprof", p.name, at.UTC().Format("20060102-150405")))
+}
+
+// Collector periodically takes the CPU and heap profiles of the apiserver and
+// the kubelets of the cluster of Kubeconfig, written to Dir.
+type Collector struct {
+	Kubeconfig string
+	Dir        string
+	Interval   time.Duration
+}
+
+// Start starts collecting profiles every c.Interval until the returned
+// function is called, which waits for the profiles being taken.
+func (c *Collector) Start() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			c.collect(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// collect takes a profile of each kind of every target once
+func (c *Collector) collect(ctx context.Context) {
+	nodes, err := exec.OutputLines(c.kubectl(ctx, "get", "nodes", "-o", "jsonpath={range .items[*]}{.metadata.name}{\"\\n\"}{end}"))
+	if err != nil {
+		if ctx.Err() == nil {
+			klog.Warningf("failed to list the nodes to profile: %v", err)
+		}
+		nodes = nil
+	}
+	at := time.Now()
+	sem := make(chan struct{}, maxConcurrentProfiles)
+	var wg sync.WaitGroup
+	for _, t := range targets(nonEmpty(nodes)) {
+		for _, p := range profiles {
+			t, p := t, p
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := c.take(ctx, t, p, at); err != nil && ctx.Err() == nil {
+					klog.V(2).Infof("failed to take the %s profile of %s: %v", p.name, t.name, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// take writes the profile p of t
+func (c *Collector) take(ctx context.Context, t target, p profile, at time.Time) error {
+	path := profilePath(c.Dir, t, p, at)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := exec.Output(c.kubectl(ctx, "get", "--raw", t.path+"/"+p.query))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// kubectl returns an unrecorded kubectl command, as the profiles are only
+// meant to be written to their .pprof files
+func (c *Collector) kubectl(ctx context.Context, args ...string) exec.Cmd {
+	cmd := exec.CommandContext(ctx, "kubectl", args...).SetRecorded(false)
+	if c.Kubeconfig != "" {
+		cmd.SetEnv(append(os.Environ(), "KUBECONFIG="+c.Kubeconfig)...)
+	}
+	return cmd
+}
+
+func nonEmpty(lines []string) []string {
+	var out []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profiling
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTargets(t *testing.T) {
+	expected := []target{
+		{name: "kube-apiserver", path: "/debug/pprof"},
+		{name: "kubelet-node-a", path: "/api/v1/nodes/node-a/proxy/debug/pprof"},
+		{name: "kubelet-node-b", path: "/api/v1/nodes/node-b/proxy/debug/pprof"},
+	}
+	if got := targets(nonEmpty([]string{"node-a", "", "node-b "})); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected targets %v, got %v", expected, got)
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 5, 0, time.UTC)
+	path := profilePath("/artifacts/profiles", target{name: "kubelet-node-a"}, profiles[1], at)
+	if expected := "/artifacts/profiles/kubelet-node-a/heap-20240301-123005.pprof"; path != expected {
+		t.Errorf("expected %s, got %s", expected, path)
+	}
+}
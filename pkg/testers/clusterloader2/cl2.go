@@ -27,6 +27,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/flagschema"
 	"sigs.k8s.io/kubetest2/pkg/testers"
 	suite "sigs.k8s.io/kubetest2/pkg/testers/clusterloader2/suite"
 )
@@ -34,6 +35,7 @@ import (
 var GitTag string
 
 type Tester struct {
+	Preset                    string `desc:"Well-known scale test setup to run against a kubemark cluster, one of load-100, load-500 or load-5000. Sets the provider, the number of nodes, the suites, the overrides and prometheus, unless set by their flags."`
 	Suites                    string `desc:"Comma separated list of standard scale testing suites e.g. load, density"`
 	TestOverrides             string `desc:"Comma separated list of paths to the config override files. The latter overrides take precedence over changes in former files."`
 	TestConfigs               string `desc:"Comma separated list of paths to test config files."`
@@ -87,6 +89,9 @@ func (t *Tester) Test() error {
 		"--kubeconfig=" + t.KubeConfig,
 		"--report-dir=" + t.ReportDir,
 	}
+	if t.Nodes > 0 {
+		args = append(args, fmt.Sprintf("--nodes=%d", t.Nodes))
+	}
 	for _, tc := range testConfigs {
 		if tc != "" {
 			args = append(args, "--testconfig="+tc)
@@ -127,10 +132,16 @@ func (t *Tester) Execute() error {
 	}
 
 	if *help {
+		if flagschema.Requested() {
+			return flagschema.Write(os.Stdout, flagschema.FromFlagSet(fs))
+		}
 		fs.SetOutput(os.Stdout)
 		fs.PrintDefaults()
 		return nil
 	}
+	if err := t.applyPreset(fs.Changed); err != nil {
+		return err
+	}
 	if err := testers.WriteVersionToMetadata(GitTag); err != nil {
 		return err
 	}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterloader2
+
+import (
+	"fmt"
+	"sort"
+)
+
+// preset is a well-known scale test setup, whose settings apply to the flags
+// that are not set explicitly
+type preset struct {
+	Provider      string
+	Nodes         int
+	Suites        string
+	TestOverrides string
+	// EnablePrometheusServer collects the measurements that need prometheus
+	EnablePrometheusServer bool
+}
+
+// presets are the setups of the kubemark scalability jobs, run against a
+// kubemark cluster of as many hollow nodes as the preset has nodes
+var presets = map[string]preset{
+	"load-100": {
+		Provider:      "kubemark",
+		Nodes:         100,
+		Suites:        "load",
+		TestOverrides: "testing/experiments/use_simple_latency_query.yaml",
+	},
+	"load-500": {
+		Provider:               "kubemark",
+		Nodes:                  500,
+		Suites:                 "load",
+		TestOverrides:          "testing/experiments/use_simple_latency_query.yaml",
+		EnablePrometheusServer: true,
+	},
+	"load-5000": {
+		Provider: "kubemark",
+		Nodes:    5000,
+		Suites:   "load",
+		TestOverrides: "testing/overrides/kubemark_5000_nodes.yaml," +
+			"testing/experiments/enable_restart_count_check.yaml," +
+			"testing/experiments/use_simple_latency_query.yaml",
+		EnablePrometheusServer: true,
+	},
+}
+
+// presetNames returns the names of the presets, sorted
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPreset applies --preset to the flags for which changed returns false
+func (t *Tester) applyPreset(changed func(flag string) bool) error {
+	if t.Preset == "" {
+		return nil
+	}
+	p, ok := presets[t.Preset]
+	if !ok {
+		return fmt.Errorf("unknown --preset %q, expected one of %v", t.Preset, presetNames())
+	}
+	if !changed("provider") {
+		t.Provider = p.Provider
+	}
+	if !changed("nodes") {
+		t.Nodes = p.Nodes
+	}
+	if !changed("suites") {
+		t.Suites = p.Suites
+	}
+	if !changed("test-overrides") {
+		t.TestOverrides = p.TestOverrides
+	}
+	if !changed("enable-prometheus-server") {
+		t.EnablePrometheusServer = p.EnablePrometheusServer
+	}
+	return nil
+}
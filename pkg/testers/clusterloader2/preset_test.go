@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterloader2
+
+import (
+	"testing"
+
+	"github.com/octago/sflags/gen/gpflag"
+)
+
+func TestApplyPreset(t *testing.T) {
+	tester := &Tester{Preset: "load-5000", Provider: "skeleton", Nodes: 4000}
+	changed := map[string]bool{"nodes": true}
+	if err := tester.applyPreset(func(flag string) bool { return changed[flag] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tester.Provider != "kubemark" || tester.Suites != "load" || !tester.EnablePrometheusServer {
+		t.Errorf("expected the preset to be applied, got %+v", tester)
+	}
+	if tester.Nodes != 4000 {
+		t.Errorf("expected --nodes to take precedence over the preset, got %d", tester.Nodes)
+	}
+
+	tester = &Tester{Preset: "load-50000"}
+	if err := tester.applyPreset(func(string) bool { return false }); err == nil {
+		t.Errorf("expected an error for an unknown preset")
+	}
+}
+
+func TestPresetFlags(t *testing.T) {
+	fs, err := gpflag.Parse(&Tester{})
+	if err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	// the flags applyPreset checks must exist
+	for _, flag := range []string{"provider", "nodes", "suites", "test-overrides", "enable-prometheus-server"} {
+		if fs.Lookup(flag) == nil {
+			t.Errorf("expected flag --%s", flag)
+		}
+	}
+}
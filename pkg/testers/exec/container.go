@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// containerEnvPrefix is the prefix of the variables kubetest2 sets for
+// testers, passed to the container of --image
+const containerEnvPrefix = "KUBETEST2_"
+
+// containerArgv returns the command running argv in a container of --image.
+// The paths the test command is given by kubetest2 are mounted at the same
+// paths, so that they need no translation, and it runs as the current user so
+// that the artifacts it writes are owned by it.
+func (t *Tester) containerArgv(argv []string, artifactsDir string) ([]string, error) {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(artifactsDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create the artifacts directory: %v", err)
+	}
+	run := []string{t.ContainerRuntime, "run", "--rm",
+		// the API server may only be reachable from the host, e.g. with kind
+		"--network=host",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		// the current user has no home in the image
+		"-e", "HOME=/tmp",
+		"-e", "ARTIFACTS=" + artifactsDir,
+		"-v", workdir + ":" + workdir,
+		"-w", workdir,
+	}
+	if artifactsDir != workdir {
+		run = append(run, "-v", artifactsDir+":"+artifactsDir)
+	}
+	for _, kubeconfig := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if kubeconfig == "" {
+			continue
+		}
+		if kubeconfig, err = filepath.Abs(kubeconfig); err != nil {
+			return nil, err
+		}
+		run = append(run, "-v", kubeconfig+":"+kubeconfig+":ro")
+	}
+
+	keys, err := t.containerEnvKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		// the values are taken from the environment of the runtime, to keep
+		// them out of its command line
+		run = append(run, "-e", key)
+	}
+	run = append(run, t.Image)
+	return append(run, argv...), nil
+}
+
+// containerEnvKeys returns the names of the variables passed to the container
+// of --image, the host environment being that of another system
+func (t *Tester) containerEnvKeys() ([]string, error) {
+	extra, err := t.extraEnv()
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if key == "KUBECONFIG" || strings.HasPrefix(key, containerEnvPrefix) {
+			set[key] = true
+		}
+	}
+	for _, kv := range extra {
+		key, _, _ := strings.Cut(kv, "=")
+		if key != "ARTIFACTS" {
+			set[key] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
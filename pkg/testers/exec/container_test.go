@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestContainerArgv(t *testing.T) {
+	workdir := t.TempDir()
+	artifactsDir := filepath.Join(workdir, "_artifacts")
+	t.Setenv("KUBECONFIG", "/tmp/kind.kubeconfig")
+	t.Setenv("KUBETEST2_RUN_ID", "abc")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/host/creds.json")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tester := &Tester{
+		Image:            "golang:1.20",
+		ContainerRuntime: "podman",
+		Env:              []string{"FOO=bar", "ARTIFACTS=/elsewhere"},
+	}
+	argv, err := tester.containerArgv([]string{"go", "test", "./..."}, artifactsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"podman", "run", "--rm",
+		"--network=host",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-e", "HOME=/tmp",
+		"-e", "ARTIFACTS=" + artifactsDir,
+		"-v", workdir + ":" + workdir,
+		"-w", workdir,
+		"-v", artifactsDir + ":" + artifactsDir,
+		"-v", "/tmp/kind.kubeconfig:/tmp/kind.kubeconfig:ro",
+		"-e", "FOO",
+		"-e", "KUBECONFIG",
+		"-e", "KUBETEST2_RUN_ID",
+		"golang:1.20", "go", "test", "./...",
+	}
+	if !reflect.DeepEqual(argv, expected) {
+		t.Errorf("expected %q, got %q", expected, argv)
+	}
+	if _, err := os.Stat(artifactsDir); err != nil {
+		t.Errorf("expected the artifacts directory to be created: %v", err)
+	}
+}
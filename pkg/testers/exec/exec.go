@@ -17,13 +17,21 @@ limitations under the License.
 package exec
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	osexec "os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/octago/sflags/gen/gpflag"
 	"k8s.io/klog/v2"
 
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/flagschema"
+	"sigs.k8s.io/kubetest2/pkg/metadata"
 	"sigs.k8s.io/kubetest2/pkg/process"
 	"sigs.k8s.io/kubetest2/pkg/testers"
 )
@@ -31,10 +39,28 @@ import (
 var GitTag string
 
 type Tester struct {
+	// bound in Execute, as values may contain commas
+	Env     []string `flag:"-"`
+	EnvFile string   `desc:"Path to a file of environment variables to set for the test command, one KEY=VALUE per line. Blank lines and lines starting with # are ignored."`
+	Workdir string   `desc:"Directory to run the test command in."`
+
+	ResultFormat     string `desc:"If set, parse the output of the test command into testcases written to junit_exec.xml among the artifacts, one of tap for the Test Anything Protocol, or regex to use --pass-regex, --fail-regex and --skip-regex."`
+	PassRegex        string `desc:"Regular expression matching the lines of passed testcases with --result-format=regex, whose first group, if any, is the name of the testcase, e.g. '^--- PASS: (\\S+)'."`
+	FailRegex        string `desc:"Regular expression matching the lines of failed testcases with --result-format=regex, as --pass-regex. The output since the previous testcase is kept as that of the failure."`
+	SkipRegex        string `desc:"Regular expression matching the lines of skipped testcases with --result-format=regex, as --pass-regex."`
+	SuccessExitCodes []int  `desc:"Exit codes of the test command considered a success. Defaults to 0."`
+
+	Timeout time.Duration `desc:"If set, the test command is killed once it has run for this long, failing the attempt."`
+	Retries int           `desc:"Number of times a failed test command is retried. Each attempt is recorded in junit_exec.xml among the artifacts, as testcases of the same name."`
+
+	Image            string `desc:"If set, the test command is run in a container of this image, on the host network, with the kubeconfig, the artifacts and the working directory mounted at the same paths. Only KUBECONFIG, ARTIFACTS, KUBETEST2_* and the variables of --env and --env-file are passed to the container."`
+	ContainerRuntime string `desc:"Container runtime CLI to run --image with, e.g. docker or podman."`
+
 	argv []string
 }
 
-const usage = `kubetest2 --test=exec --  [TestCommand] [TestArgs]
+const usage = `kubetest2 --test=exec -- [Flags] [TestCommand] [TestArgs]
+  Flags:       flags of the exec tester, before the test command
   TestCommand: the command to invoke for testing
   TestArgs:    arguments passed to test command
 `
@@ -45,8 +71,11 @@ func (t *Tester) Execute() error {
 		return fmt.Errorf("failed to initialize tester: %v", err)
 	}
 
+	fs.StringArrayVar(&t.Env, "env", nil, "KEY=VALUE environment variable to set for the test command, can be repeated. Takes precedence over --env-file.")
+
 	fs.Usage = func() {
 		fmt.Print(usage)
+		fs.PrintDefaults()
 	}
 
 	if len(os.Args) < 2 {
@@ -54,17 +83,22 @@ func (t *Tester) Execute() error {
 		return nil
 	}
 
-	// gracefully handle -h or --help if it is the only argument
 	help := fs.BoolP("help", "h", false, "")
-	// we don't care about errors, only if -h / --help was set
-	_ = fs.Parse(os.Args[1:2])
+	// the flags of the tester end at the test command
+	fs.SetInterspersed(false)
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return fmt.Errorf("failed to parse flags: %v", err)
+	}
 
-	if *help {
+	if *help && flagschema.Requested() {
+		return flagschema.Write(os.Stdout, flagschema.FromFlagSet(fs))
+	}
+	if *help || fs.NArg() == 0 {
 		fs.Usage()
 		return nil
 	}
 
-	t.argv = os.Args[1:]
+	t.argv = fs.Args()
 	if err := testers.WriteVersionToMetadata(GitTag); err != nil {
 		return err
 	}
@@ -86,12 +120,189 @@ func expandEnv(args []string) []string {
 }
 
 func (t *Tester) Test() error {
+	env, err := t.testEnv()
+	if err != nil {
+		return err
+	}
+	var parser *resultParser
+	if t.ResultFormat != "" {
+		if parser, err = newResultParser(t.ResultFormat, t.PassRegex, t.FailRegex, t.SkipRegex); err != nil {
+			return err
+		}
+	}
+	// resolved before changing to --workdir, as it may be relative
+	artifactsDir := artifacts.BaseDir()
+	resultsPath := filepath.Join(artifactsDir, resultsFile)
+	if t.Workdir != "" {
+		if err := os.Chdir(t.Workdir); err != nil {
+			return fmt.Errorf("failed to change to --workdir: %v", err)
+		}
+	}
+
 	expandedArgs := expandEnv(t.argv)
-	return process.ExecJUnit(expandedArgs[0], expandedArgs[1:], os.Environ())
+	suiteName := filepath.Base(expandedArgs[0])
+	if t.Image != "" {
+		if expandedArgs, err = t.containerArgv(expandedArgs, artifactsDir); err != nil {
+			return err
+		}
+	}
+	if parser == nil && t.Retries == 0 {
+		return t.run(expandedArgs, env, nil)
+	}
+
+	var cases []testCase
+	for attempt := 1; ; attempt++ {
+		if parser != nil && attempt > 1 {
+			parser, _ = newResultParser(t.ResultFormat, t.PassRegex, t.FailRegex, t.SkipRegex)
+		}
+		err = t.run(expandedArgs, env, parser)
+		cases = append(cases, attemptCases(suiteName, attempt, err, parser)...)
+		if err == nil || attempt > t.Retries {
+			break
+		}
+		klog.Warningf("Attempt %d of %d of the test command failed: %v, retrying", attempt, t.Retries+1, err)
+	}
+	if werr := writeResults(resultsPath, suiteName, cases); werr != nil {
+		klog.Warningf("failed to write the test results: %v", werr)
+	}
+	return err
+}
+
+// run runs the test command once, within --timeout, teeing its output to
+// parser if set
+func (t *Tester) run(argv, env []string, parser *resultParser) error {
+	ctx := context.Background()
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	var err error
+	if parser != nil {
+		err = process.ExecJUnitContextTee(ctx, parser, argv[0], argv[1:], env)
+	} else {
+		err = process.ExecJUnitContext(ctx, argv[0], argv[1:], env)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		klog.Errorf("The test command timed out after --timeout=%v", t.Timeout)
+	}
+	return t.result(err)
+}
+
+// attemptCases returns the testcases of an attempt of the test command that
+// failed with err, those parsed by parser or else one for the whole command
+func attemptCases(suiteName string, attempt int, err error, parser *resultParser) []testCase {
+	var cases []testCase
+	if parser != nil {
+		cases = parser.Cases()
+	}
+	if err == nil && len(cases) > 0 {
+		return cases
+	}
+	if err != nil && hasFailure(cases) {
+		return cases
+	}
+	// the failure must show in the results even if no testcase failed
+	c := testCase{Name: suiteName + " succeeds"}
+	var output string
+	var junitErr metadata.JUnitError
+	if parser == nil && errors.As(err, &junitErr) {
+		output = junitErr.SystemOut()
+		if len(output) > maxCaseOutput {
+			output = output[len(output)-maxCaseOutput:]
+		}
+	}
+	if err != nil {
+		c.Failure = &message{Message: fmt.Sprintf("attempt %d: %v", attempt, err)}
+	}
+	c.setOutput(output)
+	return append(cases, c)
+}
+
+// result returns the result of the test command given the error it exited
+// with, nil if its exit code is one of --success-exit-codes
+func (t *Tester) result(err error) error {
+	var exitErr *osexec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	for _, code := range t.SuccessExitCodes {
+		if exitErr.ExitCode() == code {
+			klog.V(0).Infof("Exit code %d of the test command is considered a success", code)
+			return nil
+		}
+	}
+	return err
+}
+
+func hasFailure(cases []testCase) bool {
+	for _, c := range cases {
+		if c.Failure != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// testEnv returns the environment of the test command, that of the tester
+// with the variables of --env-file and --env
+func (t *Tester) testEnv() ([]string, error) {
+	extra, err := t.extraEnv()
+	if err != nil {
+		return nil, err
+	}
+	return process.SanitizeEnv(append(os.Environ(), extra...)), nil
+}
+
+// extraEnv returns the variables of --env-file and --env
+func (t *Tester) extraEnv() ([]string, error) {
+	var env []string
+	if t.EnvFile != "" {
+		fileEnv, err := readEnvFile(t.EnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --env-file: %v", err)
+		}
+		env = append(env, fileEnv...)
+	}
+	for _, kv := range t.Env {
+		if !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("invalid --env %q, expected KEY=VALUE", kv)
+		}
+	}
+	return append(env, t.Env...), nil
+}
+
+// readEnvFile reads the KEY=VALUE lines of the file at path, ignoring blank
+// lines and comments. An optional export prefix and quotes around the value
+// are stripped, so that simple shell env files can be used.
+func readEnvFile(path string) ([]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env []string
+	for i, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, i+1)
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
 }
 
 func NewDefaultTester() *Tester {
-	return &Tester{}
+	return &Tester{
+		ContainerRuntime: "docker",
+	}
 }
 
 func Main() {
@@ -18,7 +18,9 @@ package exec
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -75,3 +77,73 @@ func TestExpandEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestTestEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "test.env")
+	content := `# run metadata
+export SUITE=conformance
+REGION="us-central1"
+QUOTED='a b'
+
+EMPTY=
+`
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REGION", "us-east1")
+
+	tester := &Tester{
+		Env:     []string{"SUITE=smoke", "FLAGS=a=b,c"},
+		EnvFile: envFile,
+	}
+	env, err := tester.testEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[string]string{}
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		got[key] = value
+	}
+	expected := map[string]string{
+		"SUITE":  "smoke",
+		"REGION": "us-central1",
+		"QUOTED": "a b",
+		"EMPTY":  "",
+		"FLAGS":  "a=b,c",
+	}
+	for key, value := range expected {
+		if actual, ok := got[key]; !ok || actual != value {
+			t.Errorf("expected %s=%q, got %q", key, value, actual)
+		}
+	}
+}
+
+func TestTestEnvErrors(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(envFile, []byte("NOT A VARIABLE\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name   string
+		tester *Tester
+	}{
+		{
+			name:   "invalid env",
+			tester: &Tester{Env: []string{"FOO"}},
+		},
+		{
+			name:   "invalid env file",
+			tester: &Tester{EnvFile: envFile},
+		},
+		{
+			name:   "missing env file",
+			tester: &Tester{EnvFile: filepath.Join(t.TempDir(), "missing.env")},
+		},
+	}
+	for _, tc := range testCases {
+		if _, err := tc.tester.testEnv(); err == nil {
+			t.Errorf("%s: expected error but got none", tc.name)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// formats of --result-format
+	formatTAP   = "tap"
+	formatRegex = "regex"
+
+	// resultsFile is the name of the JUnit file the testcases parsed from
+	// the output are written to, among the artifacts
+	resultsFile = "junit_exec.xml"
+	// maxCaseOutput bounds the output kept per testcase
+	maxCaseOutput = 64 * 1024
+)
+
+// tapRegex matches the test lines of the Test Anything Protocol, e.g.
+// "not ok 2 - connects # TODO not implemented"
+var tapRegex = regexp.MustCompile(`^(ok|not ok)\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(\w+)\b\s*(.*))?$`)
+
+type testCase struct {
+	Name    string   `xml:"name,attr"`
+	Failure *message `xml:"failure,omitempty"`
+	Skipped *message `xml:"skipped,omitempty"`
+	Output  string   `xml:"system-out,omitempty"`
+}
+
+type message struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// resultParser parses the testcases out of the output of the test command
+// written to it, with the test lines of TAP or the given regexes
+type resultParser struct {
+	format string
+	pass   *regexp.Regexp
+	fail   *regexp.Regexp
+	skip   *regexp.Regexp
+
+	buf   bytes.Buffer
+	cases []testCase
+	// output since the last testcase, attached to the next testcase with
+	// regexes, as with go test, and to the previous one with TAP, whose
+	// diagnostics follow the test lines
+	output strings.Builder
+}
+
+func newResultParser(format, pass, fail, skip string) (*resultParser, error) {
+	p := &resultParser{format: format}
+	switch format {
+	case formatTAP:
+		return p, nil
+	case formatRegex:
+		if pass == "" && fail == "" {
+			return nil, fmt.Errorf("--result-format=%s requires --pass-regex or --fail-regex", formatRegex)
+		}
+		for _, r := range []struct {
+			flag    string
+			pattern string
+			re      **regexp.Regexp
+		}{
+			{"--pass-regex", pass, &p.pass},
+			{"--fail-regex", fail, &p.fail},
+			{"--skip-regex", skip, &p.skip},
+		} {
+			if r.pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(r.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", r.flag, err)
+			}
+			*r.re = re
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown --result-format %q, expected %s or %s", format, formatTAP, formatRegex)
+	}
+}
+
+func (p *resultParser) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		line, err := p.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			p.buf.Reset()
+			p.buf.WriteString(line)
+			return len(b), nil
+		}
+		p.parseLine(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Cases returns the testcases parsed so far, parsing any incomplete line
+// left as the last one
+func (p *resultParser) Cases() []testCase {
+	if p.buf.Len() > 0 {
+		p.parseLine(p.buf.String())
+		p.buf.Reset()
+	}
+	if p.format == formatTAP {
+		p.attachOutput()
+	}
+	return p.cases
+}
+
+func (p *resultParser) parseLine(line string) {
+	var c *testCase
+	if p.format == formatTAP {
+		c = parseTAPLine(line, len(p.cases)+1)
+	} else {
+		c = p.parseRegexLine(line)
+	}
+	if c == nil {
+		if p.output.Len() < maxCaseOutput {
+			p.output.WriteString(line + "\n")
+		}
+		return
+	}
+	if p.format == formatTAP {
+		p.attachOutput()
+		p.cases = append(p.cases, *c)
+		return
+	}
+	c.setOutput(p.output.String())
+	p.output.Reset()
+	p.cases = append(p.cases, *c)
+}
+
+// attachOutput attaches the output since the last TAP test line to it
+func (p *resultParser) attachOutput() {
+	if len(p.cases) > 0 && p.output.Len() > 0 {
+		p.cases[len(p.cases)-1].setOutput(p.output.String())
+	}
+	p.output.Reset()
+}
+
+// setOutput sets the output of the testcase, in its failure if it failed
+func (c *testCase) setOutput(output string) {
+	if c.Failure != nil {
+		c.Failure.Text = output
+		return
+	}
+	c.Output = output
+}
+
+// parseTAPLine returns the testcase of a TAP test line, the n-th one
+func parseTAPLine(line string, n int) *testCase {
+	match := tapRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	if match[2] != "" {
+		fmt.Sscan(match[2], &n)
+	}
+	c := &testCase{Name: match[3]}
+	if c.Name == "" {
+		c.Name = fmt.Sprintf("test %d", n)
+	}
+	switch directive := strings.ToUpper(match[4]); {
+	case directive == "SKIP", directive == "TODO":
+		// failures of TODO tests are expected
+		c.Skipped = &message{Message: strings.TrimSpace(match[5])}
+	case match[1] == "not ok":
+		c.Failure = &message{Message: "not ok"}
+	}
+	return c
+}
+
+// parseRegexLine returns the testcase of a line matching one of the regexes,
+// named after the first group of the regex or else the whole match
+func (p *resultParser) parseRegexLine(line string) *testCase {
+	name := func(re *regexp.Regexp) (string, bool) {
+		if re == nil {
+			return "", false
+		}
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			return "", false
+		}
+		if len(match) > 1 && match[1] != "" {
+			return match[1], true
+		}
+		return match[0], true
+	}
+	if n, ok := name(p.fail); ok {
+		return &testCase{Name: n, Failure: &message{Message: line}}
+	}
+	if n, ok := name(p.skip); ok {
+		return &testCase{Name: n, Skipped: &message{Message: line}}
+	}
+	if n, ok := name(p.pass); ok {
+		return &testCase{Name: n}
+	}
+	return nil
+}
+
+// writeResults writes the testcases to a JUnit file at path
+func writeResults(path, suiteName string, cases []testCase) error {
+	suite := struct {
+		XMLName  xml.Name   `xml:"testsuite"`
+		Name     string     `xml:"name,attr"`
+		Tests    int        `xml:"tests,attr"`
+		Failures int        `xml:"failures,attr"`
+		Skipped  int        `xml:"skipped,attr"`
+		Cases    []testCase `xml:"testcase"`
+	}{Name: suiteName, Tests: len(cases), Cases: cases}
+	for _, c := range cases {
+		switch {
+		case c.Failure != nil:
+			suite.Failures++
+		case c.Skipped != nil:
+			suite.Skipped++
+		}
+	}
+	raw, err := xml.MarshalIndent(suite, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), raw...), 0644)
+}
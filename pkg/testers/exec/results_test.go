@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultParser(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		pass     string
+		fail     string
+		skip     string
+		output   []string
+		expected []testCase
+	}{
+		{
+			name:   "tap",
+			format: formatTAP,
+			output: []string{
+				"1..4\nok 1 - connects\nnot ok 2 - reads\n",
+				"# expected 1, got 2\nok 3 # SKIP no network\nnot ok 4 - writes # TODO later",
+			},
+			expected: []testCase{
+				{Name: "connects"},
+				{Name: "reads", Failure: &message{Message: "not ok", Text: "# expected 1, got 2\n"}},
+				{Name: "test 3", Skipped: &message{Message: "no network"}},
+				{Name: "writes", Skipped: &message{Message: "later"}},
+			},
+		},
+		{
+			name:   "go test",
+			format: formatRegex,
+			pass:   `^--- PASS: (\S+)`,
+			fail:   `^--- FAIL: (\S+)`,
+			skip:   `^--- SKIP: (\S+)`,
+			output: []string{
+				"=== RUN   TestA\n--- PASS: TestA (0.00s)\n=== RUN   TestB\n    b_test.go:10: wr",
+				"ong\n--- FAIL: TestB (0.01s)\n--- SKIP: TestC (0.00s)\nFAIL\n",
+			},
+			expected: []testCase{
+				{Name: "TestA", Output: "=== RUN   TestA\n"},
+				{
+					Name:    "TestB",
+					Failure: &message{Message: "--- FAIL: TestB (0.01s)", Text: "=== RUN   TestB\n    b_test.go:10: wrong\n"},
+				},
+				{Name: "TestC", Skipped: &message{Message: "--- SKIP: TestC (0.00s)"}},
+			},
+		},
+		{
+			name:   "whole match without groups",
+			format: formatRegex,
+			fail:   `^FAILED .*$`,
+			output: []string{"FAILED test_one\n"},
+			expected: []testCase{
+				{Name: "FAILED test_one", Failure: &message{Message: "FAILED test_one"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newResultParser(tc.format, tc.pass, tc.fail, tc.skip)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, o := range tc.output {
+				fmt.Fprint(p, o)
+			}
+			if cases := p.Cases(); !reflect.DeepEqual(cases, tc.expected) {
+				t.Errorf("expected testcases %+v, got %+v", tc.expected, cases)
+			}
+		})
+	}
+}
+
+func TestNewResultParserErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"junit", "", "", ""},
+		{formatRegex, "", "", ""},
+		{formatRegex, "(", "", ""},
+		{formatRegex, "", "ok", "["},
+	} {
+		if _, err := newResultParser(args[0], args[1], args[2], args[3]); err == nil {
+			t.Errorf("expected an error for %q", args)
+		}
+	}
+}
+
+func TestResult(t *testing.T) {
+	exitErr := func(code int) error {
+		err := osexec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+		if err == nil && code != 0 {
+			t.Fatalf("expected exit code %d", code)
+		}
+		return err
+	}
+	other := errors.New("not started")
+
+	testCases := []struct {
+		name             string
+		err              error
+		successExitCodes []int
+		expectErr        bool
+	}{
+		{name: "success", err: nil},
+		{name: "failure", err: exitErr(1), expectErr: true},
+		{name: "successful exit code", err: exitErr(5), successExitCodes: []int{0, 5}},
+		{name: "wrapped successful exit code", err: fmt.Errorf("test failed: %w", exitErr(5)), successExitCodes: []int{5}},
+		{name: "other exit code", err: exitErr(2), successExitCodes: []int{5}, expectErr: true},
+		{name: "not an exit error", err: other, successExitCodes: []int{1}, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tester := &Tester{SuccessExitCodes: tc.successExitCodes}
+			if err := tester.result(tc.err); (err != nil) != tc.expectErr {
+				t.Errorf("expected error: %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	counter := filepath.Join(dir, "attempts")
+	// fails on the first attempt only
+	script := fmt.Sprintf(`echo run >> %[1]s; [ "$(wc -l < %[1]s)" -gt 1 ]`, counter)
+
+	tester := &Tester{Retries: 2, argv: []string{"sh", "-c", script}}
+	if err := tester.Test(); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, resultsFile))
+	if err != nil {
+		t.Fatalf("failed to read the results: %v", err)
+	}
+	var suite struct {
+		Failures int        `xml:"failures,attr"`
+		Cases    []testCase `xml:"testcase"`
+	}
+	if err := xml.Unmarshal(raw, &suite); err != nil {
+		t.Fatalf("failed to parse the results: %v", err)
+	}
+	if len(suite.Cases) != 2 || suite.Failures != 1 {
+		t.Fatalf("expected a failed and a passed attempt, got %+v", suite)
+	}
+	if suite.Cases[0].Name != suite.Cases[1].Name || suite.Cases[0].Failure == nil || suite.Cases[1].Failure != nil {
+		t.Errorf("expected a failed and a passed attempt of the same testcase, got %+v", suite.Cases)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Setenv("ARTIFACTS", t.TempDir())
+	tester := &Tester{Timeout: 100 * time.Millisecond, argv: []string{"sleep", "10"}}
+	start := time.Now()
+	err := tester.Test()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the test command to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the test command to be killed, it ran for %v", elapsed)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+// featureSpecs are the regexes of the specs requiring each feature a
+// deployer may report, see types.DeployerWithFeatures
+var featureSpecs = map[string]string{
+	types.FeatureLoadBalancer:      `\[Feature:LoadBalancer\]`,
+	types.FeaturePersistentVolumes: `\[Feature:StorageProvider\]|\[sig-storage\] Dynamic Provisioning`,
+	types.FeatureIPv6:              `\[Feature:Networking-IPv6\]`,
+	types.FeatureDualStack:         `\[Feature:IPv6DualStack\]`,
+	types.FeatureWindows:           `\[Feature:Windows\]`,
+	types.FeatureNodeSSH:           `\[sig-node\] SSH`,
+}
+
+// initUnsupportedFeatures adds the specs requiring the features the deployer
+// reports as unsupported to --skip-regex
+func (t *Tester) initUnsupportedFeatures() {
+	features, ok := os.LookupEnv(types.DeployerFeaturesEnv)
+	if !ok || !t.SkipUnsupported {
+		return
+	}
+	unsupported := unsupportedFeatures(strings.Split(features, ","))
+	if len(unsupported) == 0 {
+		return
+	}
+	klog.V(0).Infof("Skipping the specs requiring the features unsupported by the cluster: %s", strings.Join(unsupported, ", "))
+	for _, feature := range unsupported {
+		t.SkipRegex = anyRegex(t.SkipRegex, featureSpecs[feature])
+	}
+}
+
+// unsupportedFeatures returns the features of featureSpecs not in supported,
+// sorted
+func unsupportedFeatures(supported []string) []string {
+	isSupported := map[string]bool{}
+	for _, feature := range supported {
+		isSupported[feature] = true
+	}
+	var unsupported []string
+	for feature := range featureSpecs {
+		if !isSupported[feature] {
+			unsupported = append(unsupported, feature)
+		}
+	}
+	sort.Strings(unsupported)
+	return unsupported
+}
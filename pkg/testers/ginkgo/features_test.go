@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"sigs.k8s.io/kubetest2/pkg/types"
+)
+
+func TestInitUnsupportedFeatures(t *testing.T) {
+	cases := []struct {
+		name            string
+		features        *string
+		skipUnsupported bool
+		skipped         []string
+		notSkipped      []string
+	}{
+		{
+			name:            "features not reported",
+			skipUnsupported: true,
+			notSkipped:      []string{"[sig-network] LoadBalancers [Feature:LoadBalancer] should work"},
+		},
+		{
+			name:            "unsupported features",
+			features:        stringPtr("PersistentVolumes,NodeSSH"),
+			skipUnsupported: true,
+			skipped: []string{
+				"[sig-network] LoadBalancers [Feature:LoadBalancer] should work",
+				"[sig-network] [Feature:IPv6DualStack] should create pod",
+				"[sig-apps] Slow spec",
+			},
+			notSkipped: []string{
+				"[sig-node] SSH should SSH to all nodes",
+				"[sig-storage] Dynamic Provisioning should provision storage",
+			},
+		},
+		{
+			name:       "--skip-unsupported=false",
+			features:   stringPtr(""),
+			skipped:    []string{"[sig-apps] Slow spec"},
+			notSkipped: []string{"[sig-network] LoadBalancers [Feature:LoadBalancer] should work"},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.features != nil {
+				t.Setenv(types.DeployerFeaturesEnv, *tc.features)
+			}
+			tester := &Tester{SkipRegex: "Slow", SkipUnsupported: tc.skipUnsupported}
+			tester.initUnsupportedFeatures()
+			skip := regexp.MustCompile(tester.SkipRegex)
+			for _, spec := range tc.skipped {
+				if !skip.MatchString(spec) {
+					t.Errorf("expected %q to be skipped by %q", spec, tester.SkipRegex)
+				}
+			}
+			for _, spec := range tc.notSkipped {
+				if skip.MatchString(spec) {
+					t.Errorf("expected %q not to be skipped by %q", spec, tester.SkipRegex)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsupportedFeatures(t *testing.T) {
+	got := unsupportedFeatures([]string{types.FeatureLoadBalancer, types.FeaturePersistentVolumes, types.FeatureNodeSSH, "Unknown"})
+	expected := []string{types.FeatureDualStack, types.FeatureIPv6, types.FeatureWindows}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
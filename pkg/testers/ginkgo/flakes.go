@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// flakesFile is the name of the report of the specs that passed on retry
+// and those that failed in every attempt, written to the artifacts
+const flakesFile = "flakes.json"
+
+// testResults returns the paths of the JUnit results of e2e.test
+func testResults() []string {
+	paths, err := filepath.Glob(filepath.Join(artifacts.BaseDir(), "junit_*.xml"))
+	if err != nil {
+		return nil
+	}
+	var results []string
+	for _, path := range paths {
+		// skip the results of kubetest2 itself
+		if filepath.Base(path) != "junit_runner.xml" {
+			results = append(results, path)
+		}
+	}
+	return results
+}
+
+// reportFlakes writes the flake report of the JUnit results of e2e.test,
+// so that specs which only passed on retry can be quarantined
+func (t *Tester) reportFlakes() {
+	results := testResults()
+	if len(results) == 0 {
+		return
+	}
+	report, err := artifacts.ReadFlakeReport(results)
+	if err != nil {
+		klog.Warningf("failed to read flakes from test results: %v", err)
+		return
+	}
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		klog.Warningf("failed to marshal flake report: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(artifacts.BaseDir(), flakesFile), raw, 0644); err != nil {
+		klog.Warningf("failed to write flake report: %v", err)
+		return
+	}
+	klog.V(0).Infof("%d specs failed, %d specs flaked and passed on retry, see %s", len(report.Failed), len(report.Flaked), flakesFile)
+}
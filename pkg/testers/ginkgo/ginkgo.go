@@ -17,11 +17,13 @@ limitations under the License.
 package ginkgo
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	stdexec "os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -33,34 +35,61 @@ import (
 	"sigs.k8s.io/kubetest2/pkg/artifacts"
 	"sigs.k8s.io/kubetest2/pkg/build"
 	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/flagschema"
 	"sigs.k8s.io/kubetest2/pkg/testers"
+	"sigs.k8s.io/kubetest2/pkg/types"
 )
 
 var GitTag string
 
 type Tester struct {
-	FlakeAttempts       int           `desc:"Make up to this many attempts to run each spec."`
+	FlakeAttempts       int           `desc:"Make up to this many attempts to run each spec. The specs that passed on retry are reported in flakes.json among the artifacts."`
 	GinkgoArgs          string        `desc:"Additional arguments supported by the ginkgo binary."`
-	Parallel            int           `desc:"Run this many tests in parallel at once."`
+	Parallel            string        `desc:"Run this many tests in parallel at once, or 'auto' to pick the parallelism based on the number of schedulable nodes of the cluster."`
+	SplitSerial         bool          `desc:"Skip [Serial] and [Disruptive] specs in a first parallel pass, then run them in a second serial pass."`
 	SkipRegex           string        `desc:"Regular expression of jobs to skip."`
 	FocusRegex          string        `desc:"Regular expression of jobs to focus on."`
-	TestPackageVersion  string        `desc:"The ginkgo tester uses a test package made during the kubernetes build. The tester downloads this test package from one of the release tars published to the Release bucket. Defaults to latest. visit https://kubernetes.io/releases/ to find release names. Example: v1.20.0-alpha.0"`
+	FocusFile           string        `desc:"File listing patterns of jobs to focus on in addition to --focus-regex, one per line. Lines starting with # are comments, lines starting with [ are test names matched literally and other lines are regular expressions."`
+	SkipFile            string        `desc:"File listing patterns of jobs to skip in addition to --skip-regex, in the format of --focus-file."`
+	SkipUnsupported     bool          `desc:"Skip the specs requiring features the deployer reports the cluster does not support, e.g. LoadBalancer. Has no effect with deployers that do not report their features."`
+	LabelFilter         string        `desc:"Label filter query selecting the specs to run, e.g. '!Slow && !Disruptive'. Requires an e2e.test built with ginkgo v2."`
+	TestPackageVersion  string        `desc:"The ginkgo tester uses a test package made during the kubernetes build. The tester downloads this test package from one of the release tars published to the Release bucket. visit https://kubernetes.io/releases/ to find release names. Example: v1.20.0-alpha.0. Defaults to the version of the cluster under test, or the version in the marker when that cannot be determined."`
 	TestPackageBucket   string        `desc:"The bucket which release tars will be downloaded from to acquire the test package. Defaults to the main kubernetes project bucket."`
 	TestPackageDir      string        `desc:"The directory in the bucket which represents the type of release. Default to the release directory."`
 	TestPackageMarker   string        `desc:"The version marker in the directory containing the package version to download when unspecified. Defaults to latest.txt."`
+	TestPackageArch     string        `desc:"The architecture of the test package to download, e.g. arm64 to run the tests from an arm64 host such as a node of an arm64 cluster. Defaults to the architecture of the host."`
+	ListTests           bool          `desc:"Print the specs selected by the focus, skip and label filter with their counts per SIG instead of running them. No cluster is needed."`
+	Progress            bool          `desc:"Print a concise line per spec as it completes instead of the output of ginkgo, which is written to ginkgo.log among the artifacts."`
+	ProgressEvents      bool          `desc:"Write an event per spec as it completes to progress.jsonl among the artifacts."`
+	ReadinessTimeout    time.Duration `desc:"If set, wait up to this long (in golang duration format) before running the specs for the nodes to be Ready, the pods of kube-system to be ready or completed and the --readiness-crds to be established."`
+	ReadinessCRDs       []string      `desc:"Names of the CRDs to wait for with --readiness-timeout, e.g. for the addons the specs need."`
+	Repeat              int           `desc:"Run the specs this many more times after the first, with the results of each iteration written to their own JUnit files and the failure rate of each spec to repeat-summary.json."`
+	UntilItFails        bool          `desc:"Run the specs repeatedly until they fail, as with --repeat."`
+	ShardIndex          int           `desc:"Index of the shard of specs to run, in [0, --shard-count). Defaults to $KUBETEST2_SHARD_INDEX, which is set by what runs the shards, e.g. each prow job of a sharded job."`
+	ShardCount          int           `desc:"Number of shards to partition the specs into, balanced by their durations in --shard-timings. Defaults to $KUBETEST2_SHARD_COUNT."`
+	ShardTimings        string        `desc:"Path or glob of the JUnit files of a previous run, whose spec durations are used to partition the specs into shards. Specs without timings are spread across the shards with the mean duration."`
 	TestArgs            string        `desc:"Additional arguments supported by the e2e test framework (https://godoc.org/k8s.io/kubernetes/test/e2e/framework#TestContextType)."`
+	DeployerTestArgs    bool          `desc:"Pass the provider specific arguments supplied by the deployer, e.g. --provider, to the e2e test framework before --test-args, which take precedence."`
 	UseBuiltBinaries    bool          `desc:"Look for binaries in _rundir/$KUBETEST2_RUN_DIR instead of extracting from tars downloaded from GCS."`
 	UseBinariesFromPath bool          `desc:"Look for binaries in the $PATH instead of extracting from tars downloaded from GCS."`
-	Timeout             time.Duration `desc:"How long (in golang duration format) to wait for ginkgo tests to complete."`
+	Timeout             time.Duration `desc:"Deprecated: use --suite-timeout."`
+	SuiteTimeout        time.Duration `desc:"How long (in golang duration format) to wait for ginkgo tests to complete, after which ginkgo interrupts them and reports the specs run so far. ginkgo is killed if it is still running 10 minutes later. Defaults to --timeout."`
+	SpecTimeout         time.Duration `desc:"How long (in golang duration format) to wait for each spec to complete. Requires an e2e.test built with ginkgo v2."`
 	Env                 []string      `desc:"List of env variables to pass to ginkgo libraries"`
 
 	kubeconfigPath string
 	runDir         string
+	// e2e.test arguments supplied by the deployer, see initKubetest2Info()
+	deployerTestArgs []string
 
 	// These paths are set up by AcquireTestPackage()
 	e2eTestPath string
 	ginkgoPath  string
 	kubectlPath string
+
+	// checksums of the test package version, from its checksum manifest if it
+	// has one, see getChecksums()
+	checksums map[string]string
 }
 
 // Test runs the test
@@ -73,47 +102,57 @@ func (t *Tester) Test() error {
 		return err
 	}
 
-	e2eTestArgs := []string{
-		"--kubeconfig=" + t.kubeconfigPath,
-		"--kubectl-path=" + t.kubectlPath,
-		"--ginkgo.skip=" + t.SkipRegex,
-		"--ginkgo.focus=" + t.FocusRegex,
-		"--report-dir=" + artifacts.BaseDir(),
-		"--ginkgo.timeout=" + t.Timeout.String(),
+	if t.ListTests {
+		return t.listTests()
 	}
 
-	// some ginkgo flags and behaviors are not backwards compatible
-	switch v := t.ginkgoMajorVersion(); v {
-	case "2":
-		e2eTestArgs = append(e2eTestArgs,
-			"--ginkgo.flake-attempts="+strconv.Itoa(t.FlakeAttempts),
-		)
-	default:
-		return fmt.Errorf("unsupported ginkgo version: %s", v)
+	if t.ReadinessTimeout != 0 {
+		if err := t.waitForCluster(); err != nil {
+			return err
+		}
 	}
 
-	extraE2EArgs, err := shellquote.Split(t.TestArgs)
+	parallel, err := t.parallelism()
 	if err != nil {
-		return fmt.Errorf("error parsing --test-args: %v", err)
+		return err
 	}
-	e2eTestArgs = append(e2eTestArgs, extraE2EArgs...)
 
-	extraGingkoArgs, err := shellquote.Split(t.GinkgoArgs)
+	passes, err := t.shardPasses(t.testPasses(parallel))
 	if err != nil {
-		return fmt.Errorf("error parsing --gingko-args: %v", err)
+		return err
+	}
+	if len(passes) == 0 {
+		klog.V(0).Infof("No specs to run in shard %d of %d", t.ShardIndex, t.ShardCount)
+		return nil
+	}
+
+	version := t.ginkgoMajorVersion()
+	defer t.printFailureDigest()
+	defer t.reportFlakes()
+	if t.Repeat > 0 || t.UntilItFails {
+		return t.repeatPasses(version, passes)
 	}
+	return t.runPasses(version, passes)
+}
 
-	ginkgoArgs := append(extraGingkoArgs,
-		"--nodes="+strconv.Itoa(t.Parallel),
-		t.e2eTestPath,
-		"--")
-	ginkgoArgs = append(ginkgoArgs, e2eTestArgs...)
+// runPasses runs ginkgo for each of the passes
+func (t *Tester) runPasses(version string, passes []testPass) error {
+	var errs []error
+	for _, pass := range passes {
+		ginkgoArgs, err := t.buildArgs(version, pass)
+		if err != nil {
+			return err
+		}
 
-	klog.V(0).Infof("Running ginkgo test as %s %+v", t.ginkgoPath, ginkgoArgs)
-	cmd := exec.Command(t.ginkgoPath, ginkgoArgs...)
-	cmd.SetEnv(t.Env...)
-	exec.InheritOutput(cmd)
-	return cmd.Run()
+		klog.V(0).Infof("Running ginkgo test as %s %+v", t.ginkgoPath, ginkgoArgs)
+		if err := t.runGinkgo(pass, ginkgoArgs); err != nil {
+			if len(passes) == 1 {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("%s pass failed: %w", pass.name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (t *Tester) pretestSetup() error {
@@ -197,9 +236,106 @@ func (t *Tester) validateBinariesFromPath() error {
 	return nil
 }
 
-// ginkgoMajorVersion returns the ginkgo major version
+// buildArgs returns the arguments of the ginkgo CLI running the given pass
+// for the given ginkgo major version, including those of e2e.test after "--"
+func (t *Tester) buildArgs(version string, pass testPass) ([]string, error) {
+	e2eTestArgs := []string{
+		"--kubeconfig=" + t.kubeconfigPath,
+		"--kubectl-path=" + t.kubectlPath,
+		"--ginkgo.skip=" + pass.skip,
+		"--ginkgo.focus=" + pass.focus,
+		"--report-dir=" + artifacts.BaseDir(),
+	}
+	if pass.name != "" {
+		e2eTestArgs = append(e2eTestArgs, "--report-prefix="+pass.name)
+	}
+	if t.Progress || t.ProgressEvents {
+		// the progress is parsed from the verbose output
+		e2eTestArgs = append(e2eTestArgs, "--ginkgo.v")
+	}
+	var ginkgoArgs []string
+
+	// some ginkgo flags and behaviors are not backwards compatible
+	switch version {
+	case "1":
+		if t.LabelFilter != "" {
+			return nil, fmt.Errorf("--label-filter requires ginkgo v2, %s is built with ginkgo v1", t.e2eTestPath)
+		}
+		if t.SpecTimeout != 0 {
+			return nil, fmt.Errorf("--spec-timeout requires ginkgo v2, %s is built with ginkgo v1", t.e2eTestPath)
+		}
+		e2eTestArgs = append(e2eTestArgs,
+			"--ginkgo.flakeAttempts="+strconv.Itoa(t.FlakeAttempts),
+		)
+		ginkgoArgs = append(ginkgoArgs,
+			"--nodes="+strconv.Itoa(pass.parallel),
+			"--timeout="+t.suiteTimeout().String(),
+		)
+	case "2":
+		e2eTestArgs = append(e2eTestArgs,
+			"--ginkgo.timeout="+t.suiteTimeout().String(),
+			"--ginkgo.flake-attempts="+strconv.Itoa(t.FlakeAttempts),
+		)
+		if t.SpecTimeout != 0 {
+			e2eTestArgs = append(e2eTestArgs, "--ginkgo.spec-timeout="+t.SpecTimeout.String())
+		}
+		if t.LabelFilter != "" {
+			e2eTestArgs = append(e2eTestArgs, "--ginkgo.label-filter="+t.LabelFilter)
+		}
+		ginkgoArgs = append(ginkgoArgs,
+			"--procs="+strconv.Itoa(pass.parallel),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported ginkgo version: %s", version)
+	}
+
+	e2eTestArgs = append(e2eTestArgs, t.deployerTestArgs...)
+	extraE2EArgs, err := shellquote.Split(t.TestArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing --test-args: %v", err)
+	}
+	e2eTestArgs = append(e2eTestArgs, extraE2EArgs...)
+
+	extraGingkoArgs, err := shellquote.Split(t.GinkgoArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing --gingko-args: %v", err)
+	}
+
+	ginkgoArgs = append(extraGingkoArgs, ginkgoArgs...)
+	ginkgoArgs = append(ginkgoArgs, t.e2eTestPath, "--")
+	return append(ginkgoArgs, e2eTestArgs...), nil
+}
+
+// ginkgoMajorVersion returns the ginkgo major version e2e.test is built
+// with, as told by its flags, or else that of the ginkgo CLI.
 // empty if not found
 func (t *Tester) ginkgoMajorVersion() string {
+	klog.V(2).Infof("checking the ginkgo version of e2e.test ...")
+	// the usage is printed whether or not --help exits with an error
+	help, _ := exec.CombinedOutputLines(exec.Command(t.e2eTestPath, "--help"))
+	if v := ginkgoVersionFromFlags(help); v != "" {
+		return v
+	}
+	return t.ginkgoCLIMajorVersion()
+}
+
+// ginkgoVersionFromFlags returns the ginkgo major version from the usage
+// of e2e.test, as ginkgo v2 renamed the camel cased flags of v1
+func ginkgoVersionFromFlags(usage []string) string {
+	for _, line := range usage {
+		switch {
+		case strings.Contains(line, "ginkgo.flake-attempts"), strings.Contains(line, "ginkgo.label-filter"):
+			return "2"
+		case strings.Contains(line, "ginkgo.flakeAttempts"):
+			return "1"
+		}
+	}
+	return ""
+}
+
+// ginkgoCLIMajorVersion returns the ginkgo CLI major version
+// empty if not found
+func (t *Tester) ginkgoCLIMajorVersion() string {
 	klog.V(2).Infof("checking ginkgo version ...")
 	cmd := exec.Command(t.ginkgoPath, "version")
 	lines, err := exec.OutputLines(cmd)
@@ -235,6 +371,9 @@ func (t *Tester) Execute() error {
 	}
 
 	if *help {
+		if flagschema.Requested() {
+			return flagschema.Write(os.Stdout, flagschema.FromFlagSet(fs))
+		}
 		fs.SetOutput(os.Stdout)
 		fs.PrintDefaults()
 		return nil
@@ -251,6 +390,28 @@ func (t *Tester) initKubetest2Info() error {
 	if t.UseBuiltBinaries && t.UseBinariesFromPath {
 		return fmt.Errorf("--use-built-binaries and --use-binaries-from-path are mutually exclusive")
 	}
+	if t.Repeat > 0 && t.UntilItFails {
+		return fmt.Errorf("--repeat and --until-it-fails are mutually exclusive")
+	}
+	if args, ok := os.LookupEnv(types.DeployerTestArgsEnv); ok && t.DeployerTestArgs {
+		deployerTestArgs, err := shellquote.Split(args)
+		if err != nil {
+			return fmt.Errorf("error parsing $%s: %v", types.DeployerTestArgsEnv, err)
+		}
+		t.deployerTestArgs = deployerTestArgs
+	}
+	// the specs of the Windows test pass of a mixed-OS run target the Windows
+	// nodes, before --test-args which take precedence
+	if os.Getenv(types.NodeOSEnv) == types.NodeOSWindows {
+		t.deployerTestArgs = append(t.deployerTestArgs, "--node-os-distro="+types.NodeOSWindows)
+	}
+	if err := t.initPatternFiles(); err != nil {
+		return err
+	}
+	t.initUnsupportedFeatures()
+	if err := t.initShard(); err != nil {
+		return err
+	}
 	if dir, ok := os.LookupEnv("KUBETEST2_RUN_DIR"); ok {
 		t.runDir = dir
 		return nil
@@ -276,10 +437,12 @@ func (t *Tester) SetRunDir(dir string) {
 func NewDefaultTester() *Tester {
 	return &Tester{
 		FlakeAttempts:     1,
-		Parallel:          1,
+		SkipUnsupported:   true,
+		Parallel:          "1",
 		TestPackageBucket: "kubernetes-release",
 		TestPackageDir:    "release",
 		TestPackageMarker: "latest.txt",
+		TestPackageArch:   runtime.GOARCH,
 		Timeout:           24 * time.Hour,
 		Env:               nil,
 	}
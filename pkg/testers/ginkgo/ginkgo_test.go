@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+func TestBuildArgs(t *testing.T) {
+	reportDir := "--report-dir=" + artifacts.BaseDir()
+	cases := []struct {
+		name         string
+		version      string
+		labelFilter  string
+		specTimeout  time.Duration
+		deployerArgs []string
+		expected     []string
+		expectError  bool
+	}{
+		{
+			name:    "ginkgo v1",
+			version: "1",
+			expected: []string{"--ginkgo-arg", "--nodes=4", "--timeout=1h0m0s", "e2e.test", "--",
+				"--kubeconfig=kubeconfig", "--kubectl-path=kubectl", "--ginkgo.skip=Slow", "--ginkgo.focus=Conformance",
+				reportDir, "--ginkgo.flakeAttempts=2", "--test-arg"},
+		},
+		{
+			name:        "ginkgo v1 with label filter",
+			version:     "1",
+			labelFilter: "!Slow",
+			expectError: true,
+		},
+		{
+			name:        "ginkgo v2",
+			version:     "2",
+			labelFilter: "!Slow && !Disruptive",
+			expected: []string{"--ginkgo-arg", "--procs=4", "e2e.test", "--",
+				"--kubeconfig=kubeconfig", "--kubectl-path=kubectl", "--ginkgo.skip=Slow", "--ginkgo.focus=Conformance",
+				reportDir, "--ginkgo.timeout=1h0m0s", "--ginkgo.flake-attempts=2", "--ginkgo.label-filter=!Slow && !Disruptive", "--test-arg"},
+		},
+		{
+			name:        "ginkgo v1 with spec timeout",
+			version:     "1",
+			specTimeout: time.Minute,
+			expectError: true,
+		},
+		{
+			name:        "ginkgo v2 with spec timeout",
+			version:     "2",
+			specTimeout: 5 * time.Minute,
+			expected: []string{"--ginkgo-arg", "--procs=4", "e2e.test", "--",
+				"--kubeconfig=kubeconfig", "--kubectl-path=kubectl", "--ginkgo.skip=Slow", "--ginkgo.focus=Conformance",
+				reportDir, "--ginkgo.timeout=1h0m0s", "--ginkgo.flake-attempts=2", "--ginkgo.spec-timeout=5m0s", "--test-arg"},
+		},
+		{
+			name:         "ginkgo v2 with deployer test args",
+			version:      "2",
+			deployerArgs: []string{"--provider=gce", "--gce-zone=us-central1-b"},
+			expected: []string{"--ginkgo-arg", "--procs=4", "e2e.test", "--",
+				"--kubeconfig=kubeconfig", "--kubectl-path=kubectl", "--ginkgo.skip=Slow", "--ginkgo.focus=Conformance",
+				reportDir, "--ginkgo.timeout=1h0m0s", "--ginkgo.flake-attempts=2", "--provider=gce", "--gce-zone=us-central1-b", "--test-arg"},
+		},
+		{
+			name:        "unknown version",
+			version:     "",
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tester := &Tester{
+				FlakeAttempts:    2,
+				GinkgoArgs:       "--ginkgo-arg",
+				SkipRegex:        "Slow",
+				FocusRegex:       "Conformance",
+				LabelFilter:      tc.labelFilter,
+				SpecTimeout:      tc.specTimeout,
+				TestArgs:         "--test-arg",
+				Timeout:          time.Hour,
+				kubeconfigPath:   "kubeconfig",
+				deployerTestArgs: tc.deployerArgs,
+				e2eTestPath:      "e2e.test",
+				kubectlPath:      "kubectl",
+			}
+			args, err := tester.buildArgs(tc.version, tester.testPasses(4)[0])
+			if err == nil && tc.expectError {
+				t.Fatal("expected error but got none")
+			}
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(args, " ") != strings.Join(tc.expected, " ") {
+				t.Errorf("expected args %q, got %q", tc.expected, args)
+			}
+		})
+	}
+}
+
+func TestGinkgoVersionFromFlags(t *testing.T) {
+	cases := []struct {
+		usage    []string
+		expected string
+	}{
+		{[]string{"  -ginkgo.flakeAttempts int", "  -kubeconfig string"}, "1"},
+		{[]string{"  -ginkgo.flake-attempts int", "  -ginkgo.label-filter string"}, "2"},
+		{[]string{"flag provided but not defined: -help"}, ""},
+	}
+	for _, tc := range cases {
+		if v := ginkgoVersionFromFlags(tc.usage); v != tc.expected {
+			t.Errorf("expected version %q for %q, got %q", tc.expected, tc.usage, v)
+		}
+	}
+}
+
+func TestReleaseVersion(t *testing.T) {
+	cases := []struct {
+		gitVersion  string
+		expected    string
+		expectError bool
+	}{
+		{gitVersion: "v1.28.3", expected: "v1.28.3"},
+		{gitVersion: "v1.28.3-gke.100", expected: "v1.28.3"},
+		{gitVersion: "v1.27.4-eks-2d98532", expected: "v1.27.4"},
+		{gitVersion: "v1.26.6+k3s1", expected: "v1.26.6"},
+		{gitVersion: "v1.29.0-alpha.0", expected: "v1.29.0-alpha.0"},
+		{gitVersion: "v1.29.0-rc.1.12+0123456789abcd", expected: "v1.29.0-rc.1"},
+		{gitVersion: "1.28", expectError: true},
+		{gitVersion: "", expectError: true},
+	}
+	for _, tc := range cases {
+		version, err := releaseVersion(tc.gitVersion)
+		if err == nil && tc.expectError {
+			t.Errorf("expected error for %q but got none", tc.gitVersion)
+		}
+		if err != nil && !tc.expectError {
+			t.Errorf("unexpected error for %q: %v", tc.gitVersion, err)
+		}
+		if version != tc.expected {
+			t.Errorf("expected release %q for %q, got %q", tc.expected, tc.gitVersion, version)
+		}
+	}
+}
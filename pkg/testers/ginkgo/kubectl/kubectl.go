@@ -17,6 +17,7 @@ limitations under the License.
 package kubectl
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -48,6 +49,33 @@ func APIServerURL() (string, error) {
 	return apiServerURL, nil
 }
 
+// ServerVersion obtains the git version of the cluster's API server
+// (e.g. v1.28.3-gke.100) from kubectl
+func ServerVersion(kubeconfig string) (string, error) {
+	out, err := execAndResult(kubectl, "version", "--output=json", "--kubeconfig="+kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("could not get server version: %v", err)
+	}
+	return parseServerVersion([]byte(out))
+}
+
+// parseServerVersion extracts the server git version from the output
+// of kubectl version --output=json
+func parseServerVersion(out []byte) (string, error) {
+	var version struct {
+		ServerVersion *struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal(out, &version); err != nil {
+		return "", fmt.Errorf("could not parse kubectl version output: %v", err)
+	}
+	if version.ServerVersion == nil || version.ServerVersion.GitVersion == "" {
+		return "", fmt.Errorf("kubectl version output has no server version")
+	}
+	return version.ServerVersion.GitVersion, nil
+}
+
 // execAndResult runs command with args and returns the entire output (or error)
 func execAndResult(command string, args ...string) (string, error) {
 	cmd := exec.Command(command, args...)
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// sigRegex matches the SIG a spec belongs to
+var sigRegex = regexp.MustCompile(`\[sig-([\w-]+)\]`)
+
+// listTests prints the specs selected by the focus, skip and label filter
+// without running them, by dry running e2e.test
+func (t *Tester) listTests() error {
+	version := t.ginkgoMajorVersion()
+	passes, err := t.shardPasses(t.testPasses(1))
+	if err != nil {
+		return err
+	}
+	var specs []string
+	for _, pass := range passes {
+		passSpecs, err := t.listSpecs(version, pass)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, passSpecs...)
+	}
+
+	sort.Strings(specs)
+	for _, spec := range specs {
+		fmt.Println(spec)
+	}
+	counts := countBySIG(specs)
+	sigs := make([]string, 0, len(counts))
+	for sig := range counts {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	fmt.Printf("\n%d specs would run:\n", len(specs))
+	for _, sig := range sigs {
+		fmt.Printf("%6d %s\n", counts[sig], sig)
+	}
+	return nil
+}
+
+// dryRunArgs returns the arguments of e2e.test listing the specs of the
+// pass for the given ginkgo major version
+func (t *Tester) dryRunArgs(version string, pass testPass) ([]string, error) {
+	args := []string{
+		"--ginkgo.focus=" + pass.focus,
+		"--ginkgo.skip=" + pass.skip,
+		"--ginkgo.v",
+	}
+	// some ginkgo flags are not backwards compatible
+	switch version {
+	case "1":
+		if t.LabelFilter != "" {
+			return nil, fmt.Errorf("--label-filter requires ginkgo v2, %s is built with ginkgo v1", t.e2eTestPath)
+		}
+		args = append(args, "--ginkgo.noColor", "--ginkgo.dryRun")
+	case "2":
+		args = append(args, "--ginkgo.no-color", "--ginkgo.dry-run")
+		if t.LabelFilter != "" {
+			args = append(args, "--ginkgo.label-filter="+t.LabelFilter)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ginkgo version: %s", version)
+	}
+	return args, nil
+}
+
+// countBySIG returns the number of specs per SIG
+func countBySIG(specs []string) map[string]int {
+	counts := map[string]int{}
+	for _, spec := range specs {
+		sig := "no SIG"
+		if match := sigRegex.FindStringSubmatch(spec); match != nil {
+			sig = "sig-" + match[1]
+		}
+		counts[sig]++
+	}
+	return counts
+}
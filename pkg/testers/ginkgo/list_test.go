@@ -0,0 +1,74 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDryRunArgs(t *testing.T) {
+	pass := testPass{focus: "Conformance", skip: "Slow"}
+	cases := []struct {
+		version     string
+		labelFilter string
+		expected    []string
+		expectError bool
+	}{
+		{
+			version:  "1",
+			expected: []string{"--ginkgo.focus=Conformance", "--ginkgo.skip=Slow", "--ginkgo.v", "--ginkgo.noColor", "--ginkgo.dryRun"},
+		},
+		{
+			version:     "1",
+			labelFilter: "!Slow",
+			expectError: true,
+		},
+		{
+			version:     "2",
+			labelFilter: "!Slow",
+			expected:    []string{"--ginkgo.focus=Conformance", "--ginkgo.skip=Slow", "--ginkgo.v", "--ginkgo.no-color", "--ginkgo.dry-run", "--ginkgo.label-filter=!Slow"},
+		},
+	}
+	for _, tc := range cases {
+		tester := &Tester{LabelFilter: tc.labelFilter, e2eTestPath: "e2e.test"}
+		args, err := tester.dryRunArgs(tc.version, pass)
+		if err == nil && tc.expectError {
+			t.Errorf("expected error for ginkgo v%s but got none", tc.version)
+		}
+		if err != nil && !tc.expectError {
+			t.Errorf("unexpected error for ginkgo v%s: %v", tc.version, err)
+		}
+		if strings.Join(args, " ") != strings.Join(tc.expected, " ") {
+			t.Errorf("expected args %q, got %q", tc.expected, args)
+		}
+	}
+}
+
+func TestCountBySIG(t *testing.T) {
+	specs := []string{
+		"[sig-apps] Deployment works",
+		"[sig-apps] DaemonSet works",
+		"[sig-node] [NodeConformance] Pods work",
+		"Kubectl works",
+	}
+	expected := map[string]int{"sig-apps": 2, "sig-node": 1, "no SIG": 1}
+	if counts := countBySIG(specs); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected counts %v, got %v", expected, counts)
+	}
+}
@@ -25,12 +25,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/build"
 	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/testers/ginkgo/kubectl"
 )
 
 // AcquireTestPackage obtains three test binaries and places them in $KUBETEST2_RUN_DIR.
@@ -38,7 +41,18 @@ import (
 // The second is "e2e.test", which contains kubernetes e2e test cases.
 // The third is "kubectl".
 func (t *Tester) AcquireTestPackage() error {
-	// first, get the name of the latest release (e.g. v1.20.0-alpha.0)
+	// first, match the version of the cluster under test, to avoid skew
+	// between the cluster and the test binaries
+	if t.TestPackageVersion == "" {
+		if version, err := t.clusterReleaseVersion(); err != nil {
+			klog.Warningf("Could not determine the cluster version, falling back to %s: %v", t.TestPackageMarker, err)
+		} else {
+			t.TestPackageVersion = version
+			klog.V(1).Infof("Test package version was not specified. Defaulting to the cluster version: %s", t.TestPackageVersion)
+		}
+	}
+
+	// otherwise, get the name of the latest release (e.g. v1.20.0-alpha.0)
 	if t.TestPackageVersion == "" {
 		cmd := exec.Command(
 			"gsutil",
@@ -57,7 +71,7 @@ func (t *Tester) AcquireTestPackage() error {
 		klog.V(1).Infof("Test package version was not specified. Defaulting to version from %s: %s", t.TestPackageMarker, t.TestPackageVersion)
 	}
 
-	releaseTar := fmt.Sprintf("kubernetes-test-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	releaseTar := fmt.Sprintf("kubernetes-test-%s-%s.tar.gz", runtime.GOOS, t.TestPackageArch)
 
 	downloadDir, err := os.UserCacheDir()
 	if err != nil {
@@ -77,6 +91,31 @@ func (t *Tester) AcquireTestPackage() error {
 	return t.ensureKubectl(t.kubectlPath)
 }
 
+// clusterReleaseVersion returns the release matching the version of the
+// cluster the deployer brought up
+func (t *Tester) clusterReleaseVersion() (string, error) {
+	gitVersion, err := kubectl.ServerVersion(t.kubeconfigPath)
+	if err != nil {
+		return "", err
+	}
+	return releaseVersion(gitVersion)
+}
+
+// releaseVersionRegex matches the release a server version was built from,
+// ignoring any vendor suffix (e.g. v1.28.3-gke.100 or v1.27.4-eks-2d98532)
+// and build metadata
+var releaseVersionRegex = regexp.MustCompile(`^(v\d+\.\d+\.\d+(?:-(?:alpha|beta|rc)\.\d+)?)(?:[-+.].*)?$`)
+
+// releaseVersion maps a server git version to the name of the release
+// whose test package should be used against it
+func releaseVersion(gitVersion string) (string, error) {
+	match := releaseVersionRegex.FindStringSubmatch(gitVersion)
+	if match == nil {
+		return "", fmt.Errorf("unrecognized server version %q", gitVersion)
+	}
+	return match[1], nil
+}
+
 func (t *Tester) extractBinaries(downloadPath string) error {
 	// ensure the artifacts dir
 	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
@@ -159,7 +198,7 @@ func (t *Tester) ensureKubectl(downloadPath string) error {
 		t.TestPackageDir,
 		t.TestPackageVersion,
 		runtime.GOOS,
-		runtime.GOARCH,
+		t.TestPackageArch,
 	)
 	if _, err := os.Stat(downloadPath); err == nil {
 		klog.V(0).Infof("Found existing kubectl at %v", downloadPath)
@@ -176,6 +215,9 @@ func (t *Tester) ensureKubectl(downloadPath string) error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to download kubectl for release %s: %s", t.TestPackageVersion, err)
 	}
+	if err := t.verifyDownload(downloadPath, kubectlPathInGCS); err != nil {
+		return err
+	}
 	if err := os.Chmod(downloadPath, 0700); err != nil {
 		return fmt.Errorf("failed to make %s executable: %s", downloadPath, err)
 	}
@@ -213,10 +255,52 @@ func (t *Tester) ensureReleaseTar(downloadPath, releaseTar string) error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to download release tar %s for release %s: %s", releaseTar, t.TestPackageVersion, err)
 	}
+	return t.verifyDownload(downloadPath, releaseTarPathInGCS)
+}
+
+// verifyDownload verifies a file freshly downloaded from gcsFilePath,
+// removing it if it does not match, e.g. if it was truncated in staging.
+func (t *Tester) verifyDownload(downloadPath, gcsFilePath string) error {
+	if err := t.compareSHA(downloadPath, gcsFilePath); err != nil {
+		os.Remove(downloadPath)
+		return fmt.Errorf("failed to verify %s downloaded from %s: %v", downloadPath, gcsFilePath, err)
+	}
+	klog.V(0).Infof("Validated hash for downloaded %v", downloadPath)
 	return nil
 }
 
+// versionPathInGCS returns the GCS path of the test package version
+func (t *Tester) versionPathInGCS() string {
+	return fmt.Sprintf("gs://%s/%s/%s", t.TestPackageBucket, t.TestPackageDir, t.TestPackageVersion)
+}
+
+// getChecksums returns the checksums of the checksum manifest staged with the
+// test package version, or nil if it has none, as older versions were staged
+// with a .sha256 file per artifact only.
+func (t *Tester) getChecksums() map[string]string {
+	if t.checksums != nil {
+		return t.checksums
+	}
+	t.checksums = map[string]string{}
+	manifest, err := exec.Output(exec.Command("gsutil", "cat", t.versionPathInGCS()+"/"+build.ChecksumsFile))
+	if err != nil {
+		klog.V(1).Infof("no %s for release %s, using per file checksums", build.ChecksumsFile, t.TestPackageVersion)
+		return t.checksums
+	}
+	checksums, err := build.ParseChecksums(manifest)
+	if err != nil {
+		klog.Warningf("failed to parse %s for release %s: %v", build.ChecksumsFile, t.TestPackageVersion, err)
+		return t.checksums
+	}
+	t.checksums = checksums
+	return t.checksums
+}
+
 func (t *Tester) compareSHA(downloadPath string, gcsFilePath string) error {
+	if checksums := t.getChecksums(); len(checksums) > 0 {
+		name := strings.TrimPrefix(gcsFilePath, t.versionPathInGCS()+"/")
+		return build.VerifyChecksum(checksums, name, downloadPath)
+	}
 	cmd := exec.Command("gsutil", "cat",
 		fmt.Sprintf("%s.sha256", gcsFilePath),
 	)
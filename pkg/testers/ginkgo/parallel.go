@@ -0,0 +1,192 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// autoParallel is the value of --parallel picking the parallelism
+	// based on the cluster, see autoParallelism()
+	autoParallel = "auto"
+	// specs run per schedulable node with --parallel=auto
+	autoParallelPerNode = 4
+	// upper bound of --parallel=auto, beyond which the API server rather
+	// than the nodes tends to be the bottleneck
+	maxAutoParallel = 30
+)
+
+// serialSpecsRegex matches the specs that must not run in parallel
+// with any other spec
+const serialSpecsRegex = `\[Serial\]|\[Disruptive\]`
+
+// serialFocusRegex matches a focus selecting serial specs
+var serialFocusRegex = regexp.MustCompile(`Serial|Disruptive`)
+
+// serialLabelRegex matches a label filter selecting serial specs, i.e.
+// one mentioning Serial or Disruptive without negating it
+var serialLabelRegex = regexp.MustCompile(`(^|[^!\w])(Serial|Disruptive)\b`)
+
+// testPass is one invocation of ginkgo
+type testPass struct {
+	// name of the pass, used to prefix its reports when not empty
+	name     string
+	parallel int
+	focus    string
+	skip     string
+}
+
+// testPasses returns the ginkgo invocations to run the selected specs,
+// that is a single one unless --split-serial is set, in which case the
+// serial specs are skipped in a first parallel pass and run by
+// themselves in a second pass
+func (t *Tester) testPasses(parallel int) []testPass {
+	if !t.SplitSerial {
+		return []testPass{{
+			parallel: parallel,
+			focus:    t.FocusRegex,
+			skip:     t.SkipRegex,
+		}}
+	}
+	return []testPass{
+		{
+			name:     "parallel",
+			parallel: parallel,
+			focus:    t.FocusRegex,
+			skip:     anyRegex(t.SkipRegex, serialSpecsRegex),
+		},
+		{
+			name:     "serial",
+			parallel: 1,
+			focus:    allRegex(t.FocusRegex, serialSpecsRegex),
+			skip:     t.SkipRegex,
+		},
+	}
+}
+
+// anyRegex returns a regex matching either a or b, ignoring empty ones
+func anyRegex(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return fmt.Sprintf("(?:%s)|(?:%s)", a, b)
+}
+
+// allRegex returns a regex matching text where both a and b match,
+// ignoring empty ones
+func allRegex(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return fmt.Sprintf("(?:%s).*(?:%s)|(?:%s).*(?:%s)", a, b, b, a)
+}
+
+// parallelism returns the number of specs to run in parallel as set by
+// --parallel
+func (t *Tester) parallelism() (int, error) {
+	if t.Parallel == autoParallel {
+		return t.autoParallelism()
+	}
+	parallel, err := strconv.Atoi(t.Parallel)
+	if err != nil || parallel < 1 {
+		return 0, fmt.Errorf("invalid --parallel %q: must be a positive integer or %q", t.Parallel, autoParallel)
+	}
+	return parallel, nil
+}
+
+// autoParallelism picks the parallelism based on the schedulable nodes of
+// the cluster, running serially when only serial specs are selected
+func (t *Tester) autoParallelism() (int, error) {
+	if !t.SplitSerial && t.selectsSerialSpecs() {
+		klog.V(0).Infof("Running specs serially, as serial specs are selected")
+		return 1, nil
+	}
+	cmd := exec.Command(t.kubectlPath, "get", "nodes", "--output=json", "--kubeconfig="+t.kubeconfigPath)
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list nodes for --parallel=%s: %v", autoParallel, err)
+	}
+	nodes, err := schedulableNodes(out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count nodes for --parallel=%s: %v", autoParallel, err)
+	}
+	parallel := nodes * autoParallelPerNode
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > maxAutoParallel {
+		parallel = maxAutoParallel
+	}
+	klog.V(0).Infof("Running %d specs in parallel on %d schedulable nodes", parallel, nodes)
+	return parallel, nil
+}
+
+// selectsSerialSpecs returns true when the focus or the label filter
+// select serial or disruptive specs
+func (t *Tester) selectsSerialSpecs() bool {
+	return serialFocusRegex.MatchString(t.FocusRegex) ||
+		serialLabelRegex.MatchString(t.LabelFilter)
+}
+
+// schedulableNodes returns the number of nodes regular pods can be
+// scheduled on in the output of kubectl get nodes --output=json
+func schedulableNodes(out []byte) (int, error) {
+	var list struct {
+		Items []struct {
+			Spec struct {
+				Unschedulable bool `json:"unschedulable"`
+				Taints        []struct {
+					Effect string `json:"effect"`
+				} `json:"taints"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, node := range list.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		schedulable := true
+		for _, taint := range node.Spec.Taints {
+			if taint.Effect == "NoSchedule" || taint.Effect == "NoExecute" {
+				schedulable = false
+				break
+			}
+		}
+		if schedulable {
+			count++
+		}
+	}
+	return count, nil
+}
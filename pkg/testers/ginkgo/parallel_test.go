@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSplitSerialPasses(t *testing.T) {
+	tester := &Tester{
+		SplitSerial: true,
+		FocusRegex:  `\[Conformance\]`,
+		SkipRegex:   `\[Slow\]`,
+	}
+	passes := tester.testPasses(8)
+	if len(passes) != 2 {
+		t.Fatalf("expected 2 passes, got %d", len(passes))
+	}
+	parallel, serial := passes[0], passes[1]
+	if parallel.parallel != 8 || serial.parallel != 1 {
+		t.Errorf("expected parallelism 8 and 1, got %d and %d", parallel.parallel, serial.parallel)
+	}
+
+	cases := []struct {
+		spec     string
+		parallel bool
+		serial   bool
+	}{
+		{spec: "[sig-apps] Deployment works [Conformance]", parallel: true},
+		{spec: "[sig-apps] Daemon set works [Serial] [Conformance]", serial: true},
+		{spec: "[sig-node] Restart [Disruptive] [Conformance]", serial: true},
+		{spec: "[sig-node] Restart [Disruptive]"},
+		{spec: "[sig-apps] Deployment works [Slow] [Conformance]"},
+		{spec: "[sig-apps] Daemon set works [Serial] [Slow] [Conformance]"},
+	}
+	for _, tc := range cases {
+		if runs := selects(parallel, tc.spec); runs != tc.parallel {
+			t.Errorf("expected parallel pass to run %q: %v, got %v", tc.spec, tc.parallel, runs)
+		}
+		if runs := selects(serial, tc.spec); runs != tc.serial {
+			t.Errorf("expected serial pass to run %q: %v, got %v", tc.spec, tc.serial, runs)
+		}
+	}
+}
+
+// selects returns true if the pass runs the spec, as ginkgo would
+func selects(pass testPass, spec string) bool {
+	if pass.focus != "" && !regexp.MustCompile(pass.focus).MatchString(spec) {
+		return false
+	}
+	return pass.skip == "" || !regexp.MustCompile(pass.skip).MatchString(spec)
+}
+
+func TestSchedulableNodes(t *testing.T) {
+	out := []byte(`{"items": [
+		{"spec": {"taints": [{"key": "node-role.kubernetes.io/control-plane", "effect": "NoSchedule"}]}},
+		{"spec": {}},
+		{"spec": {"taints": [{"key": "example.com/dedicated", "effect": "PreferNoSchedule"}]}},
+		{"spec": {"unschedulable": true}}
+	]}`)
+	nodes, err := schedulableNodes(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nodes != 2 {
+		t.Errorf("expected 2 schedulable nodes, got %d", nodes)
+	}
+}
+
+func TestSelectsSerialSpecs(t *testing.T) {
+	cases := []struct {
+		focus       string
+		labelFilter string
+		expected    bool
+	}{
+		{focus: `\[Conformance\]`},
+		{focus: `\[Serial\]`, expected: true},
+		{labelFilter: "!Slow && !Disruptive"},
+		{labelFilter: "Feature:Foo && Serial", expected: true},
+		{labelFilter: "Disruptive", expected: true},
+	}
+	for _, tc := range cases {
+		tester := &Tester{FocusRegex: tc.focus, LabelFilter: tc.labelFilter}
+		if selected := tester.selectsSerialSpecs(); selected != tc.expected {
+			t.Errorf("expected %v for focus %q and label filter %q, got %v", tc.expected, tc.focus, tc.labelFilter, selected)
+		}
+	}
+}
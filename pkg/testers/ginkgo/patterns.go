@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// initPatternFiles adds the patterns of --focus-file and --skip-file to
+// --focus-regex and --skip-regex
+func (t *Tester) initPatternFiles() error {
+	if t.FocusFile != "" {
+		focus, err := readPatternFile(t.FocusFile)
+		if err != nil {
+			return fmt.Errorf("failed to read --focus-file: %v", err)
+		}
+		t.FocusRegex = anyRegex(t.FocusRegex, focus)
+	}
+	if t.SkipFile != "" {
+		skip, err := readPatternFile(t.SkipFile)
+		if err != nil {
+			return fmt.Errorf("failed to read --skip-file: %v", err)
+		}
+		t.SkipRegex = anyRegex(t.SkipRegex, skip)
+	}
+	return nil
+}
+
+// readPatternFile returns a regex matching any of the patterns listed in
+// the file at path, one per line. Blank lines and lines starting with #
+// are ignored. Lines starting with [ are test names, e.g.
+// "[sig-apps] Deployment should run the lifecycle of a Deployment", which
+// are matched literally, while the other lines are regular expressions.
+func readPatternFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			patterns = append(patterns, regexp.QuoteMeta(line))
+		default:
+			if _, err := regexp.Compile(line); err != nil {
+				return "", fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+			patterns = append(patterns, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(patterns) == 0 {
+		return "", fmt.Errorf("%s lists no patterns", path)
+	}
+	return strings.Join(patterns, "|"), nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPatternFile(t *testing.T) {
+	cases := []struct {
+		name        string
+		content     string
+		expected    string
+		expectError bool
+	}{
+		{
+			name: "patterns and test names",
+			content: `# flaky on this provider
+\[Slow\]
+
+  [sig-apps] Deployment works (with a rollback)
+Feature:.*
+`,
+			expected: `\[Slow\]|\[sig-apps\] Deployment works \(with a rollback\)|Feature:.*`,
+		},
+		{
+			name:        "invalid pattern",
+			content:     "Feature:(\n",
+			expectError: true,
+		},
+		{
+			name:        "no patterns",
+			content:     "# nothing to skip\n",
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "patterns.txt")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			pattern, err := readPatternFile(path)
+			if err == nil && tc.expectError {
+				t.Fatal("expected error but got none")
+			}
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pattern != tc.expected {
+				t.Errorf("expected pattern %q, got %q", tc.expected, pattern)
+			}
+		})
+	}
+}
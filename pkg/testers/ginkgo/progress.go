@@ -0,0 +1,254 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+const (
+	// ginkgoLogFile is the name of the file the output of ginkgo is
+	// written to with --progress, among the artifacts
+	ginkgoLogFile = "ginkgo.log"
+	// progressEventsFile is the name of the file the spec events are
+	// written to with --progress-events, among the artifacts
+	progressEventsFile = "progress.jsonl"
+	// digestOutputLines is the number of lines of the output of each failed
+	// spec printed in the failure digest
+	digestOutputLines = 20
+)
+
+var (
+	// specSeparatorRegex matches the lines separating the specs in the
+	// verbose output of ginkgo
+	specSeparatorRegex = regexp.MustCompile(`^-{10,}$`)
+	// specResultRegex matches the line heading the result of a spec, e.g.
+	// "• [FAILED] [1.234 seconds]" with ginkgo v2 or
+	// "• Failure [1.234 seconds]" with ginkgo v1
+	specResultRegex = regexp.MustCompile(`^([•S])\s*(?:\[([A-Z]+)\]|([A-Z][a-z]+)[^\[]*)?\s*\[([\d.]+) seconds\]`)
+	// locationRegex matches the code locations printed with the specs
+	locationRegex = regexp.MustCompile(`\.go:\d+$`)
+)
+
+// progressEvent is the result of a spec as it completes
+type progressEvent struct {
+	Time            time.Time `json:"time"`
+	Spec            string    `json:"spec"`
+	State           string    `json:"state"`
+	DurationSeconds float64   `json:"durationSeconds"`
+}
+
+// progressWriter parses the verbose output of ginkgo written to it into a
+// concise line per spec written to out, and optionally events written to
+// events as JSON lines. This is best effort, as the format of the output
+// differs between ginkgo versions.
+type progressWriter struct {
+	out    io.Writer
+	events io.Writer
+	// observe is called with each event, if set
+	observe func(progressEvent)
+
+	buf bytes.Buffer
+	// the result of the spec being parsed, if any
+	pending *progressEvent
+	// whether the spec text of the pending result is complete
+	textDone bool
+
+	total  int
+	counts map[string]int
+}
+
+func newProgressWriter(out, events io.Writer) *progressWriter {
+	return &progressWriter{out: out, events: events, counts: map[string]int{}}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		line, err := p.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			p.buf.Reset()
+			p.buf.WriteString(line)
+			return len(b), nil
+		}
+		p.parseLine(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush reports the pending result, if any
+func (p *progressWriter) Flush() {
+	if p.buf.Len() > 0 {
+		p.parseLine(p.buf.String())
+		p.buf.Reset()
+	}
+	p.report()
+}
+
+func (p *progressWriter) parseLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	if specSeparatorRegex.MatchString(trimmed) {
+		p.report()
+		return
+	}
+	if match := specResultRegex.FindStringSubmatch(trimmed); match != nil {
+		p.report()
+		duration, _ := strconv.ParseFloat(match[4], 64)
+		p.pending = &progressEvent{
+			Time:            time.Now(),
+			State:           specState(match[1], match[2], match[3]),
+			DurationSeconds: duration,
+		}
+		p.textDone = false
+		return
+	}
+	if p.pending == nil || p.textDone {
+		return
+	}
+	switch {
+	case trimmed == "":
+		p.textDone = p.pending.Spec != ""
+	case locationRegex.MatchString(trimmed):
+	default:
+		p.pending.Spec = strings.TrimSpace(p.pending.Spec + " " + trimmed)
+	}
+}
+
+// specState normalizes the state of a spec from its result heading
+func specState(marker, state, v1State string) string {
+	switch {
+	case state != "":
+		state = strings.ToLower(state)
+		if state == "skipping" {
+			return "skipped"
+		}
+		return state
+	case v1State == "Failure":
+		return "failed"
+	case v1State != "":
+		return strings.ToLower(v1State)
+	case marker == "S":
+		return "skipped"
+	default:
+		return "passed"
+	}
+}
+
+// report writes the pending result, if any
+func (p *progressWriter) report() {
+	e := p.pending
+	p.pending = nil
+	if e == nil || e.Spec == "" {
+		return
+	}
+	p.total++
+	p.counts[e.State]++
+	failed := p.total - p.counts["passed"] - p.counts["skipped"]
+	fmt.Fprintf(p.out, "%-8s [%.1fs] %s (%d passed, %d failed, %d skipped)\n",
+		strings.ToUpper(e.State), e.DurationSeconds, e.Spec,
+		p.counts["passed"], failed, p.counts["skipped"])
+	if p.observe != nil {
+		p.observe(*e)
+	}
+	if p.events != nil {
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		_, _ = p.events.Write(append(raw, '\n'))
+	}
+}
+
+// printFailureDigest prints the failed specs of the JUnit results of
+// e2e.test with an excerpt of their output, so that the failures need not be
+// looked for in the whole output
+func (t *Tester) printFailureDigest() {
+	results := testResults()
+	if len(results) == 0 {
+		return
+	}
+	failures, err := artifacts.JUnitFailures(results, digestOutputLines)
+	if err != nil {
+		klog.Warningf("failed to read failures from test results: %v", err)
+		return
+	}
+	if len(failures) == 0 {
+		return
+	}
+	var digest strings.Builder
+	fmt.Fprintf(&digest, "\n%d specs failed:\n", len(failures))
+	for i, f := range failures {
+		fmt.Fprintf(&digest, "\n[%d] %s\n    %s\n", i+1, f.Name, f.Message)
+		if f.Output != "" {
+			fmt.Fprintf(&digest, "    ...\n    %s\n", strings.ReplaceAll(f.Output, "\n", "\n    "))
+		}
+	}
+	fmt.Print(digest.String())
+}
+
+// ginkgoOutput returns the writers for the output of ginkgo as set by
+// --progress and --progress-events, and a function to call once it exits
+func (t *Tester) ginkgoOutput() (io.Writer, io.Writer, func(), error) {
+	if !t.Progress && !t.ProgressEvents {
+		return os.Stdout, os.Stderr, func() {}, nil
+	}
+	var closers []io.Closer
+	done := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	var events io.Writer
+	if t.ProgressEvents {
+		f, err := os.OpenFile(filepath.Join(artifacts.BaseDir(), progressEventsFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create progress events file: %v", err)
+		}
+		closers = append(closers, f)
+		events = f
+	}
+
+	if !t.Progress {
+		progress := newProgressWriter(io.Discard, events)
+		return io.MultiWriter(os.Stdout, progress), os.Stderr, func() { progress.Flush(); done() }, nil
+	}
+
+	logPath := filepath.Join(artifacts.BaseDir(), ginkgoLogFile)
+	log, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		done()
+		return nil, nil, nil, fmt.Errorf("failed to create ginkgo log file: %v", err)
+	}
+	closers = append(closers, log)
+	klog.V(0).Infof("Writing the output of ginkgo to %s", logPath)
+	progress := newProgressWriter(os.Stdout, events)
+	return io.MultiWriter(log, progress), log, func() { progress.Flush(); done() }, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProgressWriter(t *testing.T) {
+	output := `Running Suite: Kubernetes e2e suite
+------------------------------
+• [0.512 seconds]
+[sig-apps] Deployment
+/workspace/test/e2e/apps/deployment.go:79
+  should run the lifecycle of a Deployment
+  /workspace/test/e2e/apps/deployment.go:185
+------------------------------
+• [FAILED] [12.345 seconds]
+[sig-node] Pods
+/workspace/test/e2e/node/pods.go:10
+  should be restarted
+  /workspace/test/e2e/node/pods.go:20
+
+  [FAILED] timed out waiting
+------------------------------
+• Failure [3.100 seconds]
+[sig-network] DNS
+/go/src/k8s.io/kubernetes/test/e2e/network/dns.go:40
+  should resolve [Conformance]
+  /go/src/k8s.io/kubernetes/test/e2e/network/dns.go:50
+
+  Expected error
+------------------------------
+S [SKIPPED] [0.001 seconds]
+[sig-storage] CSI
+/workspace/test/e2e/storage/csi.go:1
+  should mount [Slow]
+  /workspace/test/e2e/storage/csi.go:2`
+
+	var out, events bytes.Buffer
+	p := newProgressWriter(&out, &events)
+	// write in chunks splitting the lines
+	for len(output) > 0 {
+		n := 7
+		if n > len(output) {
+			n = len(output)
+		}
+		if _, err := p.Write([]byte(output[:n])); err != nil {
+			t.Fatal(err)
+		}
+		output = output[n:]
+	}
+	p.Flush()
+
+	expected := []string{
+		"PASSED   [0.5s] [sig-apps] Deployment should run the lifecycle of a Deployment (1 passed, 0 failed, 0 skipped)",
+		"FAILED   [12.3s] [sig-node] Pods should be restarted (1 passed, 1 failed, 0 skipped)",
+		"FAILED   [3.1s] [sig-network] DNS should resolve [Conformance] (1 passed, 2 failed, 0 skipped)",
+		"SKIPPED  [0.0s] [sig-storage] CSI should mount [Slow] (1 passed, 2 failed, 1 skipped)",
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected progress:\n%s\ngot:\n%s", strings.Join(expected, "\n"), out.String())
+	}
+	if n := strings.Count(events.String(), "\n"); n != len(expected) {
+		t.Errorf("expected %d events, got %d:\n%s", len(expected), n, events.String())
+	}
+	if !strings.Contains(events.String(), `"spec":"[sig-node] Pods should be restarted","state":"failed","durationSeconds":12.345`) {
+		t.Errorf("expected the failed spec in the events, got:\n%s", events.String())
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// readinessPollInterval is how often the readiness of the cluster is
+// checked while waiting for it
+const readinessPollInterval = 10 * time.Second
+
+// waitForCluster waits up to --readiness-timeout for the nodes to be Ready,
+// the pods of kube-system to be ready or completed and the
+// --readiness-crds to be established, as specs run against a cluster that
+// is still coming up fail for no fault of their own
+func (t *Tester) waitForCluster() error {
+	klog.V(0).Infof("Waiting up to %v for the cluster to be ready", t.ReadinessTimeout)
+	deadline := time.Now().Add(t.ReadinessTimeout)
+	for {
+		problems, err := t.clusterReadiness()
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		if len(problems) == 0 {
+			klog.V(0).Infof("The cluster is ready")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the cluster is not ready after %v: %s", t.ReadinessTimeout, strings.Join(problems, "; "))
+		}
+		klog.V(1).Infof("Waiting for the cluster to be ready: %s", strings.Join(problems, "; "))
+		time.Sleep(readinessPollInterval)
+	}
+}
+
+// clusterReadiness returns what the cluster is not ready for yet
+func (t *Tester) clusterReadiness() ([]string, error) {
+	var problems []string
+
+	out, err := t.kubectlJSON("get", "nodes")
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := notReadyNodes(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) > 0 {
+		problems = append(problems, "nodes not Ready: "+strings.Join(nodes, ", "))
+	}
+
+	out, err = t.kubectlJSON("get", "pods", "--namespace=kube-system")
+	if err != nil {
+		return nil, err
+	}
+	pods, err := unhealthyPods(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) > 0 {
+		problems = append(problems, "kube-system pods not ready: "+strings.Join(pods, ", "))
+	}
+
+	if len(t.ReadinessCRDs) > 0 {
+		out, err = t.kubectlJSON("get", "customresourcedefinitions")
+		if err != nil {
+			return nil, err
+		}
+		crds, err := unestablishedCRDs(out, t.ReadinessCRDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(crds) > 0 {
+			problems = append(problems, "CRDs not established: "+strings.Join(crds, ", "))
+		}
+	}
+	return problems, nil
+}
+
+// kubectlJSON runs kubectl with args against the cluster, returning its
+// output as JSON
+func (t *Tester) kubectlJSON(args ...string) ([]byte, error) {
+	args = append(args, "--output=json", "--kubeconfig="+t.kubeconfigPath)
+	out, err := exec.Output(exec.Command(t.kubectlPath, args...))
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s failed: %v", args[0]+" "+args[1], err)
+	}
+	return out, nil
+}
+
+type condition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+type objectList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Phase             string      `json:"phase"`
+			Conditions        []condition `json:"conditions"`
+			ContainerStatuses []struct {
+				Ready bool `json:"ready"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+func hasCondition(conditions []condition, conditionType string) bool {
+	for _, c := range conditions {
+		if c.Type == conditionType {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+// notReadyNodes returns the names of the nodes which are not Ready in the
+// output of kubectl get nodes --output=json, or an error if there are none
+func notReadyNodes(out []byte) ([]string, error) {
+	var list objectList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("no nodes registered")
+	}
+	var names []string
+	for _, node := range list.Items {
+		if !hasCondition(node.Status.Conditions, "Ready") {
+			names = append(names, node.Metadata.Name)
+		}
+	}
+	return names, nil
+}
+
+// unhealthyPods returns the names of the pods which are neither completed
+// nor running with all their containers ready in the output of
+// kubectl get pods --output=json
+func unhealthyPods(out []byte) ([]string, error) {
+	var list objectList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pod := range list.Items {
+		switch pod.Status.Phase {
+		case "Succeeded":
+			continue
+		case "Running":
+			ready := true
+			for _, c := range pod.Status.ContainerStatuses {
+				ready = ready && c.Ready
+			}
+			if ready {
+				continue
+			}
+		}
+		names = append(names, pod.Metadata.Name)
+	}
+	return names, nil
+}
+
+// unestablishedCRDs returns those of the names of CRDs which are missing or
+// not established in the output of
+// kubectl get customresourcedefinitions --output=json
+func unestablishedCRDs(out []byte, names []string) ([]string, error) {
+	var list objectList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, err
+	}
+	established := map[string]bool{}
+	for _, crd := range list.Items {
+		established[crd.Metadata.Name] = hasCondition(crd.Status.Conditions, "Established")
+	}
+	var missing []string
+	for _, name := range names {
+		if !established[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
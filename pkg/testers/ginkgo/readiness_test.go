@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotReadyNodes(t *testing.T) {
+	out := []byte(`{"items": [
+		{"metadata": {"name": "ready"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}},
+		{"metadata": {"name": "not-ready"}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}},
+		{"metadata": {"name": "unknown"}, "status": {}}
+	]}`)
+	nodes, err := notReadyNodes(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"not-ready", "unknown"}; !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("expected nodes %v, got %v", expected, nodes)
+	}
+
+	if _, err := notReadyNodes([]byte(`{"items": []}`)); err == nil {
+		t.Errorf("expected error without nodes but got none")
+	}
+}
+
+func TestUnhealthyPods(t *testing.T) {
+	out := []byte(`{"items": [
+		{"metadata": {"name": "running"}, "status": {"phase": "Running", "containerStatuses": [{"ready": true}, {"ready": true}]}},
+		{"metadata": {"name": "starting"}, "status": {"phase": "Running", "containerStatuses": [{"ready": true}, {"ready": false}]}},
+		{"metadata": {"name": "completed"}, "status": {"phase": "Succeeded"}},
+		{"metadata": {"name": "pending"}, "status": {"phase": "Pending"}}
+	]}`)
+	pods, err := unhealthyPods(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"starting", "pending"}; !reflect.DeepEqual(pods, expected) {
+		t.Errorf("expected pods %v, got %v", expected, pods)
+	}
+}
+
+func TestUnestablishedCRDs(t *testing.T) {
+	out := []byte(`{"items": [
+		{"metadata": {"name": "established.example.com"}, "status": {"conditions": [{"type": "Established", "status": "True"}]}},
+		{"metadata": {"name": "pending.example.com"}, "status": {"conditions": [{"type": "Established", "status": "False"}]}}
+	]}`)
+	crds, err := unestablishedCRDs(out, []string{"established.example.com", "pending.example.com", "missing.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"pending.example.com", "missing.example.com"}; !reflect.DeepEqual(crds, expected) {
+		t.Errorf("expected CRDs %v, got %v", expected, crds)
+	}
+}
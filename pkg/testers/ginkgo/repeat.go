@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// repeatSummaryFile is the name of the summary of the iterations of
+// --repeat and --until-it-fails, written to the artifacts
+const repeatSummaryFile = "repeat-summary.json"
+
+// repeatSummary aggregates the results of repeated iterations
+type repeatSummary struct {
+	Iterations       int `json:"iterations"`
+	FailedIterations int `json:"failedIterations"`
+	// Specs are those which failed or flaked in any iteration
+	Specs []specRate `json:"specs"`
+}
+
+// specRate is the failure rate of a spec over the iterations
+type specRate struct {
+	Name     string `json:"name"`
+	Failures int    `json:"failures"`
+	// Flakes are the iterations where the spec passed on retry
+	Flakes      int     `json:"flakes"`
+	FailureRate float64 `json:"failureRate"`
+}
+
+// repeatPasses runs the passes repeatedly for --repeat or --until-it-fails,
+// prefixing the reports of each iteration with its number
+func (t *Tester) repeatPasses(version string, passes []testPass) error {
+	var errs []error
+	var reports []*artifacts.FlakeReport
+	failedIterations := 0
+	for i := 1; t.UntilItFails || i <= t.Repeat+1; i++ {
+		prefix := fmt.Sprintf("iteration-%02d_", i)
+		iteration := make([]testPass, len(passes))
+		for j, pass := range passes {
+			pass.name = prefix + pass.name
+			iteration[j] = pass
+		}
+
+		klog.V(0).Infof("Running iteration %d", i)
+		err := t.runPasses(version, iteration)
+		if report, rerr := t.iterationReport(prefix); rerr != nil {
+			klog.Warningf("failed to read the results of iteration %d: %v", i, rerr)
+		} else {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			failedIterations++
+			errs = append(errs, fmt.Errorf("iteration %d failed: %w", i, err))
+			if t.UntilItFails {
+				break
+			}
+		}
+	}
+
+	summary := summarizeIterations(reports)
+	summary.FailedIterations = failedIterations
+	t.writeRepeatSummary(summary)
+	return errors.Join(errs...)
+}
+
+// iterationReport reads the flake report of the JUnit files of the
+// iteration with the given report prefix
+func (t *Tester) iterationReport(prefix string) (*artifacts.FlakeReport, error) {
+	paths, err := filepath.Glob(filepath.Join(artifacts.BaseDir(), "junit_"+prefix+"*.xml"))
+	if err != nil {
+		return nil, err
+	}
+	return artifacts.ReadFlakeReport(paths)
+}
+
+// summarizeIterations returns the failure rates of the specs which failed
+// or flaked in the reports of the iterations, most failing first
+func summarizeIterations(reports []*artifacts.FlakeReport) repeatSummary {
+	rates := map[string]*specRate{}
+	rate := func(name string) *specRate {
+		if _, ok := rates[name]; !ok {
+			rates[name] = &specRate{Name: name}
+		}
+		return rates[name]
+	}
+	for _, report := range reports {
+		for _, c := range report.Failed {
+			rate(c.Name).Failures++
+		}
+		for _, c := range report.Flaked {
+			rate(c.Name).Flakes++
+		}
+	}
+
+	summary := repeatSummary{Iterations: len(reports), Specs: []specRate{}}
+	for _, r := range rates {
+		r.FailureRate = float64(r.Failures) / float64(len(reports))
+		summary.Specs = append(summary.Specs, *r)
+	}
+	sort.Slice(summary.Specs, func(i, j int) bool {
+		a, b := summary.Specs[i], summary.Specs[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		if a.Flakes != b.Flakes {
+			return a.Flakes > b.Flakes
+		}
+		return a.Name < b.Name
+	})
+	return summary
+}
+
+func (t *Tester) writeRepeatSummary(summary repeatSummary) {
+	klog.V(0).Infof("%d of %d iterations failed", summary.FailedIterations, summary.Iterations)
+	for _, r := range summary.Specs {
+		klog.V(0).Infof("%.0f%% failed (%d failures, %d flakes): %s", 100*r.FailureRate, r.Failures, r.Flakes, r.Name)
+	}
+	raw, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		klog.Warningf("failed to marshal repeat summary: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(artifacts.BaseDir(), repeatSummaryFile), raw, 0644); err != nil {
+		klog.Warningf("failed to write repeat summary: %v", err)
+	}
+}
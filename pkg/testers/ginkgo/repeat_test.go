@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+func TestSummarizeIterations(t *testing.T) {
+	reports := []*artifacts.FlakeReport{
+		{
+			Failed: []artifacts.ReportedCase{{Name: "races"}},
+		},
+		{
+			Failed: []artifacts.ReportedCase{{Name: "races"}, {Name: "times out"}},
+			Flaked: []artifacts.ReportedCase{{Name: "retries"}},
+		},
+		{},
+		{
+			Flaked: []artifacts.ReportedCase{{Name: "times out"}},
+		},
+	}
+	expected := repeatSummary{
+		Iterations: 4,
+		Specs: []specRate{
+			{Name: "races", Failures: 2, FailureRate: 0.5},
+			{Name: "times out", Failures: 1, Flakes: 1, FailureRate: 0.25},
+			{Name: "retries", Flakes: 1},
+		},
+	}
+	if summary := summarizeIterations(reports); !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+}
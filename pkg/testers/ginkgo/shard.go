@@ -0,0 +1,258 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// environment variables selecting the shard when the flags are unset,
+	// so that a job sharded across clusters can select it per cluster.
+	// kubetest2 does not set them, they are set by what runs the shards,
+	// e.g. in the env of each of the prow jobs of a sharded job.
+	shardIndexEnv = "KUBETEST2_SHARD_INDEX"
+	shardCountEnv = "KUBETEST2_SHARD_COUNT"
+
+	// maxFocusLength bounds the regexes selecting the specs of a shard,
+	// as the kernel bounds the length of a single argument to 128KiB
+	maxFocusLength = 100 * 1024
+)
+
+// initShard defaults --shard-index and --shard-count from the environment
+// and validates them
+func (t *Tester) initShard() error {
+	if t.ShardCount == 0 {
+		if count, ok := os.LookupEnv(shardCountEnv); ok {
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %v", shardCountEnv, count, err)
+			}
+			t.ShardCount = n
+		}
+		if index, ok := os.LookupEnv(shardIndexEnv); ok {
+			n, err := strconv.Atoi(index)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %v", shardIndexEnv, index, err)
+			}
+			t.ShardIndex = n
+		}
+	}
+	if t.ShardCount <= 1 {
+		return nil
+	}
+	if t.ShardIndex < 0 || t.ShardIndex >= t.ShardCount {
+		return fmt.Errorf("--shard-index must be in [0, %d), got %d", t.ShardCount, t.ShardIndex)
+	}
+	if t.ShardTimings == "" {
+		return fmt.Errorf("--shard-timings is required to partition the specs into %d shards", t.ShardCount)
+	}
+	return nil
+}
+
+// shardPasses restricts each of the passes to the specs of the selected
+// shard, balanced by their durations in the --shard-timings JUnit files.
+// The specs of each pass are listed by dry running e2e.test, so that the
+// specs missing from those are spread across the shards too, and each shard
+// only has to focus on its own specs.
+func (t *Tester) shardPasses(passes []testPass) ([]testPass, error) {
+	if t.ShardCount <= 1 {
+		return passes, nil
+	}
+	paths, err := filepath.Glob(t.ShardTimings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --shard-timings %q: %v", t.ShardTimings, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no JUnit files match --shard-timings %q", t.ShardTimings)
+	}
+	durations, err := artifacts.JUnitDurations(paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec timings: %v", err)
+	}
+	timings := specTimings(durations)
+
+	version := t.ginkgoMajorVersion()
+	var sharded []testPass
+	for _, pass := range passes {
+		specs, err := t.listSpecs(version, pass)
+		if err != nil {
+			return nil, err
+		}
+		shard, ok := t.shardPass(pass, specs, timings)
+		if !ok {
+			continue
+		}
+		if len(shard.focus) > maxFocusLength {
+			return nil, fmt.Errorf("too many specs to shard, narrow them down with --focus-regex or --skip-regex, or use more shards")
+		}
+		sharded = append(sharded, shard)
+	}
+	return sharded, nil
+}
+
+// shardPass restricts the pass to the specs of the selected shard among the
+// specs it runs, or returns false if there are none. The specs without
+// timings are assumed to take the mean duration of the others.
+func (t *Tester) shardPass(pass testPass, specs []string, timings map[string]float64) (testPass, bool) {
+	var total float64
+	var known int
+	for _, spec := range specs {
+		if d, ok := timings[spec]; ok {
+			total += d
+			known++
+		}
+	}
+	mean := 1.0
+	if known > 0 {
+		mean = total / float64(known)
+	}
+	durations := map[string]float64{}
+	for _, spec := range specs {
+		if d, ok := timings[spec]; ok {
+			durations[spec] = d
+		} else {
+			durations[spec] = mean
+		}
+	}
+	shards := partition(specs, durations, t.ShardCount)
+	selected := shards[t.ShardIndex]
+	if len(selected) == 0 {
+		return pass, false
+	}
+	klog.V(0).Infof("Running %d of %d specs in shard %d of %d, %d of them without timings", len(selected), len(specs), t.ShardIndex, t.ShardCount, len(specs)-known)
+	pass.focus = exactRegex(selected)
+	return pass, true
+}
+
+// specTimings returns the durations of the testcases by the text of their
+// specs, which is what dry running e2e.test lists
+func specTimings(durations map[string]float64) map[string]float64 {
+	timings := map[string]float64{}
+	for name, d := range durations {
+		// ginkgo v2 prefixes the names of the testcases with the node type
+		// and suffixes them with the labels of the spec
+		name = strings.TrimPrefix(name, "[It] ")
+		timings[name] = d
+		if text := specText(name); text != name {
+			if _, ok := timings[text]; !ok {
+				timings[text] = d
+			}
+		}
+	}
+	return timings
+}
+
+// specText returns the name without its last bracketed group, which may be
+// the labels of the spec, see specRegex
+func specText(name string) string {
+	if strings.HasSuffix(name, "]") {
+		if i := strings.LastIndex(name, " ["); i > 0 {
+			return name[:i]
+		}
+	}
+	return name
+}
+
+// listSpecs returns the sorted specs the pass runs, by dry running e2e.test
+func (t *Tester) listSpecs(version string, pass testPass) ([]string, error) {
+	args, err := t.dryRunArgs(version, pass)
+	if err != nil {
+		return nil, err
+	}
+	var specs []string
+	progress := newProgressWriter(io.Discard, nil)
+	progress.observe = func(e progressEvent) {
+		if e.State == "passed" {
+			specs = append(specs, e.Spec)
+		}
+	}
+	cmd := exec.Command(t.e2eTestPath, args...)
+	cmd.SetEnv(t.Env...)
+	cmd.SetStdout(progress)
+	err = cmd.Run()
+	progress.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the specs of %s: %v", t.e2eTestPath, err)
+	}
+	sort.Strings(specs)
+	return specs, nil
+}
+
+// partition splits specs into count shards of similar total duration,
+// assigning the longest specs first to the shortest shard. The result only
+// depends on its arguments, so every shard computes the same partition.
+func partition(specs []string, timings map[string]float64, count int) [][]string {
+	sorted := append([]string(nil), specs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if timings[sorted[i]] != timings[sorted[j]] {
+			return timings[sorted[i]] > timings[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	shards := make([][]string, count)
+	totals := make([]float64, count)
+	for _, spec := range sorted {
+		shortest := 0
+		for i := range totals {
+			if totals[i] < totals[shortest] {
+				shortest = i
+			}
+		}
+		shards[shortest] = append(shards[shortest], spec)
+		totals[shortest] += timings[spec]
+	}
+	for _, shard := range shards {
+		sort.Strings(shard)
+	}
+	return shards
+}
+
+// exactRegex returns a regex matching exactly the given specs
+func exactRegex(specs []string) string {
+	if len(specs) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(specs))
+	for i, spec := range specs {
+		quoted[i] = specRegex(spec)
+	}
+	return "^(?:" + strings.Join(quoted, "|") + ")$"
+}
+
+// specRegex returns a regex matching the text of the spec with the given
+// testcase name. ginkgo v2 suffixes the names with the labels of the spec,
+// which are not part of the text focus and skip regexes are matched with,
+// so the last bracketed group of the name is optional.
+func specRegex(name string) string {
+	if text := specText(name); text != name {
+		return regexp.QuoteMeta(text) + "(?:" + regexp.QuoteMeta(name[len(text):]) + ")?"
+	}
+	return regexp.QuoteMeta(name)
+}
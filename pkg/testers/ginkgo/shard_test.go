@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+func TestPartition(t *testing.T) {
+	timings := map[string]float64{"a": 10, "b": 7, "c": 5, "d": 3, "e": 2, "f": 1}
+	shards := partition([]string{"a", "b", "c", "d", "e", "f"}, timings, 2)
+	expected := [][]string{{"a", "d", "f"}, {"b", "c", "e"}}
+	if !reflect.DeepEqual(shards, expected) {
+		t.Errorf("expected shards %v, got %v", expected, shards)
+	}
+}
+
+func TestSpecRegex(t *testing.T) {
+	cases := []struct {
+		name    string
+		text    string
+		matches bool
+	}{
+		{name: "[sig-apps] Deployment works", text: "[sig-apps] Deployment works", matches: true},
+		{name: "[sig-apps] Deployment works [Conformance]", text: "[sig-apps] Deployment works [Conformance]", matches: true},
+		{name: "[sig-apps] Deployment works [Conformance, sig-apps]", text: "[sig-apps] Deployment works", matches: true},
+		{name: "[sig-apps] Deployment works", text: "[sig-apps] Deployment works too"},
+		{name: "[sig-apps] Deployment works", text: "[sig-apps] Deployment"},
+	}
+	for _, tc := range cases {
+		re := regexp.MustCompile(exactRegex([]string{tc.name}))
+		if matches := re.MatchString(tc.text); matches != tc.matches {
+			t.Errorf("expected %q to match %q: %v, got %v", re, tc.text, tc.matches, matches)
+		}
+	}
+}
+
+func TestShardPass(t *testing.T) {
+	timings := filepath.Join(t.TempDir(), "junit_01.xml")
+	content := `<testsuite name="e2e">
+    <testcase name="[It] slow" classname="e2e" time="30"></testcase>
+    <testcase name="[It] medium [Feature:Medium]" classname="e2e" time="20"></testcase>
+    <testcase name="[It] fast" classname="e2e" time="10"></testcase>
+    <testcase name="[It] skipped" classname="e2e" time="10"></testcase>
+</testsuite>`
+	if err := os.WriteFile(timings, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	durations, err := artifacts.JUnitDurations([]string{timings})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// new is missing from the timings, so it is assumed to take 20s
+	specs := []string{"fast", "medium", "new", "slow"}
+	base := testPass{parallel: 1, skip: "skipped"}
+	cases := []struct {
+		index    int
+		expected testPass
+	}{
+		{index: 0, expected: testPass{parallel: 1, focus: "^(?:fast|slow)$", skip: "skipped"}},
+		{index: 1, expected: testPass{parallel: 1, focus: "^(?:medium|new)$", skip: "skipped"}},
+	}
+	for _, tc := range cases {
+		tester := &Tester{ShardIndex: tc.index, ShardCount: 2, ShardTimings: timings}
+		pass, ok := tester.shardPass(base, specs, specTimings(durations))
+		if !ok {
+			t.Fatalf("expected specs in shard %d", tc.index)
+		}
+		if !reflect.DeepEqual(pass, tc.expected) {
+			t.Errorf("expected pass %+v for shard %d, got %+v", tc.expected, tc.index, pass)
+		}
+	}
+
+	tester := &Tester{ShardIndex: 2, ShardCount: 3, ShardTimings: timings}
+	if pass, ok := tester.shardPass(base, []string{"slow", "fast"}, specTimings(durations)); ok {
+		t.Errorf("expected no specs in the last shard, got %+v", pass)
+	}
+}
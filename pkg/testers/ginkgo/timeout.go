@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/process"
+)
+
+// suiteTimeoutGrace is how long ginkgo is given past the suite timeout to
+// interrupt the specs and write its reports before it is killed
+const suiteTimeoutGrace = 10 * time.Minute
+
+// suiteTimeout returns --suite-timeout, or else the deprecated --timeout
+func (t *Tester) suiteTimeout() time.Duration {
+	if t.SuiteTimeout != 0 {
+		return t.SuiteTimeout
+	}
+	return t.Timeout
+}
+
+// runGinkgo runs ginkgo with the given arguments for the pass, killing it
+// if it wedges past the suite timeout, in which case a failed testcase is
+// reported for the pass in lieu of the reports ginkgo did not write
+func (t *Tester) runGinkgo(pass testPass, ginkgoArgs []string) error {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	var timeout time.Duration
+	if t.suiteTimeout() != 0 {
+		timeout = t.suiteTimeout() + suiteTimeoutGrace
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	stdout, stderr, done, err := t.ginkgoOutput()
+	if err != nil {
+		return err
+	}
+	err = process.ExecContextWithOutput(ctx, t.ginkgoPath, ginkgoArgs, t.Env, stdout, stderr)
+	done()
+	if errors.Is(err, context.DeadlineExceeded) {
+		klog.Errorf("ginkgo did not exit within %v of the suite timeout and was killed", suiteTimeoutGrace)
+		if werr := writeTimeoutJUnit(pass, timeout); werr != nil {
+			klog.Warningf("failed to report the suite timeout: %v", werr)
+		}
+	}
+	return err
+}
+
+// writeTimeoutJUnit writes a JUnit file with a failed testcase for a pass
+// that was killed after the given timeout
+func writeTimeoutJUnit(pass testPass, timeout time.Duration) error {
+	type failure struct {
+		Message string `xml:"message,attr"`
+	}
+	type testcase struct {
+		Name      string  `xml:"name,attr"`
+		ClassName string  `xml:"classname,attr"`
+		Time      float64 `xml:"time,attr"`
+		Failure   failure `xml:"failure"`
+	}
+	type testsuite struct {
+		XMLName  xml.Name   `xml:"testsuite"`
+		Name     string     `xml:"name,attr"`
+		Tests    int        `xml:"tests,attr"`
+		Failures int        `xml:"failures,attr"`
+		Cases    []testcase `xml:"testcase"`
+	}
+	name, file := "ginkgo", "junit_timeout.xml"
+	if pass.name != "" {
+		passName := strings.TrimSuffix(pass.name, "_")
+		name = fmt.Sprintf("ginkgo %s pass", passName)
+		file = fmt.Sprintf("junit_%s_timeout.xml", passName)
+	}
+	suite := testsuite{
+		Name:     "kubetest2-ginkgo",
+		Tests:    1,
+		Failures: 1,
+		Cases: []testcase{{
+			Name:      fmt.Sprintf("%s completes", name),
+			ClassName: "kubetest2-ginkgo",
+			Time:      timeout.Seconds(),
+			Failure:   failure{Message: fmt.Sprintf("%s was killed after %v, past the suite timeout", name, timeout)},
+		}},
+	}
+	raw, err := xml.MarshalIndent(suite, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(artifacts.BaseDir(), file), append([]byte(xml.Header), raw...), 0644)
+}
@@ -21,6 +21,7 @@ limitations under the License.
 package node
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -31,9 +32,9 @@ import (
 	"github.com/octago/sflags/gen/gpflag"
 	"k8s.io/klog/v2"
 
-	"sigs.k8s.io/boskos/client"
 	"sigs.k8s.io/kubetest2/pkg/boskos"
 	"sigs.k8s.io/kubetest2/pkg/exec"
+	"sigs.k8s.io/kubetest2/pkg/flagschema"
 	"sigs.k8s.io/kubetest2/pkg/fs"
 	"sigs.k8s.io/kubetest2/pkg/testers"
 )
@@ -47,39 +48,34 @@ const (
 )
 
 type Tester struct {
-	RepoRoot                       string        `desc:"Absolute path to the kubernetes or provider-aws-test-infra repository root."`
-	GCPProject                     string        `desc:"GCP Project to create VMs in. If unset, the deployer will attempt to get a project from boskos."`
-	GCPZone                        string        `desc:"GCP Zone to create VMs in."`
-	SkipRegex                      string        `desc:"Regular expression of jobs to skip."`
-	FocusRegex                     string        `desc:"Regular expression of jobs to focus on."`
-	TestArgs                       string        `desc:"A space-separated list of arguments to pass to node e2e test."`
-	BoskosAcquireTimeoutSeconds    int           `desc:"How long (in seconds) to hang on a request to Boskos to acquire a resource before erroring."`
-	BoskosHeartbeatIntervalSeconds int           `desc:"How often (in seconds) to send a heartbeat to Boskos to hold the acquired resource. 0 means no heartbeat."`
-	BoskosLocation                 string        `desc:"If set, manually specifies the location of the boskos server. If unset and boskos is needed"`
-	ImageConfigFile                string        `desc:"Path to a file containing image configuration."`
-	Images                         string        `desc:"List of images to use when creating instances separated by commas"`
-	ImageProject                   string        `desc:"A GCP Project containing an image to use when creating instances"`
-	InstanceType                   string        `desc:"Machine/Instance type to use on AWS/GCP"`
-	InstanceMetadata               string        `desc:"Instance Metadata to use for creating GCE instance"`
-	UserDataFile                   string        `desc:"User Data to use for creating EC2 instance"`
-	Provider                       string        `desc:"Cloud Provider to use for node tests. Valid options are ec2 and gce"`
-	UseDockerizedBuild             bool          `desc:"Use dockerized build for test artifacts"`
-	TargetBuildArch                string        `desc:"Target architecture for the test artifacts for dockerized build"`
-	ImageConfigDir                 string        `desc:"Path to image config files."`
-	Parallelism                    int           `desc:"The number of nodes to run in parallel."`
-	GCPProjectType                 string        `desc:"Explicitly indicate which project type to select from boskos."`
-	RuntimeConfig                  string        `desc:"The runtime configuration for the API server. Format: a list of key=value pairs."`
-	Timeout                        time.Duration `desc:"How long (in golang duration format) to wait for ginkgo tests to complete."`
-	DeleteInstances                bool          `desc:"Where to delete instances after running the test"`
-	NodeEnv                        string        `desc:"Additional metadata keys to add to a gce instance"`
-
-	// boskos struct field will be non-nil when the deployer is
+	RepoRoot           string        `desc:"Absolute path to the kubernetes or provider-aws-test-infra repository root."`
+	GCPProject         string        `desc:"GCP Project to create VMs in. If unset, the deployer will attempt to get a project from boskos."`
+	GCPZone            string        `desc:"GCP Zone to create VMs in."`
+	SkipRegex          string        `desc:"Regular expression of jobs to skip."`
+	FocusRegex         string        `desc:"Regular expression of jobs to focus on."`
+	TestArgs           string        `desc:"A space-separated list of arguments to pass to node e2e test."`
+	ImageConfigFile    string        `desc:"Path to a file containing image configuration."`
+	Images             string        `desc:"List of images to use when creating instances separated by commas"`
+	ImageProject       string        `desc:"A GCP Project containing an image to use when creating instances"`
+	InstanceType       string        `desc:"Machine/Instance type to use on AWS/GCP"`
+	InstanceMetadata   string        `desc:"Instance Metadata to use for creating GCE instance"`
+	UserDataFile       string        `desc:"User Data to use for creating EC2 instance"`
+	Provider           string        `desc:"Cloud Provider to use for node tests. Valid options are ec2 and gce"`
+	UseDockerizedBuild bool          `desc:"Use dockerized build for test artifacts"`
+	TargetBuildArch    string        `desc:"Target architecture for the test artifacts for dockerized build"`
+	ImageConfigDir     string        `desc:"Path to image config files."`
+	Parallelism        int           `desc:"The number of nodes to run in parallel."`
+	GCPProjectType     string        `desc:"Explicitly indicate which project type to select from boskos."`
+	RuntimeConfig      string        `desc:"The runtime configuration for the API server. Format: a list of key=value pairs."`
+	Timeout            time.Duration `desc:"How long (in golang duration format) to wait for ginkgo tests to complete."`
+	DeleteInstances    bool          `desc:"Where to delete instances after running the test"`
+	NodeEnv            string        `desc:"Additional metadata keys to add to a gce instance"`
+
+	*boskos.Options
+
+	// lease will be non-nil when the tester is
 	// using boskos to acquire a GCP project
-	boskos *client.Client
-
-	// this channel serves as a signal channel for the hearbeat goroutine
-	// so that it can be explicitly closed
-	boskosHeartbeatClose chan struct{}
+	lease *boskos.Lease
 
 	// this contains ssh key path
 	privateKey string
@@ -88,15 +84,12 @@ type Tester struct {
 
 func NewDefaultTester() *Tester {
 	return &Tester{
-		SkipRegex:                      `\[Flaky\]|\[Slow\]|\[Serial\]`,
-		BoskosLocation:                 "http://boskos.test-pods.svc.cluster.local.",
-		BoskosAcquireTimeoutSeconds:    5 * 60,
-		BoskosHeartbeatIntervalSeconds: 5 * 60,
-		Parallelism:                    8,
-		boskosHeartbeatClose:           make(chan struct{}),
-		GCPProjectType:                 "gce-project",
-		Provider:                       "gce",
-		DeleteInstances:                true,
+		SkipRegex:       `\[Flaky\]|\[Slow\]|\[Serial\]`,
+		Options:         boskos.NewOptions(),
+		Parallelism:     8,
+		GCPProjectType:  "gce-project",
+		Provider:        "gce",
+		DeleteInstances: true,
 	}
 }
 
@@ -114,6 +107,9 @@ func (t *Tester) Execute() error {
 	}
 
 	if *help {
+		if flagschema.Requested() {
+			return flagschema.Write(os.Stdout, flagschema.FromFlagSet(fs))
+		}
 		fs.SetOutput(os.Stdout)
 		fs.PrintDefaults()
 		return nil
@@ -140,37 +136,25 @@ func (t *Tester) Execute() error {
 		if t.GCPProject == "" {
 			klog.V(1).Info("no GCP project provided, acquiring from Boskos ...")
 
-			boskosClient, err := boskos.NewClient(t.BoskosLocation)
+			lease, err := t.NewLease()
 			if err != nil {
 				return fmt.Errorf("failed to make boskos client: %s", err)
 			}
-			t.boskos = boskosClient
-
-			resource, err := boskos.Acquire(
-				t.boskos,
-				t.GCPProjectType,
-				time.Duration(t.BoskosAcquireTimeoutSeconds)*time.Second,
-				time.Duration(t.BoskosHeartbeatIntervalSeconds)*time.Second,
-				t.boskosHeartbeatClose,
-			)
+			t.lease = lease
 
+			resources, err := t.lease.Acquire(t.GCPProjectType, 1)
 			if err != nil {
 				return fmt.Errorf("init failed to get project from boskos: %s", err)
 			}
-			t.GCPProject = resource.Name
+			t.GCPProject = resources[0].Name
 			klog.V(1).Infof("got project %s from boskos", t.GCPProject)
 		}
 	}
 
 	defer func() {
-		if t.boskos != nil {
+		if t.lease != nil {
 			klog.V(1).Info("releasing boskos project")
-			err := boskos.Release(
-				t.boskos,
-				[]string{t.GCPProject},
-				t.boskosHeartbeatClose,
-			)
-			if err != nil {
+			if err := t.lease.Release(); err != nil {
 				klog.Errorf("failed to release boskos project: %v", err)
 			}
 		}
@@ -275,9 +259,23 @@ func (t *Tester) Test() error {
 	var args []string
 	args = append(args, target)
 	args = append(args, t.constructArgs()...)
-	cmd := exec.Command("make", args...)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "make", args...)
 	cmd.SetDir(t.RepoRoot)
 	exec.InheritOutput(cmd)
+	// stop the tests if the lease of the project is lost, so that the
+	// lease is released and the instances are not used after cleanup
+	if t.lease != nil {
+		go func() {
+			select {
+			case <-t.lease.Lost():
+				klog.Errorf("The lease of project %s was lost, stopping the tests", t.GCPProject)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
 	return cmd.Run()
 }
 
@@ -19,6 +19,8 @@ limitations under the License.
 package types
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 )
 
@@ -62,6 +64,34 @@ type Options interface {
 	RunDir() string
 	// if this is true, kubetest2 will copy the RunDIR to ARTIFACTS
 	RundirInArtifacts() bool
+	// UpgradeTo returns the version a DeployerWithUpgrades is upgraded to
+	// after the first test pass, if set
+	UpgradeTo() string
+	// if this is true, the cluster is downgraded back to its initial version
+	// after the test pass following UpgradeTo
+	ShouldDowngrade() bool
+	// if this is true, kubetest2 will report the resources the tester leaves
+	// behind in the cluster as a failed JUnit test case
+	ShouldCheckLeaks() bool
+	// ProfileInterval returns how often kubetest2 takes pprof profiles of the
+	// control plane and the nodes while testing, 0 if it does not
+	ProfileInterval() time.Duration
+	// MonitorInterval returns how often kubetest2 records the events, the
+	// apiserver metrics and the node conditions while testing, 0 if it does not
+	MonitorInterval() time.Duration
+	// if this is true and the deployer has Windows nodes, kubetest2 runs a
+	// test pass for the Linux nodes and another for the Windows nodes
+	ShouldTestMixedOS() bool
+	// WindowsTest returns the tester of the Windows test pass of a mixed-OS
+	// run, empty for the one of --test
+	WindowsTest() string
+	// WindowsTestArgs returns the shell quoted arguments of the tester of the
+	// Windows test pass of a mixed-OS run, empty for those of --test
+	WindowsTestArgs() string
+	// if this is true, kubetest2 builds with the boringcrypto FIPS 140 module
+	// and the deployer deploys FIPS enabled nodes if it supports it, see
+	// DeployerWithFIPS
+	FIPS() bool
 }
 
 // Deployer defines the interface between kubetest and a deployer
@@ -105,6 +135,20 @@ type DeployerWithProvider interface {
 	Provider() string
 }
 
+// DeployerTestArgsEnv is the environment variable the test args of a
+// DeployerWithTestArgs are passed to the tester in, shell quoted.
+const DeployerTestArgsEnv = "KUBETEST2_DEPLOYER_TEST_ARGS"
+
+// DeployerWithTestArgs adds the ability to supply the provider specific
+// arguments of e2e.test, e.g. --provider or --gce-zone, so that they need
+// not be repeated in the tester args.
+type DeployerWithTestArgs interface {
+	Deployer
+
+	// TestArgs returns the e2e.test arguments describing the cluster.
+	TestArgs() ([]string, error)
+}
+
 // DeployerWithPostTester adds the ability to define after-test behavior
 // based on the results of the test.
 type DeployerWithPostTester interface {
@@ -115,6 +159,151 @@ type DeployerWithPostTester interface {
 	PostTest(testErr error) error
 }
 
+// UpgradeStep is a single step of an in-place cluster upgrade.
+type UpgradeStep struct {
+	// Name identifies the step in the results, e.g. UpgradeControlPlane.
+	Name string
+	// Run performs the upgrade step.
+	Run func() error
+}
+
+// DeployerWithUpgrades adds the ability to upgrade the cluster in place.
+//
+// The upgrade steps configured by the flags of the deployer are run after
+// the initial test pass, and the tester is re-run after each step.
+// Alternatively, with --upgrade-to, kubetest2 moves the whole cluster to the
+// version of its choice, tests it, and with --downgrade moves it back and
+// tests it again. Both cannot be used together.
+type DeployerWithUpgrades interface {
+	Deployer
+
+	// UpgradeSteps returns the upgrade steps to run in order, if any.
+	UpgradeSteps() []UpgradeStep
+	// CurrentVersion returns the version the cluster runs at.
+	CurrentVersion() (string, error)
+	// Upgrade moves the control plane and then the nodes to version, which
+	// is older than the current one when downgrading.
+	Upgrade(version string) error
+}
+
+// DeployerWithVersionSkew adds the ability to report the kubernetes versions
+// of the control plane and the nodes, so that the skew between them is
+// recorded in the metadata of the run for test selection.
+type DeployerWithVersionSkew interface {
+	Deployer
+
+	// VersionSkew returns the versions of the control plane and the nodes,
+	// empty if not set explicitly.
+	VersionSkew() (controlPlane, node string)
+}
+
+// DeployerWithCloudInventory adds the ability to list the cloud resources
+// of the cluster, so that those left behind by the tests are reported as
+// leaks along with the leaked kubernetes resources.
+type DeployerWithCloudInventory interface {
+	Deployer
+
+	// CloudResources returns the cloud resources the tests may create for
+	// the cluster, e.g. load balancers or disks, each named as kind/name.
+	CloudResources() ([]string, error)
+}
+
+// DeployerStateFile is the file of the run dir the state of a
+// DeployerWithState is persisted to.
+const DeployerStateFile = "deployer-state.json"
+
+// DeployerWithState adds the ability to persist the state Down needs into the
+// run dir, so that a run that died before Down can be torn down with
+// --down --run-id=<the id of that run>.
+type DeployerWithState interface {
+	Deployer
+
+	// State returns the state Down needs as JSON, e.g. the project, the
+	// location and the names of the resources created by Up.
+	State() ([]byte, error)
+	// RestoreState restores the State of a previous run before Down. The
+	// flags set explicitly take precedence over the state.
+	RestoreState(state []byte) error
+}
+
+// The operating systems of the nodes of a DeployerWithNodeOS.
+const (
+	NodeOSLinux   = "linux"
+	NodeOSWindows = "windows"
+)
+
+// NodeOSEnv is the environment variable the operating system of the nodes a
+// test pass of a mixed-OS run is for is passed to the tester in, one of
+// NodeOSLinux or NodeOSWindows.
+const NodeOSEnv = "KUBETEST2_NODE_OS"
+
+// DeployerWithNodeOS adds the ability to report the operating systems of the
+// nodes of the cluster, so that the runner can test mixed-OS clusters with a
+// test pass per operating system.
+type DeployerWithNodeOS interface {
+	Deployer
+
+	// NodeOS returns the operating systems of the nodes of the cluster,
+	// e.g. NodeOSLinux and NodeOSWindows.
+	NodeOS() []string
+}
+
+// DeployerWithFIPS adds the ability to deploy nodes running a FIPS enabled
+// image with Options.FIPS().
+type DeployerWithFIPS interface {
+	Deployer
+
+	// FIPSNodes returns true if the nodes of the cluster run a FIPS enabled
+	// image.
+	FIPSNodes() bool
+}
+
+// The features of the cluster a DeployerWithFeatures may support.
+const (
+	// FeatureLoadBalancer is the support of Services of type LoadBalancer.
+	FeatureLoadBalancer = "LoadBalancer"
+	// FeaturePersistentVolumes is the dynamic provisioning of
+	// PersistentVolumes by a default StorageClass.
+	FeaturePersistentVolumes = "PersistentVolumes"
+	// FeatureIPv6 is IPv6 single-stack networking.
+	FeatureIPv6 = "IPv6"
+	// FeatureDualStack is IPv4/IPv6 dual-stack networking.
+	FeatureDualStack = "DualStack"
+	// FeatureWindows is the presence of Windows nodes.
+	FeatureWindows = "Windows"
+	// FeatureNodeSSH is ssh access to the nodes from the tester.
+	FeatureNodeSSH = "NodeSSH"
+)
+
+// DeployerFeaturesEnv is the environment variable the features supported by
+// a DeployerWithFeatures are passed to the tester in, comma separated. It is
+// unset if the deployer does not report its features, in which case none of
+// them should be assumed unsupported.
+const DeployerFeaturesEnv = "KUBETEST2_DEPLOYER_FEATURES"
+
+// DeployerWithFeatures adds the ability to report the features the cluster
+// supports, so that testers can skip the tests of those it does not instead
+// of failing them.
+type DeployerWithFeatures interface {
+	Deployer
+
+	// Features returns the features the cluster supports, e.g.
+	// FeatureLoadBalancer. The features not listed are unsupported.
+	Features() []string
+}
+
+// DeployerWithLease adds the ability to report that the lease of the
+// resources the cluster runs on, e.g. projects acquired from boskos, was lost
+// during the run, after which they may be cleaned up under the tests. The
+// runner then stops the tester and tears the cluster down as usual.
+type DeployerWithLease interface {
+	Deployer
+
+	// LeaseLost returns a channel closed when the lease is lost, nil if the
+	// cluster does not run on leased resources.
+	LeaseLost() <-chan struct{}
+}
+
 // DeployerWithVersion allows the deployer to specify it's version
 type DeployerWithVersion interface {
 	Deployer
@@ -128,4 +317,7 @@ type DeployerWithVersion interface {
 type Tester struct {
 	TesterPath string
 	TesterArgs []string
+	// TesterEnv is passed to the tester on top of the kubetest2 environment,
+	// e.g. the NodeOSEnv of a test pass of a mixed-OS run
+	TesterEnv []string
 }